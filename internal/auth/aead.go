@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// envelopeMagic starts every encrypted packet; it cannot start a JSON message
+var envelopeMagic = []byte{'M', 'C', 'A', 'E'}
+
+const (
+	envelopeVersion = 1
+	nonceSize       = 12
+	sessionSize     = 8
+)
+
+// CipherStatus is the outcome of decrypting a packet
+type CipherStatus int
+
+// Decryption outcomes
+const (
+	// Decrypted packets were encrypted with a known key and opened successfully
+	Decrypted CipherStatus = iota
+	// NotEncrypted packets carry no encryption envelope
+	NotEncrypted
+	// UnknownCipherKey packets name a key ID that is not in the key ring
+	UnknownCipherKey
+	// KeyMismatch packets name a known key ID but do not open with its secret,
+	// because the sender's key differs or the packet was modified
+	KeyMismatch
+	// Malformed packets start like an envelope but cannot be parsed
+	Malformed
+)
+
+// String returns a short description of the status
+func (s CipherStatus) String() string {
+	switch s {
+	case Decrypted:
+		return "decrypted"
+	case NotEncrypted:
+		return "not encrypted"
+	case UnknownCipherKey:
+		return "unknown key"
+	case KeyMismatch:
+		return "key mismatch"
+	case Malformed:
+		return "malformed"
+	}
+	return fmt.Sprintf("status %d", int(s))
+}
+
+// IsEncrypted reports whether data starts with an encryption envelope
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// Encrypt seals plaintext with AES-256-GCM under a key derived from key's
+// secret and the sender's random 64-bit session, so senders sharing a secret
+// never share a key. The 96-bit nonce is the low 32 bits of the session
+// followed by the sequence number, so it never repeats for one session as long
+// as the sequence does not.
+//
+// Envelope: "MCAE" | version | key ID length | key ID | session | sequence |
+// ciphertext+tag, with everything before the ciphertext authenticated as
+// additional data.
+func Encrypt(key Key, session, sequence uint64, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key, session)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+2+len(key.ID)+sessionSize+8)
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion, byte(len(key.ID)))
+	header = append(header, key.ID...)
+	header = binary.BigEndian.AppendUint64(header, session)
+	header = binary.BigEndian.AppendUint64(header, sequence)
+
+	nonce := header[len(header)-nonceSize:]
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Decrypt opens an encrypted packet with the matching key from ring. Data
+// without an envelope is returned unchanged with NotEncrypted.
+func Decrypt(ring *KeyRing, data []byte) ([]byte, string, CipherStatus) {
+	if !IsEncrypted(data) {
+		return data, "", NotEncrypted
+	}
+
+	rest := data[len(envelopeMagic):]
+	if len(rest) < 2 || rest[0] != envelopeVersion {
+		return nil, "", Malformed
+	}
+	idLen := int(rest[1])
+	headerLen := len(envelopeMagic) + 2 + idLen + sessionSize + 8
+	if len(data) < headerLen {
+		return nil, "", Malformed
+	}
+	keyID := string(rest[2 : 2+idLen])
+
+	key, err := ring.Key(keyID)
+	if err != nil || keyID == "" {
+		return nil, keyID, UnknownCipherKey
+	}
+
+	session := binary.BigEndian.Uint64(data[headerLen-sessionSize-8:])
+	aead, err := newAEAD(key, session)
+	if err != nil {
+		return nil, keyID, KeyMismatch
+	}
+
+	header := data[:headerLen]
+	nonce := header[headerLen-nonceSize:]
+	plaintext, err := aead.Open(nil, nonce, data[headerLen:], header)
+	if err != nil {
+		return nil, keyID, KeyMismatch
+	}
+
+	return plaintext, keyID, Decrypted
+}
+
+// newAEAD creates AES-256-GCM with the key of one session
+func newAEAD(key Key, session uint64) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sessionKey(key, session))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// secretKey derives a key from the shared secret, so secrets of any length
+// work and are never used directly for both HMAC and encryption
+func secretKey(key Key) []byte {
+	derive := hmac.New(sha256.New, key.Secret)
+	derive.Write([]byte("mcaster aes-256-gcm"))
+	return derive.Sum(nil)
+}
+
+// sessionKey derives the key of a session with HKDF-Expand (RFC 5869), using
+// the key derived from the secret as the pseudorandom key and the session as
+// info; 32 bytes take a single HMAC block
+func sessionKey(key Key, session uint64) []byte {
+	expand := hmac.New(sha256.New, secretKey(key))
+	expand.Write([]byte("mcaster session"))
+	expand.Write(binary.BigEndian.AppendUint64(nil, session))
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
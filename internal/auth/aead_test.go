@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	ring, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+
+	plaintext := []byte(`{"id":1,"timestamp":"2024-01-01T00:00:00Z","source":"host"}`)
+	data, err := Encrypt(k1, 0x01020304, 1, plaintext)
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(data))
+	assert.False(t, bytes.Contains(data, []byte("host")))
+
+	opened, keyID, status := Decrypt(ring, data)
+	assert.Equal(t, Decrypted, status)
+	assert.Equal(t, "k1", keyID)
+	assert.Equal(t, plaintext, opened)
+
+	// The nonce changes with the sequence, and with it the ciphertext
+	next, err := Encrypt(k1, 0x01020304, 2, plaintext)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, next)
+}
+
+func TestEncryptSessions(t *testing.T) {
+	ring, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+
+	// Sessions differing only in their high 32 bits, numbering their packets
+	// alike, must not seal under the same key and nonce
+	plaintext := []byte(`{"id":1}`)
+	first, err := Encrypt(k1, 0x00000001_0badcafe, 1, plaintext)
+	require.NoError(t, err)
+	second, err := Encrypt(k1, 0x00000002_0badcafe, 1, plaintext)
+	require.NoError(t, err)
+	assert.NotEqual(t, sessionKey(k1, 0x00000001_0badcafe), sessionKey(k1, 0x00000002_0badcafe))
+	headerLen := len(envelopeMagic) + 2 + len(k1.ID) + sessionSize + 8
+	assert.NotEqual(t, first[headerLen:], second[headerLen:])
+
+	for _, data := range [][]byte{first, second} {
+		opened, _, status := Decrypt(ring, data)
+		assert.Equal(t, Decrypted, status)
+		assert.Equal(t, plaintext, opened)
+	}
+}
+
+func TestDecryptStatus(t *testing.T) {
+	ring, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+
+	plaintext := []byte(`{"id":1}`)
+	good, err := Encrypt(k1, 7, 1, plaintext)
+	require.NoError(t, err)
+	wrongSecret, err := Encrypt(Key{ID: "k1", Secret: []byte("a-different-secret-value")}, 7, 1, plaintext)
+	require.NoError(t, err)
+	unknown, err := Encrypt(Key{ID: "k9", Secret: k1.Secret}, 7, 1, plaintext)
+	require.NoError(t, err)
+	modified := append([]byte(nil), good...)
+	modified[len(modified)-1] ^= 0xff
+	relabelled := append([]byte(nil), good...)
+	relabelled[len(envelopeMagic)+3] = '0' // k1 -> k0 breaks the additional data
+
+	tests := []struct {
+		name   string
+		data   []byte
+		status CipherStatus
+	}{
+		{name: "decrypted", data: good, status: Decrypted},
+		{name: "plain JSON", data: plaintext, status: NotEncrypted},
+		{name: "wrong secret", data: wrongSecret, status: KeyMismatch},
+		{name: "modified ciphertext", data: modified, status: KeyMismatch},
+		{name: "modified key ID", data: relabelled, status: KeyMismatch},
+		{name: "unknown key", data: unknown, status: UnknownCipherKey},
+		{name: "truncated header", data: good[:8], status: Malformed},
+		{name: "unsupported version", data: append([]byte("MCAE\x09"), good[5:]...), status: Malformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, status := Decrypt(ring, tt.data)
+			assert.Equal(t, tt.status, status)
+			assert.NotEmpty(t, status.String())
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package auth protects mcaster messages with shared keys: an HMAC-SHA256
+// trailer lets receivers reject spoofed or modified test traffic, and AES-GCM
+// encryption keeps message contents private on shared networks.
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// trailerPrefix starts the line appended to signed messages. Encoded messages
+// never contain a raw newline, so the last one always starts the trailer.
+const trailerPrefix = "\nmcaster-hmac-sha256:"
+
+// MinSecretLength is the shortest secret accepted, in bytes
+const MinSecretLength = 16
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// Status is the outcome of verifying a packet
+type Status int
+
+// Verification outcomes
+const (
+	// Authenticated packets carry a valid trailer made with a known key
+	Authenticated Status = iota
+	// Unauthenticated packets carry no trailer at all
+	Unauthenticated
+	// UnknownKey packets are signed with a key ID that is not in the key ring
+	UnknownKey
+	// Tampered packets carry a trailer that does not match their contents
+	Tampered
+)
+
+// String returns a short description of the status
+func (s Status) String() string {
+	switch s {
+	case Authenticated:
+		return "authenticated"
+	case Unauthenticated:
+		return "unauthenticated"
+	case UnknownKey:
+		return "unknown key"
+	case Tampered:
+		return "tampered"
+	}
+	return fmt.Sprintf("status %d", int(s))
+}
+
+// Key is a shared secret identified by a key ID
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyRing holds the keys a receiver accepts; several keys allow rotation
+type KeyRing struct {
+	keys []Key
+}
+
+// LoadKeyFile reads a key file with one "<key-id> <secret>" pair per line. The
+// secret is taken literally unless prefixed with hex: or base64:. Empty lines
+// and lines starting with # are ignored.
+func LoadKeyFile(path string) (*KeyRing, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer file.Close()
+
+	ring, err := ParseKeys(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return ring, nil
+}
+
+// ParseKeys reads keys in the key file format
+func ParseKeys(r io.Reader) (*KeyRing, error) {
+	ring := &KeyRing{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<key-id> <secret>\"", line)
+		}
+
+		key, err := parseKey(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, err := ring.Key(key.ID); err == nil {
+			return nil, fmt.Errorf("line %d: duplicate key ID %s", line, key.ID)
+		}
+		ring.keys = append(ring.keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ring.keys) == 0 {
+		return nil, fmt.Errorf("no keys found")
+	}
+	return ring, nil
+}
+
+func parseKey(id, secret string) (Key, error) {
+	if !keyIDPattern.MatchString(id) {
+		return Key{}, fmt.Errorf("invalid key ID %q: use up to 32 letters, digits, '.', '_' or '-'", id)
+	}
+
+	var (
+		decoded []byte
+		err     error
+	)
+	switch {
+	case strings.HasPrefix(secret, "hex:"):
+		decoded, err = hex.DecodeString(strings.TrimPrefix(secret, "hex:"))
+	case strings.HasPrefix(secret, "base64:"):
+		decoded, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "base64:"))
+	default:
+		decoded = []byte(secret)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid secret for key %s: %w", id, err)
+	}
+	if len(decoded) < MinSecretLength {
+		return Key{}, fmt.Errorf("secret for key %s is too short: use at least %d bytes", id, MinSecretLength)
+	}
+
+	return Key{ID: id, Secret: decoded}, nil
+}
+
+// Key returns the key with the given ID, or the first key if id is empty
+func (k *KeyRing) Key(id string) (Key, error) {
+	if id == "" && len(k.keys) > 0 {
+		return k.keys[0], nil
+	}
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("key %q not found", id)
+}
+
+// IDs returns the key IDs in file order
+func (k *KeyRing) IDs() []string {
+	ids := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		ids[i] = key.ID
+	}
+	return ids
+}
+
+// Sign appends an HMAC-SHA256 trailer computed with key over payload
+func Sign(key Key, payload []byte) []byte {
+	trailer := trailerPrefix + key.ID + ":" + base64.StdEncoding.EncodeToString(computeMAC(key, payload))
+	signed := make([]byte, 0, len(payload)+len(trailer))
+	signed = append(signed, payload...)
+	return append(signed, trailer...)
+}
+
+// SplitTrailer separates a signed packet into its payload, key ID and MAC. ok is
+// false when data carries no trailer; a malformed trailer yields an empty MAC.
+func SplitTrailer(data []byte) (payload []byte, keyID string, mac []byte, ok bool) {
+	index := bytes.LastIndex(data, []byte(trailerPrefix))
+	if index < 0 {
+		return data, "", nil, false
+	}
+
+	trailer := string(data[index+len(trailerPrefix):])
+	keyID, encoded, found := strings.Cut(trailer, ":")
+	if !found {
+		return data[:index], keyID, nil, true
+	}
+	mac, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return data[:index], keyID, nil, true
+	}
+	return data[:index], keyID, mac, true
+}
+
+// Verify checks the trailer of a packet against the key ring and returns the
+// payload without the trailer
+func Verify(ring *KeyRing, data []byte) ([]byte, string, Status) {
+	payload, keyID, mac, ok := SplitTrailer(data)
+	if !ok {
+		return payload, "", Unauthenticated
+	}
+
+	key, err := ring.Key(keyID)
+	if err != nil || keyID == "" {
+		return payload, keyID, UnknownKey
+	}
+	if !hmac.Equal(mac, computeMAC(key, payload)) {
+		return payload, keyID, Tampered
+	}
+	return payload, keyID, Authenticated
+}
+
+func computeMAC(key Key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeys = `# current and previous key
+k2 hex:000102030405060708090a0b0c0d0e0f
+k1 this-is-a-long-enough-secret
+k0 base64:AAECAwQFBgcICQoLDA0ODw==
+`
+
+func TestParseKeys(t *testing.T) {
+	ring, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k2", "k1", "k0"}, ring.IDs())
+
+	first, err := ring.Key("")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", first.ID)
+	assert.Len(t, first.Secret, 16)
+
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("this-is-a-long-enough-secret"), k1.Secret)
+
+	k0, err := ring.Key("k0")
+	require.NoError(t, err)
+	assert.Equal(t, first.Secret, k0.Secret)
+
+	_, err = ring.Key("missing")
+	assert.Error(t, err)
+}
+
+func TestParseKeysErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{name: "empty", input: "# nothing\n", errMsg: "no keys found"},
+		{name: "missing secret", input: "k1\n", errMsg: "expected"},
+		{name: "short secret", input: "k1 short\n", errMsg: "too short"},
+		{name: "bad hex", input: "k1 hex:zz\n", errMsg: "invalid secret"},
+		{name: "bad key ID", input: "k/1 this-is-a-long-enough-secret\n", errMsg: "invalid key ID"},
+		{name: "duplicate", input: "k1 this-is-a-long-enough-secret\nk1 another-long-enough-secret\n", errMsg: "duplicate key ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeys(strings.NewReader(tt.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(testKeys), 0o600))
+
+	ring, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Len(t, ring.IDs(), 3)
+
+	_, err = LoadKeyFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestSignVerify(t *testing.T) {
+	ring, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	k1, _ := ring.Key("k1")
+
+	otherRing, err := ParseKeys(strings.NewReader("k9 some-other-long-secret\n"))
+	require.NoError(t, err)
+
+	payload := []byte(`{"id":1,"timestamp":"2024-01-02T03:04:05Z","source":"host"}`)
+	signed := Sign(k1, payload)
+	assert.True(t, strings.HasPrefix(string(signed), string(payload)))
+
+	tampered := []byte(strings.Replace(string(signed), `"id":1`, `"id":2`, 1))
+
+	// A trailer signed with a different secret under a known key ID
+	forged := Sign(Key{ID: "k1", Secret: []byte("an-attacker-guessed-secret")}, payload)
+
+	tests := []struct {
+		name     string
+		ring     *KeyRing
+		data     []byte
+		expected Status
+		keyID    string
+	}{
+		{name: "authenticated", ring: ring, data: signed, expected: Authenticated, keyID: "k1"},
+		{name: "unsigned", ring: ring, data: payload, expected: Unauthenticated},
+		{name: "modified payload", ring: ring, data: tampered, expected: Tampered, keyID: "k1"},
+		{name: "wrong secret", ring: ring, data: forged, expected: Tampered, keyID: "k1"},
+		{name: "key not in ring", ring: otherRing, data: signed, expected: UnknownKey, keyID: "k1"},
+		{name: "malformed trailer", ring: ring, data: append(append([]byte{}, payload...), "\nmcaster-hmac-sha256:k1"...), expected: Tampered, keyID: "k1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verified, keyID, status := Verify(tt.ring, tt.data)
+			assert.Equal(t, tt.expected, status)
+			assert.Equal(t, tt.keyID, keyID)
+			if tt.expected != Unauthenticated {
+				assert.NotContains(t, string(verified), "mcaster-hmac-sha256")
+			}
+		})
+	}
+}
+
+func TestSplitTrailer(t *testing.T) {
+	payload, keyID, mac, ok := SplitTrailer([]byte("plain"))
+	assert.False(t, ok)
+	assert.Equal(t, "plain", string(payload))
+	assert.Empty(t, keyID)
+	assert.Nil(t, mac)
+
+	key := Key{ID: "k1", Secret: []byte("this-is-a-long-enough-secret")}
+	payload, keyID, mac, ok = SplitTrailer(Sign(key, []byte("plain")))
+	assert.True(t, ok)
+	assert.Equal(t, "plain", string(payload))
+	assert.Equal(t, "k1", keyID)
+	assert.Len(t, mac, 32)
+}
+
+func TestStatusString(t *testing.T) {
+	assert.Equal(t, "authenticated", Authenticated.String())
+	assert.Equal(t, "unauthenticated", Unauthenticated.String())
+	assert.Equal(t, "unknown key", UnknownKey.String())
+	assert.Equal(t, "tampered", Tampered.String())
+}
@@ -0,0 +1,19 @@
+// Package clock waits for the timed steps of test runs
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep waits for d and reports whether ctx is still live
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
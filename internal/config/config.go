@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -8,12 +9,44 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	Group     string        `mapstructure:"group"`
-	Interface string        `mapstructure:"interface"`
-	Interval  time.Duration `mapstructure:"interval"`
-	TTL       int           `mapstructure:"ttl"`
-	SPort     int           `mapstructure:"sport"`
-	DPort     int           `mapstructure:"dport"`
+	Group        string        `mapstructure:"group"`
+	Interface    string        `mapstructure:"interface"`
+	Interval     time.Duration `mapstructure:"interval"`
+	TTL          int           `mapstructure:"ttl"`
+	SPort        int           `mapstructure:"sport"`
+	DPort        int           `mapstructure:"dport"`
+	Loopback     string        `mapstructure:"loopback"`
+	IgnoreLocal  bool          `mapstructure:"ignore-local"`
+	SourceIP     string        `mapstructure:"source-ip"`
+	BindToDevice bool          `mapstructure:"bind-device"`
+	Bind         string        `mapstructure:"bind"`
+	ReuseAddr    string        `mapstructure:"reuseaddr"`
+	ReusePort    string        `mapstructure:"reuseport"`
+	AllowUnicast bool          `mapstructure:"allow-unicast"`
+	Decode       string        `mapstructure:"decode"`
+	RTP          bool          `mapstructure:"rtp"`
+	RTPClockRate int           `mapstructure:"rtp-clock-rate"`
+	RTPPT        int           `mapstructure:"rtp-payload-type"`
+	RTPSSRC      uint32        `mapstructure:"rtp-ssrc"`
+	RTPPackets   int           `mapstructure:"rtp-packets-per-frame"`
+	RTPFrameRate float64       `mapstructure:"rtp-frame-rate"`
+	MPEGTS       bool          `mapstructure:"mpegts"`
+	TSBitrate    int           `mapstructure:"mpegts-bitrate"`
+	TSProgram    uint16        `mapstructure:"mpegts-program"`
+	TSPMTPID     uint16        `mapstructure:"mpegts-pmt-pid"`
+	TSPID        uint16        `mapstructure:"mpegts-pid"`
+	Announce     bool          `mapstructure:"announce"`
+	SAPInterval  time.Duration `mapstructure:"announce-interval"`
+	SAPGroup     string        `mapstructure:"sap-group"`
+	GroupRange   string        `mapstructure:"group-range"`
+	SenderID     string        `mapstructure:"sender-id"`
+	StreamID     string        `mapstructure:"stream-id"`
+	Labels       []string      `mapstructure:"label"`
+	PayloadFile  string        `mapstructure:"payload-file"`
+	AuthKeyFile  string        `mapstructure:"auth-key-file"`
+	AuthKeyID    string        `mapstructure:"auth-key-id"`
+	CryptKeyFile string        `mapstructure:"encrypt-key-file"`
+	CryptKeyID   string        `mapstructure:"encrypt-key-id"`
 }
 
 // Load reads configuration from file and environment
@@ -26,9 +59,42 @@ func Load() (*Config, error) {
 	viper.SetDefault("ttl", 1)
 	viper.SetDefault("sport", 0)
 	viper.SetDefault("dport", 0)
+	viper.SetDefault("loopback", "on")
+	viper.SetDefault("ignore-local", false)
+	viper.SetDefault("source-ip", "")
+	viper.SetDefault("bind-device", false)
+	viper.SetDefault("bind", "group")
+	viper.SetDefault("reuseaddr", "on")
+	viper.SetDefault("reuseport", "off")
+	viper.SetDefault("allow-unicast", false)
+	viper.SetDefault("decode", "auto")
+	viper.SetDefault("rtp", false)
+	viper.SetDefault("rtp-clock-rate", 0)
+	viper.SetDefault("rtp-payload-type", 96)
+	viper.SetDefault("rtp-ssrc", 0)
+	viper.SetDefault("rtp-packets-per-frame", 1)
+	viper.SetDefault("rtp-frame-rate", 25.0)
+	viper.SetDefault("mpegts", false)
+	viper.SetDefault("mpegts-bitrate", 2000000)
+	viper.SetDefault("mpegts-program", 1)
+	viper.SetDefault("mpegts-pmt-pid", 0x1000)
+	viper.SetDefault("mpegts-pid", 0x100)
+	viper.SetDefault("announce", false)
+	viper.SetDefault("announce-interval", 10*time.Second)
+	viper.SetDefault("sap-group", "224.2.127.254:9875")
+	viper.SetDefault("group-range", "")
+	viper.SetDefault("sender-id", "")
+	viper.SetDefault("stream-id", "")
+	viper.SetDefault("label", []string{})
+	viper.SetDefault("payload-file", "")
+	viper.SetDefault("auth-key-file", "")
+	viper.SetDefault("auth-key-id", "")
+	viper.SetDefault("encrypt-key-file", "")
+	viper.SetDefault("encrypt-key-id", "")
 
 	// Environment variables
 	viper.SetEnvPrefix("MULTICAST")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 
 	if err := viper.Unmarshal(&cfg); err != nil {
@@ -25,6 +25,36 @@ func TestConfigDefaults(t *testing.T) {
 	assert.Equal(t, 0, cfg.SPort)
 	assert.Equal(t, 0, cfg.DPort)
 	assert.Equal(t, "", cfg.Interface) // Interface should be empty by default
+	assert.Equal(t, "on", cfg.Loopback)
+	assert.False(t, cfg.IgnoreLocal)
+	assert.Equal(t, "group", cfg.Bind)
+	assert.Equal(t, "on", cfg.ReuseAddr)
+	assert.Equal(t, "off", cfg.ReusePort)
+	assert.False(t, cfg.AllowUnicast)
+	assert.Empty(t, cfg.GroupRange)
+	assert.Equal(t, "auto", cfg.Decode)
+	assert.False(t, cfg.RTP)
+	assert.Equal(t, 0, cfg.RTPClockRate)
+	assert.Equal(t, 96, cfg.RTPPT)
+	assert.Zero(t, cfg.RTPSSRC)
+	assert.Equal(t, 1, cfg.RTPPackets)
+	assert.Equal(t, 25.0, cfg.RTPFrameRate)
+	assert.False(t, cfg.MPEGTS)
+	assert.Equal(t, 2000000, cfg.TSBitrate)
+	assert.Equal(t, uint16(1), cfg.TSProgram)
+	assert.Equal(t, uint16(0x1000), cfg.TSPMTPID)
+	assert.Equal(t, uint16(0x100), cfg.TSPID)
+	assert.False(t, cfg.Announce)
+	assert.Equal(t, 10*time.Second, cfg.SAPInterval)
+	assert.Equal(t, "224.2.127.254:9875", cfg.SAPGroup)
+	assert.Empty(t, cfg.SenderID)
+	assert.Empty(t, cfg.StreamID)
+	assert.Empty(t, cfg.Labels)
+	assert.Empty(t, cfg.PayloadFile)
+	assert.Empty(t, cfg.AuthKeyFile)
+	assert.Empty(t, cfg.AuthKeyID)
+	assert.Empty(t, cfg.CryptKeyFile)
+	assert.Empty(t, cfg.CryptKeyID)
 }
 
 func TestConfigEnvironmentOverrides(t *testing.T) {
@@ -284,6 +314,37 @@ func clearEnvVars() {
 		"MULTICAST_TTL",
 		"MULTICAST_SPORT",
 		"MULTICAST_DPORT",
+		"MULTICAST_LOOPBACK",
+		"MULTICAST_IGNORE_LOCAL",
+		"MULTICAST_SOURCE_IP",
+		"MULTICAST_BIND_DEVICE",
+		"MULTICAST_BIND",
+		"MULTICAST_REUSEADDR",
+		"MULTICAST_REUSEPORT",
+		"MULTICAST_ALLOW_UNICAST",
+		"MULTICAST_DECODE",
+		"MULTICAST_RTP",
+		"MULTICAST_RTP_CLOCK_RATE",
+		"MULTICAST_RTP_PAYLOAD_TYPE",
+		"MULTICAST_RTP_SSRC",
+		"MULTICAST_RTP_PACKETS_PER_FRAME",
+		"MULTICAST_RTP_FRAME_RATE",
+		"MULTICAST_MPEGTS",
+		"MULTICAST_MPEGTS_BITRATE",
+		"MULTICAST_MPEGTS_PROGRAM",
+		"MULTICAST_MPEGTS_PMT_PID",
+		"MULTICAST_MPEGTS_PID",
+		"MULTICAST_ANNOUNCE",
+		"MULTICAST_ANNOUNCE_INTERVAL",
+		"MULTICAST_SAP_GROUP",
+		"MULTICAST_GROUP_RANGE",
+		"MULTICAST_SENDER_ID",
+		"MULTICAST_STREAM_ID",
+		"MULTICAST_PAYLOAD_FILE",
+		"MULTICAST_AUTH_KEY_FILE",
+		"MULTICAST_AUTH_KEY_ID",
+		"MULTICAST_ENCRYPT_KEY_FILE",
+		"MULTICAST_ENCRYPT_KEY_ID",
 	}
 
 	for _, envVar := range envVars {
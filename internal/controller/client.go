@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// clientTimeout bounds each request to an agent
+const clientTimeout = 10 * time.Second
+
+// Client calls the API of an agent, as served by "mcaster agent" or "mcaster serve"
+type Client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// NewClient creates a client of the agent at addr, a host:port or a URL,
+// sending token to agents that require one
+func NewClient(addr, token string) *Client {
+	base := strings.TrimSuffix(addr, "/")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return &Client{base: base, token: token, http: &http.Client{Timeout: clientTimeout}}
+}
+
+func (c *Client) String() string {
+	return c.base
+}
+
+// Info describes the agent's host
+func (c *Client) Info(ctx context.Context) (server.Info, error) {
+	var info server.Info
+	err := c.do(ctx, http.MethodGet, "/api/info", nil, &info)
+	return info, err
+}
+
+// Create starts a job on the agent
+func (c *Client) Create(ctx context.Context, spec server.JobSpec) (server.JobInfo, error) {
+	var info server.JobInfo
+	err := c.do(ctx, http.MethodPost, "/api/jobs", spec, &info)
+	return info, err
+}
+
+// Stop stops a job, keeping its results
+func (c *Client) Stop(ctx context.Context, id string) (server.JobInfo, error) {
+	var info server.JobInfo
+	err := c.do(ctx, http.MethodPost, "/api/jobs/"+id+"/stop", nil, &info)
+	return info, err
+}
+
+// Summary returns the totals of a job
+func (c *Client) Summary(ctx context.Context, id string) (server.Summary, error) {
+	var summary server.Summary
+	err := c.do(ctx, http.MethodGet, "/api/jobs/"+id+"/summary", nil, &summary)
+	return summary, err
+}
+
+// Remove stops a job and makes the agent forget it
+func (c *Client) Remove(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/jobs/"+id, nil, nil)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON result;
+// errors reported by the agent are returned with its message
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach agent %s: %w", c.base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("agent %s: %s", c.base, apiErr.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response of agent %s: %w", c.base, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+func TestNewClient(t *testing.T) {
+	assert.Equal(t, "http://192.0.2.1:7400", NewClient("192.0.2.1:7400", "").String())
+	assert.Equal(t, "https://agent.example:7400", NewClient("https://agent.example:7400/", "").String())
+}
+
+func TestClient(t *testing.T) {
+	m := server.NewManager(io.Discard)
+	defer m.Shutdown()
+	srv := httptest.NewServer(server.NewHandler(m))
+	defer srv.Close()
+	client := NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	info, err := client.Info(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Hostname)
+
+	job, err := client.Create(ctx, server.JobSpec{Type: server.TypeReceiver, Group: "239.23.23.55:23258"})
+	require.NoError(t, err)
+	assert.Equal(t, server.StateRunning, job.State)
+
+	job, err = client.Stop(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, server.StateStopped, job.State)
+
+	summary, err := client.Summary(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, summary.ID)
+	require.NotNil(t, summary.Received)
+
+	require.NoError(t, client.Remove(ctx, job.ID))
+
+	// Errors carry the agent's message
+	_, err = client.Summary(ctx, job.ID)
+	assert.ErrorContains(t, err, "job not found")
+	_, err = client.Create(ctx, server.JobSpec{Type: "relay"})
+	assert.ErrorContains(t, err, "job type must be sender or receiver")
+
+	// Errors without a JSON body carry the status
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	_, err = NewClient(plain.URL, "").Info(ctx)
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	_, err = NewClient("127.0.0.1:1", "").Info(ctx)
+	assert.ErrorContains(t, err, "failed to reach agent")
+}
+
+func TestClientToken(t *testing.T) {
+	m := server.NewManager(io.Discard)
+	defer m.Shutdown()
+	srv := httptest.NewServer(server.RequireToken(server.NewHandler(m), "s3cret"))
+	defer srv.Close()
+	ctx := context.Background()
+
+	_, err := NewClient(srv.URL, "s3cret").Info(ctx)
+	assert.NoError(t, err)
+	_, err = NewClient(srv.URL, "").Info(ctx)
+	assert.ErrorContains(t, err, "missing or invalid token")
+	_, err = NewClient(srv.URL, "wrong").Info(ctx)
+	assert.ErrorContains(t, err, "missing or invalid token")
+}
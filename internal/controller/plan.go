@@ -0,0 +1,184 @@
+// Package controller runs test plans across hosts: it starts receivers and
+// then senders through the agents on the hosts and gathers what every
+// receiver got of every group.
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// Default timings of a plan
+const (
+	DefaultDuration = 10 * time.Second
+	DefaultSettle   = 2 * time.Second
+	DefaultDrain    = time.Second
+)
+
+// Plan describes a test across hosts: the agents to use and the senders and
+// receivers each of them runs
+type Plan struct {
+	// Agents maps agent names to the host:port their API listens on
+	Agents map[string]string `yaml:"agents"`
+	// Duration is how long the senders send
+	Duration server.Duration `yaml:"duration"`
+	// Settle is the time between starting the receivers and the senders, so
+	// joins can propagate through the network
+	Settle server.Duration `yaml:"settle"`
+	// Drain is the time between stopping the senders and the receivers, so
+	// packets in flight arrive
+	Drain     server.Duration `yaml:"drain"`
+	Senders   []Endpoint      `yaml:"senders"`
+	Receivers []Endpoint      `yaml:"receivers"`
+	// Token is sent to agents that require one; it is kept out of plan files
+	Token string `yaml:"-"`
+}
+
+// Endpoint is a sender or receiver run by an agent
+type Endpoint struct {
+	Agent string `yaml:"agent"`
+	// Name labels a receiver in the results (default: its agent's name)
+	Name           string `yaml:"name,omitempty"`
+	server.JobSpec `yaml:",inline"`
+}
+
+// Groups returns the addresses an endpoint sends to or receives
+func (e Endpoint) Groups() ([]string, error) {
+	spec := e.JobSpec.WithDefaults()
+	addrs, err := network.ResolveGroups(spec.Group, spec.DPort)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, len(addrs))
+	for i, addr := range addrs {
+		groups[i] = addr.String()
+	}
+	return groups, nil
+}
+
+// Load reads a plan from a YAML file
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	plan, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// Parse reads a plan from YAML, fills in the defaults and validates it
+func Parse(data []byte) (*Plan, error) {
+	var plan Plan
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Duration == 0 {
+		plan.Duration = server.Duration(DefaultDuration)
+	}
+	if plan.Settle == 0 {
+		plan.Settle = server.Duration(DefaultSettle)
+	}
+	if plan.Drain == 0 {
+		plan.Drain = server.Duration(DefaultDrain)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// validate checks a plan and names its receivers
+func (p *Plan) validate() error {
+	if p.Duration < 0 || p.Settle < 0 || p.Drain < 0 {
+		return fmt.Errorf("duration, settle and drain must not be negative")
+	}
+	if len(p.Senders) == 0 {
+		return fmt.Errorf("no senders")
+	}
+	if len(p.Receivers) == 0 {
+		return fmt.Errorf("no receivers")
+	}
+	for name, addr := range p.Agents {
+		if addr == "" {
+			return fmt.Errorf("agent %s has no address", name)
+		}
+	}
+
+	check := func(kind string, i int, e *Endpoint) error {
+		where := fmt.Sprintf("%s %d", kind, i+1)
+		if e.Agent == "" {
+			return fmt.Errorf("%s: no agent", where)
+		}
+		if _, ok := p.Agents[e.Agent]; !ok {
+			return fmt.Errorf("%s: unknown agent %q", where, e.Agent)
+		}
+		if e.Type != "" && e.Type != kind {
+			return fmt.Errorf("%s: type %q does not match the section", where, e.Type)
+		}
+		e.Type = kind
+		// The plan decides when jobs stop
+		if e.JobSpec.Duration != 0 {
+			return fmt.Errorf("%s: set the duration of the whole plan instead", where)
+		}
+		if _, err := e.Groups(); err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+		return nil
+	}
+	for i := range p.Senders {
+		if err := check(server.TypeSender, i, &p.Senders[i]); err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]int)
+	for i := range p.Receivers {
+		r := &p.Receivers[i]
+		if err := check(server.TypeReceiver, i, r); err != nil {
+			return err
+		}
+		if r.Name == "" {
+			r.Name = r.Agent
+		}
+		names[r.Name]++
+	}
+	// Receivers sharing an agent's name are numbered in plan order
+	seen := make(map[string]int)
+	for i := range p.Receivers {
+		r := &p.Receivers[i]
+		if names[r.Name] > 1 {
+			seen[r.Name]++
+			r.Name += "#" + strconv.Itoa(seen[r.Name])
+		}
+	}
+	return nil
+}
+
+// agentNames returns the agents the senders and receivers use, sorted
+func (p *Plan) agentNames() []string {
+	used := make(map[string]bool)
+	for _, e := range append(append([]Endpoint(nil), p.Senders...), p.Receivers...) {
+		used[e.Agent] = true
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
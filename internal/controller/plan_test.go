@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+const testPlan = `
+duration: 5s
+agents:
+  src: 192.0.2.10:7400
+  leaf: 192.0.2.20:7400
+  spine: 192.0.2.30:7400
+senders:
+  - agent: src
+    group: 239.1.1.1:5000
+    interface: eth0
+    interval: 100ms
+    ttl: 16
+    sender_id: a
+receivers:
+  - agent: leaf
+    group: 239.1.1.1,239.1.1.2
+    dport: 5000
+  - agent: spine
+    name: spine-rx
+  - agent: leaf
+    reuseport: true
+`
+
+func TestParse(t *testing.T) {
+	plan, err := Parse([]byte(testPlan))
+	require.NoError(t, err)
+
+	assert.Equal(t, server.Duration(5*time.Second), plan.Duration)
+	assert.Equal(t, server.Duration(DefaultSettle), plan.Settle)
+	assert.Equal(t, server.Duration(DefaultDrain), plan.Drain)
+	assert.Equal(t, []string{"leaf", "spine", "src"}, plan.agentNames())
+
+	require.Len(t, plan.Senders, 1)
+	sender := plan.Senders[0]
+	assert.Equal(t, server.TypeSender, sender.Type)
+	assert.Equal(t, "eth0", sender.Interface)
+	assert.Equal(t, server.Duration(100*time.Millisecond), sender.Interval)
+	assert.Equal(t, 16, sender.TTL)
+	assert.Equal(t, "a", sender.SenderID)
+
+	require.Len(t, plan.Receivers, 3)
+	for _, r := range plan.Receivers {
+		assert.Equal(t, server.TypeReceiver, r.Type)
+	}
+	// Receivers sharing an agent are numbered
+	assert.Equal(t, "leaf#1", plan.Receivers[0].Name)
+	assert.Equal(t, "spine-rx", plan.Receivers[1].Name)
+	assert.Equal(t, "leaf#2", plan.Receivers[2].Name)
+	assert.True(t, plan.Receivers[2].ReusePort)
+
+	groups, err := plan.Receivers[0].Groups()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"239.1.1.1:5000", "239.1.1.2:5000"}, groups)
+	// The default group of the receive command
+	groups, err = plan.Receivers[1].Groups()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"239.23.23.23:2323"}, groups)
+}
+
+func TestParseErrors(t *testing.T) {
+	const agents = "agents: {a: 127.0.0.1:7400}\n"
+	tests := []struct {
+		name string
+		plan string
+		err  string
+	}{
+		{name: "no senders", plan: agents + "receivers: [{agent: a}]", err: "no senders"},
+		{name: "no receivers", plan: agents + "senders: [{agent: a}]", err: "no receivers"},
+		{name: "no agent", plan: agents + "senders: [{group: 239.1.1.1:5000}]\nreceivers: [{agent: a}]", err: "sender 1: no agent"},
+		{name: "unknown agent", plan: agents + "senders: [{agent: a}]\nreceivers: [{agent: b}]", err: `receiver 1: unknown agent "b"`},
+		{name: "agent without address", plan: "agents: {a: ''}\nsenders: [{agent: a}]\nreceivers: [{agent: a}]", err: "agent a has no address"},
+		{name: "wrong type", plan: agents + "senders: [{agent: a, type: receiver}]\nreceivers: [{agent: a}]", err: "does not match the section"},
+		{name: "job duration", plan: agents + "senders: [{agent: a, duration: 5s}]\nreceivers: [{agent: a}]", err: "duration of the whole plan"},
+		{name: "invalid group", plan: agents + "senders: [{agent: a, group: 'nowhere:x'}]\nreceivers: [{agent: a}]", err: "sender 1"},
+		{name: "negative duration", plan: agents + "duration: -1s\nsenders: [{agent: a}]\nreceivers: [{agent: a}]", err: "must not be negative"},
+		{name: "invalid duration", plan: agents + "settle: soon\nsenders: [{agent: a}]\nreceivers: [{agent: a}]", err: "invalid duration"},
+		{name: "unknown field", plan: agents + "senders: [{agent: a, rate: 5}]\nreceivers: [{agent: a}]", err: "field rate not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.plan))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPlan), 0o644))
+	plan, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, plan.Receivers, 3)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read plan")
+
+	require.NoError(t, os.WriteFile(path, []byte("senders: 5"), 0o644))
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "invalid plan")
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+	"github.com/hyposcaler-bot/mcaster/internal/table"
+)
+
+// Results are what every receiver of a plan got of every group sent
+type Results struct {
+	// Groups are the groups the senders sent to, in plan order
+	Groups []string
+	// Sent counts the messages sent to each group
+	Sent      map[string]int
+	Receivers []ReceiverResult
+}
+
+// ReceiverResult is what one receiver got
+type ReceiverResult struct {
+	Name  string
+	Agent string
+	Host  string
+	// Joined are the groups the receiver joined
+	Joined map[string]bool
+	Groups map[string]GroupResult
+}
+
+// GroupResult totals the streams a receiver got on one group
+type GroupResult struct {
+	Received int
+	Lost     int
+	// DelayP50 and DelayP99 are the highest of the group's streams; they are
+	// only meaningful with synchronised clocks across hosts
+	DelayP50 time.Duration
+	DelayP99 time.Duration
+	HasDelay bool
+}
+
+// LossPercent returns the share of packets lost, in percent
+func (g GroupResult) LossPercent() float64 {
+	if g.Received+g.Lost == 0 {
+		return 0
+	}
+	return float64(g.Lost) * 100 / float64(g.Received+g.Lost)
+}
+
+// addReceiver adds up the streams of a receiver per group
+func (r *Results) addReceiver(e Endpoint, host string, joined []string, summary server.Summary) {
+	result := ReceiverResult{
+		Name:   e.Name,
+		Agent:  e.Agent,
+		Host:   host,
+		Joined: make(map[string]bool),
+		Groups: make(map[string]GroupResult),
+	}
+	for _, group := range joined {
+		result.Joined[group] = true
+	}
+	if summary.Received != nil {
+		for _, s := range summary.Received.PerStream {
+			g := result.Groups[s.Group]
+			g.Received += s.Received
+			g.Lost += s.Lost
+			if s.DelayP50 != nil {
+				g.HasDelay = true
+				g.DelayP50 = max(g.DelayP50, time.Duration(*s.DelayP50))
+				g.DelayP99 = max(g.DelayP99, time.Duration(*s.DelayP99))
+			}
+			result.Groups[s.Group] = g
+		}
+	}
+	r.Receivers = append(r.Receivers, result)
+}
+
+// Missing counts the groups sent that a receiver joined but got nothing of
+func (r *Results) Missing() int {
+	missing := 0
+	for _, receiver := range r.Receivers {
+		for _, group := range r.Groups {
+			if receiver.Joined[group] && receiver.Groups[group].Received == 0 {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// Lossy counts the groups a receiver got with loss
+func (r *Results) Lossy() int {
+	lossy := 0
+	for _, receiver := range r.Receivers {
+		for _, group := range r.Groups {
+			if g := receiver.Groups[group]; g.Received > 0 && g.Lost > 0 {
+				lossy++
+			}
+		}
+	}
+	return lossy
+}
+
+// Print writes the matrix of receivers by groups: ✓ for a group received
+// without loss, ! with loss and ✗ for a group joined but not received, with
+// the messages received of those sent, the loss and the median delay
+func (r *Results) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n📊 %d receiver(s) × %d group(s)\n", len(r.Receivers), len(r.Groups))
+
+	nameWidth := len("RECEIVER")
+	for _, receiver := range r.Receivers {
+		nameWidth = max(nameWidth, len(receiver.label()))
+	}
+	cellWidth := 26
+	for _, group := range r.Groups {
+		cellWidth = max(cellWidth, len(group))
+	}
+
+	widths := []int{nameWidth}
+	for range r.Groups {
+		widths = append(widths, cellWidth)
+	}
+	table.WriteRow(w, "", append([]string{"RECEIVER"}, r.Groups...), widths)
+	for _, receiver := range r.Receivers {
+		row := []string{receiver.label()}
+		for _, group := range r.Groups {
+			row = append(row, r.cell(receiver, group))
+		}
+		table.WriteRow(w, "", row, widths)
+	}
+
+	fmt.Fprintln(w)
+	missing, lossy := r.Missing(), r.Lossy()
+	switch {
+	case missing > 0:
+		fmt.Fprintf(w, "❌ %d joined group(s) not received, %d with loss\n", missing, lossy)
+	case lossy > 0:
+		fmt.Fprintf(w, "⚠️  Every joined group was received, %d with loss\n", lossy)
+	default:
+		fmt.Fprintf(w, "✅ Every receiver got every group it joined without loss\n")
+	}
+}
+
+// label names a receiver and the host its agent runs on
+func (r ReceiverResult) label() string {
+	if r.Host == "" || r.Host == r.Name {
+		return r.Name
+	}
+	return r.Name + " (" + r.Host + ")"
+}
+
+// cell describes what a receiver got of a group
+func (r *Results) cell(receiver ReceiverResult, group string) string {
+	g, got := receiver.Groups[group]
+	if !got || g.Received == 0 {
+		if receiver.Joined[group] {
+			return "✗ none"
+		}
+		return "-"
+	}
+	mark := "✓"
+	if g.Lost > 0 {
+		mark = "!"
+	}
+	delay := "-"
+	if g.HasDelay {
+		delay = table.FormatDelay(g.DelayP50)
+	}
+	return fmt.Sprintf("%s %d/%d %.1f%% %s", mark, g.Received, r.Sent[group], g.LossPercent(), delay)
+}
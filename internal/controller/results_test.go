@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+func TestResults(t *testing.T) {
+	results := &Results{Sent: make(map[string]int)}
+	results.addSender([]string{"239.1.1.1:5000"}, server.Summary{})
+	assert.Empty(t, results.Groups)
+
+	results.addSender([]string{"239.1.1.1:5000", "239.1.1.2:5000"}, senderSummary(100))
+	results.addSender([]string{"239.1.1.1:5000"}, senderSummary(50))
+	assert.Equal(t, []string{"239.1.1.1:5000", "239.1.1.2:5000"}, results.Groups)
+	assert.Equal(t, 150, results.Sent["239.1.1.1:5000"])
+	assert.Equal(t, 100, results.Sent["239.1.1.2:5000"])
+
+	p50, p99 := server.Duration(2*time.Millisecond), server.Duration(5*time.Millisecond)
+	later := server.Duration(3 * time.Millisecond)
+	results.addReceiver(Endpoint{Name: "leaf1", Agent: "leaf1"}, "host-1", []string{"239.1.1.1:5000", "239.1.1.2:5000"},
+		receiverSummary(
+			server.StreamReport{Group: "239.1.1.1:5000", Received: 100, DelayP50: &p50, DelayP99: &p99},
+			server.StreamReport{Group: "239.1.1.1:5000", Received: 50, DelayP50: &later, DelayP99: &p99},
+			server.StreamReport{Group: "239.1.1.2:5000", Received: 90, Lost: 10},
+		))
+	results.addReceiver(Endpoint{Name: "leaf2", Agent: "leaf2"}, "leaf2", []string{"239.1.1.2:5000"}, receiverSummary())
+
+	leaf1 := results.Receivers[0].Groups["239.1.1.1:5000"]
+	assert.Equal(t, 150, leaf1.Received)
+	assert.Equal(t, 3*time.Millisecond, leaf1.DelayP50)
+	assert.Equal(t, 5*time.Millisecond, leaf1.DelayP99)
+	assert.Equal(t, 10.0, results.Receivers[0].Groups["239.1.1.2:5000"].LossPercent())
+	assert.Zero(t, GroupResult{}.LossPercent())
+
+	assert.Equal(t, 1, results.Missing())
+	assert.Equal(t, 1, results.Lossy())
+
+	var out strings.Builder
+	results.Print(&out)
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "📊 2 receiver(s) × 2 group(s)", lines[1])
+	assert.Equal(t, "RECEIVER        239.1.1.1:5000              239.1.1.2:5000", lines[2])
+	assert.Equal(t, "leaf1 (host-1)  ✓ 150/150 0.0% 3ms          ! 90/100 10.0% -", lines[3])
+	assert.Equal(t, "leaf2           -                           ✗ none", lines[4])
+	assert.Contains(t, out.String(), "❌ 1 joined group(s) not received, 1 with loss")
+}
+
+func TestResultsPrintSummary(t *testing.T) {
+	results := &Results{Groups: []string{"239.1.1.1:5000"}, Sent: map[string]int{"239.1.1.1:5000": 10}}
+	results.addReceiver(Endpoint{Name: "leaf"}, "", []string{"239.1.1.1:5000"},
+		receiverSummary(server.StreamReport{Group: "239.1.1.1:5000", Received: 10}))
+
+	var out strings.Builder
+	results.Print(&out)
+	assert.Contains(t, out.String(), "✅ Every receiver got every group it joined without loss")
+
+	results.Receivers[0].Groups["239.1.1.1:5000"] = GroupResult{Received: 9, Lost: 1}
+	out.Reset()
+	results.Print(&out)
+	assert.Contains(t, out.String(), "⚠️  Every joined group was received, 1 with loss")
+}
+
+func senderSummary(messages int) server.Summary {
+	return server.Summary{Sent: &multicast.SenderStats{Messages: messages}}
+}
+
+func receiverSummary(streams ...server.StreamReport) server.Summary {
+	return server.Summary{Received: &server.ReceivedTotals{PerStream: streams}}
+}
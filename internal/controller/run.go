@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/clock"
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// job is a sender or receiver started on an agent
+type job struct {
+	endpoint Endpoint
+	client   *Client
+	host     string
+	id       string
+}
+
+// Run executes a plan: it checks that every agent answers, starts the
+// receivers, waits for them to settle, runs the senders for the plan's
+// duration, stops everything and collects the results. Cancelling ctx ends
+// the run early; the results so far are still collected.
+func Run(ctx context.Context, plan *Plan, out io.Writer) (*Results, error) {
+	clients := make(map[string]*Client)
+	hosts := make(map[string]string)
+	names := plan.agentNames()
+	fmt.Fprintf(out, "🛰️  Checking %d agent(s)\n", len(names))
+	for _, name := range names {
+		client := NewClient(plan.Agents[name], plan.Token)
+		info, err := client.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", name, err)
+		}
+		clients[name], hosts[name] = client, info.Hostname
+		fmt.Fprintf(out, "   %s at %s: host %s, %d job(s) running\n", name, client, info.Hostname, info.Running)
+	}
+
+	var receivers, senders []*job
+	// Jobs are removed from the agents whatever happens, once their results are in
+	defer func() {
+		for _, j := range append(receivers, senders...) {
+			j.client.Remove(context.Background(), j.id)
+		}
+	}()
+
+	start := func(e Endpoint) (*job, error) {
+		j := &job{endpoint: e, client: clients[e.Agent], host: hosts[e.Agent]}
+		info, err := j.client.Create(ctx, e.JobSpec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start %s on agent %s: %w", e.Type, e.Agent, err)
+		}
+		j.id = info.ID
+		return j, nil
+	}
+
+	for _, e := range plan.Receivers {
+		j, err := start(e)
+		if err != nil {
+			return nil, err
+		}
+		receivers = append(receivers, j)
+		groups, _ := e.Groups()
+		fmt.Fprintf(out, "👂 [%s] Receiver %s on agent %s (%s) joined %s\n",
+			time.Now().Format("15:04:05.000"), e.Name, e.Agent, j.id, strings.Join(groups, ", "))
+	}
+
+	fmt.Fprintf(out, "⏳ Waiting %v for the joins to settle\n", time.Duration(plan.Settle))
+	if clock.Sleep(ctx, time.Duration(plan.Settle)) {
+		for _, e := range plan.Senders {
+			// A sender stops by itself after the plan's duration should the controller go away
+			e.JobSpec.Duration = plan.Duration
+			j, err := start(e)
+			if err != nil {
+				return nil, err
+			}
+			senders = append(senders, j)
+			groups, _ := e.Groups()
+			fmt.Fprintf(out, "🚀 [%s] Sender on agent %s (%s) to %s\n",
+				time.Now().Format("15:04:05.000"), e.Agent, j.id, strings.Join(groups, ", "))
+		}
+
+		fmt.Fprintf(out, "⏱️  Sending for %v\n", time.Duration(plan.Duration))
+		clock.Sleep(ctx, time.Duration(plan.Duration))
+	}
+
+	// Stopping and collecting must finish even once ctx is cancelled
+	results := &Results{Sent: make(map[string]int)}
+	for _, j := range senders {
+		if _, err := j.client.Stop(context.Background(), j.id); err != nil {
+			return nil, err
+		}
+	}
+	if len(senders) > 0 {
+		fmt.Fprintf(out, "⏹️  [%s] Senders stopped, draining for %v\n", time.Now().Format("15:04:05.000"), time.Duration(plan.Drain))
+		clock.Sleep(ctx, time.Duration(plan.Drain))
+	}
+	for _, j := range receivers {
+		if _, err := j.client.Stop(context.Background(), j.id); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, j := range senders {
+		summary, err := j.client.Summary(context.Background(), j.id)
+		if err != nil {
+			return nil, err
+		}
+		groups, _ := j.endpoint.Groups()
+		results.addSender(groups, summary)
+	}
+	for _, j := range receivers {
+		summary, err := j.client.Summary(context.Background(), j.id)
+		if err != nil {
+			return nil, err
+		}
+		groups, _ := j.endpoint.Groups()
+		results.addReceiver(j.endpoint, j.host, groups, summary)
+	}
+	return results, nil
+}
+
+// addSender counts the messages sent to each group
+func (r *Results) addSender(groups []string, summary server.Summary) {
+	if summary.Sent == nil {
+		return
+	}
+	for _, group := range groups {
+		if _, ok := r.Sent[group]; !ok {
+			r.Groups = append(r.Groups, group)
+		}
+		r.Sent[group] += summary.Sent.Messages
+	}
+}
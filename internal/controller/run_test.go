@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// startAgents serves n agents on this host
+func startAgents(t *testing.T, n int) ([]string, []*server.Manager) {
+	var addrs []string
+	var managers []*server.Manager
+	for i := 0; i < n; i++ {
+		m := server.NewManager(io.Discard)
+		srv := httptest.NewServer(server.NewHandler(m))
+		t.Cleanup(func() {
+			m.Shutdown()
+			srv.Close()
+		})
+		addrs = append(addrs, srv.URL)
+		managers = append(managers, m)
+	}
+	return addrs, managers
+}
+
+func TestRun(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	addrs, managers := startAgents(t, 3)
+	plan, err := Parse([]byte(fmt.Sprintf(`
+duration: 300ms
+settle: 100ms
+drain: 100ms
+agents: {src: %s, leaf1: %s, leaf2: %s}
+senders:
+  - {agent: src, group: 239.23.23.55:23258, interface: lo, interval: 20ms}
+  - {agent: src, group: 239.23.23.56:23258, interface: lo, interval: 20ms, sender_id: b}
+receivers:
+  - {agent: leaf1, group: "239.23.23.55:23258,239.23.23.56:23258", interface: lo}
+  - {agent: leaf2, group: 239.23.23.55:23258, interface: lo}
+`, addrs[0], addrs[1], addrs[2])))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	results, err := Run(context.Background(), plan, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Checking 3 agent(s)")
+	assert.Contains(t, out.String(), "Receiver leaf1 on agent leaf1")
+
+	assert.Equal(t, []string{"239.23.23.55:23258", "239.23.23.56:23258"}, results.Groups)
+	assert.Positive(t, results.Sent["239.23.23.55:23258"])
+	require.Len(t, results.Receivers, 2)
+	assert.True(t, results.Receivers[0].Joined["239.23.23.56:23258"])
+	assert.False(t, results.Receivers[1].Joined["239.23.23.56:23258"])
+	if results.Receivers[0].Groups["239.23.23.55:23258"].Received == 0 {
+		t.Skip("multicast loopback not available")
+	}
+	assert.Zero(t, results.Missing())
+	assert.Positive(t, results.Receivers[1].Groups["239.23.23.55:23258"].Received)
+
+	// The jobs are gone from the agents
+	for _, m := range managers {
+		assert.Empty(t, m.List())
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	addrs, managers := startAgents(t, 1)
+	plan, err := Parse([]byte(fmt.Sprintf(`
+settle: 1m
+agents: {a: %s}
+senders: [{agent: a, group: 239.23.23.55:23258}]
+receivers: [{agent: a, group: 239.23.23.55:23258}]
+`, addrs[0])))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results, err := Run(ctx, plan, io.Discard)
+	require.NoError(t, err)
+	// The senders never started, and the receiver's results are still collected
+	assert.Empty(t, results.Groups)
+	require.Len(t, results.Receivers, 1)
+	assert.Empty(t, managers[0].List())
+}
+
+func TestRunAgentErrors(t *testing.T) {
+	addrs, managers := startAgents(t, 1)
+
+	plan, err := Parse([]byte(`
+agents: {a: 127.0.0.1:1}
+senders: [{agent: a}]
+receivers: [{agent: a}]
+`))
+	require.NoError(t, err)
+	_, err = Run(context.Background(), plan, io.Discard)
+	assert.ErrorContains(t, err, "agent a: failed to reach agent")
+
+	// A job the agent rejects stops the run, and the jobs started are removed
+	plan, err = Parse([]byte(fmt.Sprintf(`
+agents: {a: %s}
+senders: [{agent: a, group: 239.23.23.55:23258, ttl: 300}]
+receivers: [{agent: a, group: 239.23.23.55:23258}]
+settle: 10ms
+`, addrs[0])))
+	require.NoError(t, err)
+	_, err = Run(context.Background(), plan, io.Discard)
+	assert.ErrorContains(t, err, "failed to start sender on agent a")
+	assert.Empty(t, managers[0].List())
+}
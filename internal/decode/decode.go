@@ -0,0 +1,100 @@
+// Package decode describes datagrams that are not mcaster messages: it
+// recognizes common multicast protocols and formats anything else as a hex dump.
+package decode
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode selects how foreign datagrams are shown
+type Mode string
+
+// Decode modes
+const (
+	// ModeAuto identifies known protocols and summarizes them, falling back to a hex dump
+	ModeAuto Mode = "auto"
+	// ModeHex always shows a hex/ASCII dump
+	ModeHex Mode = "hex"
+	// ModeRaw prints the bytes unmodified
+	ModeRaw Mode = "raw"
+	// ModeNone only reports the size of the datagram
+	ModeNone Mode = "none"
+)
+
+// ParseMode parses a --decode argument
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(s)); mode {
+	case ModeAuto, ModeHex, ModeRaw, ModeNone:
+		return mode, nil
+	case "":
+		return ModeAuto, nil
+	}
+	return "", fmt.Errorf("invalid decode mode %q: use auto, hex, raw or none", s)
+}
+
+// HexDump formats up to limit bytes of data as offset, hex and ASCII columns,
+// 16 bytes per line. A limit of 0 dumps everything.
+func HexDump(data []byte, limit int) string {
+	shown := data
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+
+	var b strings.Builder
+	for offset := 0; offset < len(shown); offset += 16 {
+		line := shown[offset:min(offset+16, len(shown))]
+
+		fmt.Fprintf(&b, "%08x  ", offset)
+		for i := 0; i < 16; i++ {
+			if i < len(line) {
+				fmt.Fprintf(&b, "%02x ", line[i])
+			} else {
+				b.WriteString("   ")
+			}
+			if i == 7 {
+				b.WriteByte(' ')
+			}
+		}
+
+		b.WriteString(" |")
+		for _, c := range line {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteString("|\n")
+	}
+
+	if len(shown) < len(data) {
+		fmt.Fprintf(&b, "... %d more bytes\n", len(data)-len(shown))
+	}
+	return b.String()
+}
+
+// isText reports whether data is printable UTF-8 text
+func isText(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+		if r == utf8.RuneError || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,50 @@
+package decode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"auto", "hex", "raw", "none", "HEX"} {
+		mode, err := ParseMode(s)
+		require.NoError(t, err)
+		assert.Equal(t, Mode(strings.ToLower(s)), mode)
+	}
+
+	mode, err := ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeAuto, mode)
+
+	_, err = ParseMode("json")
+	assert.Error(t, err)
+}
+
+func TestHexDump(t *testing.T) {
+	data := []byte("Hello, multicast!\x00\x01\xff")
+
+	expected := "" +
+		"00000000  48 65 6c 6c 6f 2c 20 6d  75 6c 74 69 63 61 73 74  |Hello, multicast|\n" +
+		"00000010  21 00 01 ff                                       |!...|\n"
+	assert.Equal(t, expected, HexDump(data, 0))
+
+	limited := HexDump(data, 16)
+	assert.True(t, strings.HasSuffix(limited, "... 4 more bytes\n"))
+	assert.Equal(t, 2, strings.Count(limited, "\n"))
+
+	assert.Empty(t, HexDump(nil, 0))
+}
+
+func TestIsText(t *testing.T) {
+	assert.True(t, isText([]byte("hello\r\nworld\t!")))
+	assert.True(t, isText([]byte("héllo")))
+	assert.False(t, isText([]byte("a\x00b")))
+	assert.False(t, isText([]byte{0xff, 0xfe}))
+	assert.False(t, isText(nil))
+
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab…", truncate("abc", 2))
+}
@@ -0,0 +1,119 @@
+package decode
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidDNS = errors.New("invalid DNS message")
+
+var dnsTypes = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 12: "PTR", 13: "HINFO", 16: "TXT", 28: "AAAA", 33: "SRV", 41: "OPT", 47: "NSEC", 255: "ANY",
+}
+
+func dnsTypeName(t uint16) string {
+	if name, ok := dnsTypes[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// describeDNS summarizes a DNS message by its questions and answers
+func describeDNS(msg []byte) (string, error) {
+	if len(msg) < 12 {
+		return "", errInvalidDNS
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(msg[4+2*i:]))
+	}
+	if counts[0]+counts[1]+counts[2]+counts[3] == 0 {
+		return "", errInvalidDNS
+	}
+
+	offset := 12
+	var questions, answers []string
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil || next+4 > len(msg) {
+			return "", errInvalidDNS
+		}
+		questions = append(questions, name+" "+dnsTypeName(binary.BigEndian.Uint16(msg[next:])))
+		offset = next + 4
+	}
+	// Answer, authority and additional records share a layout
+	for i := 0; i < counts[1]+counts[2]+counts[3]; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil || next+10 > len(msg) {
+			return "", errInvalidDNS
+		}
+		rdLength := int(binary.BigEndian.Uint16(msg[next+8:]))
+		if next+10+rdLength > len(msg) {
+			return "", errInvalidDNS
+		}
+		if i < counts[1] {
+			answers = append(answers, name+" "+dnsTypeName(binary.BigEndian.Uint16(msg[next:])))
+		}
+		offset = next + 10 + rdLength
+	}
+
+	kind := "query"
+	if flags&0x8000 != 0 {
+		kind = "response"
+	}
+	parts := []string{kind}
+	if len(questions) > 0 {
+		parts = append(parts, fmt.Sprintf("%d question(s): %s", len(questions), listSome(questions, 3)))
+	}
+	if len(answers) > 0 {
+		parts = append(parts, fmt.Sprintf("%d answer(s): %s", len(answers), listSome(answers, 3)))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// readDNSName reads a possibly compressed name and returns the offset after it
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errInvalidDNS
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, errInvalidDNS
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+			jumps++
+		case length&0xc0 != 0:
+			return "", 0, errInvalidDNS
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errInvalidDNS
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// listSome joins up to n items, noting how many were left out
+func listSome(items []string, n int) string {
+	if len(items) <= n {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s, +%d more", strings.Join(items[:n], ", "), len(items)-n)
+}
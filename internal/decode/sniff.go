@@ -0,0 +1,228 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+	"github.com/hyposcaler-bot/mcaster/internal/sap"
+)
+
+// Well-known ports used to tell protocols apart
+const (
+	ntpPort  = 123
+	mdnsPort = 5353
+	ssdpPort = 1900
+)
+
+// Result names the protocol of a datagram and describes it in one line
+type Result struct {
+	Protocol string
+	Summary  string
+}
+
+// sniffer recognizes one protocol
+type sniffer func(data []byte, srcPort, dstPort int) (Result, bool)
+
+// sniffers are tried in order: protocols identified by port first, then by content
+var sniffers = []sniffer{
+	sniffMPEGTS,
+	sniffSAP,
+	sniffMDNS,
+	sniffNTP,
+	sniffSSDP,
+	sniffSDP,
+	sniffRTCP,
+	sniffRTP,
+	sniffText,
+}
+
+// Sniff identifies the protocol of a datagram from its contents and ports
+func Sniff(data []byte, srcPort, dstPort int) (Result, bool) {
+	for _, sniff := range sniffers {
+		if result, ok := sniff(data, srcPort, dstPort); ok {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+func onPort(port, srcPort, dstPort int) bool {
+	return srcPort == port || dstPort == port
+}
+
+func sniffMPEGTS(data []byte, _, _ int) (Result, bool) {
+	if !mpegts.IsTransportStream(data) {
+		return Result{}, false
+	}
+	return Result{Protocol: "MPEG-TS", Summary: describeTS(data)}, true
+}
+
+// describeTS lists the packet count and PIDs of transport stream packets
+func describeTS(data []byte) string {
+	seen := make(map[uint16]bool)
+	var pids []int
+	for _, pkt := range mpegts.Packets(data) {
+		header, err := mpegts.ParseHeader(pkt)
+		if err != nil || seen[header.PID] {
+			continue
+		}
+		seen[header.PID] = true
+		pids = append(pids, int(header.PID))
+	}
+	sort.Ints(pids)
+
+	names := make([]string, len(pids))
+	for i, pid := range pids {
+		names[i] = fmt.Sprintf("0x%04x", pid)
+	}
+	return fmt.Sprintf("%d packets, PIDs %s", len(data)/mpegts.PacketSize, strings.Join(names, " "))
+}
+
+func sniffSAP(data []byte, srcPort, dstPort int) (Result, bool) {
+	if !onPort(sap.Port, srcPort, dstPort) {
+		return Result{}, false
+	}
+	pkt, err := sap.Parse(data)
+	if err != nil {
+		return Result{}, false
+	}
+
+	kind := "announcement"
+	if pkt.Deletion {
+		kind = "deletion"
+	}
+	summary := fmt.Sprintf("%s from %s", kind, pkt.Origin)
+	if pkt.IsSDP() {
+		if session, err := sap.ParseSDP(string(pkt.Payload)); err == nil {
+			summary += ": " + session.Summary()
+		}
+	} else {
+		summary += fmt.Sprintf(" (%s, %d bytes)", pkt.PayloadType, len(pkt.Payload))
+	}
+	return Result{Protocol: "SAP", Summary: summary}, true
+}
+
+func sniffSDP(data []byte, _, _ int) (Result, bool) {
+	if !bytes.HasPrefix(data, []byte("v=0")) {
+		return Result{}, false
+	}
+	session, err := sap.ParseSDP(string(data))
+	if err != nil {
+		return Result{}, false
+	}
+	return Result{Protocol: "SDP", Summary: session.Summary()}, true
+}
+
+func sniffMDNS(data []byte, srcPort, dstPort int) (Result, bool) {
+	if !onPort(mdnsPort, srcPort, dstPort) {
+		return Result{}, false
+	}
+	summary, err := describeDNS(data)
+	if err != nil {
+		return Result{}, false
+	}
+	return Result{Protocol: "mDNS", Summary: summary}, true
+}
+
+// ntpEpoch is the NTP era 0 epoch, 1900-01-01
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var ntpModes = []string{"reserved", "symmetric active", "symmetric passive", "client", "server", "broadcast", "control", "private"}
+
+func sniffNTP(data []byte, srcPort, dstPort int) (Result, bool) {
+	if !onPort(ntpPort, srcPort, dstPort) || len(data) < 48 {
+		return Result{}, false
+	}
+	version := data[0] >> 3 & 0x07
+	if version < 1 || version > 4 {
+		return Result{}, false
+	}
+
+	seconds := binary.BigEndian.Uint32(data[40:])
+	fraction := binary.BigEndian.Uint32(data[44:])
+	transmit := ntpEpoch.Add(time.Duration(seconds)*time.Second +
+		time.Duration(uint64(fraction)*uint64(time.Second)>>32))
+
+	summary := fmt.Sprintf("v%d %s, stratum %d, time %s",
+		version, ntpModes[data[0]&0x07], data[1], transmit.Format(time.RFC3339Nano))
+	return Result{Protocol: "NTP", Summary: summary}, true
+}
+
+var ssdpStartLines = []string{"NOTIFY * HTTP/1.1", "M-SEARCH * HTTP/1.1", "HTTP/1.1 200 OK"}
+
+func sniffSSDP(data []byte, srcPort, dstPort int) (Result, bool) {
+	text := string(data)
+	startLine, rest, _ := strings.Cut(text, "\n")
+	startLine = strings.TrimRight(startLine, "\r")
+
+	known := false
+	for _, line := range ssdpStartLines {
+		if strings.EqualFold(startLine, line) {
+			known = true
+		}
+	}
+	if !known {
+		return Result{}, false
+	}
+
+	headers := make(map[string]string)
+	for _, line := range strings.Split(rest, "\n") {
+		name, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if ok {
+			headers[strings.ToUpper(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+
+	method, _, _ := strings.Cut(startLine, " ")
+	parts := []string{method}
+	for _, name := range []string{"NTS", "NT", "ST", "USN", "LOCATION", "SERVER"} {
+		if value := headers[name]; value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", strings.ToLower(name), value))
+		}
+	}
+	return Result{Protocol: "SSDP", Summary: strings.Join(parts, " ")}, true
+}
+
+var rtcpTypes = map[byte]string{200: "SR", 201: "RR", 202: "SDES", 203: "BYE", 204: "APP"}
+
+func sniffRTCP(data []byte, _, _ int) (Result, bool) {
+	if len(data) < 8 || !rtp.IsRTCP(data) {
+		return Result{}, false
+	}
+	ssrc := binary.BigEndian.Uint32(data[4:])
+	return Result{Protocol: "RTCP", Summary: fmt.Sprintf("%s, SSRC 0x%08x", rtcpTypes[data[1]], ssrc)}, true
+}
+
+func sniffRTP(data []byte, _, _ int) (Result, bool) {
+	header, err := rtp.Parse(data)
+	if err != nil || header.PayloadSize <= 0 {
+		return Result{}, false
+	}
+
+	payload := data[header.PayloadOffset : header.PayloadOffset+header.PayloadSize]
+	protocol := "RTP"
+	if mpegts.IsTransportStream(payload) {
+		protocol = "RTP/MPEG-TS"
+	}
+
+	summary := fmt.Sprintf("PT %d (%s), seq %d, ts %d, SSRC 0x%08x, %d payload bytes",
+		header.PayloadType, rtp.PayloadTypeName(header.PayloadType), header.Sequence,
+		header.Timestamp, header.SSRC, header.PayloadSize)
+	if header.Marker {
+		summary += ", marker"
+	}
+	return Result{Protocol: protocol, Summary: summary}, true
+}
+
+func sniffText(data []byte, _, _ int) (Result, bool) {
+	if !isText(data) {
+		return Result{}, false
+	}
+	return Result{Protocol: "text", Summary: fmt.Sprintf("%q", truncate(string(data), 120))}, true
+}
@@ -0,0 +1,169 @@
+package decode
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tsPackets(pids ...uint16) []byte {
+	var data []byte
+	for _, pid := range pids {
+		pkt := make([]byte, 188)
+		pkt[0] = 0x47
+		pkt[1] = byte(pid >> 8)
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10
+		data = append(data, pkt...)
+	}
+	return data
+}
+
+func ntpPacket() []byte {
+	pkt := make([]byte, 48)
+	pkt[0] = 4<<3 | 5 // version 4, broadcast
+	pkt[1] = 2
+	// 2024-01-01T00:00:00Z in NTP seconds, plus half a second
+	binary.BigEndian.PutUint32(pkt[40:], 3913056000)
+	binary.BigEndian.PutUint32(pkt[44:], 1<<31)
+	return pkt
+}
+
+func mdnsQuery() []byte {
+	msg := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}
+	msg = append(msg, 5, '_', 'h', 't', 't', 'p', 4, '_', 't', 'c', 'p', 5, 'l', 'o', 'c', 'a', 'l', 0)
+	return append(msg, 0, 12, 0, 1)
+}
+
+func mdnsResponse() []byte {
+	msg := []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+	msg = append(msg, 7, 'p', 'r', 'i', 'n', 't', 'e', 'r', 5, 'l', 'o', 'c', 'a', 'l', 0)
+	msg = append(msg, 0, 1, 0x80, 1, 0, 0, 0, 120, 0, 4, 192, 0, 2, 7)
+	return msg
+}
+
+func TestSniff(t *testing.T) {
+	rtpTS := append([]byte{0x80, 0xa1, 0, 7, 0, 0, 0, 9, 0, 0, 0x12, 0x34}, tsPackets(0x100)...)
+	sapData := append([]byte{0x20, 0, 0, 1, 192, 0, 2, 10},
+		"application/sdp\x00v=0\r\ns=News\r\nc=IN IP4 239.1.1.1\r\nm=video 5000 RTP/AVP 33\r\n"...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		srcPort  int
+		dstPort  int
+		expected Result
+		unknown  bool
+	}{
+		{
+			name:     "MPEG-TS",
+			data:     tsPackets(0x100, 0, 0x100, 0x1fff),
+			dstPort:  5000,
+			expected: Result{Protocol: "MPEG-TS", Summary: "4 packets, PIDs 0x0000 0x0100 0x1fff"},
+		},
+		{
+			name:    "RTP carrying MPEG-TS",
+			data:    rtpTS,
+			dstPort: 5000,
+			expected: Result{Protocol: "RTP/MPEG-TS",
+				Summary: "PT 33 (MP2T), seq 7, ts 9, SSRC 0x00001234, 188 payload bytes, marker"},
+		},
+		{
+			name:     "RTCP",
+			data:     []byte{0x80, 200, 0, 6, 0, 0, 0x12, 0x34},
+			expected: Result{Protocol: "RTCP", Summary: "SR, SSRC 0x00001234"},
+		},
+		{
+			name:     "SAP",
+			data:     sapData,
+			srcPort:  40000,
+			dstPort:  9875,
+			expected: Result{Protocol: "SAP", Summary: `announcement from 192.0.2.10: "News": video 239.1.1.1:5000 RTP/AVP 33`},
+		},
+		{
+			name:     "mDNS query",
+			data:     mdnsQuery(),
+			srcPort:  5353,
+			dstPort:  5353,
+			expected: Result{Protocol: "mDNS", Summary: "query, 1 question(s): _http._tcp.local. PTR"},
+		},
+		{
+			name:     "mDNS response",
+			data:     mdnsResponse(),
+			dstPort:  5353,
+			expected: Result{Protocol: "mDNS", Summary: "response, 1 answer(s): printer.local. A"},
+		},
+		{
+			name:     "NTP",
+			data:     ntpPacket(),
+			srcPort:  123,
+			dstPort:  123,
+			expected: Result{Protocol: "NTP", Summary: "v4 broadcast, stratum 2, time 2024-01-01T00:00:00.5Z"},
+		},
+		{
+			name:    "SSDP",
+			data:    []byte("NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNT: upnp:rootdevice\r\nNTS: ssdp:alive\r\nLOCATION: http://192.0.2.7/desc.xml\r\n\r\n"),
+			dstPort: 1900,
+			expected: Result{Protocol: "SSDP",
+				Summary: "NOTIFY nts=ssdp:alive nt=upnp:rootdevice location=http://192.0.2.7/desc.xml"},
+		},
+		{
+			name:     "bare SDP",
+			data:     []byte("v=0\r\ns=Radio\r\n"),
+			expected: Result{Protocol: "SDP", Summary: `"Radio"`},
+		},
+		{
+			name:     "text",
+			data:     []byte("hello from a legacy app"),
+			expected: Result{Protocol: "text", Summary: `"hello from a legacy app"`},
+		},
+		{
+			name:    "NTP port but not NTP",
+			data:    []byte{0x00, 0x01, 0x02},
+			dstPort: 123,
+			unknown: true,
+		},
+		{
+			name:    "mDNS port but not DNS",
+			data:    []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f},
+			dstPort: 5353,
+			unknown: true,
+		},
+		{
+			name:    "binary",
+			data:    []byte{0x01, 0x02, 0x03, 0xff},
+			unknown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := Sniff(tt.data, tt.srcPort, tt.dstPort)
+			if tt.unknown {
+				assert.False(t, ok, "sniffed as %+v", result)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestReadDNSName(t *testing.T) {
+	// "a.b." followed by a pointer back to it and a pointer loop
+	msg := []byte{1, 'a', 1, 'b', 0, 1, 'c', 0xc0, 0, 0xc0, 9}
+
+	name, next, err := readDNSName(msg, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.b.", name)
+	assert.Equal(t, 5, next)
+
+	name, next, err = readDNSName(msg, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.a.b.", name)
+	assert.Equal(t, 9, next)
+
+	_, _, err = readDNSName(msg, 9)
+	assert.Error(t, err)
+}
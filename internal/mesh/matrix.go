@@ -0,0 +1,178 @@
+package mesh
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hyposcaler-bot/mcaster/internal/table"
+)
+
+// Status describes a path from one node to another
+type Status int
+
+// Path statuses
+const (
+	// StatusUnknown means the receiving node's reports were not heard
+	StatusUnknown Status = iota
+	// StatusNone means the receiving node got nothing from the sending one
+	StatusNone
+	// StatusLossy means some messages were lost on the way
+	StatusLossy
+	// StatusOK means every message arrived
+	StatusOK
+)
+
+// Matrix holds what every node of a mesh receives from every other node, as
+// seen from one of them
+type Matrix struct {
+	// Self is the node the matrix is seen from
+	Self  string
+	Group string
+	// Nodes are every node known, sorted
+	Nodes   []string
+	reports map[string]*Report
+}
+
+// NewMatrix builds the matrix from the reports of the nodes; peers are
+// nodes expected even if nothing was heard of them
+func NewMatrix(self, group string, peers []string, reports []*Report) *Matrix {
+	m := &Matrix{Self: self, Group: group, reports: make(map[string]*Report)}
+	known := map[string]bool{self: true}
+	for _, peer := range peers {
+		known[peer] = true
+	}
+	for _, r := range reports {
+		m.reports[r.Node] = r
+		known[r.Node] = true
+		for peer := range r.Peers {
+			known[peer] = true
+		}
+	}
+	for node := range known {
+		m.Nodes = append(m.Nodes, node)
+	}
+	sort.Strings(m.Nodes)
+	return m
+}
+
+// Path returns what node to received from node from, and whether to's
+// report was heard at all
+func (m *Matrix) Path(from, to string) (PeerStats, bool) {
+	r, ok := m.reports[to]
+	if !ok {
+		return PeerStats{}, false
+	}
+	return r.Peers[from], true
+}
+
+// Status classifies the path from one node to another
+func (m *Matrix) Status(from, to string) Status {
+	p, ok := m.Path(from, to)
+	switch {
+	case !ok:
+		return StatusUnknown
+	case p.Received == 0:
+		return StatusNone
+	case p.Lost > 0:
+		return StatusLossy
+	}
+	return StatusOK
+}
+
+// Failed returns the paths on which nothing arrived, as "from → to"
+func (m *Matrix) Failed() []string {
+	var failed []string
+	for _, from := range m.Nodes {
+		for _, to := range m.Nodes {
+			if from != to && m.Status(from, to) == StatusNone {
+				failed = append(failed, from+" → "+to)
+			}
+		}
+	}
+	return failed
+}
+
+// Asymmetric returns the pairs of nodes where traffic flows one way only,
+// as "from → to" for the direction that fails
+func (m *Matrix) Asymmetric() []string {
+	var pairs []string
+	for i, a := range m.Nodes {
+		for _, b := range m.Nodes[i+1:] {
+			switch {
+			case m.asymmetric(a, b):
+				pairs = append(pairs, a+" → "+b)
+			case m.asymmetric(b, a):
+				pairs = append(pairs, b+" → "+a)
+			}
+		}
+	}
+	return pairs
+}
+
+// asymmetric reports whether the path from one node to another fails while
+// the reverse path works
+func (m *Matrix) asymmetric(from, to string) bool {
+	return m.Status(from, to) == StatusNone && m.Status(to, from) >= StatusLossy
+}
+
+// Print writes the matrix with a row per sending node and a column per
+// receiving node: ✓ for a path without loss, ! with loss, ✗ for a path on
+// which nothing arrived and ? for a node whose reports were not heard.
+// Failures of one direction of a pair are marked with *.
+func (m *Matrix) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n🕸️  Mesh of %d node(s) on %s, seen from %s\n", len(m.Nodes), m.Group, m.Self)
+
+	nameWidth := len("FROM \\ TO")
+	for _, node := range m.Nodes {
+		nameWidth = max(nameWidth, len(node))
+	}
+	cellWidth := 20
+	for _, node := range m.Nodes {
+		cellWidth = max(cellWidth, len(node))
+	}
+
+	widths := []int{nameWidth}
+	for range m.Nodes {
+		widths = append(widths, cellWidth)
+	}
+	table.WriteRow(w, "", append([]string{"FROM \\ TO"}, m.Nodes...), widths)
+	for _, from := range m.Nodes {
+		row := []string{from}
+		for _, to := range m.Nodes {
+			row = append(row, m.cell(from, to))
+		}
+		table.WriteRow(w, "", row, widths)
+	}
+
+	fmt.Fprintln(w)
+	for _, path := range m.Asymmetric() {
+		fmt.Fprintf(w, "⚠️  Asymmetric: %s fails while the reverse path works\n", path)
+	}
+	if failed := m.Failed(); len(failed) > 0 {
+		fmt.Fprintf(w, "❌ %d path(s) received nothing: %s\n", len(failed), strings.Join(failed, ", "))
+	} else {
+		fmt.Fprintf(w, "✅ Every reporting node receives every other node\n")
+	}
+}
+
+// cell describes the path from one node to another
+func (m *Matrix) cell(from, to string) string {
+	if from == to {
+		return "·"
+	}
+	p, _ := m.Path(from, to)
+	switch m.Status(from, to) {
+	case StatusUnknown:
+		return "?"
+	case StatusNone:
+		if m.asymmetric(from, to) {
+			return "✗ none *"
+		}
+		return "✗ none"
+	case StatusLossy:
+		return fmt.Sprintf("! %.1f%% %s", p.LossPercent(), table.FormatDelay(p.DelayP50))
+	}
+	return fmt.Sprintf("✓ %.1f%% %s", p.LossPercent(), table.FormatDelay(p.DelayP50))
+}
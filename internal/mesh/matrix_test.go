@@ -0,0 +1,67 @@
+package mesh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMatrix is a mesh of a, b, c and d where b receives nothing from c while
+// c receives b, a loses some of c, and d never reported
+func testMatrix() *Matrix {
+	return NewMatrix("a", "239.1.1.1:5000", []string{"d"}, []*Report{
+		{Node: "a", Peers: map[string]PeerStats{
+			"b": {Received: 100, DelayP50: time.Millisecond},
+			"c": {Received: 95, Lost: 5, DelayP50: 2 * time.Millisecond},
+		}},
+		{Node: "b", Peers: map[string]PeerStats{
+			"a": {Received: 100, DelayP50: 1100 * time.Microsecond},
+		}},
+		{Node: "c", Peers: map[string]PeerStats{
+			"a": {Received: 100, DelayP50: 900 * time.Microsecond},
+			"b": {Received: 100, DelayP50: 1200 * time.Microsecond},
+		}},
+	})
+}
+
+func TestMatrixStatus(t *testing.T) {
+	m := testMatrix()
+	assert.Equal(t, []string{"a", "b", "c", "d"}, m.Nodes)
+
+	assert.Equal(t, StatusOK, m.Status("b", "a"))
+	assert.Equal(t, StatusLossy, m.Status("c", "a"))
+	assert.Equal(t, StatusNone, m.Status("c", "b"))
+	assert.Equal(t, StatusOK, m.Status("b", "c"))
+	assert.Equal(t, StatusNone, m.Status("d", "a"))
+	assert.Equal(t, StatusUnknown, m.Status("a", "d"))
+
+	p, ok := m.Path("c", "a")
+	assert.True(t, ok)
+	assert.Equal(t, 95, p.Received)
+	_, ok = m.Path("a", "d")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"c → b"}, m.Asymmetric())
+	assert.Equal(t, []string{"c → b", "d → a", "d → b", "d → c"}, m.Failed())
+}
+
+func TestMatrixPrint(t *testing.T) {
+	var out strings.Builder
+	testMatrix().Print(&out)
+	lines := strings.Split(out.String(), "\n")
+
+	assert.Equal(t, "🕸️  Mesh of 4 node(s) on 239.1.1.1:5000, seen from a", lines[1])
+	assert.Equal(t, "FROM \\ TO  a                     b                     c                     d", lines[2])
+	assert.Equal(t, "a          ·                     ✓ 0.0% 1.1ms          ✓ 0.0% 900µs          ?", lines[3])
+	assert.Equal(t, "b          ✓ 0.0% 1ms            ·                     ✓ 0.0% 1.2ms          ?", lines[4])
+	assert.Equal(t, "c          ! 5.0% 2ms            ✗ none *              ·                     ?", lines[5])
+	assert.Equal(t, "d          ✗ none                ✗ none                ✗ none                ·", lines[6])
+	assert.Contains(t, out.String(), "⚠️  Asymmetric: c → b fails while the reverse path works")
+	assert.Contains(t, out.String(), "❌ 4 path(s) received nothing: c → b, d → a, d → b, d → c")
+
+	out.Reset()
+	NewMatrix("a", "239.1.1.1:5000", nil, []*Report{{Node: "a"}}).Print(&out)
+	assert.Contains(t, out.String(), "✅ Every reporting node receives every other node")
+}
@@ -0,0 +1,208 @@
+// Package mesh runs full-mesh tests: every node sends its own stream to a
+// shared group and receives the streams of all others, and the nodes
+// multicast what they receive so each can show the whole N×N matrix.
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+// streamID names the streams of mesh nodes, so other senders on the group
+// are not taken for nodes
+const streamID = "mesh"
+
+// DefaultReportInterval is how often nodes multicast their reports
+const DefaultReportInterval = time.Second
+
+// Node is one participant of a mesh: a sender identified by the node's name
+// and a receiver of the group
+type Node struct {
+	name           string
+	group          string
+	peers          []string
+	reportInterval time.Duration
+	sender         *multicast.Sender
+	receiver       *multicast.Receiver
+	out            io.Writer
+	mu             sync.Mutex
+	reports        map[string]*Report
+	heard          map[string]bool
+}
+
+// Option configures optional Node behaviour
+type Option func(*Node)
+
+// WithPeers names the nodes expected in the mesh, so nodes never heard of
+// show up in the matrix too
+func WithPeers(names []string) Option {
+	return func(n *Node) {
+		n.peers = names
+	}
+}
+
+// WithReportInterval multicasts the node's report every d (default: 1s)
+func WithReportInterval(d time.Duration) Option {
+	return func(n *Node) {
+		n.reportInterval = d
+	}
+}
+
+// WithOutput writes the node's progress to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(n *Node) {
+		n.out = w
+	}
+}
+
+// NewNode creates the node name of a mesh on group, sending every interval.
+// Several nodes may run on one host; they share the group's port.
+func NewNode(name, group, interfaceName string, interval time.Duration, ttl int, opts ...Option) (*Node, error) {
+	if name == "" || strings.ContainsAny(name, " \t,") {
+		return nil, fmt.Errorf("node name must be non-empty without spaces or commas, got %q", name)
+	}
+
+	node := &Node{
+		name:           name,
+		group:          group,
+		reportInterval: DefaultReportInterval,
+		out:            io.Discard,
+		reports:        make(map[string]*Report),
+		heard:          make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(node)
+	}
+	if node.reportInterval <= 0 {
+		return nil, fmt.Errorf("report interval must be positive, got %v", node.reportInterval)
+	}
+
+	receiver, err := multicast.NewReceiver(group, interfaceName, 0,
+		multicast.WithQuiet(),
+		multicast.WithOutput(io.Discard),
+		multicast.WithDecoder(&reportDecoder{node: node}))
+	if err != nil {
+		return nil, err
+	}
+	// Loopback stays on so nodes on the same host hear each other
+	sender, err := multicast.NewSender(group, interfaceName, interval, ttl, 0, 0,
+		multicast.WithSenderID(name),
+		multicast.WithStreamID(streamID),
+		multicast.WithLoopback(true),
+		multicast.WithSenderOutput(io.Discard))
+	if err != nil {
+		receiver.Close()
+		return nil, err
+	}
+	node.sender, node.receiver = sender, receiver
+	return node, nil
+}
+
+// Run sends, receives and exchanges reports until ctx is done, then sends a
+// last report and returns the matrix
+func (n *Node) Run(ctx context.Context) (*Matrix, error) {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- n.receiver.Start()
+	}()
+	go func() {
+		errs <- n.sender.Start()
+	}()
+
+	ticker := time.NewTicker(n.reportInterval)
+	defer ticker.Stop()
+	var err error
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case err = <-errs:
+			break loop
+		case <-ticker.C:
+			n.sendReport()
+			n.announcePeers()
+		}
+	}
+
+	n.sendReport()
+	n.sender.Close()
+	n.receiver.Close()
+	return n.Matrix(), err
+}
+
+// sendReport multicasts what the node receives
+func (n *Node) sendReport() {
+	data, err := n.Report().Marshal()
+	if err == nil {
+		err = n.sender.Send(data)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to send mesh report: %v", err)
+	}
+}
+
+// announcePeers prints the nodes heard from for the first time
+func (n *Node) announcePeers() {
+	for peer := range n.Report().Peers {
+		n.mu.Lock()
+		first := !n.heard[peer]
+		n.heard[peer] = true
+		n.mu.Unlock()
+		if first {
+			fmt.Fprintf(n.out, "📥 [%s] Receiving node %s\n", time.Now().Format("15:04:05.000"), peer)
+		}
+	}
+}
+
+// Report returns what the node receives from every other node, adding up
+// the sessions of nodes that restarted
+func (n *Node) Report() *Report {
+	report := &Report{Node: n.name, Time: time.Now(), Peers: make(map[string]PeerStats)}
+	for key, stats := range n.receiver.Streams() {
+		if key.Stream != streamID || key.SenderID == "" || key.SenderID == n.name {
+			continue
+		}
+		peer := report.Peers[key.SenderID]
+		peer.Received += stats.Received
+		peer.Lost += stats.Lost
+		if stats.LastSeen.After(peer.LastSeen) {
+			peer.LastSeen = stats.LastSeen
+			peer.DelayP50, _ = stats.DelayPercentile(50)
+			peer.DelayP99, _ = stats.DelayPercentile(99)
+		}
+		report.Peers[key.SenderID] = peer
+	}
+	return report
+}
+
+// addReport keeps the latest report of another node
+func (n *Node) addReport(r *Report) {
+	if r.Node == n.name {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if known, ok := n.reports[r.Node]; !ok || !r.Time.Before(known.Time) {
+		n.reports[r.Node] = r
+	}
+}
+
+// Matrix returns the mesh as this node sees it: its own report and the
+// latest report of every other node
+func (n *Node) Matrix() *Matrix {
+	reports := []*Report{n.Report()}
+	n.mu.Lock()
+	for _, r := range n.reports {
+		reports = append(reports, r)
+	}
+	n.mu.Unlock()
+	return NewMatrix(n.name, n.group, n.peers, reports)
+}
@@ -0,0 +1,75 @@
+package mesh
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNodeValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		node string
+		opts []Option
+		ttl  int
+		err  string
+	}{
+		{name: "no name", node: "", ttl: 1, err: "node name must be non-empty"},
+		{name: "name with comma", node: "a,b", ttl: 1, err: "without spaces or commas"},
+		{name: "report interval", node: "a", ttl: 1, opts: []Option{WithReportInterval(0)}, err: "report interval must be positive"},
+		{name: "invalid TTL", node: "a", ttl: 0, err: "TTL must be between 1 and 255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewNode(tt.node, "239.23.23.58:23259", "", time.Second, tt.ttl, tt.opts...)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestMesh(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	names := []string{"a", "b", "c"}
+	nodes := make([]*Node, len(names))
+	for i, name := range names {
+		node, err := NewNode(name, "239.23.23.58:23259", "lo", 20*time.Millisecond, 1,
+			WithPeers(names), WithReportInterval(100*time.Millisecond))
+		require.NoError(t, err)
+		nodes[i] = node
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	matrices := make(chan *Matrix, len(nodes))
+	for _, node := range nodes {
+		go func(node *Node) {
+			matrix, err := node.Run(ctx)
+			assert.NoError(t, err)
+			matrices <- matrix
+		}(node)
+	}
+
+	for range nodes {
+		m := <-matrices
+		assert.Equal(t, names, m.Nodes)
+		if m.Status("b", m.Self) == StatusNone && m.Status("c", m.Self) == StatusNone {
+			t.Skip("multicast loopback not available")
+		}
+		// Every node sees every path, including those between the two others
+		for _, from := range names {
+			for _, to := range names {
+				if from != to {
+					assert.GreaterOrEqual(t, m.Status(from, to), StatusLossy, "%s: %s → %s", m.Self, from, to)
+				}
+			}
+		}
+		assert.Empty(t, m.Failed())
+	}
+}
@@ -0,0 +1,87 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+// reportPrefix starts every report datagram, so receivers tell reports apart
+// from mcaster messages, which are JSON objects
+var reportPrefix = []byte("MCASTER-MESH/1\n")
+
+// Report is what one node receives from every other node. Nodes multicast
+// their reports to the mesh group so each can show the whole matrix.
+type Report struct {
+	Node  string               `json:"node"`
+	Time  time.Time            `json:"time"`
+	Peers map[string]PeerStats `json:"peers"`
+}
+
+// PeerStats is what a node receives from one peer
+type PeerStats struct {
+	Received int `json:"received"`
+	Lost     int `json:"lost"`
+	// DelayP50 and DelayP99 are the delay percentiles of the latest messages
+	DelayP50 time.Duration `json:"delay_p50"`
+	DelayP99 time.Duration `json:"delay_p99"`
+	LastSeen time.Time     `json:"last_seen"`
+}
+
+// LossPercent returns the share of packets lost, in percent
+func (p PeerStats) LossPercent() float64 {
+	if p.Received+p.Lost == 0 {
+		return 0
+	}
+	return float64(p.Lost) * 100 / float64(p.Received+p.Lost)
+}
+
+// Marshal encodes a report as a datagram
+func (r *Report) Marshal() ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return append(append([]byte(nil), reportPrefix...), data...), nil
+}
+
+// ParseReport decodes a report datagram
+func ParseReport(data []byte) (*Report, error) {
+	if !bytes.HasPrefix(data, reportPrefix) {
+		return nil, fmt.Errorf("not a mesh report")
+	}
+	var r Report
+	if err := json.Unmarshal(data[len(reportPrefix):], &r); err != nil {
+		return nil, fmt.Errorf("invalid mesh report: %w", err)
+	}
+	if r.Node == "" {
+		return nil, fmt.Errorf("invalid mesh report: no node")
+	}
+	return &r, nil
+}
+
+// reportDecoder hands the reports of other nodes to a node
+type reportDecoder struct {
+	node *Node
+}
+
+func (d *reportDecoder) Decode(pkt *multicast.Packet, group string, out io.Writer) bool {
+	if !bytes.HasPrefix(pkt.Data, reportPrefix) {
+		return false
+	}
+	if report, err := ParseReport(pkt.Data); err == nil {
+		d.node.addReport(report)
+	}
+	return true
+}
+
+// PrintSummary prints nothing; the node prints the matrix
+func (d *reportDecoder) PrintSummary(w io.Writer) {}
+
+func (d *reportDecoder) String() string {
+	return "mesh reports"
+}
@@ -0,0 +1,77 @@
+package mesh
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+func TestReportRoundTrip(t *testing.T) {
+	report := &Report{
+		Node: "a",
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Peers: map[string]PeerStats{
+			"b": {Received: 98, Lost: 2, DelayP50: time.Millisecond, DelayP99: 3 * time.Millisecond},
+		},
+	}
+	data, err := report.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, "MCASTER-MESH/1\n{", string(data[:16]))
+
+	parsed, err := ParseReport(data)
+	require.NoError(t, err)
+	assert.Equal(t, report.Node, parsed.Node)
+	assert.True(t, report.Time.Equal(parsed.Time))
+	assert.Equal(t, report.Peers, parsed.Peers)
+	assert.Equal(t, 2.0, parsed.Peers["b"].LossPercent())
+	assert.Zero(t, PeerStats{}.LossPercent())
+}
+
+func TestParseReportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "message", data: `{"id":1}`, err: "not a mesh report"},
+		{name: "invalid JSON", data: "MCASTER-MESH/1\n{", err: "invalid mesh report"},
+		{name: "no node", data: "MCASTER-MESH/1\n{}", err: "no node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseReport([]byte(tt.data))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestReportDecoder(t *testing.T) {
+	node := &Node{name: "a", reports: make(map[string]*Report)}
+	d := &reportDecoder{node: node}
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4000}
+
+	decode := func(r *Report) bool {
+		data, err := r.Marshal()
+		require.NoError(t, err)
+		return d.Decode(&multicast.Packet{Data: data, Source: source}, "", io.Discard)
+	}
+	now := time.Now()
+	assert.True(t, decode(&Report{Node: "b", Time: now}))
+	// An older report arriving late does not replace the latest
+	assert.True(t, decode(&Report{Node: "b", Time: now.Add(-time.Second), Peers: map[string]PeerStats{"a": {}}}))
+	// The node's own reports come back through loopback and are ignored
+	assert.True(t, decode(&Report{Node: "a", Time: now}))
+
+	require.Len(t, node.reports, 1)
+	assert.Empty(t, node.reports["b"].Peers)
+
+	assert.False(t, d.Decode(&multicast.Packet{Data: []byte("RTP?"), Source: source}, "", io.Discard))
+	// Broken reports are still claimed so they are not shown as foreign traffic
+	assert.True(t, d.Decode(&multicast.Packet{Data: []byte("MCASTER-MESH/1\nnope"), Source: source}, "", io.Discard))
+}
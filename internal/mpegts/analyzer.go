@@ -0,0 +1,366 @@
+package mpegts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Timeouts from ETSI TR 101 290
+const (
+	// PSIInterval is the longest gap allowed between PATs, and between PMTs on each PMT PID
+	PSIInterval = 500 * time.Millisecond
+	// PCRInterval is the longest gap allowed between PCRs
+	PCRInterval = 40 * time.Millisecond
+	// PIDInterval is how long a PID referenced in a PMT may go missing
+	PIDInterval = 5 * time.Second
+)
+
+// Indicator names a TR 101 290 check
+type Indicator string
+
+// Priority 1 indicators, plus the priority 2 CRC and PCR checks
+const (
+	SyncLoss        Indicator = "TS_sync_loss"
+	SyncByteError   Indicator = "Sync_byte_error"
+	PATError        Indicator = "PAT_error"
+	ContinuityError Indicator = "Continuity_count_error"
+	PMTError        Indicator = "PMT_error"
+	PIDError        Indicator = "PID_error"
+	CRCError        Indicator = "CRC_error"
+	PCRRepetition   Indicator = "PCR_repetition_error"
+)
+
+// Indicators lists the checks in report order
+var Indicators = []Indicator{SyncLoss, SyncByteError, PATError, ContinuityError, PMTError, PIDError, CRCError, PCRRepetition}
+
+// Event is a single failed check
+type Event struct {
+	Indicator Indicator
+	PID       uint16
+	Detail    string
+}
+
+// String formats the event for display
+func (e Event) String() string {
+	return fmt.Sprintf("%s on PID 0x%04x: %s", e.Indicator, e.PID, e.Detail)
+}
+
+// PIDStats tracks one PID of a transport stream
+type PIDStats struct {
+	PID      uint16
+	Kind     string
+	Packets  int
+	CCErrors int
+
+	PCRs         int
+	PCRMaxGap    time.Duration
+	PCRTotalGap  time.Duration
+	PCRMaxJitter time.Duration
+	lastCC       int
+	duplicates   int
+	lastSeen     time.Time
+	missing      bool
+	lastPCR      int64
+	lastPCRAt    time.Time
+	pcrGaps      int
+}
+
+// PCRAverageGap returns the mean interval between PCRs
+func (p *PIDStats) PCRAverageGap() time.Duration {
+	if p.pcrGaps == 0 {
+		return 0
+	}
+	return p.PCRTotalGap / time.Duration(p.pcrGaps)
+}
+
+// Analyzer runs TR 101 290 checks on a transport stream carried in datagrams
+type Analyzer struct {
+	Datagrams int
+	Packets   int
+	Errors    map[Indicator]int
+
+	pids     map[uint16]*PIDStats
+	pmtPIDs  map[uint16]*psiTimer
+	esPIDs   map[uint16]bool
+	pat      psiTimer
+	started  time.Time
+	programs map[uint16]uint16
+}
+
+// psiTimer tracks when a table was last seen, flagging each overdue gap once
+type psiTimer struct {
+	last time.Time
+	late bool
+}
+
+// NewAnalyzer creates an analyzer for one transport stream
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{
+		Errors:   make(map[Indicator]int),
+		pids:     make(map[uint16]*PIDStats),
+		pmtPIDs:  make(map[uint16]*psiTimer),
+		esPIDs:   make(map[uint16]bool),
+		programs: make(map[uint16]uint16),
+	}
+}
+
+// Analyze checks the transport stream packets in a datagram received at the given time
+func (a *Analyzer) Analyze(data []byte, at time.Time) []Event {
+	var events []Event
+	report := func(indicator Indicator, pid uint16, format string, args ...interface{}) {
+		a.Errors[indicator]++
+		events = append(events, Event{Indicator: indicator, PID: pid, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if a.started.IsZero() {
+		a.started = at
+		a.pat.last = at
+	}
+	a.Datagrams++
+
+	if rest := len(data) % PacketSize; rest != 0 {
+		report(SyncByteError, PIDNull, "%d trailing bytes after %d packets", rest, len(data)/PacketSize)
+	}
+
+	badSyncs := 0
+	for offset := 0; offset+PacketSize <= len(data); offset += PacketSize {
+		pkt := data[offset : offset+PacketSize]
+		if pkt[0] != SyncByte {
+			badSyncs++
+			report(SyncByteError, PIDNull, "sync byte 0x%02x at offset %d", pkt[0], offset)
+			if badSyncs == 2 {
+				report(SyncLoss, PIDNull, "consecutive corrupted sync bytes at offset %d", offset)
+			}
+			continue
+		}
+		badSyncs = 0
+		a.Packets++
+		a.packet(pkt, at, report)
+	}
+
+	a.checkTimers(at, report)
+	return events
+}
+
+type reporter func(indicator Indicator, pid uint16, format string, args ...interface{})
+
+func (a *Analyzer) packet(pkt []byte, at time.Time, report reporter) {
+	header, err := ParseHeader(pkt)
+	if err != nil {
+		return
+	}
+	pid := header.PID
+	stats := a.pid(pid)
+	stats.Packets++
+	stats.lastSeen = at
+	stats.missing = false
+
+	adaptation, _ := ParseAdaptation(pkt)
+	if pid != PIDNull {
+		a.checkContinuity(stats, pkt, adaptation.Discontinuity, report)
+	}
+	if adaptation.HasPCR {
+		a.checkPCR(stats, adaptation, at, report)
+	}
+
+	switch {
+	case pid == PIDPAT:
+		a.pat.last, a.pat.late = at, false
+		if pkt[3]&0xc0 != 0 {
+			report(PATError, pid, "scrambled PAT")
+			return
+		}
+		if header.PayloadUnitStart {
+			a.parsePAT(pkt, report)
+		}
+	case a.pmtPIDs[pid] != nil:
+		timer := a.pmtPIDs[pid]
+		timer.last, timer.late = at, false
+		if pkt[3]&0xc0 != 0 {
+			report(PMTError, pid, "scrambled PMT")
+			return
+		}
+		if header.PayloadUnitStart {
+			a.parsePMT(pid, pkt, at, report)
+		}
+	}
+}
+
+func (a *Analyzer) pid(pid uint16) *PIDStats {
+	stats, ok := a.pids[pid]
+	if !ok {
+		stats = &PIDStats{PID: pid, lastCC: -1}
+		switch pid {
+		case PIDPAT:
+			stats.Kind = "PAT"
+		case PIDNull:
+			stats.Kind = "null"
+		}
+		a.pids[pid] = stats
+	}
+	return stats
+}
+
+// checkContinuity applies the TR 101 290 continuity rules: the counter
+// increments on packets with a payload, a packet may be repeated once, and a
+// discontinuity indicator resets it
+func (a *Analyzer) checkContinuity(stats *PIDStats, pkt []byte, discontinuity bool, report reporter) {
+	cc := int(pkt[3] & 0x0f)
+	hasPayload := pkt[3]&0x10 != 0
+
+	switch {
+	case stats.lastCC < 0 || discontinuity:
+	case !hasPayload:
+		if cc != stats.lastCC {
+			stats.CCErrors++
+			report(ContinuityError, stats.PID, "counter changed from %d to %d without a payload", stats.lastCC, cc)
+		}
+	case cc == stats.lastCC:
+		stats.duplicates++
+		if stats.duplicates > 1 {
+			stats.CCErrors++
+			report(ContinuityError, stats.PID, "packet with counter %d repeated %d times", cc, stats.duplicates)
+		}
+		return
+	case cc != (stats.lastCC+1)&0x0f:
+		stats.CCErrors++
+		report(ContinuityError, stats.PID, "expected counter %d, got %d", (stats.lastCC+1)&0x0f, cc)
+	}
+	stats.lastCC = cc
+	stats.duplicates = 0
+}
+
+// checkPCR measures the interval between PCRs and how far it strays from the
+// interval between their arrivals
+func (a *Analyzer) checkPCR(stats *PIDStats, adaptation Adaptation, at time.Time, report reporter) {
+	stats.PCRs++
+	defer func() {
+		stats.lastPCR, stats.lastPCRAt = adaptation.PCR, at
+	}()
+	if stats.PCRs == 1 || adaptation.Discontinuity {
+		return
+	}
+
+	gap := PCRDuration(PCRDelta(stats.lastPCR, adaptation.PCR))
+	stats.pcrGaps++
+	stats.PCRTotalGap += gap
+	if gap > stats.PCRMaxGap {
+		stats.PCRMaxGap = gap
+	}
+	if gap > PCRInterval {
+		report(PCRRepetition, stats.PID, "%v between PCRs", gap.Round(time.Microsecond))
+	}
+
+	jitter := at.Sub(stats.lastPCRAt) - gap
+	if jitter < 0 {
+		jitter = -jitter
+	}
+	if jitter > stats.PCRMaxJitter {
+		stats.PCRMaxJitter = jitter
+	}
+}
+
+func (a *Analyzer) parsePAT(pkt []byte, report reporter) {
+	section, err := ParseSection(Payload(pkt))
+	if err == ErrCRC {
+		report(CRCError, PIDPAT, "PAT CRC mismatch")
+		return
+	}
+	if err != nil {
+		return
+	}
+	if section.TableID != TableIDPAT {
+		report(PATError, PIDPAT, "table ID 0x%02x on the PAT PID", section.TableID)
+		return
+	}
+	programs, err := ParsePAT(section)
+	if err != nil {
+		return
+	}
+	for program, pid := range programs {
+		if _, ok := a.pmtPIDs[pid]; !ok {
+			a.pmtPIDs[pid] = &psiTimer{last: a.pat.last}
+			a.pid(pid).Kind = fmt.Sprintf("PMT program %d", program)
+		}
+		a.programs[program] = pid
+	}
+}
+
+func (a *Analyzer) parsePMT(pid uint16, pkt []byte, at time.Time, report reporter) {
+	section, err := ParseSection(Payload(pkt))
+	if err == ErrCRC {
+		report(CRCError, pid, "PMT CRC mismatch")
+		return
+	}
+	if err != nil {
+		return
+	}
+	if section.TableID != TableIDPMT {
+		report(PMTError, pid, "table ID 0x%02x on a PMT PID", section.TableID)
+		return
+	}
+	pmt, err := ParsePMT(section)
+	if err != nil {
+		return
+	}
+	for _, es := range pmt.Streams {
+		stats := a.pid(es.PID)
+		stats.Kind = StreamTypeName(es.Type)
+		if !a.esPIDs[es.PID] {
+			a.esPIDs[es.PID] = true
+			if stats.lastSeen.IsZero() {
+				stats.lastSeen = at
+			}
+		}
+	}
+}
+
+// checkTimers flags a missing PAT, PMT or elementary stream once per gap
+func (a *Analyzer) checkTimers(at time.Time, report reporter) {
+	if !a.pat.late && at.Sub(a.pat.last) > PSIInterval {
+		a.pat.late = true
+		report(PATError, PIDPAT, "no PAT for %v", at.Sub(a.pat.last).Round(time.Millisecond))
+	}
+	for _, pid := range sortedPIDs(a.pmtPIDs) {
+		timer := a.pmtPIDs[pid]
+		if !timer.late && at.Sub(timer.last) > PSIInterval {
+			timer.late = true
+			report(PMTError, pid, "no PMT for %v", at.Sub(timer.last).Round(time.Millisecond))
+		}
+	}
+	for _, pid := range sortedPIDs(a.esPIDs) {
+		stats := a.pids[pid]
+		if !stats.missing && at.Sub(stats.lastSeen) > PIDInterval {
+			stats.missing = true
+			report(PIDError, pid, "%s missing for %v", stats.Kind, at.Sub(stats.lastSeen).Round(time.Millisecond))
+		}
+	}
+}
+
+// PIDs returns the statistics of every PID seen, in PID order
+func (a *Analyzer) PIDs() []PIDStats {
+	pids := make([]PIDStats, 0, len(a.pids))
+	for _, pid := range sortedPIDs(a.pids) {
+		pids = append(pids, *a.pids[pid])
+	}
+	return pids
+}
+
+// Programs returns the PMT PID of each program announced in the PAT
+func (a *Analyzer) Programs() map[uint16]uint16 {
+	programs := make(map[uint16]uint16, len(a.programs))
+	for program, pid := range a.programs {
+		programs[program] = pid
+	}
+	return programs
+}
+
+func sortedPIDs[V any](m map[uint16]V) []uint16 {
+	pids := make([]uint16, 0, len(m))
+	for pid := range m {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids
+}
@@ -0,0 +1,216 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStream builds datagrams of a single-program stream: a PAT and PMT in
+// every datagram, followed by video packets with a PCR in the first one
+type testStream struct {
+	cc  map[uint16]uint8
+	pcr int64
+}
+
+func newTestStream() *testStream {
+	return &testStream{cc: make(map[uint16]uint8)}
+}
+
+func (s *testStream) next(pid uint16) uint8 {
+	cc := s.cc[pid]
+	s.cc[pid] = (cc + 1) & 0x0f
+	return cc
+}
+
+// datagram returns seven packets covering interval of stream time
+func (s *testStream) datagram(interval time.Duration) []byte {
+	data := patPacket(s.next(PIDPAT), map[uint16]uint16{1: 0x1000})
+	data = append(data, pmtPacket(0x1000, s.next(0x1000), 1, 0x100, ElementaryStream{Type: 0x1b, PID: 0x100})...)
+	data = append(data, pcrPacket(0x100, s.next(0x100), s.pcr)...)
+	for i := 0; i < 4; i++ {
+		data = append(data, tsPacket(0x100, s.next(0x100))...)
+	}
+	s.pcr += int64(interval) * PCRClock / int64(time.Second)
+	return data
+}
+
+func TestAnalyzerCleanStream(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := newTestStream()
+	a := NewAnalyzer()
+
+	for i := 0; i < 50; i++ {
+		events := a.Analyze(stream.datagram(20*time.Millisecond), start.Add(time.Duration(i)*20*time.Millisecond))
+		require.Empty(t, events)
+	}
+
+	assert.Equal(t, 50, a.Datagrams)
+	assert.Equal(t, 350, a.Packets)
+	for _, indicator := range Indicators {
+		assert.Zero(t, a.Errors[indicator], indicator)
+	}
+	assert.Equal(t, map[uint16]uint16{1: 0x1000}, a.Programs())
+
+	pids := a.PIDs()
+	require.Len(t, pids, 3)
+	assert.Equal(t, "PAT", pids[0].Kind)
+	assert.Equal(t, "H.264 video", pids[1].Kind)
+	assert.Equal(t, "PMT program 1", pids[2].Kind)
+	assert.Equal(t, 250, pids[1].Packets)
+	assert.Equal(t, 50, pids[1].PCRs)
+	assert.Equal(t, 20*time.Millisecond, pids[1].PCRAverageGap())
+	assert.Equal(t, 20*time.Millisecond, pids[1].PCRMaxGap)
+	assert.Zero(t, pids[1].PCRMaxJitter)
+}
+
+func TestAnalyzerErrors(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		corrupt   func(i int, data []byte) []byte
+		indicator Indicator
+		count     int
+	}{
+		{
+			name: "lost video packet",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					return append(data[:4*PacketSize:4*PacketSize], data[5*PacketSize:]...)
+				}
+				return data
+			},
+			indicator: ContinuityError,
+			count:     1,
+		},
+		{
+			name: "packet repeated twice",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					video := data[6*PacketSize:]
+					return append(append(data, video...), video...)
+				}
+				return data
+			},
+			indicator: ContinuityError,
+			count:     1,
+		},
+		{
+			name: "discontinuity indicator resets the counter",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					data[2*PacketSize+5] |= 0x80
+					for p := 2; p < 7; p++ {
+						data[p*PacketSize+3] = data[p*PacketSize+3]&0xf0 | byte(p-2)
+					}
+				}
+				return data
+			},
+			indicator: ContinuityError,
+			// the next datagram continues from the sender's counter, not the reset one
+			count: 1,
+		},
+		{
+			name: "corrupted sync bytes",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					data[3*PacketSize] = 0
+					data[4*PacketSize] = 0
+				}
+				return data
+			},
+			indicator: SyncLoss,
+			count:     1,
+		},
+		{
+			name: "truncated datagram",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					return data[:len(data)-10]
+				}
+				return data
+			},
+			indicator: SyncByteError,
+			count:     1,
+		},
+		{
+			name: "PAT and PMT missing",
+			corrupt: func(i int, data []byte) []byte {
+				if i >= 5 && i < 40 {
+					return data[2*PacketSize:]
+				}
+				return data
+			},
+			indicator: PATError,
+			count:     1,
+		},
+		{
+			name: "corrupted PAT",
+			corrupt: func(i int, data []byte) []byte {
+				if i == 5 {
+					data[10] ^= 0xff
+				}
+				return data
+			},
+			indicator: CRCError,
+			count:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := newTestStream()
+			a := NewAnalyzer()
+			for i := 0; i < 50; i++ {
+				data := stream.datagram(20 * time.Millisecond)
+				if tt.corrupt != nil {
+					data = tt.corrupt(i, data)
+				}
+				a.Analyze(data, start.Add(time.Duration(i)*20*time.Millisecond))
+			}
+			assert.Equal(t, tt.count, a.Errors[tt.indicator])
+		})
+	}
+}
+
+func TestAnalyzerPSITimeouts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := newTestStream()
+	a := NewAnalyzer()
+
+	a.Analyze(stream.datagram(20*time.Millisecond), start)
+
+	// Only null packets for 6 seconds: PAT, PMT and the video PID go missing once each
+	var events []Event
+	for i := 1; i <= 60; i++ {
+		events = append(events, a.Analyze(tsPacket(PIDNull, 0), start.Add(time.Duration(i)*100*time.Millisecond))...)
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, PATError, events[0].Indicator)
+	assert.Equal(t, PMTError, events[1].Indicator)
+	assert.Equal(t, uint16(0x1000), events[1].PID)
+	assert.Equal(t, PIDError, events[2].Indicator)
+	assert.Equal(t, "PID_error on PID 0x0100: H.264 video missing for 5.1s", events[2].String())
+
+	// The timers restart when the tables return
+	a.Analyze(stream.datagram(20*time.Millisecond), start.Add(7*time.Second))
+	assert.Empty(t, a.Analyze(stream.datagram(20*time.Millisecond), start.Add(7100*time.Millisecond)))
+}
+
+func TestAnalyzerPCR(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream := newTestStream()
+	a := NewAnalyzer()
+
+	a.Analyze(stream.datagram(60*time.Millisecond), start)
+	events := a.Analyze(stream.datagram(60*time.Millisecond), start.Add(62*time.Millisecond))
+	require.Len(t, events, 1)
+	assert.Equal(t, PCRRepetition, events[0].Indicator)
+
+	pids := a.PIDs()
+	assert.Equal(t, 60*time.Millisecond, pids[1].PCRMaxGap)
+	assert.Equal(t, 2*time.Millisecond, pids[1].PCRMaxJitter)
+}
@@ -0,0 +1,138 @@
+// Package mpegts parses, checks and generates MPEG transport stream (ISO/IEC
+// 13818-1) packets.
+package mpegts
+
+import (
+	"errors"
+	"time"
+)
+
+// PacketSize is the size of a transport stream packet
+const PacketSize = 188
+
+// SyncByte starts every transport stream packet
+const SyncByte = 0x47
+
+// Well-known PIDs
+const (
+	PIDPAT  = 0x0000
+	PIDNull = 0x1fff
+)
+
+// ErrNotTS is returned for data that is not a sequence of transport stream packets
+var ErrNotTS = errors.New("not an MPEG transport stream")
+
+// Header is the header of one transport stream packet
+type Header struct {
+	TransportError    bool
+	PayloadUnitStart  bool
+	PID               uint16
+	Scrambling        uint8
+	HasAdaptation     bool
+	HasPayload        bool
+	ContinuityCounter uint8
+}
+
+// ParseHeader parses the 4-byte header of a transport stream packet
+func ParseHeader(pkt []byte) (Header, error) {
+	if len(pkt) < 4 || pkt[0] != SyncByte {
+		return Header{}, ErrNotTS
+	}
+	return Header{
+		TransportError:    pkt[1]&0x80 != 0,
+		PayloadUnitStart:  pkt[1]&0x40 != 0,
+		PID:               uint16(pkt[1]&0x1f)<<8 | uint16(pkt[2]),
+		Scrambling:        pkt[3] >> 6,
+		HasAdaptation:     pkt[3]&0x20 != 0,
+		HasPayload:        pkt[3]&0x10 != 0,
+		ContinuityCounter: pkt[3] & 0x0f,
+	}, nil
+}
+
+// IsTransportStream reports whether data is a whole number of transport
+// stream packets, each starting with the sync byte
+func IsTransportStream(data []byte) bool {
+	if len(data) == 0 || len(data)%PacketSize != 0 {
+		return false
+	}
+	for i := 0; i < len(data); i += PacketSize {
+		if data[i] != SyncByte {
+			return false
+		}
+	}
+	return true
+}
+
+// Packets splits data into transport stream packets, stopping at the first
+// packet that does not start with the sync byte
+func Packets(data []byte) [][]byte {
+	var packets [][]byte
+	for len(data) >= PacketSize && data[0] == SyncByte {
+		packets = append(packets, data[:PacketSize])
+		data = data[PacketSize:]
+	}
+	return packets
+}
+
+// PCRClock is the frequency of the program clock reference
+const PCRClock = 27000000
+
+// pcrWrap is where the 33-bit PCR base multiplied by 300 wraps around
+const pcrWrap = (1 << 33) * 300
+
+// Adaptation is the part of an adaptation field mcaster analyses
+type Adaptation struct {
+	Discontinuity bool
+	HasPCR        bool
+	// PCR is in 27 MHz ticks
+	PCR int64
+}
+
+// ParseAdaptation parses the adaptation field of a transport stream packet
+func ParseAdaptation(pkt []byte) (Adaptation, bool) {
+	if len(pkt) < 6 || pkt[3]&0x20 == 0 || pkt[4] == 0 {
+		return Adaptation{}, false
+	}
+	length := int(pkt[4])
+	flags := pkt[5]
+	a := Adaptation{Discontinuity: flags&0x80 != 0}
+	if flags&0x10 != 0 && length >= 7 && len(pkt) >= 12 {
+		base := int64(pkt[6])<<25 | int64(pkt[7])<<17 | int64(pkt[8])<<9 | int64(pkt[9])<<1 | int64(pkt[10])>>7
+		ext := int64(pkt[10]&0x01)<<8 | int64(pkt[11])
+		a.HasPCR = true
+		a.PCR = base*300 + ext
+	}
+	return a, true
+}
+
+// PCRDelta returns the ticks from PCR a to PCR b, allowing for wrap-around
+func PCRDelta(a, b int64) int64 {
+	delta := b - a
+	if delta < 0 {
+		delta += pcrWrap
+	}
+	return delta
+}
+
+// PCRDuration converts 27 MHz ticks to a duration
+func PCRDuration(ticks int64) time.Duration {
+	return time.Duration(ticks * 1000 / (PCRClock / 1000000))
+}
+
+// Payload returns the payload of a transport stream packet, after any adaptation field
+func Payload(pkt []byte) []byte {
+	if len(pkt) < 4 || pkt[3]&0x10 == 0 {
+		return nil
+	}
+	offset := 4
+	if pkt[3]&0x20 != 0 {
+		if len(pkt) < 5 {
+			return nil
+		}
+		offset += 1 + int(pkt[4])
+	}
+	if offset > len(pkt) {
+		return nil
+	}
+	return pkt[offset:]
+}
@@ -0,0 +1,94 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tsPacket builds a transport stream packet with the given PID and continuity counter
+func tsPacket(pid uint16, cc uint8) []byte {
+	pkt := make([]byte, PacketSize)
+	pkt[0] = SyncByte
+	pkt[1] = byte(pid >> 8 & 0x1f)
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | cc&0x0f
+	return pkt
+}
+
+func TestParseHeader(t *testing.T) {
+	pkt := tsPacket(0x1234&0x1fff, 7)
+	pkt[1] |= 0x40
+	pkt[3] |= 0x20
+
+	h, err := ParseHeader(pkt)
+	require.NoError(t, err)
+	assert.Equal(t, Header{PayloadUnitStart: true, PID: 0x1234, HasAdaptation: true,
+		HasPayload: true, ContinuityCounter: 7}, h)
+
+	_, err = ParseHeader([]byte{0x46, 0, 0, 0})
+	assert.ErrorIs(t, err, ErrNotTS)
+}
+
+func TestIsTransportStream(t *testing.T) {
+	two := append(tsPacket(0, 0), tsPacket(0x100, 0)...)
+	assert.True(t, IsTransportStream(two))
+	assert.Len(t, Packets(two), 2)
+
+	assert.False(t, IsTransportStream(two[:200]))
+	assert.False(t, IsTransportStream(nil))
+
+	broken := append([]byte(nil), two...)
+	broken[PacketSize] = 0
+	assert.False(t, IsTransportStream(broken))
+	assert.Len(t, Packets(broken), 1)
+}
+
+// pcrPacket builds a packet with an adaptation field carrying a PCR in 27 MHz ticks
+func pcrPacket(pid uint16, cc uint8, pcr int64) []byte {
+	pkt := tsPacket(pid, cc)
+	pkt[3] |= 0x20
+	pkt[4] = 7
+	pkt[5] = 0x10
+	base, ext := pcr/300, pcr%300
+	pkt[6] = byte(base >> 25)
+	pkt[7] = byte(base >> 17)
+	pkt[8] = byte(base >> 9)
+	pkt[9] = byte(base >> 1)
+	pkt[10] = byte(base<<7) | 0x7e | byte(ext>>8)
+	pkt[11] = byte(ext)
+	return pkt
+}
+
+func TestParseAdaptation(t *testing.T) {
+	pcr := int64(1<<33-1)*300 + 299
+	a, ok := ParseAdaptation(pcrPacket(0x100, 0, pcr))
+	require.True(t, ok)
+	assert.Equal(t, Adaptation{HasPCR: true, PCR: pcr}, a)
+
+	pkt := pcrPacket(0x100, 0, 0)
+	pkt[5] = 0x80
+	a, ok = ParseAdaptation(pkt)
+	require.True(t, ok)
+	assert.Equal(t, Adaptation{Discontinuity: true}, a)
+
+	_, ok = ParseAdaptation(tsPacket(0x100, 0))
+	assert.False(t, ok)
+}
+
+func TestPayload(t *testing.T) {
+	assert.Len(t, Payload(tsPacket(0x100, 0)), PacketSize-4)
+	assert.Len(t, Payload(pcrPacket(0x100, 0, 0)), PacketSize-12)
+
+	adaptationOnly := pcrPacket(0x100, 0, 0)
+	adaptationOnly[3] &^= 0x10
+	assert.Nil(t, Payload(adaptationOnly))
+}
+
+func TestPCRDelta(t *testing.T) {
+	assert.Equal(t, int64(PCRClock/25), PCRDelta(1000, 1000+PCRClock/25))
+	assert.Equal(t, int64(400), PCRDelta(pcrWrap-100, 300))
+	assert.Equal(t, 40*time.Millisecond, PCRDuration(PCRClock/25))
+}
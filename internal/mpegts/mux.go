@@ -0,0 +1,297 @@
+package mpegts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PacketsPerDatagram is the usual number of transport stream packets in a UDP datagram
+const PacketsPerDatagram = 7
+
+// MinBitrate is the lowest bitrate at which datagrams, and so PCRs, are at most 40ms apart
+const MinBitrate = PacketsPerDatagram * PacketSize * 8 * 25
+
+// StreamIDPrivate1 is the PES stream ID of private data
+const StreamIDPrivate1 = 0xbd
+
+// StreamTypePrivate is the PMT stream type of private data in PES packets
+const StreamTypePrivate = 0x06
+
+const (
+	// psiInterval is how often the muxer repeats the PAT and PMT, in PCR ticks
+	psiInterval = PCRClock / 10
+	// pcrInterval is how often the muxer sends a PCR, in PCR ticks
+	pcrInterval = PCRClock / 50
+	// pesHeaderSize is the PES header with a PTS
+	pesHeaderSize = 14
+	// pcrFieldSize is an adaptation field carrying only a PCR, with its length byte
+	pcrFieldSize = 8
+)
+
+// MaxPayload is the largest payload that fits in one datagram
+const MaxPayload = PacketsPerDatagram*(PacketSize-4) - pcrFieldSize - pesHeaderSize
+
+// ErrPayloadTooLarge is returned for payloads that do not fit in one datagram
+var ErrPayloadTooLarge = errors.New("payload does not fit in one datagram")
+
+// MuxConfig describes the single-program transport stream a Muxer generates
+type MuxConfig struct {
+	Program uint16
+	PMTPID  uint16
+	// PID carries the payload and the PCR
+	PID uint16
+	// Bitrate is the constant bitrate of the stream in bits per second
+	Bitrate int
+}
+
+// Validate checks the configuration
+func (c MuxConfig) Validate() error {
+	if c.Program == 0 {
+		return fmt.Errorf("MPEG-TS program number must not be 0")
+	}
+	for _, pid := range []uint16{c.PMTPID, c.PID} {
+		if pid < 0x10 || pid >= PIDNull {
+			return fmt.Errorf("MPEG-TS PID 0x%04x is outside 0x0010-0x1ffe", pid)
+		}
+	}
+	if c.PMTPID == c.PID {
+		return fmt.Errorf("MPEG-TS PMT PID and payload PID must differ, both are 0x%04x", c.PID)
+	}
+	if c.Bitrate < MinBitrate {
+		return fmt.Errorf("MPEG-TS bitrate must be at least %d bit/s for a PCR every 40ms, got %d", MinBitrate, c.Bitrate)
+	}
+	return nil
+}
+
+// Muxer generates a constant bitrate, single-program transport stream in
+// datagrams of seven packets: the PAT and PMT, one PES packet per payload on
+// the payload PID, PCRs on the payload PID and null packets as stuffing
+type Muxer struct {
+	MuxConfig
+	continuity map[uint16]uint8
+	packets    int64
+	lastPSI    int64
+	lastPCR    int64
+}
+
+// NewMuxer creates a muxer; the first datagram carries the PAT and PMT
+func NewMuxer(cfg MuxConfig) (*Muxer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Muxer{
+		MuxConfig:  cfg,
+		continuity: make(map[uint16]uint8),
+		lastPSI:    -psiInterval,
+		lastPCR:    -pcrInterval,
+	}, nil
+}
+
+// DatagramInterval returns the time between datagrams at the configured bitrate
+func (m *Muxer) DatagramInterval() time.Duration {
+	return time.Duration(PacketsPerDatagram * PacketSize * 8 * int64(time.Second) / int64(m.Bitrate))
+}
+
+// clock returns the time of the next packet from its position in the stream, in PCR ticks
+func (m *Muxer) clock() int64 {
+	return int64(float64(m.packets) * PacketSize * 8 * PCRClock / float64(m.Bitrate))
+}
+
+// pcr returns the PCR of the next packet
+func (m *Muxer) pcr() int64 {
+	return m.clock() % pcrWrap
+}
+
+// Datagram returns the next datagram. A non-nil payload is sent as one PES
+// packet filling the datagram from its start, so receivers can extract it
+// without reassembly; otherwise the datagram carries a PCR when it is due,
+// the PAT and PMT when they are due, and stuffing.
+func (m *Muxer) Datagram(payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d", ErrPayloadTooLarge, len(payload), MaxPayload)
+	}
+
+	data := make([]byte, 0, PacketsPerDatagram*PacketSize)
+	if payload != nil {
+		data = m.appendPES(data, payload)
+	} else {
+		// PCRs lead the datagram, so they are a whole number of datagrams apart
+		if m.clock()-m.lastPCR >= pcrInterval {
+			data = m.appendPCR(data)
+		}
+		if m.clock()-m.lastPSI >= psiInterval {
+			m.lastPSI = m.clock()
+			data = m.appendSection(data, PIDPAT, m.pat())
+			data = m.appendSection(data, m.PMTPID, m.pmt())
+		}
+	}
+	for len(data) < cap(data) {
+		data = m.appendNull(data)
+	}
+	return data, nil
+}
+
+// header appends a packet header, counting continuity on packets with a payload
+func (m *Muxer) header(data []byte, pid uint16, start, adaptation, payload bool) []byte {
+	b1 := byte(pid >> 8 & 0x1f)
+	if start {
+		b1 |= 0x40
+	}
+	// Without a payload the counter repeats the previous packet's
+	b3 := (m.continuity[pid] - 1) & 0x0f
+	if payload {
+		b3 = m.continuity[pid] | 0x10
+		m.continuity[pid] = (m.continuity[pid] + 1) & 0x0f
+	}
+	if adaptation {
+		b3 |= 0x20
+	}
+	m.packets++
+	return append(data, SyncByte, b1, byte(pid), b3)
+}
+
+// appendPCR appends an adaptation-only packet carrying a PCR
+func (m *Muxer) appendPCR(data []byte) []byte {
+	pcr := m.pcr()
+	m.lastPCR = m.clock()
+	data = m.header(data, m.PID, false, true, false)
+	data = append(data, PacketSize-5, 0x10)
+	data = appendPCRField(data, pcr)
+	for i := 12; i < PacketSize; i++ {
+		data = append(data, 0xff)
+	}
+	return data
+}
+
+// appendPES appends a PES packet with the payload, the first packet carrying a PCR
+func (m *Muxer) appendPES(data, payload []byte) []byte {
+	pcr := m.pcr()
+	m.lastPCR = m.clock()
+
+	pes := []byte{0, 0, 1, StreamIDPrivate1, 0, 0, 0x80, 0x80, 5}
+	binary.BigEndian.PutUint16(pes[4:], uint16(pesHeaderSize-6+len(payload)))
+	pes = appendPTS(pes, pcr/300)
+	pes = append(pes, payload...)
+
+	for first := true; first || len(pes) > 0; first = false {
+		room := PacketSize - 4
+		if first {
+			room -= pcrFieldSize
+		}
+		stuffing := room - len(pes)
+		if stuffing < 0 {
+			stuffing = 0
+		}
+		adaptation := first || stuffing > 0
+		data = m.header(data, m.PID, first, adaptation, true)
+		if adaptation {
+			data = appendAdaptation(data, first, pcr, stuffing)
+		}
+		n := room - stuffing
+		data = append(data, pes[:n]...)
+		pes = pes[n:]
+	}
+	return data
+}
+
+// appendAdaptation appends an adaptation field with an optional PCR, padded
+// by stuffing bytes
+func appendAdaptation(data []byte, withPCR bool, pcr int64, stuffing int) []byte {
+	if !withPCR && stuffing == 1 {
+		return append(data, 0)
+	}
+	length := stuffing - 1
+	flags := byte(0)
+	if withPCR {
+		length = pcrFieldSize - 1 + stuffing
+		flags = 0x10
+	}
+	data = append(data, byte(length), flags)
+	if withPCR {
+		data = appendPCRField(data, pcr)
+	}
+	fill := length - 1
+	if withPCR {
+		fill -= 6
+	}
+	for i := 0; i < fill; i++ {
+		data = append(data, 0xff)
+	}
+	return data
+}
+
+// appendSection appends a PSI section in one packet
+func (m *Muxer) appendSection(data []byte, pid uint16, section []byte) []byte {
+	data = m.header(data, pid, true, false, true)
+	data = append(data, 0)
+	data = append(data, section...)
+	for i := 5 + len(section); i < PacketSize; i++ {
+		data = append(data, 0xff)
+	}
+	return data
+}
+
+func (m *Muxer) appendNull(data []byte) []byte {
+	m.packets++
+	data = append(data, SyncByte, PIDNull>>8, PIDNull&0xff, 0x10)
+	for i := 4; i < PacketSize; i++ {
+		data = append(data, 0xff)
+	}
+	return data
+}
+
+func (m *Muxer) pat() []byte {
+	var body []byte
+	body = binary.BigEndian.AppendUint16(body, m.Program)
+	body = binary.BigEndian.AppendUint16(body, 0xe000|m.PMTPID)
+	return section(TableIDPAT, 1, body)
+}
+
+func (m *Muxer) pmt() []byte {
+	body := []byte{0xe0 | byte(m.PID>>8), byte(m.PID), 0xf0, 0}
+	body = append(body, StreamTypePrivate, 0xe0|byte(m.PID>>8), byte(m.PID), 0xf0, 0)
+	return section(TableIDPMT, m.Program, body)
+}
+
+// section builds a PSI section of version 0 with its CRC
+func section(tableID uint8, extension uint16, body []byte) []byte {
+	s := []byte{tableID, 0, 0, byte(extension >> 8), byte(extension), 0xc1, 0, 0}
+	s = append(s, body...)
+	binary.BigEndian.PutUint16(s[1:], 0xb000|uint16(len(s)-3+4))
+	return binary.BigEndian.AppendUint32(s, CRC32(s))
+}
+
+func appendPCRField(data []byte, pcr int64) []byte {
+	base, ext := pcr/300, pcr%300
+	return append(data, byte(base>>25), byte(base>>17), byte(base>>9), byte(base>>1),
+		byte(base<<7)|0x7e|byte(ext>>8), byte(ext))
+}
+
+func appendPTS(data []byte, pts int64) []byte {
+	return append(data,
+		0x21|byte(pts>>29)&0x0e,
+		byte(pts>>22), byte(pts>>14)|0x01,
+		byte(pts>>7), byte(pts<<1)|0x01)
+}
+
+// ParsePES returns the stream ID and the payload of a PES packet
+func ParsePES(data []byte) (uint8, []byte, error) {
+	if len(data) < 9 || data[0] != 0 || data[1] != 0 || data[2] != 1 {
+		return 0, nil, fmt.Errorf("no PES start code")
+	}
+	streamID := data[3]
+	end := len(data)
+	if length := int(binary.BigEndian.Uint16(data[4:])); length > 0 {
+		if 6+length > len(data) {
+			return 0, nil, fmt.Errorf("PES packet of %d bytes is truncated to %d", 6+length, len(data))
+		}
+		end = 6 + length
+	}
+	start := 9 + int(data[8])
+	if start > end {
+		return 0, nil, fmt.Errorf("invalid PES header length %d", data[8])
+	}
+	return streamID, data[start:end], nil
+}
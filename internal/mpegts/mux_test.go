@@ -0,0 +1,114 @@
+package mpegts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMux = MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000}
+
+func TestMuxConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *MuxConfig)
+		err    string
+	}{
+		{name: "valid", modify: func(c *MuxConfig) {}},
+		{name: "program 0", modify: func(c *MuxConfig) { c.Program = 0 }, err: "program number"},
+		{name: "reserved PID", modify: func(c *MuxConfig) { c.PID = 0x0001 }, err: "outside"},
+		{name: "null PID", modify: func(c *MuxConfig) { c.PMTPID = PIDNull }, err: "outside"},
+		{name: "same PIDs", modify: func(c *MuxConfig) { c.PMTPID = c.PID }, err: "must differ"},
+		{name: "bitrate", modify: func(c *MuxConfig) { c.Bitrate = MinBitrate - 1 }, err: "bitrate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testMux
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+// TestMuxerPassesAnalysis feeds the generated stream, at the pace of its
+// bitrate, through the TR 101 290 checks
+func TestMuxerPassesAnalysis(t *testing.T) {
+	for _, bitrate := range []int{MinBitrate, 2000000, 15000000} {
+		cfg := testMux
+		cfg.Bitrate = bitrate
+		m, err := NewMuxer(cfg)
+		require.NoError(t, err)
+		a := NewAnalyzer()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 2000; i++ {
+			var payload []byte
+			if i%10 == 0 {
+				payload = bytes.Repeat([]byte{'x'}, i%MaxPayload+1)
+			}
+			data, err := m.Datagram(payload)
+			require.NoError(t, err)
+			require.Len(t, data, PacketsPerDatagram*PacketSize)
+
+			events := a.Analyze(data, start.Add(time.Duration(i)*m.DatagramInterval()))
+			require.Empty(t, events, "datagram %d at %d bit/s", i, bitrate)
+		}
+
+		assert.Equal(t, map[uint16]uint16{1: 0x1000}, a.Programs())
+		pids := a.PIDs()
+		require.Len(t, pids, 4)
+		assert.Equal(t, "private data", pids[1].Kind)
+		assert.LessOrEqual(t, pids[1].PCRMaxGap, PCRInterval)
+		assert.Less(t, pids[1].PCRMaxJitter, time.Microsecond)
+	}
+}
+
+func TestMuxerPayload(t *testing.T) {
+	m, err := NewMuxer(testMux)
+	require.NoError(t, err)
+
+	for _, size := range []int{1, 150, 162, 163, 170, 183, 184, 500, MaxPayload} {
+		payload := bytes.Repeat([]byte{byte(size)}, size)
+		data, err := m.Datagram(payload)
+		require.NoError(t, err)
+
+		// The PES starts in the first packet and continues on the same PID
+		var pes []byte
+		for _, pkt := range Packets(data) {
+			header, err := ParseHeader(pkt)
+			require.NoError(t, err)
+			if header.PID == testMux.PID {
+				pes = append(pes, Payload(pkt)...)
+			}
+		}
+		streamID, got, err := ParsePES(pes)
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, uint8(StreamIDPrivate1), streamID)
+		assert.Equal(t, payload, got, "size %d", size)
+	}
+
+	_, err = m.Datagram(make([]byte, MaxPayload+1))
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+}
+
+func TestParsePES(t *testing.T) {
+	_, _, err := ParsePES([]byte{0, 0, 2, 0xbd, 0, 0, 0x80, 0, 0})
+	assert.Error(t, err)
+	_, _, err = ParsePES([]byte{0, 0, 1, 0xbd, 0, 10, 0x80, 0, 0})
+	assert.Error(t, err)
+
+	// Video PES packets may leave the length unset
+	_, payload, err := ParsePES([]byte{0, 0, 1, 0xe0, 0, 0, 0x80, 0, 0, 'a', 'b'})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ab"), payload)
+}
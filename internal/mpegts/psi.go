@@ -0,0 +1,155 @@
+package mpegts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Table IDs of the program specific information mcaster analyses
+const (
+	TableIDPAT = 0x00
+	TableIDPMT = 0x02
+)
+
+// ErrCRC is returned for sections whose CRC does not match
+var ErrCRC = errors.New("section CRC mismatch")
+
+// Section is a program specific information section
+type Section struct {
+	TableID uint8
+	// TableIDExtension is the transport stream ID of a PAT or the program number of a PMT
+	TableIDExtension uint16
+	Version          uint8
+	// Data is the section body between the header and the CRC
+	Data []byte
+}
+
+// ParseSection parses the first section starting in the payload of a packet
+// with the payload unit start indicator set. Sections that continue into the
+// next packet are not supported.
+func ParseSection(payload []byte) (*Section, error) {
+	if len(payload) < 1 {
+		return nil, fmt.Errorf("empty payload")
+	}
+	pointer := int(payload[0])
+	if 1+pointer >= len(payload) {
+		return nil, fmt.Errorf("invalid pointer field %d", pointer)
+	}
+	section := payload[1+pointer:]
+	if len(section) < 3 {
+		return nil, fmt.Errorf("truncated section header")
+	}
+
+	length := int(binary.BigEndian.Uint16(section[1:]) & 0x0fff)
+	if length < 9 || 3+length > len(section) {
+		return nil, fmt.Errorf("section length %d does not fit in the packet", length)
+	}
+	section = section[:3+length]
+	if CRC32(section[:len(section)-4]) != binary.BigEndian.Uint32(section[len(section)-4:]) {
+		return nil, ErrCRC
+	}
+
+	return &Section{
+		TableID:          section[0],
+		TableIDExtension: binary.BigEndian.Uint16(section[3:]),
+		Version:          section[5] >> 1 & 0x1f,
+		Data:             section[8 : len(section)-4],
+	}, nil
+}
+
+// ParsePAT returns the PMT PID of every program in a PAT section
+func ParsePAT(s *Section) (map[uint16]uint16, error) {
+	if s.TableID != TableIDPAT {
+		return nil, fmt.Errorf("table ID 0x%02x is not a PAT", s.TableID)
+	}
+	if len(s.Data)%4 != 0 {
+		return nil, fmt.Errorf("invalid PAT length %d", len(s.Data))
+	}
+	programs := make(map[uint16]uint16)
+	for i := 0; i < len(s.Data); i += 4 {
+		program := binary.BigEndian.Uint16(s.Data[i:])
+		pid := binary.BigEndian.Uint16(s.Data[i+2:]) & 0x1fff
+		// Program 0 points to the network information table
+		if program != 0 {
+			programs[program] = pid
+		}
+	}
+	return programs, nil
+}
+
+// PMT is a parsed program map table
+type PMT struct {
+	Program uint16
+	PCRPID  uint16
+	Streams []ElementaryStream
+}
+
+// ElementaryStream is one stream of a program
+type ElementaryStream struct {
+	Type uint8
+	PID  uint16
+}
+
+// ParsePMT parses a PMT section
+func ParsePMT(s *Section) (*PMT, error) {
+	if s.TableID != TableIDPMT {
+		return nil, fmt.Errorf("table ID 0x%02x is not a PMT", s.TableID)
+	}
+	if len(s.Data) < 4 {
+		return nil, fmt.Errorf("truncated PMT")
+	}
+	pmt := &PMT{
+		Program: s.TableIDExtension,
+		PCRPID:  binary.BigEndian.Uint16(s.Data) & 0x1fff,
+	}
+	offset := 4 + int(binary.BigEndian.Uint16(s.Data[2:])&0x0fff)
+	for offset+5 <= len(s.Data) {
+		pmt.Streams = append(pmt.Streams, ElementaryStream{
+			Type: s.Data[offset],
+			PID:  binary.BigEndian.Uint16(s.Data[offset+1:]) & 0x1fff,
+		})
+		offset += 5 + int(binary.BigEndian.Uint16(s.Data[offset+3:])&0x0fff)
+	}
+	return pmt, nil
+}
+
+var streamTypes = map[uint8]string{
+	0x01: "MPEG-1 video",
+	0x02: "MPEG-2 video",
+	0x03: "MPEG-1 audio",
+	0x04: "MPEG-2 audio",
+	0x06: "private data",
+	0x0f: "AAC audio",
+	0x11: "LATM AAC audio",
+	0x15: "metadata",
+	0x1b: "H.264 video",
+	0x24: "HEVC video",
+	0x81: "AC-3 audio",
+	0x86: "SCTE-35",
+	0x87: "E-AC-3 audio",
+}
+
+// StreamTypeName describes a PMT stream type
+func StreamTypeName(t uint8) string {
+	if name, ok := streamTypes[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("stream type 0x%02x", t)
+}
+
+// CRC32 computes the MPEG-2 CRC of a section (polynomial 0x04c11db7, not reflected)
+func CRC32(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
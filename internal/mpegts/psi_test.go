@@ -0,0 +1,106 @@
+package mpegts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// psiPacket builds a packet carrying one section with a valid CRC
+func psiPacket(pid uint16, cc uint8, tableID uint8, extension uint16, data []byte) []byte {
+	section := []byte{tableID, 0, 0, byte(extension >> 8), byte(extension), 0xc1, 0, 0}
+	section = append(section, data...)
+	binary.BigEndian.PutUint16(section[1:], 0xb000|uint16(len(section)-3+4))
+	section = binary.BigEndian.AppendUint32(section, CRC32(section))
+
+	pkt := tsPacket(pid, cc)
+	pkt[1] |= 0x40
+	pkt[4] = 0
+	copy(pkt[5:], section)
+	for i := 5 + len(section); i < PacketSize; i++ {
+		pkt[i] = 0xff
+	}
+	return pkt
+}
+
+// patPacket builds a PAT announcing each program's PMT PID
+func patPacket(cc uint8, programs map[uint16]uint16) []byte {
+	var data []byte
+	for program, pid := range programs {
+		data = binary.BigEndian.AppendUint16(data, program)
+		data = binary.BigEndian.AppendUint16(data, 0xe000|pid)
+	}
+	return psiPacket(PIDPAT, cc, TableIDPAT, 1, data)
+}
+
+// pmtPacket builds a PMT with the PCR on pcrPID and the given streams
+func pmtPacket(pid uint16, cc uint8, program, pcrPID uint16, streams ...ElementaryStream) []byte {
+	data := []byte{0xe0 | byte(pcrPID>>8), byte(pcrPID), 0xf0, 0}
+	for _, es := range streams {
+		data = append(data, es.Type, 0xe0|byte(es.PID>>8), byte(es.PID), 0xf0, 0)
+	}
+	return psiPacket(pid, cc, TableIDPMT, program, data)
+}
+
+func TestCRC32(t *testing.T) {
+	assert.Equal(t, uint32(0x0376e6e7), CRC32([]byte("123456789")))
+}
+
+func TestParsePAT(t *testing.T) {
+	pkt := patPacket(0, map[uint16]uint16{1: 0x1000})
+	section, err := ParseSection(Payload(pkt))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(TableIDPAT), section.TableID)
+	assert.Equal(t, uint8(0), section.Version)
+
+	programs, err := ParsePAT(section)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint16]uint16{1: 0x1000}, programs)
+
+	_, err = ParsePMT(section)
+	assert.Error(t, err)
+}
+
+func TestParsePMT(t *testing.T) {
+	pkt := pmtPacket(0x1000, 0, 1, 0x100,
+		ElementaryStream{Type: 0x1b, PID: 0x100}, ElementaryStream{Type: 0x0f, PID: 0x101})
+	section, err := ParseSection(Payload(pkt))
+	require.NoError(t, err)
+
+	pmt, err := ParsePMT(section)
+	require.NoError(t, err)
+	assert.Equal(t, &PMT{Program: 1, PCRPID: 0x100, Streams: []ElementaryStream{
+		{Type: 0x1b, PID: 0x100}, {Type: 0x0f, PID: 0x101},
+	}}, pmt)
+	assert.Equal(t, "H.264 video", StreamTypeName(0x1b))
+	assert.Equal(t, "stream type 0xee", StreamTypeName(0xee))
+}
+
+func TestParseSectionErrors(t *testing.T) {
+	corrupt := patPacket(0, map[uint16]uint16{1: 0x1000})
+	corrupt[15] ^= 0xff
+
+	tests := []struct {
+		name    string
+		payload []byte
+		err     error
+	}{
+		{name: "empty", payload: nil},
+		{name: "pointer past the end", payload: []byte{10, 0, 0}},
+		{name: "truncated header", payload: []byte{0, 0}},
+		{name: "length past the end", payload: []byte{0, 0, 0xb0, 0xff, 0, 0}},
+		{name: "CRC mismatch", payload: Payload(corrupt), err: ErrCRC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSection(tt.payload)
+			require.Error(t, err)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
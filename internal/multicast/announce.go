@@ -0,0 +1,169 @@
+package multicast
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+	"github.com/hyposcaler-bot/mcaster/internal/sap"
+)
+
+// DefaultSAPGroup is where globally scoped IPv4 sessions are announced
+var DefaultSAPGroup = net.JoinHostPort(sap.Address, strconv.Itoa(sap.Port))
+
+// Describe returns an SDP description of the stream the sender emits: its
+// group, port, TTL and payload format
+func (s *Sender) Describe() *sap.Session {
+	local := s.conn.LocalAddr().(*net.UDPAddr).IP
+	addrType, connection := "IP4", fmt.Sprintf("%s/%d", s.groupAddr.IP, s.ttl)
+	if s.groupAddr.IP.To4() == nil {
+		addrType, connection = "IP6", s.groupAddr.IP.String()
+	}
+	originType := "IP4"
+	if local.To4() == nil {
+		originType = "IP6"
+	}
+
+	info := "mcaster test stream from " + s.hostname
+	if s.senderID != "" {
+		info += ", sender " + s.senderID
+	}
+
+	media := sap.Media{Type: "application", Port: s.groupAddr.Port, Protocol: "udp", Formats: []string{"mcaster"}}
+	switch {
+	case s.rtp != nil:
+		media = sap.Media{Type: "audio", Port: s.groupAddr.Port, Protocol: "RTP/AVP",
+			Formats:    []string{strconv.Itoa(int(s.rtp.PayloadType))},
+			Attributes: []string{fmt.Sprintf("rtpmap:%d %s/%d", s.rtp.PayloadType, rtpEncoding(s.rtp.PayloadType), s.rtp.ClockRate)}}
+		if s.rtp.ClockRate == defaultRTPClockRate {
+			media.Type = "video"
+		}
+	case s.mux != nil:
+		media = sap.Media{Type: "video", Port: s.groupAddr.Port, Protocol: "udp", Formats: []string{"mpeg"}}
+	}
+
+	return &sap.Session{
+		Origin:     fmt.Sprintf("- %d 1 IN %s %s", uint64(s.session), originType, local),
+		Name:       s.streamID,
+		Info:       info,
+		Connection: fmt.Sprintf("IN %s %s", addrType, connection),
+		Attributes: []string{"tool:mcaster", "type:broadcast", "x-mcaster-session:" + s.session.String()},
+		Media:      []sap.Media{media},
+	}
+}
+
+// rtpEncoding names the encoding of a payload type in an rtpmap attribute
+func rtpEncoding(pt uint8) string {
+	if name := rtp.PayloadTypeName(pt); name != "dynamic" && name != "unassigned" {
+		return name
+	}
+	return "mcaster"
+}
+
+// Announcer periodically announces a sender's session with SAP (RFC 2974)
+type Announcer struct {
+	conn     *net.UDPConn
+	addr     *net.UDPAddr
+	interval time.Duration
+	packet   sap.Packet
+	out      io.Writer
+}
+
+// NewAnnouncer prepares announcements of the sender's session to the SAP
+// group sapAddr, sent from the same interface with the same TTL as the stream
+// and reported to the sender's output
+func NewAnnouncer(s *Sender, sapAddr string, interval time.Duration) (*Announcer, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("announcement interval must be positive, got %v", interval)
+	}
+	addr, err := net.ResolveUDPAddr("udp", sapAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SAP address: %w", err)
+	}
+	if err := network.ValidateGroup(addr.IP, false); err != nil {
+		return nil, err
+	}
+
+	conn, err := network.DialMulticastUDP(addr, network.DialOptions{
+		Interface:    s.iface,
+		SourceIP:     s.sourceIP,
+		BindToDevice: s.bindToDevice,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SAP connection: %w", err)
+	}
+	// RFC 2974 sends announcements with the scope of the session they describe
+	if err := network.SetMulticastTTL(conn, s.ttl); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set multicast TTL: %w", err)
+	}
+	if err := network.SetMulticastLoopback(conn, s.loopback); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set multicast loopback: %w", err)
+	}
+
+	sdp := s.Describe().String()
+	hash := fnv.New32a()
+	hash.Write([]byte(sdp))
+	sum := hash.Sum32()
+
+	return &Announcer{
+		conn:     conn,
+		addr:     addr,
+		interval: interval,
+		packet: sap.Packet{
+			MessageHash: uint16(sum>>16) ^ uint16(sum),
+			Origin:      conn.LocalAddr().(*net.UDPAddr).IP,
+			Payload:     []byte(sdp),
+		},
+		out: s.out,
+	}, nil
+}
+
+// Run announces the session until ctx is done, then sends a deletion so
+// listeners drop it at once
+func (a *Announcer) Run(ctx context.Context) {
+	defer a.conn.Close()
+
+	fmt.Fprintf(a.out, "📢 Announcing the session via SAP to %s every %v\n", a.addr, a.interval)
+	for {
+		if err := a.send(false); err != nil {
+			log.Printf("❌ Failed to send SAP announcement: %v", err)
+		}
+
+		// RFC 2974 randomises the interval by ±1/3 so announcers do not synchronise
+		wait := a.interval*2/3 + time.Duration(rand.Int63n(int64(a.interval*2/3)+1))
+		select {
+		case <-ctx.Done():
+			if err := a.send(true); err != nil {
+				log.Printf("❌ Failed to send SAP deletion: %v", err)
+				return
+			}
+			fmt.Fprintf(a.out, "🗑️  Sent SAP deletion to %s\n", a.addr)
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// send sends an announcement, or a deletion of the session
+func (a *Announcer) send(deletion bool) error {
+	packet := a.packet
+	packet.Deletion = deletion
+	data, err := packet.Marshal()
+	if err != nil {
+		return err
+	}
+	if _, err := a.conn.Write(data); err != nil {
+		return fmt.Errorf("failed to send SAP packet: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package multicast
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/hyposcaler-bot/mcaster/internal/sap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderDescribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []SenderOption
+		media    sap.Media
+		commands []string
+	}{
+		{
+			name:     "messages",
+			media:    sap.Media{Type: "application", Port: 5000, Protocol: "udp", Formats: []string{"mcaster"}},
+			commands: []string{"mcaster receive -g 239.1.1.1:5000"},
+		},
+		{
+			name: "RTP video",
+			opts: []SenderOption{WithRTP(RTPConfig{PayloadType: 96, PacketsPerFrame: 1, FrameRate: 25})},
+			media: sap.Media{Type: "video", Port: 5000, Protocol: "RTP/AVP", Formats: []string{"96"},
+				Attributes: []string{"rtpmap:96 mcaster/90000"}},
+			commands: []string{"mcaster receive -g 239.1.1.1:5000 --rtp"},
+		},
+		{
+			name: "RTP audio",
+			opts: []SenderOption{WithRTP(RTPConfig{PayloadType: 0, PacketsPerFrame: 1, FrameRate: 50})},
+			media: sap.Media{Type: "audio", Port: 5000, Protocol: "RTP/AVP", Formats: []string{"0"},
+				Attributes: []string{"rtpmap:0 PCMU/8000"}},
+			commands: []string{"mcaster receive -g 239.1.1.1:5000 --rtp"},
+		},
+		{
+			name:     "MPEG-TS",
+			opts:     []SenderOption{WithMPEGTS(mpegts.MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000})},
+			media:    sap.Media{Type: "video", Port: 5000, Protocol: "udp", Formats: []string{"mpeg"}},
+			commands: []string{"mcaster receive -g 239.1.1.1:5000 --mpegts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewSender("239.1.1.1:5000", "", time.Second, 16, 0, 0, tt.opts...)
+			require.NoError(t, err)
+			defer sender.conn.Close()
+
+			// The description survives a round trip through SDP text
+			s, err := sap.ParseSDP(sender.Describe().String())
+			require.NoError(t, err)
+			assert.Equal(t, "239.1.1.1:5000", s.Name)
+			assert.Equal(t, "IN IP4 239.1.1.1/16", s.Connection)
+			session, ok := s.Attribute("x-mcaster-session")
+			assert.True(t, ok)
+			assert.Equal(t, sender.session.String(), session)
+
+			assert.Equal(t, []sap.Media{tt.media}, s.Media)
+			assert.Equal(t, tt.commands, receiveCommands(s))
+		})
+	}
+}
+
+func TestNewAnnouncerValidation(t *testing.T) {
+	sender, err := NewSender("239.1.1.1:5000", "", time.Second, 1, 0, 0)
+	require.NoError(t, err)
+	defer sender.conn.Close()
+
+	_, err = NewAnnouncer(sender, DefaultSAPGroup, 0)
+	assert.ErrorContains(t, err, "interval must be positive")
+	_, err = NewAnnouncer(sender, "192.0.2.1:9875", time.Second)
+	assert.Error(t, err)
+}
+
+func TestAnnouncer(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	receiver, err := NewReceiver("239.23.23.45:23252", "lo", 0, WithSAPListing())
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+	listing := receiver.decoders[0].(*sapDecoder)
+
+	var out strings.Builder
+	sender, err := NewSender("239.23.23.46:23253", "lo", time.Second, 1, 0, 0, WithSenderID("probe-a"), WithSenderOutput(&out))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	announcer, err := NewAnnouncer(sender, "239.23.23.45:23252", time.Hour)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		announcer.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	if err := receiver.receivePacket(); err != nil {
+		cancel()
+		<-done
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+	require.Len(t, listing.sessions, 1)
+	for _, s := range listing.sessions {
+		assert.Equal(t, "239.23.23.46:23253", s.session.Name)
+		assert.Contains(t, s.session.Info, "sender probe-a")
+	}
+
+	// Stopping the announcer deletes the session
+	cancel()
+	<-done
+	require.NoError(t, receiver.receivePacket())
+	assert.Empty(t, listing.sessions)
+	assert.Contains(t, out.String(), "Announcing the session via SAP to 239.23.23.45:23252")
+	assert.Contains(t, out.String(), "Sent SAP deletion to 239.23.23.45:23252")
+}
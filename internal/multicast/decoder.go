@@ -0,0 +1,27 @@
+package multicast
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+)
+
+// Decoder analyses datagrams in a format other than mcaster messages, such as
+// RTP feeds, so existing traffic can be monitored without test senders
+type Decoder interface {
+	// Decode analyses a datagram and reports whether it was in the decoder's
+	// format. Events such as a new stream are written to out, which discards
+	// them when the receiver is quiet.
+	Decode(pkt *Packet, group string, out io.Writer) bool
+	// PrintSummary writes the results of the analysis to w when the receiver stops
+	PrintSummary(w io.Writer)
+	// String describes what the decoder analyses
+	String() string
+}
+
+// looksLikeMessage reports whether a datagram may be an mcaster message, which
+// is JSON, possibly signed, or encrypted
+func looksLikeMessage(data []byte) bool {
+	return auth.IsEncrypted(data) || bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("{"))
+}
@@ -0,0 +1,80 @@
+package multicast
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+// Join joins a group on the receiver's socket, which may already be
+// receiving; the group is received on the receiver's port
+func (r *Receiver) Join(group net.IP) error {
+	if !group.IsMulticast() {
+		return fmt.Errorf("cannot join %s: not a multicast group", group)
+	}
+	// A socket bound to one group address cannot receive the others
+	if bound := r.conn.LocalAddr().(*net.UDPAddr).IP; bound.IsMulticast() && !bound.Equal(group) {
+		return fmt.Errorf("cannot join %s: the socket is bound to %s; use --bind any to receive other groups", group, bound)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.joined[group.String()] {
+		return fmt.Errorf("already joined %s", group)
+	}
+	if err := network.JoinGroup(r.conn, r.iface, group); err != nil {
+		return err
+	}
+	r.joined[group.String()] = true
+	for _, known := range r.groups {
+		if known.IP.Equal(group) {
+			return nil
+		}
+	}
+	r.groups = append(r.groups, &net.UDPAddr{IP: group, Port: r.groupAddr.Port})
+	return nil
+}
+
+// Leave leaves a group joined by NewReceiver or Join; the socket stays open
+// to receive the other groups or join again
+func (r *Receiver) Leave(group net.IP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.joined[group.String()] {
+		return fmt.Errorf("cannot leave %s: not joined", group)
+	}
+	if err := network.LeaveGroup(r.conn, r.iface, group); err != nil {
+		return err
+	}
+	r.joined[group.String()] = false
+	return nil
+}
+
+// Joined returns the groups the receiver is joined to, in the order they
+// were first joined
+func (r *Receiver) Joined() []*net.UDPAddr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var joined []*net.UDPAddr
+	for _, group := range r.groups {
+		if r.joined[group.IP.String()] {
+			joined = append(joined, group)
+		}
+	}
+	return joined
+}
+
+// groupList returns every group the receiver was created with or joined since
+func (r *Receiver) groupList() []*net.UDPAddr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*net.UDPAddr(nil), r.groups...)
+}
+
+// groupCount returns how many groups the receiver was created with or joined since
+func (r *Receiver) groupCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.groups)
+}
@@ -0,0 +1,94 @@
+package multicast
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiverJoinLeave(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	receiver, err := NewReceiver("239.23.23.61:23261", "lo", 0, WithBind("any"), WithOutput(io.Discard))
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	first, second := net.ParseIP("239.23.23.61"), net.ParseIP("239.23.23.62")
+	assert.Equal(t, "239.23.23.61:23261", joinAddrs(receiver.Joined()))
+
+	require.NoError(t, receiver.Join(second))
+	assert.Len(t, receiver.Joined(), 2)
+	assert.ErrorContains(t, receiver.Join(second), "already joined")
+
+	require.NoError(t, receiver.Leave(first))
+	assert.ErrorContains(t, receiver.Leave(first), "not joined")
+	assert.Equal(t, "239.23.23.62:23261", joinAddrs(receiver.Joined()))
+
+	// Joining again keeps the order the groups were first joined in
+	require.NoError(t, receiver.Join(first))
+	assert.Equal(t, "239.23.23.61:23261, 239.23.23.62:23261", joinAddrs(receiver.Joined()))
+
+	assert.ErrorContains(t, receiver.Join(net.ParseIP("192.0.2.1")), "not a multicast group")
+	assert.ErrorContains(t, receiver.Leave(net.ParseIP("239.23.23.63")), "not joined")
+}
+
+func TestReceiverJoinBoundToGroup(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	receiver, err := NewReceiver("239.23.23.61:23261", "lo", 0, WithOutput(io.Discard))
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	assert.ErrorContains(t, receiver.Join(net.ParseIP("239.23.23.62")), "--bind any")
+	// The group it is bound to can be left and joined again
+	require.NoError(t, receiver.Leave(net.ParseIP("239.23.23.61")))
+	assert.Empty(t, receiver.Joined())
+	require.NoError(t, receiver.Join(net.ParseIP("239.23.23.61")))
+}
+
+func TestReceiverLeaveStopsDelivery(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	receiver, err := NewReceiver("239.23.23.61:23261", "lo", 0, WithOutput(io.Discard))
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- receiver.Start()
+	}()
+
+	sender, err := NewSender("239.23.23.61:23261", "lo", 10*time.Millisecond, 1, 0, 0, WithSenderOutput(io.Discard))
+	require.NoError(t, err)
+	go sender.Start()
+	defer sender.Close()
+
+	received := func() int {
+		total := 0
+		for _, stats := range receiver.Streams() {
+			total += stats.Received
+		}
+		return total
+	}
+	require.Eventually(t, func() bool { return received() > 0 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, receiver.Leave(net.ParseIP("239.23.23.61")))
+	time.Sleep(50 * time.Millisecond)
+	left := received()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, left, received(), "nothing should arrive while left")
+
+	require.NoError(t, receiver.Join(net.ParseIP("239.23.23.61")))
+	require.Eventually(t, func() bool { return received() > left }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, receiver.Close())
+	require.NoError(t, <-done)
+}
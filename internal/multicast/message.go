@@ -1,27 +1,201 @@
 package multicast
 
 import (
+	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ProtocolVersion is the message format this version of mcaster sends
+const ProtocolVersion = 2
+
+// Capabilities flags the optional features a message uses
+type Capabilities uint32
+
+// Capability flags
+const (
+	// CapIdentity messages carry a session ID, stream and optional sender ID
+	CapIdentity Capabilities = 1 << iota
+	// CapLabels messages carry key/value labels
+	CapLabels
+	// CapPayload messages carry an opaque payload
+	CapPayload
+)
+
+var capabilityNames = []struct {
+	flag Capabilities
+	name string
+}{
+	{CapIdentity, "identity"},
+	{CapLabels, "labels"},
+	{CapPayload, "payload"},
+}
+
+// Has reports whether all flags in c are set
+func (c Capabilities) Has(flags Capabilities) bool {
+	return c&flags == flags
+}
+
+// String lists the capability names, with unknown flags from newer senders in hex
+func (c Capabilities) String() string {
+	var names []string
+	for _, known := range capabilityNames {
+		if c.Has(known.flag) {
+			names = append(names, known.name)
+			c &^= known.flag
+		}
+	}
+	if c != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint32(c)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// UnsupportedVersionError is returned for messages in a protocol version this
+// receiver cannot decode, typically from a newer sender
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported protocol version %d", e.Version)
+}
+
 // Message represents a multicast test message
 type Message struct {
+	Version      int               `json:"version,omitempty"`
+	Capabilities Capabilities      `json:"caps,omitempty"`
+	ID           int               `json:"id"`
+	Timestamp    time.Time         `json:"timestamp"`
+	Source       string            `json:"source"`
+	Session      SessionID         `json:"session,omitempty"`
+	SenderID     string            `json:"sender_id,omitempty"`
+	Stream       string            `json:"stream,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Payload      []byte            `json:"payload,omitempty"`
+}
+
+// SessionID identifies one run of a sender. It is random, so senders on the
+// same host stay apart and a restarted sender starts a new session.
+type SessionID uint64
+
+// NewSessionID returns a random session ID
+func NewSessionID() (SessionID, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return SessionID(binary.BigEndian.Uint64(b[:])), nil
+}
+
+// String formats the session ID as 16 hex digits
+func (s SessionID) String() string {
+	return fmt.Sprintf("%016x", uint64(s))
+}
+
+// MarshalText encodes the session ID as hex, which JSON numbers cannot hold exactly in every language
+func (s SessionID) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a hex session ID
+func (s *SessionID) UnmarshalText(text []byte) error {
+	v, err := strconv.ParseUint(string(text), 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid session ID %q: %w", text, err)
+	}
+	*s = SessionID(v)
+	return nil
+}
+
+// messageV1 is the original message format, which had no version field
+type messageV1 struct {
 	ID        int       `json:"id"`
 	Timestamp time.Time `json:"timestamp"`
 	Source    string    `json:"source"`
 }
 
-// Marshal serializes the message to JSON
+// decoders decode each supported protocol version
+var decoders = map[int]func([]byte) (*Message, error){
+	1: decodeV1,
+	2: decodeV2,
+}
+
+// Marshal serializes the message to JSON in the current protocol version
 func (m *Message) Marshal() ([]byte, error) {
-	return json.Marshal(m)
+	return m.marshalVersion(ProtocolVersion)
+}
+
+// marshalVersion serializes the message in the given protocol version, as
+// senders of that version did, dropping fields the version cannot carry
+func (m *Message) marshalVersion(version int) ([]byte, error) {
+	switch version {
+	case 1:
+		return json.Marshal(messageV1{ID: m.ID, Timestamp: m.Timestamp, Source: m.Source})
+	case 2:
+		msg := *m
+		msg.Version = 2
+		msg.Capabilities |= m.capabilities()
+		return json.Marshal(&msg)
+	}
+	return nil, &UnsupportedVersionError{Version: version}
+}
+
+// capabilities returns the flags for the optional fields that are set
+func (m *Message) capabilities() Capabilities {
+	var caps Capabilities
+	if m.Session != 0 {
+		caps |= CapIdentity
+	}
+	if len(m.Labels) > 0 {
+		caps |= CapLabels
+	}
+	if len(m.Payload) > 0 {
+		caps |= CapPayload
+	}
+	return caps
 }
 
-// UnmarshalMessage deserializes JSON data into a Message
+// UnmarshalMessage deserializes JSON data into a Message, using the decoder
+// for the message's protocol version. Messages without a version are version 1.
 func UnmarshalMessage(data []byte) (*Message, error) {
+	var header struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if header.Version != nil {
+		version = *header.Version
+	}
+	decode, ok := decoders[version]
+	if !ok {
+		return nil, &UnsupportedVersionError{Version: version}
+	}
+	return decode(data)
+}
+
+func decodeV1(data []byte) (*Message, error) {
+	var v1 messageV1
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+	return &Message{Version: 1, ID: v1.ID, Timestamp: v1.Timestamp, Source: v1.Source}, nil
+}
+
+func decodeV2(data []byte) (*Message, error) {
 	var msg Message
-	err := json.Unmarshal(data, &msg)
-	if err != nil {
+	if err := json.Unmarshal(data, &msg); err != nil {
 		return nil, err
 	}
 	return &msg, nil
@@ -31,3 +205,26 @@ func UnmarshalMessage(data []byte) (*Message, error) {
 func (m *Message) Age() time.Duration {
 	return time.Since(m.Timestamp)
 }
+
+// LabelString formats the labels as sorted key=value pairs
+func (m *Message) LabelString() string {
+	keys := make([]string, 0, len(m.Labels))
+	for key := range m.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + m.Labels[key]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Origin names the sender for display: the sender ID label, if any, and the host
+func (m *Message) Origin() string {
+	if m.SenderID != "" {
+		return m.SenderID + "@" + m.Source
+	}
+	return m.Source
+}
@@ -326,4 +326,184 @@ func BenchmarkMessageMarshalUnmarshal(b *testing.B) {
 			b.Fatal(err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestMessageIdentity(t *testing.T) {
+	msg := &Message{
+		ID:        1,
+		Timestamp: time.Now(),
+		Source:    "test-host",
+		Session:   SessionID(0x00ab0000000000cd),
+		SenderID:  "probe-a",
+		Stream:    "239.1.1.1:5000",
+	}
+
+	data, err := msg.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"session":"00ab0000000000cd"`)
+
+	decoded, err := UnmarshalMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Session, decoded.Session)
+	assert.Equal(t, "probe-a", decoded.SenderID)
+	assert.Equal(t, "239.1.1.1:5000", decoded.Stream)
+	assert.Equal(t, "probe-a@test-host", decoded.Origin())
+
+	// Messages from older senders have no identity fields
+	legacy, err := UnmarshalMessage([]byte(`{"id":3,"timestamp":"2024-01-01T00:00:00Z","source":"old-host"}`))
+	require.NoError(t, err)
+	assert.Zero(t, legacy.Session)
+	assert.Equal(t, "old-host", legacy.Origin())
+
+	plain, err := (&Message{ID: 1, Source: "h"}).Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(plain), "session")
+
+	_, err = UnmarshalMessage([]byte(`{"version":2,"id":1,"session":"not-hex"}`))
+	assert.Error(t, err)
+}
+
+func TestNewSessionID(t *testing.T) {
+	a, err := NewSessionID()
+	require.NoError(t, err)
+	b, err := NewSessionID()
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a.String(), 16)
+}
+
+func TestMessageLabelsAndPayload(t *testing.T) {
+	msg := &Message{
+		ID:        1,
+		Timestamp: time.Now(),
+		Source:    "test-host",
+		Labels:    map[string]string{"run": "chg1234", "env": "lab"},
+		Payload:   []byte{0x00, 0xff, 'x'},
+	}
+
+	data, err := msg.Marshal()
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalMessage(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Labels, decoded.Labels)
+	assert.Equal(t, msg.Payload, decoded.Payload)
+	assert.Equal(t, "env=lab run=chg1234", decoded.LabelString())
+
+	// Older messages have neither, and messages without them stay compact
+	legacy, err := UnmarshalMessage([]byte(`{"id":3,"timestamp":"2024-01-01T00:00:00Z","source":"old-host"}`))
+	require.NoError(t, err)
+	assert.Nil(t, legacy.Labels)
+	assert.Nil(t, legacy.Payload)
+	assert.Empty(t, legacy.LabelString())
+
+	plain, err := (&Message{ID: 1, Source: "h"}).Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(plain), "labels")
+	assert.NotContains(t, string(plain), "payload")
+}
+
+// legacyMessage is how a version 1 receiver decodes messages: it only knows
+// the original fields and ignores the rest
+type legacyMessage struct {
+	ID        int       `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+}
+
+func TestMessageCompatibility(t *testing.T) {
+	sent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	msg := &Message{
+		ID:        9,
+		Timestamp: sent,
+		Source:    "host-a",
+		Session:   0xabc,
+		SenderID:  "probe",
+		Stream:    "239.1.1.1:5000",
+		Labels:    map[string]string{"run": "chg1234"},
+		Payload:   []byte("data"),
+	}
+
+	tests := []struct {
+		name         string
+		encode       int
+		legacy       bool // decode as a version 1 receiver would
+		version      int
+		capabilities Capabilities
+		full         bool // identity, labels and payload survive
+	}{
+		{name: "v1 sender, v1 receiver", encode: 1, legacy: true},
+		{name: "v1 sender, v2 receiver", encode: 1, version: 1},
+		{name: "v2 sender, v1 receiver", encode: 2, legacy: true},
+		{name: "v2 sender, v2 receiver", encode: 2, version: 2,
+			capabilities: CapIdentity | CapLabels | CapPayload, full: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := msg.marshalVersion(tt.encode)
+			require.NoError(t, err)
+
+			if tt.legacy {
+				var decoded legacyMessage
+				require.NoError(t, json.Unmarshal(data, &decoded))
+				assert.Equal(t, legacyMessage{ID: 9, Timestamp: sent, Source: "host-a"}, decoded)
+				return
+			}
+
+			decoded, err := UnmarshalMessage(data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, decoded.Version)
+			assert.Equal(t, tt.capabilities, decoded.Capabilities)
+			assert.Equal(t, 9, decoded.ID)
+			assert.Equal(t, sent, decoded.Timestamp)
+			assert.Equal(t, "host-a", decoded.Source)
+			if tt.full {
+				assert.Equal(t, msg.Session, decoded.Session)
+				assert.Equal(t, "probe", decoded.SenderID)
+				assert.Equal(t, msg.Stream, decoded.Stream)
+				assert.Equal(t, msg.Labels, decoded.Labels)
+				assert.Equal(t, msg.Payload, decoded.Payload)
+			} else {
+				assert.Zero(t, decoded.Session)
+				assert.Nil(t, decoded.Labels)
+				assert.Nil(t, decoded.Payload)
+			}
+		})
+	}
+}
+
+func TestUnmarshalUnsupportedVersion(t *testing.T) {
+	_, err := UnmarshalMessage([]byte(`{"version":3,"caps":255,"id":1,"source":"future-host"}`))
+	var unsupported *UnsupportedVersionError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, 3, unsupported.Version)
+	assert.Equal(t, "unsupported protocol version 3", err.Error())
+
+	_, err = UnmarshalMessage([]byte(`{"version":"2","id":1}`))
+	assert.Error(t, err)
+	assert.NotErrorAs(t, err, &unsupported)
+
+	_, err = (&Message{}).marshalVersion(7)
+	assert.ErrorAs(t, err, &unsupported)
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		caps     Capabilities
+		expected string
+	}{
+		{caps: 0, expected: "none"},
+		{caps: CapIdentity, expected: "identity"},
+		{caps: CapIdentity | CapPayload, expected: "identity,payload"},
+		{caps: CapLabels | 1<<8, expected: "labels,0x100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.caps.String())
+		})
+	}
+
+	assert.True(t, (CapIdentity | CapLabels).Has(CapLabels))
+	assert.False(t, CapIdentity.Has(CapIdentity|CapLabels))
+}
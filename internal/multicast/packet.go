@@ -0,0 +1,28 @@
+package multicast
+
+import (
+	"net"
+	"time"
+)
+
+// maxDatagramSize is large enough for any UDP datagram
+const maxDatagramSize = 65535
+
+// maxMessageSize is the largest UDP payload an IPv4 datagram can carry
+const maxMessageSize = 65507
+
+// Packet is a received datagram together with the metadata the kernel reported for it
+type Packet struct {
+	// Data is the UDP payload
+	Data []byte
+	// Source is the address the datagram came from
+	Source *net.UDPAddr
+	// Destination is the group (or unicast address) the datagram was sent to
+	Destination *net.UDPAddr
+	// ReceivedAt is the kernel receive timestamp, or the read time if unavailable
+	ReceivedAt time.Time
+	// TTL is the IPv4 TTL or IPv6 hop limit (-1 if unknown)
+	TTL int
+	// Truncated is set when the datagram did not fit in the receive buffer
+	Truncated bool
+}
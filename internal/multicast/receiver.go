@@ -1,81 +1,666 @@
 package multicast
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+	"github.com/hyposcaler-bot/mcaster/internal/decode"
 	"github.com/hyposcaler-bot/mcaster/internal/network"
 )
 
 // Receiver handles multicast packet reception
 type Receiver struct {
-	conn      *net.UDPConn
-	groupAddr *net.UDPAddr
-	buffer    []byte
+	conn         *net.UDPConn
+	groupAddr    *net.UDPAddr
+	groups       []*net.UDPAddr
+	iface        *net.Interface
+	buffer       []byte
+	oob          []byte
+	localHost    *network.LocalHost
+	ignoreLocal  bool
+	bindMode     string
+	reuseAddr    bool
+	reusePort    bool
+	allowUnicast bool
+	recorder     Recorder
+	keyRing      *auth.KeyRing
+	authCounts   map[auth.Status]int
+	cipherRing   *auth.KeyRing
+	cipherCounts map[auth.CipherStatus]int
+	tsSenders    map[string]bool
+	decodeMode   decode.Mode
+	streams      *streamTable
+	decoders     []Decoder
+	warnings     []string
+	quiet        bool
+	out          io.Writer
+	// mu guards groups and joined, which Join and Leave change while Start runs
+	mu     sync.Mutex
+	joined map[string]bool
+	rejoin *rejoinTester
 }
 
-// NewReceiver creates a new multicast receiver
-func NewReceiver(groupAddr, interfaceName string, dport int) (*Receiver, error) {
-	// Override destination port if specified
-	finalGroupAddr, err := network.OverrideGroupPort(groupAddr, dport)
+// ReceiverOption configures optional Receiver behaviour
+type ReceiverOption func(*Receiver)
+
+// WithIgnoreLocal drops packets sent from this host instead of labelling them as local echo
+func WithIgnoreLocal(ignore bool) ReceiverOption {
+	return func(r *Receiver) {
+		r.ignoreLocal = ignore
+	}
+}
+
+// WithBind selects what the socket binds to: "group" (the default), "any" for
+// the wildcard address, or an explicit local IP address
+func WithBind(mode string) ReceiverOption {
+	return func(r *Receiver) {
+		r.bindMode = mode
+	}
+}
+
+// WithReuseAddr controls SO_REUSEADDR on the receiving socket (default on)
+func WithReuseAddr(enabled bool) ReceiverOption {
+	return func(r *Receiver) {
+		r.reuseAddr = enabled
+	}
+}
+
+// WithReusePort controls SO_REUSEPORT on the receiving socket (default off)
+func WithReusePort(enabled bool) ReceiverOption {
+	return func(r *Receiver) {
+		r.reusePort = enabled
+	}
+}
+
+// WithUnicastGroups permits unicast addresses in place of multicast groups
+func WithUnicastGroups(allow bool) ReceiverOption {
+	return func(r *Receiver) {
+		r.allowUnicast = allow
+	}
+}
+
+// WithRecorder stores every received datagram with rec, e.g. in a capture file
+func WithRecorder(rec Recorder) ReceiverOption {
+	return func(r *Receiver) {
+		r.recorder = rec
+	}
+}
+
+// WithVerifyKeys rejects messages that are not signed with one of the keys in ring
+func WithVerifyKeys(ring *auth.KeyRing) ReceiverOption {
+	return func(r *Receiver) {
+		r.keyRing = ring
+	}
+}
+
+// WithDecryptKeys decrypts encrypted messages with the keys in ring. Messages
+// that are not encrypted are still accepted.
+func WithDecryptKeys(ring *auth.KeyRing) ReceiverOption {
+	return func(r *Receiver) {
+		r.cipherRing = ring
+	}
+}
+
+// WithDecodeMode selects how datagrams that are not mcaster messages are shown (default auto)
+func WithDecodeMode(mode decode.Mode) ReceiverOption {
+	return func(r *Receiver) {
+		r.decodeMode = mode
+	}
+}
+
+// WithDecoder analyses datagrams that are not mcaster messages with d
+func WithDecoder(d Decoder) ReceiverOption {
+	return func(r *Receiver) {
+		r.decoders = append(r.decoders, d)
+	}
+}
+
+// WithRTPAnalysis tracks RTP streams per SSRC: loss, reordering, jitter,
+// payload type and markers. A clockRate of 0 uses the rate of the payload type.
+func WithRTPAnalysis(clockRate int) ReceiverOption {
+	return func(r *Receiver) {
+		r.decoders = append(r.decoders, newRTPDecoder(r.streams, clockRate))
+	}
+}
+
+// WithTSAnalysis runs TR 101 290 checks on MPEG transport streams, carried
+// directly in UDP or in RTP, and reports them per group
+func WithTSAnalysis() ReceiverOption {
+	return func(r *Receiver) {
+		r.decoders = append(r.decoders, newTSDecoder())
+	}
+}
+
+// WithSAPListing lists the sessions announced with SAP (RFC 2974), with the
+// receive command for each of their streams
+func WithSAPListing() ReceiverOption {
+	return func(r *Receiver) {
+		r.decoders = append(r.decoders, newSAPDecoder())
+	}
+}
+
+// WithOutput writes the receiver's output to w instead of stdout
+func WithOutput(w io.Writer) ReceiverOption {
+	return func(r *Receiver) {
+		r.out = w
+	}
+}
+
+// WithQuiet suppresses the banner and the output of every packet, for a UI
+// that shows the stream stats instead; summaries are still printed on Close
+func WithQuiet() ReceiverOption {
+	return func(r *Receiver) {
+		r.quiet = true
+	}
+}
+
+// WithRejoin leaves and joins the receiver's groups again as cfg describes,
+// timing the first packet after every join and the last after every leave
+func WithRejoin(cfg RejoinConfig) ReceiverOption {
+	return func(r *Receiver) {
+		r.rejoin = newRejoinTester(r, cfg)
+	}
+}
+
+// NewReceiver creates a new multicast receiver. groupAddr may be a
+// comma-separated list of groups, which must all use the same port.
+func NewReceiver(groupAddr, interfaceName string, dport int, opts ...ReceiverOption) (*Receiver, error) {
+	groups, err := network.ResolveGroups(groupAddr, dport)
 	if err != nil {
 		return nil, err
 	}
 
-	addr, err := net.ResolveUDPAddr("udp", finalGroupAddr)
+	iface, err := network.GetInterface(interfaceName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve multicast address: %w", err)
+		return nil, err
 	}
 
-	iface, err := network.GetInterface(interfaceName)
+	localHost, err := network.NewLocalHost()
+	if err != nil {
+		return nil, err
+	}
+
+	receiver := &Receiver{
+		groupAddr:    groups[0],
+		groups:       groups,
+		iface:        iface,
+		buffer:       make([]byte, maxDatagramSize),
+		oob:          make([]byte, 256),
+		localHost:    localHost,
+		reuseAddr:    true,
+		decodeMode:   decode.ModeAuto,
+		authCounts:   make(map[auth.Status]int),
+		cipherCounts: make(map[auth.CipherStatus]int),
+		tsSenders:    make(map[string]bool),
+		streams:      newStreamTable(),
+		out:          os.Stdout,
+		joined:       make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(receiver)
+	}
+
+	for _, group := range groups {
+		if err := network.ValidateGroup(group.IP, receiver.allowUnicast); err != nil {
+			return nil, err
+		}
+		if group.Port != receiver.groupAddr.Port {
+			return nil, fmt.Errorf("all groups must use the same port: %s and %s differ", receiver.groupAddr, group)
+		}
+	}
+	receiver.warnings = network.GroupWarnings(network.GroupIPs(groups))
+
+	if receiver.rejoin != nil {
+		if err := receiver.rejoin.cfg.Validate(); err != nil {
+			return nil, err
+		}
+		if !receiver.groupAddr.IP.IsMulticast() {
+			return nil, fmt.Errorf("cannot rejoin %s: not a multicast group", receiver.groupAddr.IP)
+		}
+	}
+
+	// A socket bound to one group address cannot receive the others
+	bindMode := receiver.bindMode
+	if len(groups) > 1 {
+		switch bindMode {
+		case "":
+			bindMode = network.BindAny
+		case network.BindGroup:
+			return nil, fmt.Errorf("--bind group needs a single group; use --bind any to receive %d groups", len(groups))
+		}
+	}
+
+	bindAddr, err := network.ParseBindAddr(bindMode, receiver.groupAddr.IP)
 	if err != nil {
 		return nil, err
 	}
-	
-	conn, err := net.ListenMulticastUDP("udp", iface, addr)
+
+	// Only groups joined on this socket are delivered, so mcaster can share a
+	// port with other listeners without stealing or duplicating their traffic
+	conn, err := network.ListenMulticastUDP(receiver.groupAddr, network.ListenOptions{
+		Interface: iface,
+		BindAddr:  bindAddr,
+		ReuseAddr: receiver.reuseAddr,
+		ReusePort: receiver.reusePort,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen on multicast address: %w", err)
 	}
 
-	return &Receiver{
-		conn:      conn,
-		groupAddr: addr,
-		buffer:    make([]byte, 1024),
-	}, nil
+	receiver.conn = conn
+	if receiver.groupAddr.IP.IsMulticast() {
+		receiver.joined[receiver.groupAddr.IP.String()] = true
+	}
+	for _, group := range groups[1:] {
+		if err := receiver.Join(group.IP); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := network.EnablePacketInfo(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return receiver, nil
 }
 
 // Start begins receiving multicast packets
 func (r *Receiver) Start() error {
 	defer r.conn.Close()
 
-	fmt.Printf("🎯 Starting multicast receiver on %s\n", r.groupAddr)
-	fmt.Printf("👂 Waiting for packets...\n\n")
+	if !r.quiet {
+		r.printBanner()
+	}
+	if r.rejoin != nil {
+		go r.rejoin.run(r.Joined())
+	}
 
 	for {
 		if err := r.receivePacket(); err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				if r.rejoin != nil {
+					r.rejoin.close()
+				}
+				r.streams.printSummary(r.out)
+				for _, d := range r.decoders {
+					d.PrintSummary(r.out)
+				}
+				r.printAuthSummary()
+				r.printCipherSummary()
+				if r.rejoin != nil {
+					r.rejoin.printSummary(r.out)
+				}
+				return nil
+			}
 			log.Printf("❌ Failed to receive packet: %v", err)
 			continue
 		}
 	}
 }
 
+// printBanner describes what the receiver listens to
+func (r *Receiver) printBanner() {
+	groups := r.groupList()
+	fmt.Fprintf(r.out, "🎯 Starting multicast receiver on %s\n", joinAddrs(groups))
+	fmt.Fprintf(r.out, "🔗 Bound to %s (SO_REUSEADDR: %s, SO_REUSEPORT: %s)\n",
+		r.conn.LocalAddr(), onOff(r.reuseAddr), onOff(r.reusePort))
+	for _, group := range groups {
+		fmt.Fprintf(r.out, "🏷️  %s scope: %s\n", group.IP, network.ClassifyGroup(group.IP))
+	}
+	for _, warning := range r.warnings {
+		fmt.Fprintf(r.out, "⚠️  %s\n", warning)
+	}
+	if r.recorder != nil {
+		fmt.Fprintf(r.out, "💾 Recording packets to %s\n", r.recorder)
+	}
+	if r.keyRing != nil {
+		fmt.Fprintf(r.out, "🔐 Verifying packets with keys %s (HMAC-SHA256)\n", strings.Join(r.keyRing.IDs(), ", "))
+	}
+	if r.cipherRing != nil {
+		fmt.Fprintf(r.out, "🔒 Decrypting packets with keys %s (AES-256-GCM)\n", strings.Join(r.cipherRing.IDs(), ", "))
+	}
+	for _, d := range r.decoders {
+		fmt.Fprintf(r.out, "🔬 Analysing %s\n", d)
+	}
+	fmt.Fprintf(r.out, "👂 Waiting for packets...\n\n")
+}
+
+// printf prints per-packet output unless the receiver is quiet
+func (r *Receiver) printf(format string, args ...any) {
+	fmt.Fprintf(r.packetOut(), format, args...)
+}
+
+// packetOut returns where per-packet output goes: nowhere if the receiver is quiet
+func (r *Receiver) packetOut() io.Writer {
+	if r.quiet {
+		return io.Discard
+	}
+	return r.out
+}
+
+// Close stops the receiver; Start returns once the socket is closed
+func (r *Receiver) Close() error {
+	return r.conn.Close()
+}
+
 func (r *Receiver) receivePacket() error {
-	n, remoteAddr, err := r.conn.ReadFromUDP(r.buffer)
+	pkt, err := r.readPacket()
 	if err != nil {
-		return fmt.Errorf("failed to read UDP message: %w", err)
+		return err
+	}
+
+	if r.rejoin != nil {
+		r.rejoin.observe(pkt)
+	}
+
+	// Name the group only when there is more than one to tell apart
+	group := ""
+	if r.groupCount() > 1 {
+		group = " on " + pkt.Destination.IP.String()
+	}
+
+	// Other formats are analysed as they are; only mcaster messages are signed or encrypted.
+	// Every decoder sees the datagram, so RTP carrying MPEG-TS is analysed at both layers.
+	data := pkt.Data
+	if !looksLikeMessage(data) {
+		decoded := false
+		for _, d := range r.decoders {
+			if d.Decode(pkt, group, r.packetOut()) {
+				decoded = true
+			}
+		}
+		// Messages sent in RTP or MPEG-TS are received as usual once the outer layer is analysed
+		if message := rtpMessage(data); message != nil {
+			data = message
+		} else if message := tsMessage(data); message != nil {
+			r.tsSenders[pkt.Source.String()] = true
+			data = message
+		} else if decoded || r.tsSenders[pkt.Source.String()] {
+			// The PSI, PCR and stuffing between an mcaster sender's messages are not shown
+			return r.record(pkt, nil, false)
+		}
+	}
+
+	plaintext, accepted := r.decrypt(pkt, data, group)
+	if !accepted {
+		return r.record(pkt, nil, false)
 	}
 
-	msg, err := UnmarshalMessage(r.buffer[:n])
+	payload, note, accepted := r.authenticate(pkt, plaintext, group)
+	if !accepted {
+		return r.record(pkt, nil, false)
+	}
+
+	msg, err := UnmarshalMessage(payload)
 	if err != nil {
-		fmt.Printf("📥 [%s] Received %d bytes from %s (invalid JSON): %s\n",
-			time.Now().Format("15:04:05.000"), n, remoteAddr, string(r.buffer[:n]))
+		if err := r.record(pkt, nil, false); err != nil {
+			return err
+		}
+		var unsupported *UnsupportedVersionError
+		if errors.As(err, &unsupported) {
+			r.printf("⚠️  [%s] Unsupported version %d from %s%s (%d bytes)\n",
+				time.Now().Format("15:04:05.000"), unsupported.Version, pkt.Source, group, len(pkt.Data))
+			return nil
+		}
+		r.printForeign(pkt, group)
 		return nil
 	}
 
-	fmt.Printf("📥 [%s] Received packet #%d from %s (%s) - delay: %v\n",
-		time.Now().Format("15:04:05.000"), msg.ID, msg.Source, remoteAddr, msg.Age())
+	localEcho := r.isLocalEcho(msg, pkt.Source)
+	if err := r.record(pkt, msg, localEcho); err != nil {
+		return err
+	}
+
+	echo := ""
+	if localEcho {
+		if r.ignoreLocal {
+			return nil
+		}
+		echo = " (local echo)"
+	}
+
+	r.track(msg, pkt)
+
+	r.printf("📥 [%s] Received packet #%d from %s (%s)%s - delay: %v%s%s%s\n",
+		time.Now().Format("15:04:05.000"), msg.ID, msg.Origin(), pkt.Source, group, msg.Age(), echo, note, describeContent(msg))
+
+	return nil
+}
+
+// track accounts for a message in the state of its stream, announcing new
+// sessions of senders that were already heard from
+func (r *Receiver) track(msg *Message, pkt *Packet) {
+	r.streams.mu.Lock()
+	defer r.streams.mu.Unlock()
+
+	key := streamKey(msg, pkt.Source)
+	stats, created := r.streams.get(key)
+	if created && key.Session != 0 {
+		for i := len(r.streams.order) - 1; i >= 0; i-- {
+			known := r.streams.order[i]
+			if known != key && known.Session != 0 && known.sameSender(key) {
+				r.printf("🔄 [%s] %s restarted: session %s replaces %s\n",
+					time.Now().Format("15:04:05.000"), msg.Origin(), key.Session, known.Session)
+				break
+			}
+		}
+	}
+	stats.observe(msg.ID)
+	stats.observeTransit(pkt.ReceivedAt.Sub(msg.Timestamp).Seconds())
+	stats.observePacket(pkt)
+	stats.observeDelay(pkt.ReceivedAt.Sub(msg.Timestamp))
+}
+
+// Streams returns a snapshot of the stats of every stream heard so far; it is
+// safe to call while the receiver runs
+func (r *Receiver) Streams() map[StreamKey]StreamStats {
+	return r.streams.snapshot()
+}
+
+// authenticate verifies the HMAC trailer of a packet when keys are configured
+// and returns the payload without it. Rejected packets are reported and counted.
+func (r *Receiver) authenticate(pkt *Packet, data []byte, group string) (payload []byte, note string, accepted bool) {
+	if r.keyRing == nil {
+		// Still strip a trailer so signed messages decode without keys
+		payload, _, _, signed := auth.SplitTrailer(data)
+		if signed {
+			note = " (signature not verified)"
+		}
+		return payload, note, true
+	}
+
+	payload, keyID, status := auth.Verify(r.keyRing, data)
+	r.authCounts[status]++
+	if status == auth.Authenticated {
+		return payload, "", true
+	}
+
+	key := ""
+	if keyID != "" {
+		key = ", key " + keyID
+	}
+	r.printf("🚫 [%s] Rejected %s packet from %s%s (%d bytes%s)\n",
+		time.Now().Format("15:04:05.000"), status, pkt.Source, group, len(pkt.Data), key)
+	return nil, "", false
+}
+
+// AuthCounts returns how many packets had each verification outcome
+func (r *Receiver) AuthCounts() map[auth.Status]int {
+	counts := make(map[auth.Status]int, len(r.authCounts))
+	for status, count := range r.authCounts {
+		counts[status] = count
+	}
+	return counts
+}
+
+func (r *Receiver) printAuthSummary() {
+	if r.keyRing == nil {
+		return
+	}
+	fmt.Fprintf(r.out, "\n🔐 Authentication: %d %s, %d %s, %d %s, %d %s\n",
+		r.authCounts[auth.Authenticated], auth.Authenticated,
+		r.authCounts[auth.Unauthenticated], auth.Unauthenticated,
+		r.authCounts[auth.UnknownKey], auth.UnknownKey,
+		r.authCounts[auth.Tampered], auth.Tampered)
+}
+
+// decrypt opens an encrypted packet so it can be verified and decoded. Packets
+// that cannot be decrypted are reported by cause rather than as invalid JSON.
+func (r *Receiver) decrypt(pkt *Packet, data []byte, group string) ([]byte, bool) {
+	if !auth.IsEncrypted(data) {
+		if r.cipherRing != nil {
+			r.cipherCounts[auth.NotEncrypted]++
+		}
+		return data, true
+	}
+
+	if r.cipherRing == nil {
+		r.printf("🔒 [%s] Received encrypted packet from %s%s (%d bytes, no decryption keys)\n",
+			time.Now().Format("15:04:05.000"), pkt.Source, group, len(pkt.Data))
+		return nil, false
+	}
+
+	plaintext, keyID, status := auth.Decrypt(r.cipherRing, data)
+	r.cipherCounts[status]++
+	if status == auth.Decrypted {
+		return plaintext, true
+	}
+
+	key := ""
+	if keyID != "" {
+		key = ", key " + keyID
+	}
+	r.printf("🚫 [%s] Failed to decrypt packet from %s%s: %s (%d bytes%s)\n",
+		time.Now().Format("15:04:05.000"), pkt.Source, group, status, len(pkt.Data), key)
+	return nil, false
+}
+
+// CipherCounts returns how many packets had each decryption outcome
+func (r *Receiver) CipherCounts() map[auth.CipherStatus]int {
+	counts := make(map[auth.CipherStatus]int, len(r.cipherCounts))
+	for status, count := range r.cipherCounts {
+		counts[status] = count
+	}
+	return counts
+}
+
+func (r *Receiver) printCipherSummary() {
+	if r.cipherRing == nil {
+		return
+	}
+	fmt.Fprintf(r.out, "🔒 Decryption: %d %s, %d %s, %d %s, %d %s, %d %s\n",
+		r.cipherCounts[auth.Decrypted], auth.Decrypted,
+		r.cipherCounts[auth.NotEncrypted], auth.NotEncrypted,
+		r.cipherCounts[auth.UnknownCipherKey], auth.UnknownCipherKey,
+		r.cipherCounts[auth.KeyMismatch], auth.KeyMismatch,
+		r.cipherCounts[auth.Malformed], auth.Malformed)
+}
+
+// readPacket reads the next datagram and the metadata reported with it
+func (r *Receiver) readPacket() (*Packet, error) {
+	n, oobn, flags, remoteAddr, err := r.conn.ReadMsgUDP(r.buffer, r.oob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read UDP message: %w", err)
+	}
+
+	info := network.ParsePacketInfo(r.oob[:oobn])
+
+	pkt := &Packet{
+		Data:        r.buffer[:n],
+		Source:      remoteAddr,
+		Destination: &net.UDPAddr{IP: info.Destination, Port: r.groupAddr.Port},
+		ReceivedAt:  info.Timestamp,
+		TTL:         info.TTL,
+		Truncated:   flags&syscall.MSG_TRUNC != 0,
+	}
+	if pkt.Destination.IP == nil {
+		pkt.Destination.IP = r.groupAddr.IP
+	}
+	if pkt.ReceivedAt.IsZero() {
+		pkt.ReceivedAt = time.Now()
+	}
+
+	return pkt, nil
+}
 
+// record passes a packet to the recorder, if there is one
+func (r *Receiver) record(pkt *Packet, msg *Message, localEcho bool) error {
+	if r.recorder == nil {
+		return nil
+	}
+	if err := r.recorder.Record(pkt, msg, localEcho); err != nil {
+		return fmt.Errorf("failed to record packet: %w", err)
+	}
 	return nil
 }
+
+// foreignDumpLimit is how much of an unrecognized datagram auto mode dumps
+const foreignDumpLimit = 256
+
+// printForeign shows a datagram that is not an mcaster message as selected by the decode mode
+func (r *Receiver) printForeign(pkt *Packet, group string) {
+	now := time.Now().Format("15:04:05.000")
+
+	switch r.decodeMode {
+	case decode.ModeNone:
+		r.printf("📥 [%s] Received %d bytes from %s%s (not an mcaster message)\n",
+			now, len(pkt.Data), pkt.Source, group)
+	case decode.ModeRaw:
+		r.printf("📥 [%s] Received %d bytes from %s%s (invalid JSON): %s\n",
+			now, len(pkt.Data), pkt.Source, group, string(pkt.Data))
+	case decode.ModeHex:
+		r.printf("📥 [%s] Received %d bytes from %s%s (not an mcaster message):\n%s",
+			now, len(pkt.Data), pkt.Source, group, decode.HexDump(pkt.Data, 0))
+	default:
+		if result, ok := decode.Sniff(pkt.Data, pkt.Source.Port, pkt.Destination.Port); ok {
+			r.printf("🔎 [%s] %s from %s%s (%d bytes): %s\n",
+				now, result.Protocol, pkt.Source, group, len(pkt.Data), result.Summary)
+			return
+		}
+		r.printf("📥 [%s] Received %d bytes from %s%s (unknown payload):\n%s",
+			now, len(pkt.Data), pkt.Source, group, decode.HexDump(pkt.Data, foreignDumpLimit))
+	}
+}
+
+// describeContent summarizes the labels and payload of a message, if it has any
+func describeContent(msg *Message) string {
+	var content string
+	if len(msg.Labels) > 0 {
+		content += " [" + msg.LabelString() + "]"
+	}
+	if len(msg.Payload) > 0 {
+		content += fmt.Sprintf(" (payload: %d bytes)", len(msg.Payload))
+	}
+	return content
+}
+
+// joinAddrs formats a list of group addresses for display
+func joinAddrs(addrs []*net.UDPAddr) string {
+	parts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		parts[i] = addr.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isLocalEcho reports whether a message was sent from this host and looped back
+func (r *Receiver) isLocalEcho(msg *Message, remoteAddr *net.UDPAddr) bool {
+	if r.localHost == nil {
+		return false
+	}
+	if remoteAddr != nil && r.localHost.IsLocalIP(remoteAddr.IP) {
+		return true
+	}
+	return r.localHost.IsLocalName(msg.Source)
+}
@@ -2,10 +2,15 @@ package multicast
 
 import (
 	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+	"github.com/hyposcaler-bot/mcaster/internal/decode"
 )
 
 func TestNewReceiverValidation(t *testing.T) {
@@ -96,7 +101,7 @@ func TestNewReceiverValidation(t *testing.T) {
 					assert.NotNil(t, receiver.conn)
 					assert.NotNil(t, receiver.groupAddr)
 					assert.NotNil(t, receiver.buffer)
-					assert.Equal(t, 1024, len(receiver.buffer)) // Default buffer size
+					assert.Equal(t, maxDatagramSize, len(receiver.buffer)) // Default buffer size
 					
 					// Clean up
 					receiver.conn.Close()
@@ -220,7 +225,7 @@ func TestReceiverStructFields(t *testing.T) {
 	assert.NotNil(t, receiver.conn)
 	assert.NotNil(t, receiver.groupAddr)
 	assert.NotNil(t, receiver.buffer)
-	assert.Equal(t, 1024, len(receiver.buffer))
+	assert.Equal(t, maxDatagramSize, len(receiver.buffer))
 	
 	// Verify group address
 	assert.Equal(t, "239.23.23.23", receiver.groupAddr.IP.String())
@@ -236,8 +241,8 @@ func TestReceiverBufferSize(t *testing.T) {
 
 	// Verify buffer properties
 	assert.NotNil(t, receiver.buffer)
-	assert.Equal(t, 1024, len(receiver.buffer))
-	assert.Equal(t, 1024, cap(receiver.buffer))
+	assert.Equal(t, maxDatagramSize, len(receiver.buffer))
+	assert.Equal(t, maxDatagramSize, cap(receiver.buffer))
 	
 	// Buffer should be zero-initialized
 	for i, b := range receiver.buffer {
@@ -311,6 +316,213 @@ func TestReceiverMulticastAddressTypes(t *testing.T) {
 	}
 }
 
+func TestReceiverIgnoreLocalOption(t *testing.T) {
+	receiver, err := NewReceiver("239.23.23.23:2323", "", 0)
+	require.NoError(t, err)
+	require.NotNil(t, receiver)
+	receiver.conn.Close()
+	assert.False(t, receiver.ignoreLocal)
+	assert.NotNil(t, receiver.localHost)
+
+	receiver, err = NewReceiver("239.23.23.23:2323", "", 0, WithIgnoreLocal(true))
+	require.NoError(t, err)
+	require.NotNil(t, receiver)
+	receiver.conn.Close()
+	assert.True(t, receiver.ignoreLocal)
+}
+
+func TestReceiverIsLocalEcho(t *testing.T) {
+	receiver, err := NewReceiver("239.23.23.23:2323", "", 0)
+	require.NoError(t, err)
+	require.NotNil(t, receiver)
+	defer receiver.conn.Close()
+
+	hostname := receiver.localHost.Hostname()
+
+	tests := []struct {
+		name       string
+		source     string
+		remoteAddr *net.UDPAddr
+		expected   bool
+	}{
+		{
+			name:       "local hostname",
+			source:     hostname,
+			remoteAddr: &net.UDPAddr{IP: net.ParseIP("203.0.113.1"), Port: 1234},
+			expected:   hostname != "",
+		},
+		{
+			name:       "local source address",
+			source:     "remote-host",
+			remoteAddr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234},
+			expected:   true,
+		},
+		{
+			name:       "remote host",
+			source:     "remote-host-that-does-not-exist",
+			remoteAddr: &net.UDPAddr{IP: net.ParseIP("203.0.113.1"), Port: 1234},
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{ID: 1, Timestamp: time.Now(), Source: tt.source}
+			assert.Equal(t, tt.expected, receiver.isLocalEcho(msg, tt.remoteAddr))
+		})
+	}
+}
+
+func TestReceiverBindModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		bind     string
+		expectIP string
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "bind to group",
+			bind:     "group",
+			expectIP: "239.23.23.23",
+		},
+		{
+			name:     "bind to wildcard",
+			bind:     "any",
+			expectIP: "0.0.0.0",
+		},
+		{
+			name:    "invalid bind mode",
+			bind:    "nowhere",
+			wantErr: true,
+			errMsg:  "invalid bind mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver, err := NewReceiver("239.23.23.23:2324", "", 0, WithBind(tt.bind))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.Nil(t, receiver)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, receiver)
+			defer receiver.conn.Close()
+
+			local := receiver.conn.LocalAddr().(*net.UDPAddr)
+			assert.Equal(t, tt.expectIP, local.IP.String())
+			assert.Equal(t, 2324, local.Port)
+		})
+	}
+}
+
+func TestReceiverPortSharing(t *testing.T) {
+	t.Run("SO_REUSEPORT listeners share the port", func(t *testing.T) {
+		first, err := NewReceiver("239.23.23.23:2325", "", 0, WithReuseAddr(false), WithReusePort(true))
+		require.NoError(t, err)
+		defer first.conn.Close()
+
+		second, err := NewReceiver("239.23.23.23:2325", "", 0, WithReuseAddr(false), WithReusePort(true))
+		require.NoError(t, err)
+		defer second.conn.Close()
+
+		assert.True(t, first.reusePort)
+		assert.False(t, first.reuseAddr)
+	})
+
+	t.Run("no reuse keeps the port exclusive", func(t *testing.T) {
+		first, err := NewReceiver("239.23.23.23:2326", "", 0, WithReuseAddr(false))
+		require.NoError(t, err)
+		defer first.conn.Close()
+
+		second, err := NewReceiver("239.23.23.23:2326", "", 0, WithReuseAddr(false))
+		assert.Error(t, err)
+		assert.Nil(t, second)
+	})
+}
+
+func TestReceiverGroupValidation(t *testing.T) {
+	t.Run("unicast rejected", func(t *testing.T) {
+		receiver, err := NewReceiver("127.0.0.1:2327", "", 0)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a multicast address")
+		assert.Nil(t, receiver)
+	})
+
+	t.Run("unicast allowed", func(t *testing.T) {
+		receiver, err := NewReceiver("127.0.0.1:2327", "", 0, WithUnicastGroups(true))
+		require.NoError(t, err)
+		require.NotNil(t, receiver)
+		defer receiver.conn.Close()
+
+		assert.Equal(t, "127.0.0.1", receiver.conn.LocalAddr().(*net.UDPAddr).IP.String())
+	})
+
+	t.Run("link-local group warns", func(t *testing.T) {
+		receiver, err := NewReceiver("224.0.0.251:2327", "", 0)
+		require.NoError(t, err)
+		require.NotNil(t, receiver)
+		defer receiver.conn.Close()
+
+		require.Len(t, receiver.warnings, 1)
+		assert.Contains(t, receiver.warnings[0], "link-local")
+	})
+}
+
+func TestReceiverMultipleGroups(t *testing.T) {
+	t.Run("binds to wildcard by default", func(t *testing.T) {
+		receiver, err := NewReceiver("239.1.1.1:2328,239.1.1.2:2328", "", 0)
+		require.NoError(t, err)
+		require.NotNil(t, receiver)
+		defer receiver.conn.Close()
+
+		assert.Len(t, receiver.groups, 2)
+		assert.Equal(t, receiver.groups[0], receiver.groupAddr)
+		assert.True(t, receiver.conn.LocalAddr().(*net.UDPAddr).IP.IsUnspecified())
+		assert.Empty(t, receiver.warnings)
+	})
+
+	t.Run("explicit group bind is rejected", func(t *testing.T) {
+		receiver, err := NewReceiver("239.1.1.1:2328,239.1.1.2:2328", "", 0, WithBind("group"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "needs a single group")
+		assert.Nil(t, receiver)
+	})
+
+	t.Run("ports must match", func(t *testing.T) {
+		receiver, err := NewReceiver("239.1.1.1:2328,239.1.1.2:2329", "", 0)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must use the same port")
+		assert.Nil(t, receiver)
+	})
+
+	t.Run("destination port override unifies ports", func(t *testing.T) {
+		receiver, err := NewReceiver("239.1.1.1:2328,239.1.1.2:2329", "", 2330)
+		require.NoError(t, err)
+		require.NotNil(t, receiver)
+		defer receiver.conn.Close()
+
+		for _, group := range receiver.groups {
+			assert.Equal(t, 2330, group.Port)
+		}
+	})
+
+	t.Run("MAC aliasing groups warn", func(t *testing.T) {
+		receiver, err := NewReceiver("239.1.1.1:2328,224.1.1.1:2328", "", 0)
+		require.NoError(t, err)
+		require.NotNil(t, receiver)
+		defer receiver.conn.Close()
+
+		require.Len(t, receiver.warnings, 1)
+		assert.Contains(t, receiver.warnings[0], "share MAC address")
+	})
+}
+
 // Test edge cases and error conditions
 func TestReceiverEdgeCases(t *testing.T) {
 	t.Run("IPv6 multicast address", func(t *testing.T) {
@@ -389,4 +601,243 @@ func BenchmarkNewReceiverWithPortOverride(b *testing.B) {
 			receiver.conn.Close()
 		}
 	}
-}
\ No newline at end of file
+}
+// memoryRecorder keeps recorded packets for inspection
+type memoryRecorder struct {
+	packets  []Packet
+	messages []*Message
+}
+
+func (m *memoryRecorder) Record(pkt *Packet, msg *Message, localEcho bool) error {
+	copied := *pkt
+	copied.Data = append([]byte(nil), pkt.Data...)
+	m.packets = append(m.packets, copied)
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *memoryRecorder) String() string { return "memory" }
+
+func (m *memoryRecorder) Close() error { return nil }
+
+func TestReceiverRecording(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	recorder := &memoryRecorder{}
+	receiver, err := NewReceiver("239.23.23.31:23236", "lo", 0, WithRecorder(recorder))
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+	assert.Equal(t, maxDatagramSize, len(receiver.buffer))
+
+	sender, err := NewSender("239.23.23.31:23236", "lo", time.Second, 3, 0, 0)
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	require.NoError(t, sender.sendPacket())
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	if err := receiver.receivePacket(); err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+
+	require.Len(t, recorder.packets, 1)
+	pkt := recorder.packets[0]
+	assert.Equal(t, "239.23.23.31:23236", pkt.Destination.String())
+	assert.Equal(t, 3, pkt.TTL)
+	assert.False(t, pkt.Truncated)
+	assert.WithinDuration(t, time.Now(), pkt.ReceivedAt, 2*time.Second)
+	require.NotNil(t, recorder.messages[0])
+	assert.Equal(t, 1, recorder.messages[0].ID)
+}
+
+func TestReceiverClose(t *testing.T) {
+	receiver, err := NewReceiver("239.23.23.32:23237", "", 0)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- receiver.Start() }()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, receiver.Close())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Start did not return after Close")
+	}
+}
+
+func TestReceiverAuthentication(t *testing.T) {
+	ring, err := auth.ParseKeys(strings.NewReader("k2 second-secret-0123456789\nk1 first-secret-0123456789\n"))
+	require.NoError(t, err)
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+
+	msg := Message{ID: 7, Timestamp: time.Now(), Source: "sender"}
+	payload, err := msg.Marshal()
+	require.NoError(t, err)
+	signed := auth.Sign(k1, payload)
+	tampered := auth.Sign(k1, payload)
+	tampered[2] = 'x'
+	unknown := auth.Sign(auth.Key{ID: "k9", Secret: []byte("unknown-secret-0123456789")}, payload)
+
+	receiver, err := NewReceiver("239.23.23.34:23239", "", 0, WithVerifyKeys(ring))
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1}
+	for _, data := range [][]byte{signed, signed, payload, tampered, unknown} {
+		verified, note, accepted := receiver.authenticate(&Packet{Data: data, Source: source}, data, "")
+		if accepted {
+			assert.Equal(t, payload, verified)
+			assert.Empty(t, note)
+		}
+	}
+
+	counts := receiver.AuthCounts()
+	assert.Equal(t, 2, counts[auth.Authenticated])
+	assert.Equal(t, 1, counts[auth.Unauthenticated])
+	assert.Equal(t, 1, counts[auth.Tampered])
+	assert.Equal(t, 1, counts[auth.UnknownKey])
+
+	t.Run("without keys the trailer is stripped", func(t *testing.T) {
+		plain, err := NewReceiver("239.23.23.34:23240", "", 0)
+		require.NoError(t, err)
+		defer plain.conn.Close()
+
+		verified, note, accepted := plain.authenticate(&Packet{Data: signed, Source: source}, signed, "")
+		assert.True(t, accepted)
+		assert.Equal(t, payload, verified)
+		assert.Contains(t, note, "not verified")
+		assert.Empty(t, plain.AuthCounts())
+	})
+}
+
+func TestReceiverDecryption(t *testing.T) {
+	ring, err := auth.ParseKeys(strings.NewReader("k1 first-secret-0123456789\n"))
+	require.NoError(t, err)
+	k1, err := ring.Key("k1")
+	require.NoError(t, err)
+
+	msg := Message{ID: 3, Timestamp: time.Now(), Source: "sender"}
+	payload, err := msg.Marshal()
+	require.NoError(t, err)
+	encrypted, err := auth.Encrypt(k1, 1, 3, payload)
+	require.NoError(t, err)
+	mismatch, err := auth.Encrypt(auth.Key{ID: "k1", Secret: []byte("other-secret-0123456789")}, 1, 3, payload)
+	require.NoError(t, err)
+	unknown, err := auth.Encrypt(auth.Key{ID: "k9", Secret: []byte("first-secret-0123456789")}, 1, 3, payload)
+	require.NoError(t, err)
+
+	receiver, err := NewReceiver("239.23.23.36:23242", "", 0, WithDecryptKeys(ring))
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1}
+	for _, data := range [][]byte{encrypted, payload, mismatch, unknown} {
+		plaintext, accepted := receiver.decrypt(&Packet{Data: data, Source: source}, data, "")
+		if accepted {
+			assert.Equal(t, payload, plaintext)
+		}
+	}
+
+	counts := receiver.CipherCounts()
+	assert.Equal(t, 1, counts[auth.Decrypted])
+	assert.Equal(t, 1, counts[auth.NotEncrypted])
+	assert.Equal(t, 1, counts[auth.KeyMismatch])
+	assert.Equal(t, 1, counts[auth.UnknownCipherKey])
+
+	t.Run("without keys encrypted packets are rejected", func(t *testing.T) {
+		plain, err := NewReceiver("239.23.23.36:23243", "", 0)
+		require.NoError(t, err)
+		defer plain.conn.Close()
+
+		_, accepted := plain.decrypt(&Packet{Data: encrypted, Source: source}, encrypted, "")
+		assert.False(t, accepted)
+		plaintext, accepted := plain.decrypt(&Packet{Data: payload, Source: source}, payload, "")
+		assert.True(t, accepted)
+		assert.Equal(t, payload, plaintext)
+	})
+}
+
+func TestReceiverStreams(t *testing.T) {
+	receiver, err := NewReceiver("239.23.23.38:23245", "", 0)
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4000}
+	// Two senders on the same host, then the first one restarts
+	for _, msg := range []*Message{
+		{ID: 1, Source: "host", SenderID: "a", Session: 1, Stream: "s"},
+		{ID: 1, Source: "host", SenderID: "b", Session: 2, Stream: "s"},
+		{ID: 3, Source: "host", SenderID: "a", Session: 1, Stream: "s"},
+		{ID: 2, Source: "host", SenderID: "b", Session: 2, Stream: "s"},
+		{ID: 1, Source: "host", SenderID: "a", Session: 3, Stream: "s"},
+	} {
+		receiver.track(msg, &Packet{Source: source, ReceivedAt: time.Now()})
+	}
+
+	streams := receiver.Streams()
+	require.Len(t, streams, 3)
+	assert.Equal(t, 2, streams[StreamKey{Source: "host", SenderID: "a", Session: 1, Stream: "s"}].Received)
+	assert.Equal(t, 1, streams[StreamKey{Source: "host", SenderID: "a", Session: 1, Stream: "s"}].Lost)
+	assert.Equal(t, 0, streams[StreamKey{Source: "host", SenderID: "b", Session: 2, Stream: "s"}].Lost)
+	assert.Equal(t, 1, streams[StreamKey{Source: "host", SenderID: "a", Session: 3, Stream: "s"}].Received)
+}
+
+func TestDescribeContent(t *testing.T) {
+	assert.Empty(t, describeContent(&Message{ID: 1}))
+	assert.Equal(t, " [run=chg1234 site=lab2] (payload: 3 bytes)", describeContent(&Message{
+		Labels:  map[string]string{"site": "lab2", "run": "chg1234"},
+		Payload: []byte("abc"),
+	}))
+}
+
+func TestReceiverUnsupportedVersion(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	recorder := &memoryRecorder{}
+	receiver, err := NewReceiver("239.23.23.41:23248", "lo", 0, WithRecorder(recorder))
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	sender, err := NewSender("239.23.23.41:23248", "lo", time.Second, 1, 0, 0)
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	require.NoError(t, sender.Send([]byte(`{"version":99,"id":1,"source":"future-host"}`)))
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	if err := receiver.receivePacket(); err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+
+	require.Len(t, recorder.packets, 1)
+	assert.Nil(t, recorder.messages[0])
+	assert.Empty(t, receiver.Streams())
+}
+
+func TestReceiverDecodeMode(t *testing.T) {
+	receiver, err := NewReceiver("239.23.23.42:23249", "", 0)
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+	assert.Equal(t, decode.ModeAuto, receiver.decodeMode)
+
+	hex, err := NewReceiver("239.23.23.42:23250", "", 0, WithDecodeMode(decode.ModeHex))
+	require.NoError(t, err)
+	defer hex.conn.Close()
+	assert.Equal(t, decode.ModeHex, hex.decodeMode)
+
+	pkt := &Packet{
+		Data:        []byte{0x80, 0x60, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0xff},
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4000},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.42"), Port: 23249},
+	}
+	for _, mode := range []decode.Mode{decode.ModeAuto, decode.ModeHex, decode.ModeRaw, decode.ModeNone} {
+		receiver.decodeMode = mode
+		receiver.printForeign(pkt, "")
+	}
+}
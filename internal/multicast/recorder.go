@@ -0,0 +1,85 @@
+package multicast
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/pcap"
+)
+
+// Recorder stores received packets, e.g. in a capture file
+type Recorder interface {
+	// Record stores a packet; msg is nil when the payload is not an mcaster message
+	Record(pkt *Packet, msg *Message, localEcho bool) error
+	// String describes where packets are recorded
+	String() string
+	Close() error
+}
+
+// OpenRecorder creates a recorder writing to path, choosing the format from its
+// extension: .pcap or .pcapng captures, or .jsonl recordings
+func OpenRecorder(path string) (Recorder, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pcap", ".pcapng":
+		writer, err := pcap.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return &captureRecorder{writer: writer, path: path}, nil
+	case ".jsonl":
+		return createJSONLRecorder(path)
+	}
+	return nil, fmt.Errorf("unsupported recording file %q: use a .pcap, .pcapng or .jsonl extension", path)
+}
+
+// captureRecorder writes packets as synthesized frames to a pcap or pcapng file
+type captureRecorder struct {
+	writer *pcap.Writer
+	path   string
+}
+
+func (c *captureRecorder) Record(pkt *Packet, msg *Message, localEcho bool) error {
+	return c.writer.WritePacket(pcap.Packet{
+		Timestamp:   pkt.ReceivedAt,
+		Source:      pkt.Source,
+		Destination: pkt.Destination,
+		TTL:         pkt.TTL,
+		Payload:     pkt.Data,
+		Comment:     captureComment(pkt, msg, localEcho),
+	})
+}
+
+func (c *captureRecorder) String() string {
+	return c.path
+}
+
+func (c *captureRecorder) Close() error {
+	return c.writer.Close()
+}
+
+// captureComment describes the decoded message of a packet for the capture file
+func captureComment(pkt *Packet, msg *Message, localEcho bool) string {
+	var parts []string
+	if msg != nil {
+		parts = append(parts,
+			fmt.Sprintf("mcaster packet #%d", msg.ID),
+			fmt.Sprintf("source=%s", msg.Origin()),
+			fmt.Sprintf("sent=%s", msg.Timestamp.Format(time.RFC3339Nano)),
+			fmt.Sprintf("delay=%v", pkt.ReceivedAt.Sub(msg.Timestamp)))
+		if msg.Session != 0 {
+			parts = append(parts, fmt.Sprintf("session=%s", msg.Session))
+		}
+		if len(msg.Labels) > 0 {
+			parts = append(parts, msg.LabelString())
+		}
+	}
+	if localEcho {
+		parts = append(parts, "local echo")
+	}
+	if pkt.Truncated {
+		parts = append(parts, "truncated")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,100 @@
+package multicast
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRecorder(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("pcapng", func(t *testing.T) {
+		path := filepath.Join(dir, "out.pcapng")
+		recorder, err := OpenRecorder(path)
+		require.NoError(t, err)
+		assert.Equal(t, path, recorder.String())
+
+		pkt := &Packet{
+			Data:        []byte(`{"id":1}`),
+			Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 5000},
+			Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 2323},
+			ReceivedAt:  time.Now(),
+			TTL:         1,
+		}
+		msg := &Message{ID: 1, Timestamp: pkt.ReceivedAt.Add(-time.Millisecond), Source: "sender"}
+		require.NoError(t, recorder.Record(pkt, msg, false))
+		require.NoError(t, recorder.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "mcaster packet #1 source=sender")
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		recorder, err := OpenRecorder(filepath.Join(dir, "out.txt"))
+		assert.Error(t, err)
+		assert.Nil(t, recorder)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := OpenRecorder(filepath.Join(dir, "missing", "out.pcap"))
+		assert.Error(t, err)
+	})
+}
+
+func TestCaptureComment(t *testing.T) {
+	received := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pkt := &Packet{ReceivedAt: received}
+	msg := &Message{ID: 42, Timestamp: received.Add(-2 * time.Millisecond), Source: "host-a"}
+
+	tests := []struct {
+		name      string
+		pkt       *Packet
+		msg       *Message
+		localEcho bool
+		expected  string
+	}{
+		{
+			name:     "mcaster message",
+			pkt:      pkt,
+			msg:      msg,
+			expected: "mcaster packet #42 source=host-a sent=2024-01-02T03:04:04.998Z delay=2ms",
+		},
+		{
+			name:      "local echo",
+			pkt:       pkt,
+			msg:       msg,
+			localEcho: true,
+			expected:  "mcaster packet #42 source=host-a sent=2024-01-02T03:04:04.998Z delay=2ms local echo",
+		},
+		{
+			name: "identity and labels",
+			pkt:  pkt,
+			msg: &Message{ID: 42, Timestamp: msg.Timestamp, Source: "host-a", SenderID: "probe",
+				Session: 0xab, Labels: map[string]string{"run": "chg1234"}},
+			expected: "mcaster packet #42 source=probe@host-a sent=2024-01-02T03:04:04.998Z delay=2ms session=00000000000000ab run=chg1234",
+		},
+		{
+			name:     "foreign payload",
+			pkt:      pkt,
+			expected: "",
+		},
+		{
+			name:     "truncated foreign payload",
+			pkt:      &Packet{ReceivedAt: received, Truncated: true},
+			expected: "truncated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, captureComment(tt.pkt, tt.msg, tt.localEcho))
+		})
+	}
+}
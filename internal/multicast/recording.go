@@ -0,0 +1,185 @@
+package multicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/pcap"
+)
+
+// recordedPacket is one line of a jsonl recording
+type recordedPacket struct {
+	ReceivedAt  time.Time `json:"received_at"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	TTL         int       `json:"ttl"`
+	Payload     []byte    `json:"payload"`
+	Message     *Message  `json:"message,omitempty"`
+	LocalEcho   bool      `json:"local_echo,omitempty"`
+	Truncated   bool      `json:"truncated,omitempty"`
+}
+
+// jsonlRecorder writes one JSON object per received datagram
+type jsonlRecorder struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func createJSONLRecorder(path string) (*jsonlRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+	return &jsonlRecorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (j *jsonlRecorder) Record(pkt *Packet, msg *Message, localEcho bool) error {
+	return j.encoder.Encode(recordedPacket{
+		ReceivedAt:  pkt.ReceivedAt,
+		Source:      pkt.Source.String(),
+		Destination: pkt.Destination.String(),
+		TTL:         pkt.TTL,
+		Payload:     pkt.Data,
+		Message:     msg,
+		LocalEcho:   localEcho,
+		Truncated:   pkt.Truncated,
+	})
+}
+
+func (j *jsonlRecorder) String() string {
+	return j.file.Name()
+}
+
+func (j *jsonlRecorder) Close() error {
+	return j.file.Close()
+}
+
+// PacketReader reads the datagrams of a recording
+type PacketReader interface {
+	// ReadPacket returns the next datagram, or io.EOF at the end of the recording
+	ReadPacket() (*Packet, error)
+	// Skipped returns how many records could not be used so far
+	Skipped() int
+	Close() error
+}
+
+// OpenRecording opens a pcap or pcapng capture, or a jsonl recording written by
+// receive --write, detecting the format from the file contents
+func OpenRecording(path string) (PacketReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	buffered := bufio.NewReader(file)
+	header, _ := buffered.Peek(4)
+	if pcap.IsCapture(header) {
+		reader, err := pcap.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &captureReader{reader: reader, file: file}, nil
+	}
+
+	scanner := bufio.NewScanner(buffered)
+	// Base64 makes a 64 KiB datagram about 88 KiB long
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &jsonlReader{scanner: scanner, file: file}, nil
+}
+
+// captureReader adapts a pcap.Reader to PacketReader
+type captureReader struct {
+	reader *pcap.Reader
+	file   *os.File
+}
+
+func (c *captureReader) ReadPacket() (*Packet, error) {
+	pkt, err := c.reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	return &Packet{
+		Data:        pkt.Payload,
+		Source:      pkt.Source,
+		Destination: pkt.Destination,
+		ReceivedAt:  pkt.Timestamp,
+		TTL:         pkt.TTL,
+	}, nil
+}
+
+func (c *captureReader) Skipped() int {
+	return c.reader.Skipped()
+}
+
+func (c *captureReader) Close() error {
+	return c.file.Close()
+}
+
+// jsonlReader reads recordings written by jsonlRecorder
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	file    *os.File
+	line    int
+	skipped int
+}
+
+func (j *jsonlReader) ReadPacket() (*Packet, error) {
+	for j.scanner.Scan() {
+		j.line++
+		line := j.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record recordedPacket
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("invalid recording at line %d: %w", j.line, err)
+		}
+
+		// A truncated datagram cannot be reproduced
+		if record.Truncated {
+			j.skipped++
+			continue
+		}
+
+		source, err := net.ResolveUDPAddr("udp", record.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source at line %d: %w", j.line, err)
+		}
+		destination, err := net.ResolveUDPAddr("udp", record.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination at line %d: %w", j.line, err)
+		}
+
+		return &Packet{
+			Data:        record.Payload,
+			Source:      source,
+			Destination: destination,
+			ReceivedAt:  record.ReceivedAt,
+			TTL:         record.TTL,
+		}, nil
+	}
+
+	if err := j.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("invalid recording at line %d: line too long", j.line+1)
+		}
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return nil, io.EOF
+}
+
+func (j *jsonlReader) Skipped() int {
+	return j.skipped
+}
+
+func (j *jsonlReader) Close() error {
+	return j.file.Close()
+}
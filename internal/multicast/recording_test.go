@@ -0,0 +1,128 @@
+package multicast
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordPackets writes packets to a new recording at path and returns path
+func recordPackets(t *testing.T, path string, pkts ...*Packet) string {
+	t.Helper()
+	recorder, err := OpenRecorder(path)
+	require.NoError(t, err)
+	for _, pkt := range pkts {
+		require.NoError(t, recorder.Record(pkt, nil, false))
+	}
+	require.NoError(t, recorder.Close())
+	return path
+}
+
+// readPackets reads every packet of a recording
+func readPackets(t *testing.T, path string) ([]*Packet, int) {
+	t.Helper()
+	reader, err := OpenRecording(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	var pkts []*Packet
+	for {
+		pkt, err := reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return pkts, reader.Skipped()
+		}
+		require.NoError(t, err)
+		pkts = append(pkts, pkt)
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	received := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	pkts := []*Packet{
+		{
+			Data:        []byte(`{"id":1}`),
+			Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10").To4(), Port: 40000},
+			Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.23").To4(), Port: 2323},
+			ReceivedAt:  received,
+			TTL:         7,
+		},
+		{
+			Data:        []byte{0x80, 0x60, 0x00, 0x01},
+			Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.11").To4(), Port: 40001},
+			Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.24").To4(), Port: 5004},
+			ReceivedAt:  received.Add(20 * time.Millisecond),
+			TTL:         64,
+		},
+	}
+
+	// The format is detected from the contents, not the extension
+	for _, name := range []string{"out.jsonl", "out.pcap", "out.pcapng"} {
+		t.Run(name, func(t *testing.T) {
+			path := recordPackets(t, filepath.Join(t.TempDir(), name), pkts...)
+			renamed := path + ".bin"
+			require.NoError(t, os.Rename(path, renamed))
+
+			read, skipped := readPackets(t, renamed)
+			assert.Zero(t, skipped)
+			require.Len(t, read, len(pkts))
+			for i, pkt := range read {
+				assert.Equal(t, pkts[i].Data, pkt.Data)
+				assert.Equal(t, pkts[i].Source.String(), pkt.Source.String())
+				assert.Equal(t, pkts[i].Destination.String(), pkt.Destination.String())
+				assert.Equal(t, pkts[i].TTL, pkt.TTL)
+				assert.True(t, pkts[i].ReceivedAt.Equal(pkt.ReceivedAt), "received at %v, want %v", pkt.ReceivedAt, pkts[i].ReceivedAt)
+			}
+		})
+	}
+}
+
+func TestJSONLRecordingSkipsTruncated(t *testing.T) {
+	pkt := &Packet{
+		Data:        []byte("partial"),
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 2323},
+		ReceivedAt:  time.Now(),
+	}
+	truncated := *pkt
+	truncated.Truncated = true
+	path := recordPackets(t, filepath.Join(t.TempDir(), "out.jsonl"), &truncated, pkt)
+
+	read, skipped := readPackets(t, path)
+	assert.Equal(t, 1, skipped)
+	require.Len(t, read, 1)
+	assert.Equal(t, []byte("partial"), read[0].Data)
+}
+
+func TestOpenRecordingErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := OpenRecording(filepath.Join(dir, "missing.jsonl"))
+	assert.ErrorContains(t, err, "failed to open recording")
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "not JSON", content: "hello\n", err: "invalid recording at line 1"},
+		{name: "invalid source", content: `{"source":"nowhere","destination":"239.1.1.1:5000"}` + "\n", err: "invalid source at line 1"},
+		{name: "invalid destination", content: "\n" + `{"source":"192.0.2.1:1","destination":"x"}` + "\n", err: "invalid destination at line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+			reader, err := OpenRecording(path)
+			require.NoError(t, err)
+			defer reader.Close()
+			_, err = reader.ReadPacket()
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
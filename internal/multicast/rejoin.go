@@ -0,0 +1,312 @@
+package multicast
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RejoinConfig describes how a receiver leaves and joins its groups again to
+// measure how fast traffic starts after a join and stops after a leave
+type RejoinConfig struct {
+	// Joined is how long the groups stay joined before each leave
+	Joined time.Duration
+	// Left is how long they stay left before each join
+	Left time.Duration
+	// Random draws every period uniformly between half and one and a half
+	// times its setting, instead of keeping a fixed schedule
+	Random bool
+	// Count stops the receiver after this many rejoins (0 = until it is closed)
+	Count int
+}
+
+// Validate checks the configuration
+func (c RejoinConfig) Validate() error {
+	if c.Joined <= 0 {
+		return fmt.Errorf("time joined between rejoins must be positive, got %v", c.Joined)
+	}
+	if c.Left <= 0 {
+		return fmt.Errorf("time left between rejoins must be positive, got %v", c.Left)
+	}
+	if c.Count < 0 {
+		return fmt.Errorf("rejoin count must not be negative, got %d", c.Count)
+	}
+	return nil
+}
+
+// period returns how long to wait for a period of d
+func (c RejoinConfig) period(d time.Duration) time.Duration {
+	if !c.Random {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)+1))
+}
+
+// RejoinStats are the latencies measured on one group
+type RejoinStats struct {
+	Group   *net.UDPAddr
+	Rejoins int
+	// Joins are the times from a join syscall to the first packet of the group
+	Joins []time.Duration
+	// Leaves are the times from a leave syscall to the last packet of the
+	// group, zero if none arrived after it
+	Leaves []time.Duration
+	// Silent counts the joins after which nothing arrived before the next leave
+	Silent int
+}
+
+// rejoinTester leaves and joins the groups of a receiver on a schedule and
+// times the packets that follow. mu guards the state shared between the
+// schedule and the receive loop.
+type rejoinTester struct {
+	receiver *Receiver
+	cfg      RejoinConfig
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+	// joinedAt holds the groups waiting for their first packet since a join
+	joinedAt map[string]time.Time
+	// leftAt and lastPacket hold the groups left and their last packet since
+	leftAt     map[string]time.Time
+	lastPacket map[string]time.Time
+	stats      map[string]*RejoinStats
+}
+
+func newRejoinTester(r *Receiver, cfg RejoinConfig) *rejoinTester {
+	return &rejoinTester{
+		receiver:   r,
+		cfg:        cfg,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		joinedAt:   make(map[string]time.Time),
+		leftAt:     make(map[string]time.Time),
+		lastPacket: make(map[string]time.Time),
+		stats:      make(map[string]*RejoinStats),
+	}
+}
+
+// run leaves and joins the groups until the tester is stopped or has rejoined
+// cfg.Count times, when it closes the receiver once the last join delivered
+func (t *rejoinTester) run(groups []*net.UDPAddr) {
+	defer close(t.done)
+	t.mu.Lock()
+	for _, group := range groups {
+		t.stats[group.IP.String()] = &RejoinStats{Group: group}
+	}
+	t.mu.Unlock()
+
+	for n := 1; t.cfg.Count == 0 || n <= t.cfg.Count; n++ {
+		if !t.sleep(t.cfg.period(t.cfg.Joined)) {
+			return
+		}
+		for _, group := range groups {
+			t.leave(group)
+		}
+		if !t.sleep(t.cfg.period(t.cfg.Left)) {
+			return
+		}
+		for _, group := range groups {
+			t.join(group, n)
+		}
+	}
+	if t.sleep(t.cfg.Joined) {
+		t.receiver.Close()
+	}
+}
+
+// sleep waits for d and reports whether the tester is still running
+func (t *rejoinTester) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-t.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// leave leaves a group, noting a join that delivered nothing
+func (t *rejoinTester) leave(group *net.UDPAddr) {
+	key := group.IP.String()
+	t.mu.Lock()
+	if _, waiting := t.joinedAt[key]; waiting {
+		delete(t.joinedAt, key)
+		t.stats[key].Silent++
+		fmt.Fprintf(t.receiver.out, "⚠️  [%s] Nothing received on %s since joining\n", time.Now().Format("15:04:05.000"), group.IP)
+	}
+	// Noted before the syscall, so no packet after it is missed
+	at := time.Now()
+	t.leftAt[key] = at
+	delete(t.lastPacket, key)
+	t.mu.Unlock()
+
+	if err := t.receiver.Leave(group.IP); err != nil {
+		t.mu.Lock()
+		delete(t.leftAt, key)
+		t.mu.Unlock()
+		log.Printf("❌ %v", err)
+		return
+	}
+	t.printf("🚪 [%s] Left %s\n", at.Format("15:04:05.000"), group.IP)
+}
+
+// join joins a group again, recording when its last packet after the leave arrived
+func (t *rejoinTester) join(group *net.UDPAddr, n int) {
+	key := group.IP.String()
+	t.mu.Lock()
+	last, hadLast := t.finishLeave(key)
+	at := time.Now()
+	t.joinedAt[key] = at
+	t.mu.Unlock()
+
+	if err := t.receiver.Join(group.IP); err != nil {
+		t.mu.Lock()
+		delete(t.joinedAt, key)
+		t.mu.Unlock()
+		log.Printf("❌ %v", err)
+		return
+	}
+	lastNote := "nothing arrived after leaving"
+	if hadLast {
+		lastNote = fmt.Sprintf("the last packet arrived %v after leaving", formatLatency(last))
+	}
+	count := ""
+	if t.cfg.Count > 0 {
+		count = fmt.Sprintf(" of %d", t.cfg.Count)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats[key].Rejoins++
+	fmt.Fprintf(t.receiver.out, "🔁 [%s] Joined %s again (rejoin %d%s); %s\n",
+		at.Format("15:04:05.000"), group.IP, n, count, lastNote)
+}
+
+// printf writes to the receiver's output, which observe shares from the
+// receive loop
+func (t *rejoinTester) printf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.receiver.out, format, args...)
+}
+
+// finishLeave records the leave latency of a group that was left and
+// returns it, with whether a packet arrived after the leave. The caller holds mu.
+func (t *rejoinTester) finishLeave(key string) (time.Duration, bool) {
+	left, ok := t.leftAt[key]
+	if !ok {
+		return 0, false
+	}
+	last, hadLast := t.lastPacket[key]
+	latency := time.Duration(0)
+	if hadLast {
+		latency = last.Sub(left)
+	}
+	t.stats[key].Leaves = append(t.stats[key].Leaves, latency)
+	delete(t.leftAt, key)
+	delete(t.lastPacket, key)
+	return latency, hadLast
+}
+
+// observe times a received packet against the last join or leave of its group
+func (t *rejoinTester) observe(pkt *Packet) {
+	key := pkt.Destination.IP.String()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at, ok := t.joinedAt[key]; ok && pkt.ReceivedAt.After(at) {
+		latency := pkt.ReceivedAt.Sub(at)
+		t.stats[key].Joins = append(t.stats[key].Joins, latency)
+		delete(t.joinedAt, key)
+		fmt.Fprintf(t.receiver.out, "⏱️  [%s] First packet on %s %v after joining\n",
+			pkt.ReceivedAt.Format("15:04:05.000"), pkt.Destination.IP, formatLatency(latency))
+	}
+	if at, ok := t.leftAt[key]; ok && pkt.ReceivedAt.After(at) {
+		t.lastPacket[key] = pkt.ReceivedAt
+	}
+}
+
+// close stops the schedule and records the leaves still pending
+func (t *rejoinTester) close() {
+	close(t.stop)
+	<-t.done
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.leftAt {
+		t.finishLeave(key)
+	}
+}
+
+// results returns the latencies of every group in the order they were joined
+func (t *rejoinTester) results() []RejoinStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var results []RejoinStats
+	for _, group := range t.receiver.groupList() {
+		if stats, ok := t.stats[group.IP.String()]; ok {
+			copied := *stats
+			copied.Joins = append([]time.Duration(nil), stats.Joins...)
+			copied.Leaves = append([]time.Duration(nil), stats.Leaves...)
+			results = append(results, copied)
+		}
+	}
+	return results
+}
+
+// printSummary prints the distribution of the latencies of every group
+func (t *rejoinTester) printSummary(w io.Writer) {
+	for _, stats := range t.results() {
+		fmt.Fprintf(w, "\n⏱️  %d rejoin(s) of %s\n", stats.Rejoins, stats.Group)
+		fmt.Fprintf(w, "   Join → first packet: %s\n", latencySummary(stats.Joins))
+		fmt.Fprintf(w, "   Leave → last packet: %s\n", latencySummary(stats.Leaves))
+		if stats.Silent > 0 {
+			fmt.Fprintf(w, "   ⚠️  %d join(s) received nothing before the next leave\n", stats.Silent)
+		}
+	}
+}
+
+// RejoinStats returns the latencies measured on every group of a receiver
+// created WithRejoin; it is safe to call while the receiver runs
+func (r *Receiver) RejoinStats() []RejoinStats {
+	if r.rejoin == nil {
+		return nil
+	}
+	return r.rejoin.results()
+}
+
+// latencySummary describes the distribution of latencies
+func latencySummary(latencies []time.Duration) string {
+	if len(latencies) == 0 {
+		return "no samples"
+	}
+	samples := delaySamples{samples: latencies}
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	parts := []string{fmt.Sprintf("%d sample(s)", len(latencies))}
+	for _, p := range []struct {
+		name string
+		p    float64
+	}{{"min", 0}, {"p50", 50}, {"p90", 90}, {"p99", 99}, {"max", 100}} {
+		value, _ := samples.percentile(p.p)
+		parts = append(parts, fmt.Sprintf("%s %s", p.name, formatLatency(value)))
+	}
+	parts = append(parts, "mean "+formatLatency(total/time.Duration(len(latencies))))
+	return strings.Join(parts, ", ")
+}
+
+// formatLatency rounds a latency for display
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,99 @@
+package multicast
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejoinConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RejoinConfig
+		err  string
+	}{
+		{"valid", RejoinConfig{Joined: time.Second, Left: time.Second}, ""},
+		{"random with count", RejoinConfig{Joined: time.Second, Left: time.Second, Random: true, Count: 5}, ""},
+		{"no time joined", RejoinConfig{Left: time.Second}, "time joined"},
+		{"no time left", RejoinConfig{Joined: time.Second}, "time left"},
+		{"negative count", RejoinConfig{Joined: time.Second, Left: time.Second, Count: -1}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRejoinConfigPeriod(t *testing.T) {
+	fixed := RejoinConfig{}
+	assert.Equal(t, time.Second, fixed.period(time.Second))
+
+	random := RejoinConfig{Random: true}
+	for i := 0; i < 100; i++ {
+		d := random.period(time.Second)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, 1500*time.Millisecond)
+	}
+}
+
+func TestLatencySummary(t *testing.T) {
+	assert.Equal(t, "no samples", latencySummary(nil))
+	assert.Equal(t, "4 sample(s), min 1ms, p50 2ms, p90 8ms, p99 8ms, max 8ms, mean 3.75ms",
+		latencySummary([]time.Duration{8 * time.Millisecond, time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}))
+}
+
+func TestReceiverRejoin(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	_, err := NewReceiver("239.23.23.61:23261", "lo", 0, WithRejoin(RejoinConfig{Joined: time.Second}))
+	assert.ErrorContains(t, err, "time left")
+
+	var out bytes.Buffer
+	receiver, err := NewReceiver("239.23.23.61:23261", "lo", 0, WithOutput(&out), WithQuiet(),
+		WithRejoin(RejoinConfig{Joined: 100 * time.Millisecond, Left: 50 * time.Millisecond, Count: 3}))
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- receiver.Start()
+	}()
+
+	sender, err := NewSender("239.23.23.61:23261", "lo", 5*time.Millisecond, 1, 0, 0, WithSenderOutput(&bytes.Buffer{}))
+	require.NoError(t, err)
+	go sender.Start()
+	defer sender.Close()
+
+	// The receiver closes itself after the last rejoin
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("receiver did not stop after the last rejoin")
+	}
+
+	stats := receiver.RejoinStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "239.23.23.61:23261", stats[0].Group.String())
+	assert.Equal(t, 3, stats[0].Rejoins)
+	assert.Len(t, stats[0].Leaves, 3)
+	require.Len(t, stats[0].Joins, 3)
+	for _, latency := range stats[0].Joins {
+		assert.Less(t, latency, 100*time.Millisecond)
+	}
+	assert.Zero(t, stats[0].Silent)
+
+	assert.Contains(t, out.String(), "Joined 239.23.23.61 again (rejoin 3 of 3)")
+	assert.Contains(t, out.String(), "3 rejoin(s) of 239.23.23.61:23261")
+	assert.Contains(t, out.String(), "Join → first packet: 3 sample(s)")
+}
@@ -0,0 +1,304 @@
+package multicast
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+// GroupMapping rewrites the destination of replayed packets. A nil IP or a zero
+// port in From matches any value; in To it keeps the original value.
+type GroupMapping struct {
+	From *net.UDPAddr
+	To   *net.UDPAddr
+}
+
+// ParseGroupMapping parses FROM=TO, where each side is an address, address:port or :port
+func ParseGroupMapping(spec string) (GroupMapping, error) {
+	fromStr, toStr, found := strings.Cut(spec, "=")
+	if !found {
+		return GroupMapping{}, fmt.Errorf("invalid mapping %q: use FROM=TO", spec)
+	}
+
+	from, err := network.ParseEndpoint(fromStr)
+	if err != nil {
+		return GroupMapping{}, fmt.Errorf("invalid mapping %q: %w", spec, err)
+	}
+	to, err := network.ParseEndpoint(toStr)
+	if err != nil {
+		return GroupMapping{}, fmt.Errorf("invalid mapping %q: %w", spec, err)
+	}
+
+	return GroupMapping{From: from, To: to}, nil
+}
+
+// apply returns the remapped destination, or nil if the mapping does not match
+func (m GroupMapping) apply(dst *net.UDPAddr) *net.UDPAddr {
+	if !network.EndpointMatches(m.From, dst) {
+		return nil
+	}
+
+	mapped := &net.UDPAddr{IP: dst.IP, Port: dst.Port}
+	if m.To.IP != nil {
+		mapped.IP = m.To.IP
+	}
+	if m.To.Port != 0 {
+		mapped.Port = m.To.Port
+	}
+	return mapped
+}
+
+// Replayer resends the datagrams of a recording to their original or remapped groups
+type Replayer struct {
+	reader        PacketReader
+	path          string
+	interfaceName string
+	ttl           int
+	dport         int
+	speed         float64
+	groups        []*net.UDPAddr
+	mappings      []GroupMapping
+	senderOpts    []SenderOption
+	senders       map[string]*Sender
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// ReplayOption configures optional Replayer behaviour
+type ReplayOption func(*Replayer)
+
+// WithSpeed scales the recorded timing: 2 replays twice as fast, 0 as fast as possible
+func WithSpeed(speed float64) ReplayOption {
+	return func(r *Replayer) {
+		r.speed = speed
+	}
+}
+
+// WithGroupFilter replays only packets sent to the given groups, where a zero
+// port matches any port. Without a filter every multicast packet is replayed.
+func WithGroupFilter(groups []*net.UDPAddr) ReplayOption {
+	return func(r *Replayer) {
+		r.groups = groups
+	}
+}
+
+// WithGroupMappings rewrites destinations; the first matching mapping applies
+func WithGroupMappings(mappings []GroupMapping) ReplayOption {
+	return func(r *Replayer) {
+		r.mappings = mappings
+	}
+}
+
+// WithReplaySenderOptions passes options to the senders created for each destination
+func WithReplaySenderOptions(opts ...SenderOption) ReplayOption {
+	return func(r *Replayer) {
+		r.senderOpts = opts
+	}
+}
+
+// NewReplayer opens a pcap, pcapng or jsonl recording for replay. A sender is
+// created for every destination on first use, with the given TTL and interface.
+func NewReplayer(path, interfaceName string, ttl, dport int, opts ...ReplayOption) (*Replayer, error) {
+	if ttl < 1 || ttl > 255 {
+		return nil, fmt.Errorf("TTL must be between 1 and 255, got %d", ttl)
+	}
+	if dport < 0 || dport > 65535 {
+		return nil, fmt.Errorf("destination port must be between 1 and 65535, got %d", dport)
+	}
+
+	replayer := &Replayer{
+		path:          path,
+		interfaceName: interfaceName,
+		ttl:           ttl,
+		dport:         dport,
+		speed:         1,
+		senders:       make(map[string]*Sender),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(replayer)
+	}
+
+	if replayer.speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative, got %g", replayer.speed)
+	}
+
+	reader, err := OpenRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	replayer.reader = reader
+
+	return replayer, nil
+}
+
+// Start replays the recording until its end or until Close is called
+func (r *Replayer) Start() error {
+	defer r.closeSenders()
+	defer r.reader.Close()
+
+	fmt.Printf("🔁 Replaying %s (%s)\n", r.path, speedString(r.speed))
+	fmt.Printf("⏹️  Press Ctrl+C to stop\n\n")
+
+	var (
+		first      time.Time
+		started    = time.Now()
+		sent       int
+		sentBytes  int
+		unselected int
+	)
+
+	for {
+		pkt, err := r.reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if !r.selected(pkt.Destination) {
+			unselected++
+			continue
+		}
+		dst := r.destination(pkt.Destination)
+
+		if first.IsZero() {
+			first = pkt.ReceivedAt
+		}
+		if r.speed > 0 {
+			offset := time.Duration(float64(pkt.ReceivedAt.Sub(first)) / r.speed)
+			if !r.wait(time.Until(started.Add(offset))) {
+				break
+			}
+		} else if r.stopped() {
+			break
+		}
+
+		sender, err := r.sender(dst)
+		if err != nil {
+			return err
+		}
+		if err := sender.Send(pkt.Data); err != nil {
+			return err
+		}
+		sent++
+		sentBytes += len(pkt.Data)
+
+		fmt.Printf("📤 [%s] Replayed %d bytes from %s to %s\n",
+			time.Now().Format("15:04:05.000"), len(pkt.Data), pkt.Source, dst)
+	}
+
+	fmt.Printf("\n✅ Replayed %d packets (%d bytes) to %d group(s) in %v",
+		sent, sentBytes, len(r.senders), time.Since(started).Round(time.Millisecond))
+	if skipped := unselected + r.reader.Skipped(); skipped > 0 {
+		fmt.Printf(", skipped %d", skipped)
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// Close stops a running replay
+func (r *Replayer) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	return nil
+}
+
+// selected reports whether a packet to dst is part of the replay
+func (r *Replayer) selected(dst *net.UDPAddr) bool {
+	if len(r.groups) == 0 {
+		return dst.IP.IsMulticast()
+	}
+	for _, group := range r.groups {
+		if network.EndpointMatches(group, dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// destination applies the group mappings and the destination port override
+func (r *Replayer) destination(dst *net.UDPAddr) *net.UDPAddr {
+	mapped := &net.UDPAddr{IP: dst.IP, Port: dst.Port}
+	for _, mapping := range r.mappings {
+		if remapped := mapping.apply(dst); remapped != nil {
+			mapped = remapped
+			break
+		}
+	}
+	if r.dport > 0 {
+		mapped.Port = r.dport
+	}
+	return mapped
+}
+
+// sender returns the sender for a destination, creating it on first use
+func (r *Replayer) sender(dst *net.UDPAddr) (*Sender, error) {
+	key := dst.String()
+	if sender, ok := r.senders[key]; ok {
+		return sender, nil
+	}
+
+	sender, err := NewSender(key, r.interfaceName, 0, r.ttl, 0, 0, r.senderOpts...)
+	if err != nil {
+		return nil, err
+	}
+	r.senders[key] = sender
+
+	fmt.Printf("📡 Sending to %s (TTL: %d, loopback: %s)\n", key, r.ttl, onOff(sender.loopback))
+	for _, warning := range sender.warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	return sender, nil
+}
+
+// wait sleeps for d and reports false if the replay was stopped meanwhile
+func (r *Replayer) wait(d time.Duration) bool {
+	if d <= 0 {
+		return !r.stopped()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+func (r *Replayer) stopped() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Replayer) closeSenders() {
+	for _, sender := range r.senders {
+		sender.Close()
+	}
+}
+
+// speedString describes a replay speed for display
+func speedString(speed float64) string {
+	if speed == 0 {
+		return "as fast as possible"
+	}
+	if speed == 1 {
+		return "original timing"
+	}
+	return fmt.Sprintf("%gx speed", speed)
+}
@@ -0,0 +1,204 @@
+package multicast
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupMapping(t *testing.T) {
+	tests := []struct {
+		spec     string
+		dst      string
+		expected string
+		err      string
+	}{
+		{spec: "239.1.1.1=239.2.2.2", dst: "239.1.1.1:5000", expected: "239.2.2.2:5000"},
+		{spec: "239.1.1.1:5000=239.2.2.2:6000", dst: "239.1.1.1:5000", expected: "239.2.2.2:6000"},
+		{spec: ":5000=:6000", dst: "239.1.1.9:5000", expected: "239.1.1.9:6000"},
+		{spec: "239.1.1.1=:6000", dst: "239.1.1.1:5000", expected: "239.1.1.1:6000"},
+		{spec: "239.1.1.1:5001=239.2.2.2", dst: "239.1.1.1:5000", expected: ""},
+		{spec: "239.1.1.1", err: "use FROM=TO"},
+		{spec: "nowhere=239.2.2.2", err: "invalid mapping"},
+		{spec: "239.1.1.1=", err: "invalid mapping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			mapping, err := ParseGroupMapping(tt.spec)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			dst, err := net.ResolveUDPAddr("udp", tt.dst)
+			require.NoError(t, err)
+			mapped := mapping.apply(dst)
+			if tt.expected == "" {
+				assert.Nil(t, mapped)
+				return
+			}
+			require.NotNil(t, mapped)
+			assert.Equal(t, tt.expected, mapped.String())
+		})
+	}
+}
+
+func TestNewReplayerValidation(t *testing.T) {
+	path := recordPackets(t, filepath.Join(t.TempDir(), "empty.jsonl"))
+
+	tests := []struct {
+		name  string
+		path  string
+		ttl   int
+		dport int
+		opts  []ReplayOption
+		err   string
+	}{
+		{name: "TTL too low", path: path, ttl: 0, err: "TTL must be between 1 and 255"},
+		{name: "destination port too high", path: path, ttl: 1, dport: 70000, err: "destination port"},
+		{name: "negative speed", path: path, ttl: 1, opts: []ReplayOption{WithSpeed(-1)}, err: "speed must not be negative"},
+		{name: "missing recording", path: path + ".missing", ttl: 1, err: "failed to open recording"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReplayer(tt.path, "", tt.ttl, tt.dport, tt.opts...)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestReplayerDestination(t *testing.T) {
+	addr := func(s string) *net.UDPAddr {
+		a, err := net.ResolveUDPAddr("udp", s)
+		require.NoError(t, err)
+		return a
+	}
+	mappings := []GroupMapping{
+		{From: addr("239.1.1.1:5000"), To: addr("239.2.2.2:6000")},
+		{From: &net.UDPAddr{IP: net.ParseIP("239.1.1.1")}, To: addr("239.3.3.3:0")},
+	}
+
+	tests := []struct {
+		name     string
+		replayer *Replayer
+		dst      string
+		selected bool
+		expected string
+	}{
+		{name: "multicast by default", dst: "239.9.9.9:5000", selected: true, expected: "239.9.9.9:5000"},
+		{name: "unicast skipped by default", dst: "192.0.2.1:5000", selected: false},
+		{name: "filtered group", replayer: &Replayer{groups: []*net.UDPAddr{addr("239.1.1.1:0")}}, dst: "239.1.1.1:7000", selected: true, expected: "239.1.1.1:7000"},
+		{name: "group outside the filter", replayer: &Replayer{groups: []*net.UDPAddr{addr("239.1.1.1:5000")}}, dst: "239.1.1.1:7000", selected: false},
+		{name: "first mapping applies", replayer: &Replayer{mappings: mappings}, dst: "239.1.1.1:5000", selected: true, expected: "239.2.2.2:6000"},
+		{name: "second mapping keeps the port", replayer: &Replayer{mappings: mappings}, dst: "239.1.1.1:5001", selected: true, expected: "239.3.3.3:5001"},
+		{name: "port override after mapping", replayer: &Replayer{mappings: mappings, dport: 7777}, dst: "239.1.1.1:5000", selected: true, expected: "239.2.2.2:7777"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.replayer == nil {
+				tt.replayer = &Replayer{}
+			}
+			dst := addr(tt.dst)
+			require.Equal(t, tt.selected, tt.replayer.selected(dst))
+			if tt.selected {
+				assert.Equal(t, tt.expected, tt.replayer.destination(dst).String())
+			}
+		})
+	}
+}
+
+func TestReplayerStart(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	// Three messages a second apart to one group, and traffic to others
+	started := time.Now()
+	var pkts []*Packet
+	for i, dst := range []string{"239.23.23.64:23262", "239.23.23.66:23262", "239.23.23.64:23262", "192.0.2.1:23262", "239.23.23.64:23262"} {
+		msg := &Message{ID: len(pkts) + 1, Timestamp: started, Source: "recorded", Session: 0x64}
+		data, err := msg.Marshal()
+		require.NoError(t, err)
+		destination, err := net.ResolveUDPAddr("udp", dst)
+		require.NoError(t, err)
+		pkts = append(pkts, &Packet{
+			Data:        data,
+			Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000},
+			Destination: destination,
+			ReceivedAt:  started.Add(time.Duration(i) * 500 * time.Millisecond),
+		})
+	}
+	path := recordPackets(t, filepath.Join(t.TempDir(), "replay.jsonl"), pkts...)
+
+	receiver, err := NewReceiver("239.23.23.65:23263", "lo", 0, WithOutput(io.Discard))
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- receiver.Start()
+	}()
+	defer func() {
+		receiver.Close()
+		<-done
+	}()
+
+	filter := []*net.UDPAddr{{IP: net.ParseIP("239.23.23.64")}}
+	mapping, err := ParseGroupMapping("239.23.23.64=239.23.23.65")
+	require.NoError(t, err)
+	// The recording spans 2s; at 40x it takes about 50ms
+	replayer, err := NewReplayer(path, "lo", 1, 23263, WithSpeed(40), WithGroupFilter(filter),
+		WithGroupMappings([]GroupMapping{mapping}), WithReplaySenderOptions(WithSenderOutput(io.Discard)))
+	require.NoError(t, err)
+
+	begin := time.Now()
+	require.NoError(t, replayer.Start())
+	elapsed := time.Since(begin)
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+
+	received := func() int {
+		total := 0
+		for _, stats := range receiver.Streams() {
+			total += stats.Received
+		}
+		return total
+	}
+	require.Eventually(t, func() bool { return received() == 3 }, time.Second, 10*time.Millisecond)
+	for key, stats := range receiver.Streams() {
+		assert.Equal(t, SessionID(0x64), key.Session)
+		assert.Equal(t, "239.23.23.65:23263", stats.Group)
+	}
+}
+
+func TestReplayerClose(t *testing.T) {
+	pkt := &Packet{
+		Data:        []byte("x"),
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.64"), Port: 23262},
+		ReceivedAt:  time.Now(),
+	}
+	later := *pkt
+	later.ReceivedAt = pkt.ReceivedAt.Add(time.Hour)
+	path := recordPackets(t, filepath.Join(t.TempDir(), "slow.jsonl"), pkt, &later)
+
+	replayer, err := NewReplayer(path, "", 1, 0)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- replayer.Start()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, replayer.Close())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("replay did not stop on Close")
+	}
+}
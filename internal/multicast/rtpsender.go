@@ -0,0 +1,120 @@
+package multicast
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+)
+
+// RTPConfig describes the RTP stream a sender emits. Every packet carries an
+// mcaster message as its payload, grouped into frames like a video encoder.
+type RTPConfig struct {
+	PayloadType uint8
+	// ClockRate of 0 uses the rate of the payload type, or 90 kHz for dynamic payload types
+	ClockRate int
+	// SSRC of 0 is derived from the random session ID
+	SSRC            uint32
+	PacketsPerFrame int
+	FrameRate       float64
+}
+
+// Validate checks the configuration
+func (c RTPConfig) Validate() error {
+	if c.PayloadType > 127 {
+		return fmt.Errorf("RTP payload type must be between 0 and 127, got %d", c.PayloadType)
+	}
+	if c.ClockRate < 0 {
+		return fmt.Errorf("RTP clock rate must not be negative, got %d", c.ClockRate)
+	}
+	if c.PacketsPerFrame < 1 {
+		return fmt.Errorf("RTP packets per frame must be at least 1, got %d", c.PacketsPerFrame)
+	}
+	if c.FrameRate <= 0 || math.IsInf(c.FrameRate, 0) || math.IsNaN(c.FrameRate) {
+		return fmt.Errorf("RTP frame rate must be positive, got %v", c.FrameRate)
+	}
+	// A faster rate would truncate the frame interval to zero
+	if c.FrameRate > float64(time.Second) {
+		return fmt.Errorf("RTP frame rate must be at most %d frames per second, got %v", time.Second, c.FrameRate)
+	}
+	return nil
+}
+
+// rtpFramer numbers and timestamps the packets of an RTP stream
+type rtpFramer struct {
+	RTPConfig
+	sequence  uint16
+	timestamp uint32
+	frames    int
+}
+
+// newRTPFramer starts a stream whose SSRC, first sequence number and first
+// timestamp come from the session, which is random as RFC 3550 asks
+func newRTPFramer(cfg RTPConfig, session SessionID) *rtpFramer {
+	if cfg.ClockRate == 0 {
+		cfg.ClockRate = rtp.ClockRate(cfg.PayloadType)
+	}
+	if cfg.ClockRate == 0 {
+		cfg.ClockRate = defaultRTPClockRate
+	}
+	if cfg.SSRC == 0 {
+		cfg.SSRC = uint32(session >> 32)
+	}
+	return &rtpFramer{
+		RTPConfig: cfg,
+		sequence:  uint16(session),
+		timestamp: uint32(session >> 16),
+	}
+}
+
+// interval returns the time between frames
+func (f *rtpFramer) interval() time.Duration {
+	return time.Duration(float64(time.Second) / f.FrameRate)
+}
+
+// frameTimestamp returns the RTP timestamp of the current frame, computed
+// from the frame count so fractional frame durations do not accumulate errors
+func (f *rtpFramer) frameTimestamp() uint32 {
+	return f.timestamp + uint32(math.Round(float64(f.frames)*float64(f.ClockRate)/f.FrameRate))
+}
+
+// packet wraps one message of the current frame; the last one carries the marker
+func (f *rtpFramer) packet(payload []byte, last bool) []byte {
+	header := rtp.Header{
+		Marker:      last,
+		PayloadType: f.PayloadType,
+		Sequence:    f.sequence,
+		Timestamp:   f.frameTimestamp(),
+		SSRC:        f.SSRC,
+	}
+	f.sequence++
+	return header.Append(make([]byte, 0, rtp.HeaderSize+len(payload)), payload)
+}
+
+// nextFrame moves on to the following frame
+func (f *rtpFramer) nextFrame() {
+	f.frames++
+}
+
+func (f *rtpFramer) String() string {
+	return fmt.Sprintf("RTP PT %d (%s), clock rate %d Hz, SSRC 0x%08x, %d packet(s) per frame at %g fps",
+		f.PayloadType, rtp.PayloadTypeName(f.PayloadType), f.ClockRate, f.SSRC, f.PacketsPerFrame, f.FrameRate)
+}
+
+// rtpMessage returns the mcaster message carried in an RTP packet, as sent
+// with WithRTP, or nil if the datagram is not RTP or carries something else
+func rtpMessage(data []byte) []byte {
+	if rtp.IsRTCP(data) {
+		return nil
+	}
+	header, err := rtp.Parse(data)
+	if err != nil {
+		return nil
+	}
+	payload := data[header.PayloadOffset : header.PayloadOffset+header.PayloadSize]
+	if !looksLikeMessage(payload) {
+		return nil
+	}
+	return payload
+}
@@ -0,0 +1,129 @@
+package multicast
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPConfigValidate(t *testing.T) {
+	valid := RTPConfig{PayloadType: 96, PacketsPerFrame: 4, FrameRate: 25}
+
+	tests := []struct {
+		name   string
+		modify func(c *RTPConfig)
+		err    string
+	}{
+		{name: "valid", modify: func(c *RTPConfig) {}},
+		{name: "payload type", modify: func(c *RTPConfig) { c.PayloadType = 128 }, err: "payload type"},
+		{name: "clock rate", modify: func(c *RTPConfig) { c.ClockRate = -1 }, err: "clock rate"},
+		{name: "packets per frame", modify: func(c *RTPConfig) { c.PacketsPerFrame = 0 }, err: "packets per frame"},
+		{name: "frame rate", modify: func(c *RTPConfig) { c.FrameRate = 0 }, err: "frame rate"},
+		{name: "huge frame rate", modify: func(c *RTPConfig) { c.FrameRate = 2e9 }, err: "at most 1000000000"},
+		{name: "frame every nanosecond", modify: func(c *RTPConfig) { c.FrameRate = 1e9 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestRTPFramer(t *testing.T) {
+	session := SessionID(0x112233440000fffe)
+
+	t.Run("defaults from the session and payload type", func(t *testing.T) {
+		f := newRTPFramer(RTPConfig{PayloadType: 0, PacketsPerFrame: 1, FrameRate: 50}, session)
+		assert.Equal(t, 8000, f.ClockRate)
+		assert.Equal(t, uint32(0x11223344), f.SSRC)
+		assert.Equal(t, 20*time.Millisecond, f.interval())
+
+		dynamic := newRTPFramer(RTPConfig{PayloadType: 96, SSRC: 7, PacketsPerFrame: 1, FrameRate: 25}, session)
+		assert.Equal(t, defaultRTPClockRate, dynamic.ClockRate)
+		assert.Equal(t, uint32(7), dynamic.SSRC)
+	})
+
+	t.Run("frames", func(t *testing.T) {
+		f := newRTPFramer(RTPConfig{PayloadType: 96, ClockRate: 90000, PacketsPerFrame: 3, FrameRate: 29.97}, session)
+
+		var headers []*rtp.Header
+		for frame := 0; frame < 3; frame++ {
+			for i := 0; i < 3; i++ {
+				h, err := rtp.Parse(f.packet([]byte("{}"), i == 2))
+				require.NoError(t, err)
+				headers = append(headers, h)
+			}
+			f.nextFrame()
+		}
+
+		// The sequence number wraps from 65535 to 0 within the first frame
+		assert.Equal(t, uint16(65534), headers[0].Sequence)
+		assert.Equal(t, uint16(0), headers[2].Sequence)
+		for i, h := range headers {
+			assert.Equal(t, i%3 == 2, h.Marker, "packet %d", i)
+			assert.Equal(t, uint8(96), h.PayloadType)
+			assert.Equal(t, headers[i/3*3].Timestamp, h.Timestamp, "packet %d", i)
+		}
+		assert.Equal(t, uint32(3003), headers[3].Timestamp-headers[0].Timestamp)
+		assert.Equal(t, uint32(6006), headers[6].Timestamp-headers[0].Timestamp)
+	})
+}
+
+func TestRTPMessage(t *testing.T) {
+	header := rtp.Header{PayloadType: 96}
+	assert.Equal(t, []byte(`{"id":1}`), rtpMessage(header.Append(nil, []byte(`{"id":1}`))))
+	assert.Nil(t, rtpMessage(header.Append(nil, []byte{0x47, 0, 0, 0})))
+	assert.Nil(t, rtpMessage([]byte(`{"id":1}`)))
+	assert.Nil(t, rtpMessage([]byte{0x80, 200, 0, 1, '{'}))
+}
+
+func TestSenderRTP(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	receiver, err := NewReceiver("239.23.23.43:23250", "lo", 0, WithRTPAnalysis(0))
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	sender, err := NewSender("239.23.23.43:23250", "lo", time.Second, 1, 0, 0,
+		WithRTP(RTPConfig{PayloadType: 96, PacketsPerFrame: 3, FrameRate: 25}))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	require.NoError(t, sender.sendFrame())
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for i := 0; i < 3; i++ {
+		if err := receiver.receivePacket(); err != nil {
+			t.Skipf("multicast loopback not available: %v", err)
+		}
+	}
+
+	// The RTP layer and the messages it carries are both tracked
+	var rtpStats, messageStats StreamStats
+	for key, stats := range receiver.Streams() {
+		if key.Protocol == protocolRTP {
+			rtpStats = stats
+		} else {
+			messageStats = stats
+		}
+	}
+	assert.Equal(t, 3, rtpStats.Received)
+	assert.Equal(t, 1, rtpStats.Markers)
+	assert.Equal(t, uint8(96), rtpStats.PayloadType)
+	assert.Equal(t, 3, messageStats.Received)
+	assert.Zero(t, messageStats.Lost)
+}
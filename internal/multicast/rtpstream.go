@@ -0,0 +1,126 @@
+package multicast
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+)
+
+const protocolRTP = "RTP"
+
+// defaultRTPClockRate is assumed for dynamic payload types, as used by video
+const defaultRTPClockRate = 90000
+
+// rtpDecoder tracks the RTP streams of the received datagrams per SSRC in the
+// receiver's stream table, so they are reported like mcaster streams
+type rtpDecoder struct {
+	streams   *streamTable
+	clockRate int
+	states    map[StreamKey]*rtpState
+}
+
+// rtpState is what an RTP stream needs beyond its StreamStats
+type rtpState struct {
+	clockRate  int
+	highestSeq int64
+	timestamp  int64
+	lastRTP    uint32
+	first      time.Time
+}
+
+// newRTPDecoder creates an RTP decoder. A clockRate of 0 uses the rate of the
+// payload type, or 90 kHz for dynamic payload types.
+func newRTPDecoder(streams *streamTable, clockRate int) *rtpDecoder {
+	return &rtpDecoder{
+		streams:   streams,
+		clockRate: clockRate,
+		states:    make(map[StreamKey]*rtpState),
+	}
+}
+
+func (d *rtpDecoder) Decode(pkt *Packet, group string, out io.Writer) bool {
+	if rtp.IsRTCP(pkt.Data) {
+		return false
+	}
+	header, err := rtp.Parse(pkt.Data)
+	if err != nil {
+		return false
+	}
+
+	key := StreamKey{
+		Protocol: protocolRTP,
+		Source:   pkt.Source.String(),
+		Session:  SessionID(header.SSRC),
+		Stream:   pkt.Destination.String(),
+	}
+	now := pkt.ReceivedAt.Format("15:04:05.000")
+
+	d.streams.mu.Lock()
+	defer d.streams.mu.Unlock()
+	stats, created := d.streams.get(key)
+	state := d.states[key]
+	if created || state == nil {
+		state = &rtpState{
+			clockRate:  d.clockRateFor(header.PayloadType),
+			highestSeq: int64(header.Sequence),
+			timestamp:  int64(header.Timestamp),
+			lastRTP:    header.Timestamp,
+			first:      pkt.ReceivedAt,
+		}
+		d.states[key] = state
+		stats.PayloadType = header.PayloadType
+		fmt.Fprintf(out, "🎬 [%s] New RTP stream SSRC 0x%08x from %s%s: PT %d (%s), clock rate %d Hz\n",
+			now, header.SSRC, pkt.Source, group, header.PayloadType,
+			rtp.PayloadTypeName(header.PayloadType), state.clockRate)
+	} else if header.PayloadType != stats.PayloadType {
+		fmt.Fprintf(out, "🔁 [%s] RTP SSRC 0x%08x%s changed payload type from %d to %d\n",
+			now, header.SSRC, group, stats.PayloadType, header.PayloadType)
+		stats.PayloadType = header.PayloadType
+	}
+
+	// Extend the 16-bit sequence number relative to the highest one so far
+	seq := state.highestSeq + int64(int16(header.Sequence-uint16(state.highestSeq)))
+	if seq > state.highestSeq {
+		state.highestSeq = seq
+	}
+	lost := stats.Lost
+	stats.observe(int(seq))
+	if stats.Lost > lost {
+		fmt.Fprintf(out, "⚠️  [%s] RTP SSRC 0x%08x%s lost %d packet(s) before seq %d\n",
+			now, header.SSRC, group, stats.Lost-lost, header.Sequence)
+	}
+	if header.Marker {
+		stats.Markers++
+	}
+	stats.observePacket(pkt)
+
+	// The transit time is relative to the first packet; the offset cancels out in the jitter
+	state.timestamp += int64(int32(header.Timestamp - state.lastRTP))
+	state.lastRTP = header.Timestamp
+	sent := float64(state.timestamp) / float64(state.clockRate)
+	stats.observeTransit(pkt.ReceivedAt.Sub(state.first).Seconds() - sent)
+
+	return true
+}
+
+func (d *rtpDecoder) String() string {
+	if d.clockRate > 0 {
+		return fmt.Sprintf("RTP streams (clock rate %d Hz)", d.clockRate)
+	}
+	return "RTP streams (clock rate from payload type)"
+}
+
+// PrintSummary does nothing: RTP streams are reported with the receiver's streams
+func (d *rtpDecoder) PrintSummary(w io.Writer) {}
+
+func (d *rtpDecoder) clockRateFor(pt uint8) int {
+	if d.clockRate > 0 {
+		return d.clockRate
+	}
+	if rate := rtp.ClockRate(pt); rate > 0 {
+		return rate
+	}
+	return defaultRTPClockRate
+}
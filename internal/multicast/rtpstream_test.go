@@ -0,0 +1,168 @@
+package multicast
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rtpPacket builds an RTP datagram received at the given time
+func rtpPacket(seq uint16, ts uint32, pt uint8, marker bool, at time.Time) *Packet {
+	data := make([]byte, 20)
+	data[0] = 0x80
+	data[1] = pt
+	if marker {
+		data[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(data[2:], seq)
+	binary.BigEndian.PutUint32(data[4:], ts)
+	binary.BigEndian.PutUint32(data[8:], 0x1a2b3c4d)
+	return &Packet{
+		Data:        data,
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5004},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 5004},
+		ReceivedAt:  at,
+	}
+}
+
+func TestRTPDecoder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 8 kHz audio, one packet every 20ms (160 samples)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * 20 * time.Millisecond) }
+
+	tests := []struct {
+		name     string
+		seqs     []uint16
+		expected func(t *testing.T, stats StreamStats)
+	}{
+		{
+			name: "in order",
+			seqs: []uint16{10, 11, 12, 13},
+			expected: func(t *testing.T, stats StreamStats) {
+				assert.Equal(t, 4, stats.Received)
+				assert.Zero(t, stats.Lost)
+				assert.Zero(t, stats.Jitter)
+			},
+		},
+		{
+			name: "loss and reordering",
+			seqs: []uint16{10, 12, 11, 15, 15},
+			expected: func(t *testing.T, stats StreamStats) {
+				assert.Equal(t, 2, stats.Lost)
+				assert.Equal(t, 1, stats.Reordered)
+				assert.Equal(t, 1, stats.Duplicates)
+			},
+		},
+		{
+			name: "sequence wraps around",
+			seqs: []uint16{65534, 65535, 0, 2},
+			expected: func(t *testing.T, stats StreamStats) {
+				assert.Equal(t, 4, stats.Received)
+				assert.Equal(t, 1, stats.Lost)
+				assert.Zero(t, stats.Reordered)
+			},
+		},
+		{
+			name: "late packet from before a wrap",
+			seqs: []uint16{0, 65535},
+			expected: func(t *testing.T, stats StreamStats) {
+				assert.Equal(t, 1, stats.Reordered)
+				assert.Zero(t, stats.Lost)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := newStreamTable()
+			d := newRTPDecoder(streams, 0)
+			for _, seq := range tt.seqs {
+				// Timestamps follow the sequence number, arrival follows the packet order
+				ts := uint32(seq-tt.seqs[0]) * 160
+				require.True(t, d.Decode(rtpPacket(seq, ts, 0, false, at(int(seq-tt.seqs[0]))), "", io.Discard))
+			}
+
+			snapshot := streams.snapshot()
+			require.Len(t, snapshot, 1)
+			for key, stats := range snapshot {
+				assert.Equal(t, protocolRTP, key.Protocol)
+				assert.Equal(t, SessionID(0x1a2b3c4d), key.Session)
+				assert.Equal(t, "RTP SSRC 0x1a2b3c4d from 192.0.2.1:5004 on 239.1.1.1:5004", key.String())
+				tt.expected(t, stats)
+			}
+		})
+	}
+}
+
+func TestRTPDecoderJitterAndMarkers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	streams := newStreamTable()
+	d := newRTPDecoder(streams, 0)
+
+	// 90 kHz video at 25 fps, every other frame arrives 2ms late
+	for i := 0; i < 100; i++ {
+		arrival := start.Add(time.Duration(i) * 40 * time.Millisecond)
+		if i%2 == 1 {
+			arrival = arrival.Add(2 * time.Millisecond)
+		}
+		require.True(t, d.Decode(rtpPacket(uint16(i), uint32(i*3600), 96, i%2 == 0, arrival), "", io.Discard))
+	}
+	require.True(t, d.Decode(rtpPacket(100, 100*3600, 97, false, start.Add(4*time.Second)), "", io.Discard))
+
+	var stats StreamStats
+	for _, s := range streams.snapshot() {
+		stats = s
+	}
+	// Each transit difference is 2ms, so the jitter converges on 2ms
+	assert.InDelta(t, 2*time.Millisecond, stats.Jitter, float64(100*time.Microsecond))
+	assert.Equal(t, 50, stats.Markers)
+	assert.Equal(t, uint8(97), stats.PayloadType)
+}
+
+func TestRTPDecoderOutput(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newRTPDecoder(newStreamTable(), 0)
+
+	var out strings.Builder
+	require.True(t, d.Decode(rtpPacket(1, 0, 96, false, start), " on 239.1.1.1", &out))
+	require.True(t, d.Decode(rtpPacket(3, 7200, 96, false, start), " on 239.1.1.1", &out))
+	require.True(t, d.Decode(rtpPacket(4, 10800, 97, false, start), " on 239.1.1.1", &out))
+	assert.Contains(t, out.String(), "New RTP stream SSRC 0x1a2b3c4d from 192.0.2.1:5004 on 239.1.1.1: PT 96")
+	assert.Contains(t, out.String(), "lost 1 packet(s) before seq 3")
+	assert.Contains(t, out.String(), "changed payload type from 96 to 97")
+
+	// A quiet receiver discards the events
+	var quiet strings.Builder
+	r := &Receiver{out: &quiet, quiet: true}
+	require.True(t, newRTPDecoder(newStreamTable(), 0).Decode(rtpPacket(1, 0, 96, false, start), "", r.packetOut()))
+	assert.Empty(t, quiet.String())
+}
+
+func TestRTPDecoderIgnoresOtherTraffic(t *testing.T) {
+	d := newRTPDecoder(newStreamTable(), 90000)
+	pkt := rtpPacket(1, 1, 96, false, time.Now())
+
+	pkt.Data = []byte(`{"id":1}`)
+	assert.False(t, d.Decode(pkt, "", io.Discard))
+	pkt.Data = []byte{0x80, 200, 0, 6, 0, 0, 0, 1}
+	assert.False(t, d.Decode(pkt, "", io.Discard), "RTCP")
+	assert.Equal(t, "RTP streams (clock rate 90000 Hz)", d.String())
+
+	assert.Equal(t, 8000, newRTPDecoder(nil, 0).clockRateFor(0))
+	assert.Equal(t, defaultRTPClockRate, newRTPDecoder(nil, 0).clockRateFor(96))
+	assert.Equal(t, 48000, newRTPDecoder(nil, 48000).clockRateFor(0))
+}
+
+func TestLooksLikeMessage(t *testing.T) {
+	assert.True(t, looksLikeMessage([]byte(`{"id":1}`)))
+	assert.True(t, looksLikeMessage([]byte(" \n{")))
+	assert.True(t, looksLikeMessage([]byte("MCAE\x01")))
+	assert.False(t, looksLikeMessage([]byte{0x80, 0x60}))
+	assert.False(t, looksLikeMessage(nil))
+}
@@ -0,0 +1,168 @@
+package multicast
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/sap"
+)
+
+// sapDecoder lists the sessions announced with SAP, keyed by the SDP origin
+// without its version so updated descriptions replace the earlier ones
+type sapDecoder struct {
+	sessions map[string]*announcedSession
+	order    []string
+}
+
+// announcedSession is the latest description of an announced session
+type announcedSession struct {
+	session  *sap.Session
+	sdp      string
+	source   string
+	lastSeen time.Time
+	count    int
+}
+
+func newSAPDecoder() *sapDecoder {
+	return &sapDecoder{sessions: make(map[string]*announcedSession)}
+}
+
+func (d *sapDecoder) Decode(pkt *Packet, group string, out io.Writer) bool {
+	p, err := sap.Parse(pkt.Data)
+	if err != nil {
+		return false
+	}
+	at := pkt.ReceivedAt.Format("15:04:05.000")
+	if p.Encrypted || p.Compressed || !p.IsSDP() {
+		fmt.Fprintf(out, "⚠️  [%s] Skipped SAP packet from %s%s: %s\n", at, pkt.Source, group, sapSkipReason(p))
+		return true
+	}
+
+	text := string(p.Payload)
+	if p.Deletion && !strings.HasPrefix(strings.TrimSpace(text), "v=") {
+		// Deletions may carry only the o= line that identifies the session
+		text = "v=0\r\n" + text
+	}
+	session, err := sap.ParseSDP(text)
+	if err != nil {
+		fmt.Fprintf(out, "⚠️  [%s] Invalid SDP from %s%s: %v\n", at, pkt.Source, group, err)
+		return true
+	}
+	key := sessionKey(p.Origin, session)
+
+	known, ok := d.sessions[key]
+	if p.Deletion {
+		if ok {
+			delete(d.sessions, key)
+			fmt.Fprintf(out, "🗑️  [%s] Session %s deleted by %s\n", at, known.session.Summary(), p.Origin)
+		}
+		return true
+	}
+
+	switch {
+	case !ok:
+		known = &announcedSession{}
+		d.sessions[key] = known
+		d.order = append(d.order, key)
+		fmt.Fprintf(out, "📢 [%s] Session %s announced by %s\n", at, session.Summary(), p.Origin)
+	case known.sdp != text:
+		fmt.Fprintf(out, "🔄 [%s] Session %s updated by %s\n", at, session.Summary(), p.Origin)
+	}
+	if !ok || known.sdp != text {
+		for _, command := range receiveCommands(session) {
+			fmt.Fprintf(out, "   ▶️  %s\n", command)
+		}
+	}
+	known.session, known.sdp, known.source = session, text, p.Origin.String()
+	known.lastSeen = pkt.ReceivedAt
+	known.count++
+	return true
+}
+
+func (d *sapDecoder) PrintSummary(w io.Writer) {
+	var active []*announcedSession
+	for _, key := range d.order {
+		if s, ok := d.sessions[key]; ok {
+			active = append(active, s)
+		}
+	}
+	if len(active) == 0 {
+		fmt.Fprintf(w, "\n📢 No sessions are announced\n")
+		return
+	}
+
+	fmt.Fprintf(w, "\n📢 %d SAP session(s):\n", len(active))
+	for _, s := range active {
+		fmt.Fprintf(w, "   %s from %s, %d announcement(s), last %s ago\n",
+			s.session.Summary(), s.source, s.count, time.Since(s.lastSeen).Round(time.Second))
+		for _, command := range receiveCommands(s.session) {
+			fmt.Fprintf(w, "      ▶️  %s\n", command)
+		}
+	}
+}
+
+func (d *sapDecoder) String() string {
+	return "SAP announcements (RFC 2974)"
+}
+
+// sessionKey identifies a session by the SAP origin and the SDP origin's
+// username and session ID
+func sessionKey(origin net.IP, s *sap.Session) string {
+	fields := strings.Fields(s.Origin)
+	if len(fields) >= 2 {
+		fields = fields[:2]
+	}
+	return origin.String() + " " + strings.Join(fields, " ")
+}
+
+func sapSkipReason(p *sap.Packet) string {
+	switch {
+	case p.Encrypted:
+		return "encrypted"
+	case p.Compressed:
+		return "compressed"
+	default:
+		return "payload type " + p.PayloadType
+	}
+}
+
+// receiveCommands returns an mcaster receive command for each media stream of
+// a session, with --rtp or --mpegts to analyse the streams it carries
+func receiveCommands(s *sap.Session) []string {
+	var commands []string
+	for _, m := range s.Media {
+		addr := s.Address(m)
+		if addr == "" {
+			continue
+		}
+		command := "mcaster receive -g " + net.JoinHostPort(addr, strconv.Itoa(m.Port))
+		if strings.HasPrefix(m.Protocol, "RTP/") {
+			command += " --rtp"
+		}
+		if carriesTS(m) {
+			command += " --mpegts"
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// carriesTS reports whether a media stream is an MPEG transport stream, in
+// UDP as format "mpeg" or in RTP as payload type 33 or an MP2T rtpmap
+func carriesTS(m sap.Media) bool {
+	for _, format := range m.Formats {
+		if format == "mpeg" || (format == "33" && strings.HasPrefix(m.Protocol, "RTP/")) {
+			return true
+		}
+	}
+	for _, a := range m.Attributes {
+		if strings.HasPrefix(a, "rtpmap:") && strings.Contains(strings.ToUpper(a), " MP2T/") {
+			return true
+		}
+	}
+	return false
+}
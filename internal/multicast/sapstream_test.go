@@ -0,0 +1,96 @@
+package multicast
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/sap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAnnouncedSDP = "v=0\r\no=- 42 1 IN IP4 192.0.2.10\r\ns=News\r\nc=IN IP4 239.1.1.1/16\r\nt=0 0\r\n" +
+	"m=video 5004 RTP/AVP 33\r\n"
+
+// sapPacket builds a SAP datagram from 192.0.2.10 carrying the SDP text
+func sapPacket(t *testing.T, sdp string, deletion bool) *Packet {
+	data, err := (&sap.Packet{Deletion: deletion, MessageHash: 1, Origin: net.ParseIP("192.0.2.10"), Payload: []byte(sdp)}).Marshal()
+	require.NoError(t, err)
+	return &Packet{
+		Data:        data,
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000},
+		Destination: &net.UDPAddr{IP: net.ParseIP(sap.Address), Port: sap.Port},
+		ReceivedAt:  time.Now(),
+	}
+}
+
+func TestSAPDecoder(t *testing.T) {
+	d := newSAPDecoder()
+
+	assert.False(t, d.Decode(tsPacketTo([]byte(`{"id":1}`), sap.Address), "", io.Discard))
+
+	// Repeated announcements are one session; a new version replaces it
+	assert.True(t, d.Decode(sapPacket(t, testAnnouncedSDP, false), "", io.Discard))
+	assert.True(t, d.Decode(sapPacket(t, testAnnouncedSDP, false), "", io.Discard))
+	updated := "v=0\r\no=- 42 2 IN IP4 192.0.2.10\r\ns=News HD\r\nc=IN IP4 239.1.1.1/16\r\nt=0 0\r\nm=video 5004 RTP/AVP 33\r\n"
+	assert.True(t, d.Decode(sapPacket(t, updated, false), "", io.Discard))
+	require.Len(t, d.sessions, 1)
+	for _, s := range d.sessions {
+		assert.Equal(t, "News HD", s.session.Name)
+		assert.Equal(t, 3, s.count)
+		assert.Equal(t, []string{"mcaster receive -g 239.1.1.1:5004 --rtp --mpegts"}, receiveCommands(s.session))
+	}
+	var summary strings.Builder
+	d.PrintSummary(&summary)
+	assert.Contains(t, summary.String(), "📢 1 SAP session(s):")
+	assert.Contains(t, summary.String(), "▶️  mcaster receive -g 239.1.1.1:5004 --rtp --mpegts")
+
+	// A deletion carrying only the origin removes the session
+	var out strings.Builder
+	assert.True(t, d.Decode(sapPacket(t, "o=- 42 2 IN IP4 192.0.2.10\r\n", true), "", &out))
+	assert.Empty(t, d.sessions)
+	assert.Contains(t, out.String(), `Session "News HD": video 239.1.1.1:5004 RTP/AVP 33 deleted by 192.0.2.10`)
+	summary.Reset()
+	d.PrintSummary(&summary)
+	assert.Equal(t, "\n📢 No sessions are announced\n", summary.String())
+}
+
+func TestReceiveCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		sdp      string
+		expected []string
+	}{
+		{
+			name:     "UDP MPEG-TS",
+			sdp:      "v=0\r\nc=IN IP4 239.1.1.2/8\r\nm=video 5000 udp mpeg\r\n",
+			expected: []string{"mcaster receive -g 239.1.1.2:5000 --mpegts"},
+		},
+		{
+			name:     "RTP with MP2T rtpmap",
+			sdp:      "v=0\r\nc=IN IP4 239.1.1.2/8\r\nm=video 5000 RTP/AVP 96\r\na=rtpmap:96 MP2T/90000\r\n",
+			expected: []string{"mcaster receive -g 239.1.1.2:5000 --rtp --mpegts"},
+		},
+		{
+			name: "IPv6 audio and video",
+			sdp:  "v=0\r\nc=IN IP6 ff15::1\r\nm=audio 5002 RTP/AVP 0\r\nm=video 5004 RTP/AVP 96\r\n",
+			expected: []string{"mcaster receive -g [ff15::1]:5002 --rtp",
+				"mcaster receive -g [ff15::1]:5004 --rtp"},
+		},
+		{
+			name: "no connection",
+			sdp:  "v=0\r\nm=video 5000 udp mpeg\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := sap.ParseSDP(tt.sdp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, receiveCommands(s))
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package multicast
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+	"github.com/hyposcaler-bot/mcaster/internal/decode"
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+)
+
+// scanClassifyLimit is how many datagrams per group and port are classified;
+// the rest are only counted
+const scanClassifyLimit = 16
+
+// Scanner finds active groups by joining them in batches, as many at a time
+// as the kernel allows on one socket, and listening to each batch for a dwell time
+type Scanner struct {
+	groups    []net.IP
+	ports     []int
+	iface     string
+	dwell     time.Duration
+	batchSize int
+	limit     int
+	mu        sync.Mutex
+	current   []*Receiver
+	stopped   bool
+	results   []ScanResult
+}
+
+// ScannerOption configures optional Scanner behaviour
+type ScannerOption func(*Scanner)
+
+// WithBatchSize joins at most n groups at a time (default: the membership limit)
+func WithBatchSize(n int) ScannerOption {
+	return func(s *Scanner) {
+		s.batchSize = n
+	}
+}
+
+// ScanResult describes the traffic seen on one group and port
+type ScanResult struct {
+	Group    *net.UDPAddr
+	Packets  int
+	Bytes    int
+	Duration time.Duration
+	// Sources are the senders heard, sorted
+	Sources []string
+	// Payloads are the detected payload types, most frequent first
+	Payloads []string
+}
+
+// PacketRate returns the packets per second seen during the dwell time
+func (r ScanResult) PacketRate() float64 {
+	return float64(r.Packets) / r.Duration.Seconds()
+}
+
+// BitRate returns the UDP payload bits per second seen during the dwell time
+func (r ScanResult) BitRate() float64 {
+	return float64(r.Bytes) * 8 / r.Duration.Seconds()
+}
+
+func (r ScanResult) String() string {
+	return fmt.Sprintf("%s: %.1f pps, %s from %s (%s)", r.Group, r.PacketRate(), formatBitRate(r.BitRate()),
+		strings.Join(r.Sources, ", "), strings.Join(r.Payloads, ", "))
+}
+
+// NewScanner creates a scanner of every combination of groups and ports
+func NewScanner(groups []net.IP, ports []int, interfaceName string, dwell time.Duration, opts ...ScannerOption) (*Scanner, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no groups to scan")
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports to scan")
+	}
+	if dwell <= 0 {
+		return nil, fmt.Errorf("dwell time must be positive, got %v", dwell)
+	}
+	for _, group := range groups {
+		if err := network.ValidateGroup(group, false); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := network.GetInterface(interfaceName); err != nil {
+		return nil, err
+	}
+
+	scanner := &Scanner{
+		groups: groups,
+		ports:  ports,
+		iface:  interfaceName,
+		dwell:  dwell,
+		limit:  network.MaxMemberships(),
+	}
+	for _, opt := range opts {
+		opt(scanner)
+	}
+
+	if scanner.batchSize < 0 {
+		return nil, fmt.Errorf("batch size must not be negative, got %d", scanner.batchSize)
+	}
+	if scanner.batchSize == 0 || scanner.batchSize > scanner.limit {
+		scanner.batchSize = scanner.limit
+	}
+	return scanner, nil
+}
+
+// Batches returns the number of batches the scan takes
+func (s *Scanner) Batches() int {
+	return (len(s.groups) + s.batchSize - 1) / s.batchSize
+}
+
+// Start scans every batch in turn and prints the active groups as they are
+// found, then all of them
+func (s *Scanner) Start() error {
+	batches := s.Batches()
+	iface := s.iface
+	if iface == "" {
+		iface = "the default interface"
+	}
+	fmt.Printf("🔍 Scanning %d group(s) on %d port(s) via %s\n", len(s.groups), len(s.ports), iface)
+	fmt.Printf("🔁 %d batch(es) of up to %d group(s) (membership limit %d), %v each, about %v in total\n",
+		batches, s.batchSize, s.limit, s.dwell, time.Duration(batches)*s.dwell)
+	fmt.Printf("⏹️  Press Ctrl+C to stop\n\n")
+
+	for n := 0; n < batches; n++ {
+		if s.isStopped() {
+			break
+		}
+		batch := s.groups[n*s.batchSize : min((n+1)*s.batchSize, len(s.groups))]
+		groups := batch[0].String()
+		if len(batch) > 1 {
+			groups += "-" + batch[len(batch)-1].String()
+		}
+		fmt.Printf("🔍 [%s] Batch %d/%d: %s\n", time.Now().Format("15:04:05.000"), n+1, batches, groups)
+
+		results, err := s.scanBatch(batch)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			fmt.Printf("📡 [%s] Active %s\n", time.Now().Format("15:04:05.000"), result)
+		}
+		s.mu.Lock()
+		s.results = append(s.results, results...)
+		s.mu.Unlock()
+	}
+
+	s.printSummary()
+	return nil
+}
+
+// Close stops the scan after the current batch, which ends at once
+func (s *Scanner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for _, r := range s.current {
+		r.Close()
+	}
+	return nil
+}
+
+// Results returns the active groups found so far
+func (s *Scanner) Results() []ScanResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ScanResult(nil), s.results...)
+}
+
+func (s *Scanner) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// scanBatch joins the groups of a batch on one socket per port and returns
+// the groups with traffic once the dwell time has passed
+func (s *Scanner) scanBatch(batch []net.IP) ([]ScanResult, error) {
+	var receivers []*Receiver
+	for _, port := range s.ports {
+		addrs := make([]string, len(batch))
+		for i, group := range batch {
+			addrs[i] = net.JoinHostPort(group.String(), strconv.Itoa(port))
+		}
+		// Binding the wildcard address lets one socket receive every group of the batch
+		r, err := NewReceiver(strings.Join(addrs, ","), s.iface, 0, WithBind(network.BindAny))
+		if err != nil {
+			for _, r := range receivers {
+				r.Close()
+			}
+			return nil, fmt.Errorf("failed to join batch on port %d: %w", port, err)
+		}
+		receivers = append(receivers, r)
+	}
+
+	s.mu.Lock()
+	s.current = receivers
+	stopped := s.stopped
+	s.mu.Unlock()
+	if stopped {
+		for _, r := range receivers {
+			r.Close()
+		}
+		return nil, nil
+	}
+
+	start := time.Now()
+	activity := make([]map[string]*scanActivity, len(receivers))
+	var wg sync.WaitGroup
+	for i, r := range receivers {
+		wg.Add(1)
+		go func(i int, r *Receiver) {
+			defer wg.Done()
+			defer r.Close()
+			activity[i] = r.scan(start.Add(s.dwell))
+		}(i, r)
+	}
+	wg.Wait()
+	duration := time.Since(start)
+
+	s.mu.Lock()
+	s.current = nil
+	s.mu.Unlock()
+
+	var results []ScanResult
+	for _, groups := range activity {
+		for _, a := range groups {
+			results = append(results, a.result(duration))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return lessAddr(results[i].Group, results[j].Group)
+	})
+	return results, nil
+}
+
+func (s *Scanner) printSummary() {
+	results := s.Results()
+	if len(results) == 0 {
+		fmt.Printf("\n🔍 No active groups found\n")
+		return
+	}
+	fmt.Printf("\n🔍 %d active group(s):\n", len(results))
+	for _, result := range results {
+		fmt.Printf("   %s\n", result)
+	}
+}
+
+// scanActivity accumulates the traffic of one group and port during a scan
+type scanActivity struct {
+	group    *net.UDPAddr
+	packets  int
+	bytes    int
+	sources  map[string]bool
+	payloads map[string]int
+}
+
+func (a *scanActivity) result(duration time.Duration) ScanResult {
+	result := ScanResult{Group: a.group, Packets: a.packets, Bytes: a.bytes, Duration: duration}
+	for source := range a.sources {
+		result.Sources = append(result.Sources, source)
+	}
+	sort.Strings(result.Sources)
+	for payload := range a.payloads {
+		result.Payloads = append(result.Payloads, payload)
+	}
+	sort.Slice(result.Payloads, func(i, j int) bool {
+		pi, pj := result.Payloads[i], result.Payloads[j]
+		if a.payloads[pi] != a.payloads[pj] {
+			return a.payloads[pi] > a.payloads[pj]
+		}
+		return pi < pj
+	})
+	return result
+}
+
+// scan counts the datagrams of each group until the deadline or until the
+// receiver is closed, without printing them. The socket binds the wildcard
+// address, so datagrams sent to any other address on the port, unicast
+// included, are dropped.
+func (r *Receiver) scan(deadline time.Time) map[string]*scanActivity {
+	activity := make(map[string]*scanActivity)
+	batch := make(map[string]bool)
+	for _, group := range r.groupList() {
+		batch[group.IP.String()] = true
+	}
+	if err := r.conn.SetReadDeadline(deadline); err != nil {
+		return activity
+	}
+	for {
+		pkt, err := r.readPacket()
+		if err != nil {
+			return activity
+		}
+		if !batch[pkt.Destination.IP.String()] {
+			continue
+		}
+
+		key := pkt.Destination.String()
+		a, ok := activity[key]
+		if !ok {
+			a = &scanActivity{group: pkt.Destination, sources: make(map[string]bool), payloads: make(map[string]int)}
+			activity[key] = a
+		}
+		a.packets++
+		a.bytes += len(pkt.Data)
+		a.sources[pkt.Source.IP.String()] = true
+		if a.packets <= scanClassifyLimit {
+			a.payloads[classifyPayload(pkt)]++
+		}
+	}
+}
+
+// classifyPayload names what a datagram carries: mcaster messages, possibly
+// in RTP or MPEG-TS, an RTP payload type, or a protocol recognized by decode
+func classifyPayload(pkt *Packet) string {
+	data := pkt.Data
+	switch {
+	case auth.IsEncrypted(data):
+		return "mcaster (encrypted)"
+	case looksLikeMessage(data):
+		payload, _, _, _ := auth.SplitTrailer(data)
+		if _, err := UnmarshalMessage(payload); err == nil {
+			return "mcaster"
+		}
+	case rtpMessage(data) != nil:
+		return "mcaster in RTP"
+	case tsMessage(data) != nil:
+		return "mcaster in MPEG-TS"
+	}
+
+	if header, err := rtp.Parse(data); err == nil && !rtp.IsRTCP(data) {
+		payload := data[header.PayloadOffset : header.PayloadOffset+header.PayloadSize]
+		if mpegts.IsTransportStream(payload) {
+			return "MPEG-TS in RTP"
+		}
+		return fmt.Sprintf("RTP PT %d (%s)", header.PayloadType, rtp.PayloadTypeName(header.PayloadType))
+	}
+	if result, ok := decode.Sniff(data, pkt.Source.Port, pkt.Destination.Port); ok {
+		return result.Protocol
+	}
+	return "unknown"
+}
+
+// formatBitRate formats bits per second with a unit
+func formatBitRate(bps float64) string {
+	switch {
+	case bps >= 1e9:
+		return fmt.Sprintf("%.2f Gbit/s", bps/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2f Mbit/s", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.1f kbit/s", bps/1e3)
+	}
+	return fmt.Sprintf("%.0f bit/s", bps)
+}
+
+// lessAddr orders addresses by IP, then port
+func lessAddr(a, b *net.UDPAddr) bool {
+	if c := strings.Compare(string(a.IP.To16()), string(b.IP.To16())); c != 0 {
+		return c < 0
+	}
+	return a.Port < b.Port
+}
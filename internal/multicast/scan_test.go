@@ -0,0 +1,164 @@
+package multicast
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScanner(t *testing.T) {
+	groups := []net.IP{net.ParseIP("239.1.1.1"), net.ParseIP("239.1.1.2"), net.ParseIP("239.1.1.3")}
+
+	tests := []struct {
+		name          string
+		groups        []net.IP
+		ports         []int
+		dwell         time.Duration
+		opts          []ScannerOption
+		expectBatches int
+		expectError   string
+	}{
+		{name: "default batch size", groups: groups, ports: []int{5000}, dwell: time.Second, expectBatches: 1},
+		{name: "batches of two", groups: groups, ports: []int{5000}, dwell: time.Second,
+			opts: []ScannerOption{WithBatchSize(2)}, expectBatches: 2},
+		{name: "no groups", ports: []int{5000}, dwell: time.Second, expectError: "no groups"},
+		{name: "no ports", groups: groups, dwell: time.Second, expectError: "no ports"},
+		{name: "no dwell time", groups: groups, ports: []int{5000}, expectError: "dwell time"},
+		{name: "unicast group", groups: []net.IP{net.ParseIP("192.0.2.1")}, ports: []int{5000}, dwell: time.Second,
+			expectError: "not a multicast"},
+		{name: "negative batch size", groups: groups, ports: []int{5000}, dwell: time.Second,
+			opts: []ScannerOption{WithBatchSize(-1)}, expectError: "batch size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewScanner(tt.groups, tt.ports, "", tt.dwell, tt.opts...)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectBatches, scanner.Batches())
+			assert.LessOrEqual(t, scanner.batchSize, scanner.limit)
+		})
+	}
+}
+
+func TestClassifyPayload(t *testing.T) {
+	message, err := (&Message{ID: 1, Timestamp: time.Now(), Source: "host"}).Marshal()
+	require.NoError(t, err)
+	mux, err := mpegts.NewMuxer(mpegts.MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000})
+	require.NoError(t, err)
+	tsWithMessage, err := mux.Datagram(message)
+	require.NoError(t, err)
+	ts := tsDatagram(0, 7)
+	rtpWithTS := rtpPacket(1, 0, 33, false, time.Now())
+	rtpWithTS.Data = append(rtpWithTS.Data[:12], ts...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "message", data: message, expected: "mcaster"},
+		{name: "message in RTP", data: append(rtpPacket(1, 0, 96, false, time.Now()).Data[:12], message...), expected: "mcaster in RTP"},
+		{name: "message in MPEG-TS", data: tsWithMessage, expected: "mcaster in MPEG-TS"},
+		{name: "RTP audio", data: rtpPacket(1, 0, 0, false, time.Now()).Data, expected: "RTP PT 0 (PCMU)"},
+		{name: "MPEG-TS in RTP", data: rtpWithTS.Data, expected: "MPEG-TS in RTP"},
+		{name: "MPEG-TS", data: ts, expected: "MPEG-TS"},
+		{name: "broken JSON", data: []byte("{not json"), expected: "text"},
+		{name: "unknown", data: []byte{0x00, 0x01, 0xfe}, expected: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyPayload(tsPacketTo(tt.data, "239.1.1.1")))
+		})
+	}
+}
+
+func TestFormatBitRate(t *testing.T) {
+	assert.Equal(t, "800 bit/s", formatBitRate(800))
+	assert.Equal(t, "31.7 kbit/s", formatBitRate(31700))
+	assert.Equal(t, "2.10 Mbit/s", formatBitRate(2.1e6))
+	assert.Equal(t, "1.00 Gbit/s", formatBitRate(1e9))
+}
+
+func TestScanner(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	groups := []net.IP{net.ParseIP("239.23.23.47"), net.ParseIP("239.23.23.48"), net.ParseIP("239.23.23.49")}
+	scanner, err := NewScanner(groups, []int{23254, 23255}, "lo", 300*time.Millisecond, WithBatchSize(2))
+	require.NoError(t, err)
+
+	sender, err := NewSender("239.23.23.48:23255", "lo", time.Second, 1, 0, 0)
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				sender.sendPacket()
+			}
+		}
+	}()
+
+	results, err := scanner.scanBatch(groups[:2])
+	require.NoError(t, err)
+	if len(results) == 0 {
+		t.Skip("multicast loopback not available")
+	}
+	require.Len(t, results, 1)
+	assert.Equal(t, "239.23.23.48:23255", results[0].Group.String())
+	assert.Positive(t, results[0].PacketRate())
+	assert.Positive(t, results[0].BitRate())
+	assert.Equal(t, []string{"mcaster"}, results[0].Payloads)
+	assert.Len(t, results[0].Sources, 1)
+
+	// The other batch is quiet
+	results, err = scanner.scanBatch(groups[2:])
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestScannerIgnoresUnicast(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	groups := []net.IP{net.ParseIP("239.23.23.67")}
+	scanner, err := NewScanner(groups, []int{23264}, "lo", 200*time.Millisecond)
+	require.NoError(t, err)
+
+	// The batch socket binds the wildcard address, so datagrams sent to the
+	// port itself reach it as well
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 23264})
+	require.NoError(t, err)
+	defer conn.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				conn.Write([]byte("not multicast"))
+			}
+		}
+	}()
+
+	results, err := scanner.scanBatch(groups)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -1,33 +1,184 @@
 package multicast
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
 	"github.com/hyposcaler-bot/mcaster/internal/network"
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
 )
 
 // Sender handles multicast packet transmission
 type Sender struct {
-	conn        *net.UDPConn
-	groupAddr   *net.UDPAddr
-	hostname    string
-	interval    time.Duration
-	ttl         int
-	sport       int
-	packetCount int
+	conn         *net.UDPConn
+	groupAddr    *net.UDPAddr
+	iface        *net.Interface
+	hostname     string
+	interval     time.Duration
+	ttl          int
+	sport        int
+	loopback     bool
+	sourceIP     net.IP
+	bindToDevice bool
+	allowUnicast bool
+	signingKey   *auth.Key
+	cipherKey    *auth.Key
+	session      SessionID
+	senderID     string
+	streamID     string
+	labels       map[string]string
+	payload      []byte
+	rtpConfig    *RTPConfig
+	rtp          *rtpFramer
+	muxConfig    *mpegts.MuxConfig
+	mux          *mpegts.Muxer
+	nextMessage  time.Time
+	warnings     []string
+	packetCount  int
+	out          io.Writer
+	stats        senderCounters
+	// intervals passes interval changes to Start
+	intervals chan time.Duration
+	// stop is closed by Close so Start returns without waiting for a send
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SenderStats counts what a sender has sent so far
+type SenderStats struct {
+	Messages  int       `json:"messages"`
+	Datagrams int       `json:"datagrams"`
+	Bytes     int       `json:"bytes"`
+	LastSent  time.Time `json:"last_sent"`
+}
+
+// senderCounters are updated by Start and read by Stats from any goroutine
+type senderCounters struct {
+	messages  atomic.Int64
+	datagrams atomic.Int64
+	bytes     atomic.Int64
+	lastSent  atomic.Int64
+}
+
+// SenderOption configures optional Sender behaviour
+type SenderOption func(*Sender)
+
+// WithLoopback controls whether sent packets are delivered to receivers on this host
+func WithLoopback(enabled bool) SenderOption {
+	return func(s *Sender) {
+		s.loopback = enabled
+	}
+}
+
+// WithSourceIP binds the sender to one of the interface's addresses
+func WithSourceIP(ip net.IP) SenderOption {
+	return func(s *Sender) {
+		s.sourceIP = ip
+	}
+}
+
+// WithBindToDevice restricts the sender socket to the selected interface (SO_BINDTODEVICE)
+func WithBindToDevice(enabled bool) SenderOption {
+	return func(s *Sender) {
+		s.bindToDevice = enabled
+	}
+}
+
+// WithUnicastDestination permits a unicast destination in place of a multicast group
+func WithUnicastDestination(allow bool) SenderOption {
+	return func(s *Sender) {
+		s.allowUnicast = allow
+	}
+}
+
+// WithSigningKey appends an HMAC-SHA256 trailer made with key to every message
+func WithSigningKey(key auth.Key) SenderOption {
+	return func(s *Sender) {
+		s.signingKey = &key
+	}
+}
+
+// WithEncryptionKey encrypts every message with AES-256-GCM using key
+func WithEncryptionKey(key auth.Key) SenderOption {
+	return func(s *Sender) {
+		s.cipherKey = &key
+	}
+}
+
+// WithSenderID labels every message with id, to tell apart senders on one host
+func WithSenderID(id string) SenderOption {
+	return func(s *Sender) {
+		s.senderID = id
+	}
+}
+
+// WithStreamID names the stream in every message (default: the group address)
+func WithStreamID(id string) SenderOption {
+	return func(s *Sender) {
+		s.streamID = id
+	}
+}
+
+// WithLabels adds key/value labels, e.g. a ticket or test run, to every message
+func WithLabels(labels map[string]string) SenderOption {
+	return func(s *Sender) {
+		s.labels = labels
+	}
+}
+
+// WithPayload carries an opaque payload, e.g. the contents of a file, in every message
+func WithPayload(payload []byte) SenderOption {
+	return func(s *Sender) {
+		s.payload = payload
+	}
+}
+
+// WithRTP sends every message as the payload of an RTP packet, in frames of
+// cfg.PacketsPerFrame packets at cfg.FrameRate in place of the send interval
+func WithRTP(cfg RTPConfig) SenderOption {
+	return func(s *Sender) {
+		s.rtpConfig = &cfg
+	}
+}
+
+// WithMPEGTS sends every message as a PES packet in a constant bitrate MPEG
+// transport stream, with a PAT, PMT and PCRs so IPTV receivers can tune to it
+func WithMPEGTS(cfg mpegts.MuxConfig) SenderOption {
+	return func(s *Sender) {
+		s.muxConfig = &cfg
+	}
+}
+
+// WithSenderOutput writes the sender's output to w instead of stdout
+func WithSenderOutput(w io.Writer) SenderOption {
+	return func(s *Sender) {
+		s.out = w
+	}
 }
 
 // NewSender creates a new multicast sender
-func NewSender(groupAddr, interfaceName string, interval time.Duration, ttl, sport, dport int) (*Sender, error) {
+func NewSender(groupAddr, interfaceName string, interval time.Duration, ttl, sport, dport int, opts ...SenderOption) (*Sender, error) {
 	// Validate TTL
 	if ttl < 1 || ttl > 255 {
 		return nil, fmt.Errorf("TTL must be between 1 and 255, got %d", ttl)
 	}
 
+	// A negative interval would make the ticker panic in Start; zero is
+	// accepted for senders that never start, such as the replayer's
+	if interval < 0 {
+		return nil, fmt.Errorf("interval must not be negative, got %v", interval)
+	}
+
 	// Validate source port
 	if sport < 0 || sport > 65535 {
 		return nil, fmt.Errorf("source port must be between 0 and 65535, got %d", sport)
@@ -44,20 +195,67 @@ func NewSender(groupAddr, interfaceName string, interval time.Duration, ttl, spo
 		return nil, fmt.Errorf("failed to resolve multicast address: %w", err)
 	}
 
-	// Create local address with specified source port (0 = random)
-	localAddr := &net.UDPAddr{Port: sport}
-	conn, err := net.DialUDP("udp", localAddr, addr)
+	sender := &Sender{
+		groupAddr: addr,
+		interval:  interval,
+		ttl:       ttl,
+		sport:     sport,
+		loopback:  true,
+		out:       os.Stdout,
+		intervals: make(chan time.Duration, 1),
+		stop:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sender)
+	}
+
+	if err := network.ValidateGroup(addr.IP, sender.allowUnicast); err != nil {
+		return nil, err
+	}
+
+	if sender.rtpConfig != nil {
+		if err := sender.rtpConfig.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if sender.muxConfig != nil {
+		if sender.rtpConfig != nil {
+			return nil, fmt.Errorf("RTP and MPEG-TS sending cannot be combined")
+		}
+		if sender.mux, err = mpegts.NewMuxer(*sender.muxConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	sender.session, err = NewSessionID()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create UDP connection: %w", err)
+		return nil, err
 	}
+	if sender.rtpConfig != nil {
+		sender.rtp = newRTPFramer(*sender.rtpConfig, sender.session)
+	}
+	if sender.streamID == "" {
+		sender.streamID = addr.String()
+	}
+	sender.warnings = network.ClassifyGroup(addr.IP).Warnings
 
-	// Bind to specific interface if requested
-	if interfaceName != "" {
-		conn.Close()
-		conn, err = network.DialUDPOnInterface(interfaceName, addr, sport)
-		if err != nil {
+	// Resolve the outgoing interface by name, index or address
+	sender.iface, err = network.GetInterface(interfaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind to interface %s: %w", interfaceName, err)
+	}
+
+	conn, err := network.DialMulticastUDP(addr, network.DialOptions{
+		Interface:    sender.iface,
+		SourceIP:     sender.sourceIP,
+		SourcePort:   sport,
+		BindToDevice: sender.bindToDevice,
+	})
+	if err != nil {
+		if sender.iface != nil {
 			return nil, fmt.Errorf("failed to bind to interface %s: %w", interfaceName, err)
 		}
+		return nil, fmt.Errorf("failed to create UDP connection: %w", err)
 	}
 
 	// Set TTL for multicast packets
@@ -66,64 +264,320 @@ func NewSender(groupAddr, interfaceName string, interval time.Duration, ttl, spo
 		return nil, fmt.Errorf("failed to set multicast TTL: %w", err)
 	}
 
+	// Set loopback explicitly so local delivery never depends on the OS default
+	if err := network.SetMulticastLoopback(conn, sender.loopback); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set multicast loopback: %w", err)
+	}
+
 	hostname, _ := os.Hostname()
 	if hostname == "" {
 		hostname = "unknown"
 	}
 
-	return &Sender{
-		conn:      conn,
-		groupAddr: addr,
-		hostname:  hostname,
-		interval:  interval,
-		ttl:       ttl,
-		sport:     sport,
-	}, nil
+	sender.conn = conn
+	sender.hostname = hostname
+
+	// Labels and payload are repeated in every message, which must fit in one datagram
+	if len(sender.labels) > 0 || len(sender.payload) > 0 {
+		data, err := sender.encode(sender.message(math.MaxInt32))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		size := len(data)
+		if sender.rtp != nil {
+			size += rtp.HeaderSize
+		}
+		if sender.mux != nil && size > mpegts.MaxPayload {
+			conn.Close()
+			return nil, fmt.Errorf("message of %d bytes exceeds the %d bytes that fit in one MPEG-TS datagram", size, mpegts.MaxPayload)
+		}
+		if size > maxMessageSize {
+			conn.Close()
+			return nil, fmt.Errorf("message of %d bytes exceeds the maximum UDP payload of %d bytes", size, maxMessageSize)
+		}
+	}
+
+	return sender, nil
 }
 
 // Start begins sending multicast packets
 func (s *Sender) Start() error {
 	defer s.conn.Close()
 
+	if s.rtp == nil && s.interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %v", s.interval)
+	}
+
 	localAddr := s.conn.LocalAddr().(*net.UDPAddr)
-	fmt.Printf("🚀 Starting multicast sender to %s\n", s.groupAddr)
-	fmt.Printf("📡 Sending packets every %v (TTL: %d, source port: %d)\n", s.interval, s.ttl, localAddr.Port)
-	fmt.Printf("⏹️  Press Ctrl+C to stop\n\n")
+	fmt.Fprintf(s.out, "🚀 Starting multicast sender to %s\n", s.groupAddr)
+	if s.iface != nil {
+		fmt.Fprintf(s.out, "🌐 Using interface %s (index %d, source %s)\n", s.iface.Name, s.iface.Index, localAddr.IP)
+	}
+	interval := s.interval
+	if s.rtp != nil {
+		interval = s.rtp.interval()
+		fmt.Fprintf(s.out, "📡 Sending frames every %v (TTL: %d, source port: %d, loopback: %s)\n",
+			interval, s.ttl, localAddr.Port, onOff(s.loopback))
+		fmt.Fprintf(s.out, "🎬 %s\n", s.rtp)
+	} else if s.mux != nil {
+		fmt.Fprintf(s.out, "📡 Sending messages every %v (TTL: %d, source port: %d, loopback: %s)\n",
+			interval, s.ttl, localAddr.Port, onOff(s.loopback))
+		interval = s.mux.DatagramInterval()
+		fmt.Fprintf(s.out, "📺 MPEG-TS at %d bit/s (a datagram every %v): program %d, PMT PID 0x%04x, payload and PCR PID 0x%04x\n",
+			s.mux.Bitrate, interval, s.mux.Program, s.mux.PMTPID, s.mux.PID)
+	} else {
+		fmt.Fprintf(s.out, "📡 Sending packets every %v (TTL: %d, source port: %d, loopback: %s)\n",
+			interval, s.ttl, localAddr.Port, onOff(s.loopback))
+	}
+	fmt.Fprintf(s.out, "🏷️  %s scope: %s\n", s.groupAddr.IP, network.ClassifyGroup(s.groupAddr.IP))
+	for _, warning := range s.warnings {
+		fmt.Fprintf(s.out, "⚠️  %s\n", warning)
+	}
+	if s.senderID != "" {
+		fmt.Fprintf(s.out, "🆔 Sender %s, session %s, stream %s\n", s.senderID, s.session, s.streamID)
+	} else {
+		fmt.Fprintf(s.out, "🆔 Session %s, stream %s\n", s.session, s.streamID)
+	}
+	if len(s.labels) > 0 {
+		fmt.Fprintf(s.out, "🔖 Labels: %s\n", s.message(0).LabelString())
+	}
+	if len(s.payload) > 0 {
+		fmt.Fprintf(s.out, "📎 Payload: %d bytes\n", len(s.payload))
+	}
+	if s.signingKey != nil {
+		fmt.Fprintf(s.out, "🔐 Signing packets with key %s (HMAC-SHA256)\n", s.signingKey.ID)
+	}
+	if s.cipherKey != nil {
+		fmt.Fprintf(s.out, "🔒 Encrypting packets with key %s (AES-256-GCM)\n", s.cipherKey.ID)
+	}
+	fmt.Fprintf(s.out, "⏹️  Press Ctrl+C to stop\n\n")
+
+	if s.mux != nil {
+		// A ticker drops ticks when it falls behind; pacing from the start
+		// keeps the bitrate constant, so the PCRs match the arrival times
+		start := time.Now()
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for n := 2; ; n++ {
+			select {
+			case <-s.stop:
+				return nil
+			case <-timer.C:
+			}
+			timer.Reset(time.Until(start.Add(time.Duration(n) * interval)))
+			if err := s.sendTS(); err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return nil
+				}
+				log.Printf("❌ Failed to send packet: %v", err)
+			}
+		}
+	}
 
-	ticker := time.NewTicker(s.interval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := s.sendPacket(); err != nil {
+	send := s.sendPacket
+	if s.rtp != nil {
+		send = s.sendFrame
+	}
+	for {
+		select {
+		case <-s.stop:
+			return nil
+		case <-ticker.C:
+		case interval := <-s.intervals:
+			ticker.Reset(interval)
+			continue
+		}
+		if err := send(); err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
 			log.Printf("❌ Failed to send packet: %v", err)
 			continue
 		}
 	}
+}
+
+// Send writes a raw payload to the group, e.g. a datagram being replayed
+func (s *Sender) Send(payload []byte) error {
+	if _, err := s.conn.Write(payload); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// Close stops Start at once, even between sends, and closes the sender's socket
+func (s *Sender) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	return s.conn.Close()
+}
+
+// SetInterval changes the send interval of a sender of mcaster messages, e.g.
+// for a burst, without starting a new session; it is safe to call while Start
+// runs. RTP and MPEG-TS senders keep the pace of their stream.
+func (s *Sender) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %v", interval)
+	}
+	if s.rtp != nil || s.mux != nil {
+		return fmt.Errorf("the pace of RTP and MPEG-TS senders cannot change")
+	}
+	// Only the latest change matters if Start has not picked up the last one
+	for {
+		select {
+		case s.intervals <- interval:
+			return nil
+		default:
+			select {
+			case <-s.intervals:
+			default:
+			}
+		}
+	}
+}
+
+// Session returns the session ID carried in the sender's messages
+func (s *Sender) Session() SessionID {
+	return s.session
+}
+
+// Stats returns what the sender has sent so far; it is safe to call while Start runs
+func (s *Sender) Stats() SenderStats {
+	stats := SenderStats{
+		Messages:  int(s.stats.messages.Load()),
+		Datagrams: int(s.stats.datagrams.Load()),
+		Bytes:     int(s.stats.bytes.Load()),
+	}
+	if last := s.stats.lastSent.Load(); last != 0 {
+		stats.LastSent = time.Unix(0, last)
+	}
+	return stats
+}
 
+// write sends a datagram of the stream and counts it
+func (s *Sender) write(data []byte) error {
+	if _, err := s.conn.Write(data); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	s.stats.messages.Store(int64(s.packetCount))
+	s.stats.datagrams.Add(1)
+	s.stats.bytes.Add(int64(len(data)))
+	s.stats.lastSent.Store(time.Now().UnixNano())
 	return nil
 }
 
 func (s *Sender) sendPacket() error {
 	s.packetCount++
 
-	msg := Message{
-		ID:        s.packetCount,
+	msg := s.message(s.packetCount)
+	data, err := s.encode(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := s.write(data); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "📤 [%s] Sent packet #%d\n",
+		msg.Timestamp.Format("15:04:05.000"), s.packetCount)
+
+	return nil
+}
+
+// sendFrame sends the packets of one RTP frame
+func (s *Sender) sendFrame() error {
+	defer s.rtp.nextFrame()
+
+	first, sequence := s.packetCount+1, s.rtp.sequence
+	for i := 0; i < s.rtp.PacketsPerFrame; i++ {
+		s.packetCount++
+		data, err := s.encode(s.message(s.packetCount))
+		if err != nil {
+			return err
+		}
+		if err := s.write(s.rtp.packet(data, i == s.rtp.PacketsPerFrame-1)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(s.out, "📤 [%s] Sent frame #%d (packets #%d-#%d, seq %d-%d, ts %d)\n",
+		time.Now().Format("15:04:05.000"), s.rtp.frames+1, first, s.packetCount,
+		sequence, s.rtp.sequence-1, s.rtp.frameTimestamp())
+	return nil
+}
+
+// sendTS sends the next datagram of the transport stream, carrying a message
+// whenever the send interval has passed
+func (s *Sender) sendTS() error {
+	var msg *Message
+	var payload []byte
+	if now := time.Now(); !now.Before(s.nextMessage) {
+		s.nextMessage = now.Add(s.interval)
+		s.packetCount++
+		msg = s.message(s.packetCount)
+		data, err := s.encode(msg)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+
+	data, err := s.mux.Datagram(payload)
+	if err != nil {
+		return fmt.Errorf("failed to multiplex message: %w", err)
+	}
+	if err := s.write(data); err != nil {
+		return err
+	}
+
+	if msg != nil {
+		fmt.Fprintf(s.out, "📤 [%s] Sent packet #%d\n", msg.Timestamp.Format("15:04:05.000"), s.packetCount)
+	}
+	return nil
+}
+
+// message builds the message with the given sequence number
+func (s *Sender) message(id int) *Message {
+	return &Message{
+		ID:        id,
 		Timestamp: time.Now(),
 		Source:    s.hostname,
+		Session:   s.session,
+		SenderID:  s.senderID,
+		Stream:    s.streamID,
+		Labels:    s.labels,
+		Payload:   s.payload,
 	}
+}
 
+// encode marshals a message and signs and encrypts it as configured
+func (s *Sender) encode(msg *Message) ([]byte, error) {
 	data, err := msg.Marshal()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
-
-	_, err = s.conn.Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	if s.signingKey != nil {
+		data = auth.Sign(*s.signingKey, data)
 	}
+	if s.cipherKey != nil {
+		// Every sender numbers its packets from 1, so the key is derived per
+		// random 64-bit session to keep senders sharing a secret apart
+		data, err = auth.Encrypt(*s.cipherKey, uint64(s.session), uint64(msg.ID), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt message: %w", err)
+		}
+	}
+	return data, nil
+}
 
-	fmt.Printf("📤 [%s] Sent packet #%d\n",
-		msg.Timestamp.Format("15:04:05.000"), s.packetCount)
-
-	return nil
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
 }
@@ -1,12 +1,17 @@
 package multicast
 
 import (
+	"io"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
 )
 
 func TestNewSenderValidation(t *testing.T) {
@@ -253,6 +258,16 @@ func TestSenderWithInterface(t *testing.T) {
 			wantErr: true,
 			errMsg:  "failed to bind to interface",
 		},
+		{
+			name:    "loopback by name",
+			iface:   "lo",
+			wantErr: false,
+		},
+		{
+			name:    "loopback by address",
+			iface:   "127.0.0.1",
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -329,6 +344,194 @@ func TestSenderConnectionProperties(t *testing.T) {
 	assert.Equal(t, 2323, udpRemote.Port)
 }
 
+func TestSenderSourceIP(t *testing.T) {
+	t.Run("source IP on interface", func(t *testing.T) {
+		sender, err := NewSender("239.23.23.23:2323", "lo", time.Second, 1, 0, 0,
+			WithSourceIP(net.ParseIP("127.0.0.1")))
+		require.NoError(t, err)
+		require.NotNil(t, sender)
+		defer sender.conn.Close()
+
+		assert.Equal(t, "127.0.0.1", sender.conn.LocalAddr().(*net.UDPAddr).IP.String())
+		assert.Equal(t, "lo", sender.iface.Name)
+	})
+
+	t.Run("source IP not on interface", func(t *testing.T) {
+		sender, err := NewSender("239.23.23.23:2323", "lo", time.Second, 1, 0, 0,
+			WithSourceIP(net.ParseIP("203.0.113.254")))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is not configured on interface")
+		assert.Nil(t, sender)
+	})
+}
+
+func TestSenderGroupValidation(t *testing.T) {
+	t.Run("unicast rejected", func(t *testing.T) {
+		sender, err := NewSender("127.0.0.1:2323", "", time.Second, 1, 0, 0)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a multicast address")
+		assert.Nil(t, sender)
+	})
+
+	t.Run("unicast allowed", func(t *testing.T) {
+		sender, err := NewSender("127.0.0.1:2323", "", time.Second, 1, 0, 0, WithUnicastDestination(true))
+		require.NoError(t, err)
+		require.NotNil(t, sender)
+		defer sender.conn.Close()
+	})
+
+	t.Run("link-local group warns", func(t *testing.T) {
+		sender, err := NewSender("224.0.0.251:2323", "", time.Second, 16, 0, 0)
+		require.NoError(t, err)
+		require.NotNil(t, sender)
+		defer sender.conn.Close()
+
+		require.Len(t, sender.warnings, 1)
+		assert.Contains(t, sender.warnings[0], "TTL is ignored")
+	})
+}
+
+func TestSenderLoopback(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []SenderOption
+		expected bool
+	}{
+		{
+			name:     "default is on",
+			opts:     nil,
+			expected: true,
+		},
+		{
+			name:     "explicitly on",
+			opts:     []SenderOption{WithLoopback(true)},
+			expected: true,
+		},
+		{
+			name:     "explicitly off",
+			opts:     []SenderOption{WithLoopback(false)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, err := NewSender("239.23.23.23:2323", "", time.Second, 1, 0, 0, tt.opts...)
+			require.NoError(t, err)
+			require.NotNil(t, sender)
+			defer sender.conn.Close()
+
+			assert.Equal(t, tt.expected, sender.loopback)
+		})
+	}
+}
+
+func TestSenderSigning(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	ring, err := auth.ParseKeys(strings.NewReader("k1 first-secret-0123456789\n"))
+	require.NoError(t, err)
+	key, err := ring.Key("")
+	require.NoError(t, err)
+
+	receiver, err := NewReceiver("239.23.23.35:23241", "lo", 0)
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	sender, err := NewSender("239.23.23.35:23241", "lo", time.Second, 1, 0, 0, WithSigningKey(key))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	require.NoError(t, sender.sendPacket())
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	pkt, err := receiver.readPacket()
+	if err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+
+	payload, keyID, status := auth.Verify(ring, pkt.Data)
+	assert.Equal(t, auth.Authenticated, status)
+	assert.Equal(t, "k1", keyID)
+
+	msg, err := UnmarshalMessage(payload)
+	require.NoError(t, err)
+	assert.Equal(t, 1, msg.ID)
+}
+
+func TestSenderEncryption(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	ring, err := auth.ParseKeys(strings.NewReader("k1 first-secret-0123456789\n"))
+	require.NoError(t, err)
+	key, err := ring.Key("")
+	require.NoError(t, err)
+
+	receiver, err := NewReceiver("239.23.23.37:23244", "lo", 0)
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	sender, err := NewSender("239.23.23.37:23244", "lo", time.Second, 1, 0, 0,
+		WithSigningKey(key), WithEncryptionKey(key))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+	require.NoError(t, sender.sendPacket())
+
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	pkt, err := receiver.readPacket()
+	if err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+	assert.True(t, auth.IsEncrypted(pkt.Data))
+	assert.NotContains(t, string(pkt.Data), "source")
+
+	// Messages are signed first, so the signature is checked after decryption
+	signed, keyID, status := auth.Decrypt(ring, pkt.Data)
+	require.Equal(t, auth.Decrypted, status)
+	assert.Equal(t, "k1", keyID)
+	payload, _, verified := auth.Verify(ring, signed)
+	assert.Equal(t, auth.Authenticated, verified)
+
+	msg, err := UnmarshalMessage(payload)
+	require.NoError(t, err)
+	assert.Equal(t, 1, msg.ID)
+}
+
+func TestSenderIdentity(t *testing.T) {
+	first, err := NewSender("239.23.23.39:23246", "", time.Second, 1, 0, 0, WithSenderID("probe-a"))
+	require.NoError(t, err)
+	defer first.conn.Close()
+	second, err := NewSender("239.23.23.39:23246", "", time.Second, 1, 0, 0, WithStreamID("video-1"))
+	require.NoError(t, err)
+	defer second.conn.Close()
+
+	assert.NotZero(t, first.session)
+	assert.NotEqual(t, first.session, second.session)
+	assert.Equal(t, "probe-a", first.senderID)
+	assert.Equal(t, "239.23.23.39:23246", first.streamID)
+	assert.Equal(t, "video-1", second.streamID)
+}
+
+func TestSenderPayloadSize(t *testing.T) {
+	labels := map[string]string{"run": "chg1234"}
+	sender, err := NewSender("239.23.23.40:23247", "", time.Second, 1, 0, 0,
+		WithLabels(labels), WithPayload(make([]byte, 1000)))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+
+	msg := sender.message(1)
+	assert.Equal(t, labels, msg.Labels)
+	assert.Len(t, msg.Payload, 1000)
+
+	// The payload is base64 encoded, so 50000 bytes no longer fit in a datagram
+	_, err = NewSender("239.23.23.40:23247", "", time.Second, 1, 0, 0, WithPayload(make([]byte, 50000)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum UDP payload")
+}
+
 // Test edge cases and error conditions
 func TestSenderEdgeCases(t *testing.T) {
 	t.Run("zero interval", func(t *testing.T) {
@@ -349,13 +552,16 @@ func TestSenderEdgeCases(t *testing.T) {
 		}
 	})
 
+	t.Run("zero interval does not start", func(t *testing.T) {
+		sender, err := NewSender("239.23.23.23:2323", "", 0, 1, 0, 0, WithSenderOutput(io.Discard))
+		require.NoError(t, err)
+		assert.ErrorContains(t, sender.Start(), "interval must be positive")
+	})
+
 	t.Run("negative interval", func(t *testing.T) {
-		// Negative interval should be allowed (Go time.Ticker handles it)
-		sender, err := NewSender("239.23.23.23:2323", "", -time.Second, 1, 0, 0)
-		assert.NoError(t, err)
-		if sender != nil {
-			sender.conn.Close()
-		}
+		// A negative interval would make the ticker panic in Start
+		_, err := NewSender("239.23.23.23:2323", "", -time.Second, 1, 0, 0)
+		assert.ErrorContains(t, err, "interval must not be negative")
 	})
 }
 
@@ -384,4 +590,154 @@ func BenchmarkNewSenderWithPortOverride(b *testing.B) {
 			sender.conn.Close()
 		}
 	}
-}
\ No newline at end of file
+}
+func TestSenderMPEGTS(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+	cfg := mpegts.MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000}
+
+	receiver, err := NewReceiver("239.23.23.44:23251", "lo", 0, WithTSAnalysis())
+	require.NoError(t, err)
+	defer receiver.conn.Close()
+
+	sender, err := NewSender("239.23.23.44:23251", "lo", time.Second, 1, 0, 0, WithMPEGTS(cfg))
+	require.NoError(t, err)
+	defer sender.conn.Close()
+
+	// The first datagram carries a message, the following ones PSI, PCRs and stuffing
+	require.NoError(t, receiver.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, sender.sendTS())
+		if err := receiver.receivePacket(); err != nil {
+			t.Skipf("multicast loopback not available: %v", err)
+		}
+	}
+
+	streams := receiver.Streams()
+	require.Len(t, streams, 1)
+	for _, stats := range streams {
+		assert.Equal(t, 1, stats.Received)
+	}
+
+	analyzer := receiver.decoders[0].(*tsDecoder).analyzers["239.23.23.44:23251"]
+	require.NotNil(t, analyzer)
+	assert.Equal(t, 70, analyzer.Packets)
+	for _, indicator := range mpegts.Indicators {
+		assert.Zero(t, analyzer.Errors[indicator], indicator)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewSender("239.23.23.44:23251", "", time.Second, 1, 0, 0, WithMPEGTS(cfg),
+			WithRTP(RTPConfig{PacketsPerFrame: 1, FrameRate: 25}))
+		assert.ErrorContains(t, err, "cannot be combined")
+
+		_, err = NewSender("239.23.23.44:23251", "", time.Second, 1, 0, 0, WithMPEGTS(cfg),
+			WithPayload(make([]byte, 2000)))
+		assert.ErrorContains(t, err, "fit in one MPEG-TS datagram")
+	})
+}
+
+func TestSenderStats(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	var out strings.Builder
+	sender, err := NewSender("239.23.23.53:23256", "lo", 10*time.Millisecond, 1, 0, 0, WithSenderOutput(&out))
+	require.NoError(t, err)
+	assert.Zero(t, sender.Stats())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.Start()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, sender.Close())
+	// Start returns without an error once the sender is closed
+	require.NoError(t, <-done)
+
+	stats := sender.Stats()
+	assert.Positive(t, stats.Messages)
+	assert.Equal(t, stats.Messages, stats.Datagrams)
+	assert.Positive(t, stats.Bytes)
+	assert.False(t, stats.LastSent.IsZero())
+	assert.Contains(t, out.String(), "Starting multicast sender to 239.23.23.53:23256")
+	assert.Contains(t, out.String(), "Sent packet #1")
+}
+
+func TestSenderMPEGTSBanner(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	var out strings.Builder
+	cfg := mpegts.MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000}
+	sender, err := NewSender("239.23.23.68:23265", "lo", time.Second, 1, 0, 0, WithMPEGTS(cfg), WithSenderOutput(&out))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.Start()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, sender.Close())
+	require.NoError(t, <-done)
+
+	// Messages follow the interval, datagrams the bitrate
+	assert.Contains(t, out.String(), "Sending messages every 1s")
+	assert.Contains(t, out.String(), "a datagram every "+sender.mux.DatagramInterval().String())
+	assert.NotContains(t, out.String(), "Sending packets every")
+}
+
+func TestSenderCloseStopsStart(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	sender, err := NewSender("239.23.23.69:23266", "lo", time.Hour, 1, 0, 0, WithSenderOutput(io.Discard))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.Start()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, sender.Close())
+	// Closing twice is harmless; Start is not waiting for the next tick
+	assert.Error(t, sender.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after Close")
+	}
+}
+
+func TestSenderSetInterval(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	sender, err := NewSender("239.23.23.53:23256", "lo", time.Hour, 1, 0, 0, WithSenderOutput(io.Discard))
+	require.NoError(t, err)
+	assert.ErrorContains(t, sender.SetInterval(0), "interval must be positive")
+	// Changes before Start are picked up once it runs; only the latest counts
+	require.NoError(t, sender.SetInterval(time.Minute))
+	require.NoError(t, sender.SetInterval(10*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sender.Start()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, sender.Close())
+	require.NoError(t, <-done)
+	assert.Greater(t, sender.Stats().Messages, 3)
+
+	rtpSender, err := NewSender("239.23.23.53:23256", "lo", time.Second, 1, 0, 0,
+		WithSenderOutput(io.Discard), WithRTP(RTPConfig{PayloadType: 96, FrameRate: 25, PacketsPerFrame: 1}))
+	require.NoError(t, err)
+	defer rtpSender.Close()
+	assert.ErrorContains(t, rtpSender.SetInterval(time.Millisecond), "cannot change")
+}
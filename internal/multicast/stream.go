@@ -0,0 +1,291 @@
+package multicast
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+)
+
+// StreamKey identifies the packets of one sender session on one stream, so
+// senders that share a hostname or port are accounted separately
+type StreamKey struct {
+	// Protocol is empty for mcaster messages, or e.g. "RTP" for analysed feeds
+	Protocol string
+	Source   string
+	SenderID string
+	Session  SessionID
+	Stream   string
+}
+
+// streamKey returns the key for a message. Messages from senders that predate
+// session IDs are told apart by their source address instead.
+func streamKey(msg *Message, source *net.UDPAddr) StreamKey {
+	key := StreamKey{
+		Source:   msg.Source,
+		SenderID: msg.SenderID,
+		Session:  msg.Session,
+		Stream:   msg.Stream,
+	}
+	if msg.Session == 0 && source != nil {
+		key.Stream = source.String()
+	}
+	return key
+}
+
+// String describes the stream for display
+func (k StreamKey) String() string {
+	if k.Protocol == protocolRTP {
+		return fmt.Sprintf("RTP SSRC 0x%08x from %s on %s", uint64(k.Session), k.Source, k.Stream)
+	}
+	origin := k.Source
+	if k.SenderID != "" {
+		origin = k.SenderID + "@" + k.Source
+	}
+	if k.Session == 0 {
+		return fmt.Sprintf("%s from %s", origin, k.Stream)
+	}
+	return fmt.Sprintf("%s session %s stream %s", origin, k.Session, k.Stream)
+}
+
+// sameSender reports whether two keys belong to the same sender and stream,
+// possibly in different sessions
+func (k StreamKey) sameSender(other StreamKey) bool {
+	return k.Protocol == other.Protocol && k.Source == other.Source &&
+		k.SenderID == other.SenderID && k.Stream == other.Stream
+}
+
+// StreamStats counts the packets of one stream
+type StreamStats struct {
+	Received   int
+	Lost       int
+	Reordered  int
+	Duplicates int
+	FirstID    int
+	HighestID  int
+	// Jitter is the RFC 3550 interarrival jitter
+	Jitter time.Duration
+	// PayloadType and Markers are only set for RTP streams
+	PayloadType uint8
+	Markers     int
+	// Group is the address the packets were sent to and Address the one they came from
+	Group   string
+	Address string
+	// Bytes counts the UDP payload of every packet
+	Bytes    int
+	LastSeen time.Time
+	// TTL of the last packet (-1 if unknown)
+	TTL int
+
+	jitter jitterEstimator
+	delays delaySamples
+	seen   idWindow
+}
+
+// seenWindow is how many IDs up to the highest a stream remembers, to tell
+// late packets from duplicates
+const seenWindow = 1024
+
+// idWindow is a bitmap of the IDs seen among the last seenWindow
+type idWindow [seenWindow / 64]uint64
+
+func (w *idWindow) has(id int) bool {
+	i := uint(id) % seenWindow
+	return w[i/64]&(1<<(i%64)) != 0
+}
+
+func (w *idWindow) set(id int) {
+	i := uint(id) % seenWindow
+	w[i/64] |= 1 << (i % 64)
+}
+
+func (w *idWindow) clear(id int) {
+	i := uint(id) % seenWindow
+	w[i/64] &^= 1 << (i % 64)
+}
+
+// observePacket accounts for the size, addresses, arrival and TTL of a packet
+func (s *StreamStats) observePacket(pkt *Packet) {
+	s.Group = pkt.Destination.String()
+	s.Address = pkt.Source.String()
+	s.Bytes += len(pkt.Data)
+	s.LastSeen = pkt.ReceivedAt
+	s.TTL = pkt.TTL
+}
+
+// observeDelay records the one-way delay of a message
+func (s *StreamStats) observeDelay(delay time.Duration) {
+	s.delays.add(delay)
+}
+
+// DelayPercentile returns the pth percentile (0-100) of the delay of recent
+// messages, or false for streams without send times such as RTP
+func (s StreamStats) DelayPercentile(p float64) (time.Duration, bool) {
+	return s.delays.percentile(p)
+}
+
+// LossPercent returns the share of packets lost, in percent
+func (s StreamStats) LossPercent() float64 {
+	if s.Received+s.Lost == 0 {
+		return 0
+	}
+	return float64(s.Lost) * 100 / float64(s.Received+s.Lost)
+}
+
+// observe accounts for a message ID. Gaps count as lost until the missing
+// packets arrive late, when they count as reordered instead; an ID seen
+// before counts as a duplicate.
+func (s *StreamStats) observe(id int) {
+	s.Received++
+	switch {
+	case s.Received == 1:
+		s.FirstID = id
+		s.HighestID = id
+	case id > s.HighestID:
+		s.Lost += id - s.HighestID - 1
+		// Forget the IDs the window moves past
+		for skipped := s.HighestID + 1; skipped < id && skipped <= s.HighestID+seenWindow; skipped++ {
+			s.seen.clear(skipped)
+		}
+		s.HighestID = id
+	case s.HighestID-id >= seenWindow:
+		// Too old to tell from a duplicate: it counts as late but stays lost
+		s.Reordered++
+		return
+	case s.seen.has(id):
+		s.Duplicates++
+		return
+	default:
+		s.Reordered++
+		if s.Lost > 0 && id >= s.FirstID {
+			s.Lost--
+		}
+	}
+	s.seen.set(id)
+}
+
+// observeTransit updates the jitter with a packet's transit time in seconds,
+// the difference between its arrival and send times
+func (s *StreamStats) observeTransit(transit float64) {
+	s.Jitter = time.Duration(s.jitter.update(transit) * float64(time.Second))
+}
+
+// String formats the counts for the stream summary
+func (s StreamStats) String() string {
+	return fmt.Sprintf("%d received, %d lost, %d reordered, %d duplicates, jitter %v",
+		s.Received, s.Lost, s.Reordered, s.Duplicates, s.Jitter.Round(time.Microsecond))
+}
+
+// delayWindow is how many recent delays percentiles are computed from
+const delayWindow = 1024
+
+// delaySamples keeps the most recent delays in a ring
+type delaySamples struct {
+	samples []time.Duration
+	next    int
+}
+
+func (d *delaySamples) add(delay time.Duration) {
+	if len(d.samples) < delayWindow {
+		d.samples = append(d.samples, delay)
+		return
+	}
+	d.samples[d.next] = delay
+	d.next = (d.next + 1) % delayWindow
+}
+
+func (d delaySamples) percentile(p float64) (time.Duration, bool) {
+	if len(d.samples) == 0 {
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), d.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))], true
+}
+
+// clone copies the samples so a snapshot does not share them
+func (d delaySamples) clone() delaySamples {
+	return delaySamples{samples: append([]time.Duration(nil), d.samples...), next: d.next}
+}
+
+// jitterEstimator computes the RFC 3550 interarrival jitter: the smoothed mean
+// deviation of the difference in transit time between consecutive packets
+type jitterEstimator struct {
+	lastTransit float64
+	jitter      float64
+	started     bool
+}
+
+// update takes the transit time of the next packet and returns the jitter,
+// both in the same unit
+func (j *jitterEstimator) update(transit float64) float64 {
+	if j.started {
+		d := math.Abs(transit - j.lastTransit)
+		j.jitter += (d - j.jitter) / 16
+	}
+	j.lastTransit = transit
+	j.started = true
+	return j.jitter
+}
+
+// streamTable holds the stats of every stream in the order they were first
+// heard. mu guards the table and the stats, which snapshots read while packets
+// are received.
+type streamTable struct {
+	mu    sync.Mutex
+	stats map[StreamKey]*StreamStats
+	order []StreamKey
+}
+
+func newStreamTable() *streamTable {
+	return &streamTable{stats: make(map[StreamKey]*StreamStats)}
+}
+
+// get returns the stats of a stream, creating them for a new stream. The
+// caller holds mu.
+func (t *streamTable) get(key StreamKey) (stats *StreamStats, created bool) {
+	if stats, ok := t.stats[key]; ok {
+		return stats, false
+	}
+	stats = &StreamStats{}
+	t.stats[key] = stats
+	t.order = append(t.order, key)
+	return stats, true
+}
+
+// snapshot returns a copy of the stats of every stream
+func (t *streamTable) snapshot() map[StreamKey]StreamStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	streams := make(map[StreamKey]StreamStats, len(t.stats))
+	for key, stats := range t.stats {
+		copied := *stats
+		copied.delays = stats.delays.clone()
+		streams[key] = copied
+	}
+	return streams
+}
+
+func (t *streamTable) printSummary(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.order) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n📊 Streams:\n")
+	for _, key := range t.order {
+		stats := t.stats[key]
+		extra := ""
+		if key.Protocol == protocolRTP {
+			extra = fmt.Sprintf(", PT %d (%s), %d markers",
+				stats.PayloadType, rtp.PayloadTypeName(stats.PayloadType), stats.Markers)
+		}
+		fmt.Fprintf(w, "   %s: %s%s\n", key, stats, extra)
+	}
+}
@@ -0,0 +1,170 @@
+package multicast
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamStatsObserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []int
+		expected StreamStats
+	}{
+		{
+			name:     "in order",
+			ids:      []int{1, 2, 3},
+			expected: StreamStats{Received: 3, FirstID: 1, HighestID: 3},
+		},
+		{
+			name:     "gap",
+			ids:      []int{1, 2, 5},
+			expected: StreamStats{Received: 3, Lost: 2, FirstID: 1, HighestID: 5},
+		},
+		{
+			name:     "late packet fills a gap",
+			ids:      []int{1, 3, 2},
+			expected: StreamStats{Received: 3, Reordered: 1, FirstID: 1, HighestID: 3},
+		},
+		{
+			name:     "duplicate",
+			ids:      []int{1, 2, 2},
+			expected: StreamStats{Received: 3, Duplicates: 1, FirstID: 1, HighestID: 2},
+		},
+		{
+			name:     "late duplicate does not hide loss",
+			ids:      []int{1, 3, 5, 2, 2},
+			expected: StreamStats{Received: 5, Lost: 1, Reordered: 1, Duplicates: 1, FirstID: 1, HighestID: 5},
+		},
+		{
+			name:     "duplicate below the highest",
+			ids:      []int{1, 2, 3, 1},
+			expected: StreamStats{Received: 4, Duplicates: 1, FirstID: 1, HighestID: 3},
+		},
+		{
+			name:     "too late to tell from a duplicate",
+			ids:      []int{1, 3, 2000, 2},
+			expected: StreamStats{Received: 4, Lost: 1997, Reordered: 1, FirstID: 1, HighestID: 2000},
+		},
+		{
+			name:     "window reused after a jump",
+			ids:      []int{1, 1026, 1025},
+			expected: StreamStats{Received: 3, Lost: 1023, Reordered: 1, FirstID: 1, HighestID: 1026},
+		},
+		{
+			name:     "joined mid-stream",
+			ids:      []int{40, 41},
+			expected: StreamStats{Received: 2, FirstID: 40, HighestID: 41},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats StreamStats
+			for _, id := range tt.ids {
+				stats.observe(id)
+			}
+			stats.seen = idWindow{}
+			assert.Equal(t, tt.expected, stats)
+		})
+	}
+}
+
+func TestStreamKey(t *testing.T) {
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4000}
+
+	msg := &Message{Source: "host", SenderID: "a", Session: 7, Stream: "239.1.1.1:5000"}
+	key := streamKey(msg, source)
+	assert.Equal(t, StreamKey{Source: "host", SenderID: "a", Session: 7, Stream: "239.1.1.1:5000"}, key)
+	assert.Equal(t, "a@host session 0000000000000007 stream 239.1.1.1:5000", key.String())
+
+	restarted := streamKey(&Message{Source: "host", SenderID: "a", Session: 8, Stream: "239.1.1.1:5000"}, source)
+	assert.NotEqual(t, key, restarted)
+	assert.True(t, key.sameSender(restarted))
+
+	other := streamKey(&Message{Source: "host", SenderID: "b", Session: 9, Stream: "239.1.1.1:5000"}, source)
+	assert.False(t, key.sameSender(other))
+
+	legacy := streamKey(&Message{Source: "host"}, source)
+	assert.Equal(t, "192.0.2.1:4000", legacy.Stream)
+	assert.Equal(t, "host from 192.0.2.1:4000", legacy.String())
+}
+
+func TestJitterEstimator(t *testing.T) {
+	var j jitterEstimator
+	assert.Zero(t, j.update(5))
+	assert.Zero(t, j.update(5))
+	// A transit change of 16 moves the jitter by 1/16 of the difference
+	assert.InDelta(t, 1.0, j.update(21), 1e-9)
+	assert.InDelta(t, 1.9375, j.update(5), 1e-9)
+}
+
+func TestStreamTable(t *testing.T) {
+	table := newStreamTable()
+	a := StreamKey{Source: "a", Session: 1}
+	b := StreamKey{Source: "b", Session: 2}
+
+	stats, created := table.get(a)
+	assert.True(t, created)
+	stats.observe(1)
+	_, created = table.get(b)
+	assert.True(t, created)
+	stats, created = table.get(a)
+	assert.False(t, created)
+	stats.observe(2)
+
+	assert.Equal(t, []StreamKey{a, b}, table.order)
+	snapshot := table.snapshot()
+	assert.Equal(t, 2, snapshot[a].Received)
+
+	// The snapshot is a copy
+	stats.observe(3)
+	assert.Equal(t, 2, snapshot[a].Received)
+
+	assert.Equal(t, "3 received, 0 lost, 0 reordered, 0 duplicates, jitter 0s", stats.String())
+}
+
+func TestStreamStatsPacketsAndDelay(t *testing.T) {
+	var stats StreamStats
+	_, ok := stats.DelayPercentile(50)
+	assert.False(t, ok)
+	assert.Zero(t, stats.LossPercent())
+
+	now := time.Now()
+	pkt := &Packet{
+		Data:        make([]byte, 100),
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 40000},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 5000},
+		ReceivedAt:  now,
+		TTL:         62,
+	}
+	for i := 1; i <= 100; i++ {
+		stats.observe(i * 2)
+		stats.observePacket(pkt)
+		stats.observeDelay(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, "239.1.1.1:5000", stats.Group)
+	assert.Equal(t, "192.0.2.1:40000", stats.Address)
+	assert.Equal(t, 10000, stats.Bytes)
+	assert.Equal(t, now, stats.LastSeen)
+	assert.Equal(t, 62, stats.TTL)
+	assert.InDelta(t, 99.0/199*100, stats.LossPercent(), 0.0001)
+
+	p50, ok := stats.DelayPercentile(50)
+	assert.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, p50)
+	p99, _ := stats.DelayPercentile(99)
+	assert.Equal(t, 99*time.Millisecond, p99)
+
+	// Only the latest delays count
+	for i := 0; i < delayWindow; i++ {
+		stats.observeDelay(time.Second)
+	}
+	p50, _ = stats.DelayPercentile(50)
+	assert.Equal(t, time.Second, p50)
+	assert.Len(t, stats.delays.samples, delayWindow)
+}
@@ -0,0 +1,126 @@
+package multicast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/hyposcaler-bot/mcaster/internal/rtp"
+)
+
+// tsDecoder runs TR 101 290 checks on the MPEG transport stream of each group
+type tsDecoder struct {
+	analyzers map[string]*mpegts.Analyzer
+	order     []string
+}
+
+func newTSDecoder() *tsDecoder {
+	return &tsDecoder{analyzers: make(map[string]*mpegts.Analyzer)}
+}
+
+func (d *tsDecoder) Decode(pkt *Packet, group string, out io.Writer) bool {
+	data := tsPayload(pkt.Data)
+	if data == nil {
+		return false
+	}
+
+	key := pkt.Destination.String()
+	analyzer, ok := d.analyzers[key]
+	if !ok {
+		analyzer = mpegts.NewAnalyzer()
+		d.analyzers[key] = analyzer
+		d.order = append(d.order, key)
+		fmt.Fprintf(out, "📺 [%s] New MPEG-TS stream on %s from %s\n",
+			pkt.ReceivedAt.Format("15:04:05.000"), key, pkt.Source)
+	}
+
+	for _, event := range analyzer.Analyze(data, pkt.ReceivedAt) {
+		fmt.Fprintf(out, "⚠️  [%s] MPEG-TS%s %s\n", pkt.ReceivedAt.Format("15:04:05.000"), group, event)
+	}
+	return true
+}
+
+// tsPayload returns the transport stream packets in a datagram, either
+// directly in UDP or in RTP, or nil if it does not carry a transport stream
+func tsPayload(data []byte) []byte {
+	if len(data) >= mpegts.PacketSize && data[0] == mpegts.SyncByte {
+		return data
+	}
+	if header, err := rtp.Parse(data); err == nil && !rtp.IsRTCP(data) {
+		payload := data[header.PayloadOffset : header.PayloadOffset+header.PayloadSize]
+		if len(payload) >= mpegts.PacketSize && payload[0] == mpegts.SyncByte {
+			return payload
+		}
+	}
+	return nil
+}
+
+// tsMessage returns the mcaster message carried in a datagram of a transport
+// stream, as sent with WithMPEGTS, or nil if there is none. Such messages are
+// one PES packet within the datagram.
+func tsMessage(data []byte) []byte {
+	if !mpegts.IsTransportStream(data) {
+		return nil
+	}
+	packets := mpegts.Packets(data)
+	for i, pkt := range packets {
+		header, err := mpegts.ParseHeader(pkt)
+		if err != nil || !header.PayloadUnitStart || header.PID == mpegts.PIDNull {
+			continue
+		}
+		pes := append([]byte(nil), mpegts.Payload(pkt)...)
+		if !bytes.HasPrefix(pes, []byte{0, 0, 1, mpegts.StreamIDPrivate1}) {
+			continue
+		}
+		for _, next := range packets[i+1:] {
+			if h, err := mpegts.ParseHeader(next); err == nil && h.PID == header.PID {
+				pes = append(pes, mpegts.Payload(next)...)
+			}
+		}
+		if _, payload, err := mpegts.ParsePES(pes); err == nil && looksLikeMessage(payload) {
+			return payload
+		}
+	}
+	return nil
+}
+
+func (d *tsDecoder) String() string {
+	return "MPEG-TS (TR 101 290 priority 1)"
+}
+
+// PrintSummary reports the checks and PIDs of each group's transport stream
+func (d *tsDecoder) PrintSummary(w io.Writer) {
+	for _, key := range d.order {
+		a := d.analyzers[key]
+		fmt.Fprintf(w, "\n📺 MPEG-TS on %s: %d packets in %d datagrams\n", key, a.Packets, a.Datagrams)
+		for _, indicator := range mpegts.Indicators {
+			status := "✅"
+			if a.Errors[indicator] > 0 {
+				status = "❌"
+			}
+			fmt.Fprintf(w, "   %s %-22s %d\n", status, indicator, a.Errors[indicator])
+		}
+		for _, pid := range a.PIDs() {
+			fmt.Fprintf(w, "   PID 0x%04x %-16s %d packets, %d CC errors%s\n",
+				pid.PID, describePID(pid), pid.Packets, pid.CCErrors, describePCR(pid))
+		}
+	}
+}
+
+func describePID(pid mpegts.PIDStats) string {
+	if pid.Kind == "" {
+		return "unreferenced"
+	}
+	return pid.Kind
+}
+
+func describePCR(pid mpegts.PIDStats) string {
+	if pid.PCRs == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d PCRs, interval avg %v max %v, jitter max %v", pid.PCRs,
+		pid.PCRAverageGap().Round(time.Microsecond), pid.PCRMaxGap.Round(time.Microsecond),
+		pid.PCRMaxJitter.Round(time.Microsecond))
+}
@@ -0,0 +1,113 @@
+package multicast
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tsDatagram builds a datagram of video packets on PID 0x100 starting at continuity counter cc
+func tsDatagram(cc int, packets int) []byte {
+	var data []byte
+	for i := 0; i < packets; i++ {
+		pkt := make([]byte, mpegts.PacketSize)
+		pkt[0] = mpegts.SyncByte
+		pkt[1] = 0x01
+		pkt[3] = 0x10 | byte(cc+i)&0x0f
+		data = append(data, pkt...)
+	}
+	return data
+}
+
+func tsPacketTo(data []byte, group string) *Packet {
+	return &Packet{
+		Data:        data,
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000},
+		Destination: &net.UDPAddr{IP: net.ParseIP(group), Port: 5000},
+		ReceivedAt:  time.Now(),
+	}
+}
+
+func TestTSDecoder(t *testing.T) {
+	d := newTSDecoder()
+
+	// Two groups are analysed separately; the second loses a packet
+	assert.True(t, d.Decode(tsPacketTo(tsDatagram(0, 7), "239.1.1.1"), "", io.Discard))
+	assert.True(t, d.Decode(tsPacketTo(tsDatagram(7, 7), "239.1.1.1"), "", io.Discard))
+	assert.True(t, d.Decode(tsPacketTo(tsDatagram(0, 7), "239.1.1.2"), "", io.Discard))
+	assert.True(t, d.Decode(tsPacketTo(tsDatagram(8, 7), "239.1.1.2"), "", io.Discard))
+
+	// RTP carrying MPEG-TS is unwrapped
+	rtp := rtpPacket(1, 0, 33, false, time.Now())
+	rtp.Data = append(rtp.Data[:12], tsDatagram(15, 7)...)
+	assert.True(t, d.Decode(rtp, "", io.Discard))
+
+	require.Equal(t, []string{"239.1.1.1:5000", "239.1.1.2:5000", "239.1.1.1:5004"}, d.order)
+	assert.Zero(t, d.analyzers["239.1.1.1:5000"].Errors[mpegts.ContinuityError])
+	assert.Equal(t, 14, d.analyzers["239.1.1.1:5000"].Packets)
+	assert.Equal(t, 1, d.analyzers["239.1.1.2:5000"].Errors[mpegts.ContinuityError])
+	assert.Equal(t, 7, d.analyzers["239.1.1.1:5004"].Packets)
+
+	d.PrintSummary(io.Discard)
+}
+
+func TestTSDecoderOutput(t *testing.T) {
+	d := newTSDecoder()
+	var out strings.Builder
+	d.Decode(tsPacketTo(tsDatagram(0, 7), "239.1.1.1"), " on 239.1.1.1", &out)
+	d.Decode(tsPacketTo(tsDatagram(8, 7), "239.1.1.1"), " on 239.1.1.1", &out)
+	assert.Contains(t, out.String(), "New MPEG-TS stream on 239.1.1.1:5000 from 192.0.2.1:5000")
+	assert.Contains(t, out.String(), "MPEG-TS on 239.1.1.1")
+
+	var summary strings.Builder
+	d.PrintSummary(&summary)
+	assert.Contains(t, summary.String(), "📺 MPEG-TS on 239.1.1.1:5000: 14 packets in 2 datagrams")
+	assert.Contains(t, summary.String(), "PID 0x0100")
+}
+
+func TestTSDecoderIgnoresOtherTraffic(t *testing.T) {
+	d := newTSDecoder()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: tsDatagram(0, 1)[:100]},
+		{name: "no sync byte", data: make([]byte, mpegts.PacketSize)},
+		{name: "RTP audio", data: rtpPacket(1, 0, 0, false, time.Now()).Data},
+		{name: "message", data: []byte(`{"id":1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, d.Decode(tsPacketTo(tt.data, "239.1.1.1"), "", io.Discard))
+		})
+	}
+	assert.Empty(t, d.analyzers)
+}
+
+func TestTSMessage(t *testing.T) {
+	mux, err := mpegts.NewMuxer(mpegts.MuxConfig{Program: 1, PMTPID: 0x1000, PID: 0x100, Bitrate: 2000000})
+	require.NoError(t, err)
+
+	psi, err := mux.Datagram(nil)
+	require.NoError(t, err)
+	assert.Nil(t, tsMessage(psi))
+
+	message := []byte(`{"version":2,"id":1}`)
+	data, err := mux.Datagram(message)
+	require.NoError(t, err)
+	assert.Equal(t, message, tsMessage(data))
+
+	// PES packets of other streams, such as video, are not messages
+	video, err := mux.Datagram([]byte{0, 0, 0, 1, 0x67})
+	require.NoError(t, err)
+	assert.Nil(t, tsMessage(video))
+	assert.Nil(t, tsMessage([]byte(`{"id":1}`)))
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseEndpoint parses an address that may leave out the IP or the port:
+// "239.1.1.1", "239.1.1.1:5000", "[ff15::1]:5000" or ":5000". A missing IP is
+// returned as nil and a missing port as 0.
+func ParseEndpoint(spec string) (*net.UDPAddr, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty address")
+	}
+
+	if ip := net.ParseIP(strings.Trim(spec, "[]")); ip != nil {
+		return &net.UDPAddr{IP: ip}, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", spec, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port in %q: must be between 1 and 65535", spec)
+	}
+
+	addr := &net.UDPAddr{Port: port}
+	if host != "" {
+		if addr.IP = net.ParseIP(host); addr.IP == nil {
+			return nil, fmt.Errorf("invalid IP address in %q", spec)
+		}
+	}
+
+	return addr, nil
+}
+
+// EndpointMatches reports whether addr matches pattern, where a nil pattern IP
+// or a zero pattern port matches any value
+func EndpointMatches(pattern, addr *net.UDPAddr) bool {
+	if pattern.IP != nil && !pattern.IP.Equal(addr.IP) {
+		return false
+	}
+	return pattern.Port == 0 || pattern.Port == addr.Port
+}
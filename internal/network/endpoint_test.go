@@ -0,0 +1,58 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expectIP    string
+		expectPort  int
+		expectError bool
+	}{
+		{name: "IPv4 only", spec: "239.1.1.1", expectIP: "239.1.1.1"},
+		{name: "IPv4 and port", spec: "239.1.1.1:5000", expectIP: "239.1.1.1", expectPort: 5000},
+		{name: "IPv6 only", spec: "ff15::1", expectIP: "ff15::1"},
+		{name: "bracketed IPv6", spec: "[ff15::1]", expectIP: "ff15::1"},
+		{name: "IPv6 and port", spec: "[ff15::1]:5000", expectIP: "ff15::1", expectPort: 5000},
+		{name: "port only", spec: ":5000", expectPort: 5000},
+		{name: "empty", spec: "", expectError: true},
+		{name: "bad port", spec: "239.1.1.1:99999", expectError: true},
+		{name: "hostname", spec: "example.com:5000", expectError: true},
+		{name: "garbage", spec: "not-an-address", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := ParseEndpoint(tt.spec)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectIP == "" {
+				assert.Nil(t, addr.IP)
+			} else {
+				assert.Equal(t, tt.expectIP, addr.IP.String())
+			}
+			assert.Equal(t, tt.expectPort, addr.Port)
+		})
+	}
+}
+
+func TestEndpointMatches(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 5000}
+
+	assert.True(t, EndpointMatches(&net.UDPAddr{IP: net.ParseIP("239.1.1.1")}, addr))
+	assert.True(t, EndpointMatches(&net.UDPAddr{Port: 5000}, addr))
+	assert.True(t, EndpointMatches(&net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 5000}, addr))
+	assert.True(t, EndpointMatches(&net.UDPAddr{}, addr))
+	assert.False(t, EndpointMatches(&net.UDPAddr{IP: net.ParseIP("239.1.1.2")}, addr))
+	assert.False(t, EndpointMatches(&net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 5001}, addr))
+}
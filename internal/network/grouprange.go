@@ -0,0 +1,87 @@
+package network
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// MaxGroupRange is the largest number of groups ExpandGroupRange will return
+const MaxGroupRange = 1 << 16
+
+// ExpandGroupRange expands a group range given either in CIDR notation
+// (239.1.1.0/28) or as first-last (239.1.1.1-239.1.1.16, or 239.1.1.1-16)
+func ExpandGroupRange(spec string) ([]net.IP, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.Contains(spec, "/") {
+		_, ipnet, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group range %q: %w", spec, err)
+		}
+		first := ipnet.IP
+		last := make(net.IP, len(first))
+		for i := range first {
+			last[i] = first[i] | ^ipnet.Mask[i]
+		}
+		return expandIPRange(spec, first, last)
+	}
+
+	firstStr, lastStr, found := strings.Cut(spec, "-")
+	if !found {
+		return nil, fmt.Errorf("invalid group range %q: use CIDR notation or first-last", spec)
+	}
+
+	first := net.ParseIP(strings.TrimSpace(firstStr))
+	if first == nil {
+		return nil, fmt.Errorf("invalid group range %q: bad first address", spec)
+	}
+
+	lastStr = strings.TrimSpace(lastStr)
+	last := net.ParseIP(lastStr)
+	if last == nil {
+		// Short form: only the last octet of an IPv4 address
+		ip4 := first.To4()
+		octet, err := strconv.Atoi(lastStr)
+		if ip4 == nil || err != nil || octet < 0 || octet > 255 {
+			return nil, fmt.Errorf("invalid group range %q: bad last address", spec)
+		}
+		last = net.IPv4(ip4[0], ip4[1], ip4[2], byte(octet))
+	}
+
+	return expandIPRange(spec, first, last)
+}
+
+func expandIPRange(spec string, first, last net.IP) ([]net.IP, error) {
+	if (first.To4() == nil) != (last.To4() == nil) {
+		return nil, fmt.Errorf("invalid group range %q: mixed address families", spec)
+	}
+	if ip4 := first.To4(); ip4 != nil {
+		first, last = ip4, last.To4()
+	}
+
+	start := new(big.Int).SetBytes(first)
+	end := new(big.Int).SetBytes(last)
+	if start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("invalid group range %q: first address is after last address", spec)
+	}
+
+	count := new(big.Int).Sub(end, start)
+	if !count.IsInt64() || count.Int64() >= MaxGroupRange {
+		return nil, fmt.Errorf("group range %q is too large (maximum %d groups)", spec, MaxGroupRange)
+	}
+
+	groups := make([]net.IP, 0, count.Int64()+1)
+	current := new(big.Int).Set(start)
+	one := big.NewInt(1)
+	for current.Cmp(end) <= 0 {
+		ip := make(net.IP, len(first))
+		current.FillBytes(ip)
+		groups = append(groups, ip)
+		current.Add(current, one)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,95 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandGroupRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expectCount int
+		expectFirst string
+		expectLast  string
+		expectError bool
+	}{
+		{
+			name:        "IPv4 CIDR",
+			spec:        "239.1.1.0/28",
+			expectCount: 16,
+			expectFirst: "239.1.1.0",
+			expectLast:  "239.1.1.15",
+		},
+		{
+			name:        "IPv4 first-last",
+			spec:        "239.1.1.250-239.1.2.5",
+			expectCount: 12,
+			expectFirst: "239.1.1.250",
+			expectLast:  "239.1.2.5",
+		},
+		{
+			name:        "IPv4 short form",
+			spec:        "239.1.1.1-16",
+			expectCount: 16,
+			expectFirst: "239.1.1.1",
+			expectLast:  "239.1.1.16",
+		},
+		{
+			name:        "single group",
+			spec:        "239.1.1.1/32",
+			expectCount: 1,
+			expectFirst: "239.1.1.1",
+			expectLast:  "239.1.1.1",
+		},
+		{
+			name:        "IPv6 CIDR",
+			spec:        "ff15::100/126",
+			expectCount: 4,
+			expectFirst: "ff15::100",
+			expectLast:  "ff15::103",
+		},
+		{
+			name:        "reversed range",
+			spec:        "239.1.1.16-239.1.1.1",
+			expectError: true,
+		},
+		{
+			name:        "too large",
+			spec:        "239.0.0.0/8",
+			expectError: true,
+		},
+		{
+			name:        "mixed families",
+			spec:        "239.1.1.1-ff15::1",
+			expectError: true,
+		},
+		{
+			name:        "not a range",
+			spec:        "239.1.1.1",
+			expectError: true,
+		},
+		{
+			name:        "bad short form",
+			spec:        "239.1.1.1-300",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := ExpandGroupRange(tt.spec)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, groups, tt.expectCount)
+			assert.Equal(t, tt.expectFirst, groups[0].String())
+			assert.Equal(t, tt.expectLast, groups[len(groups)-1].String())
+		})
+	}
+}
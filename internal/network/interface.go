@@ -8,47 +8,128 @@ import (
 	"syscall"
 )
 
-// GetInterface returns a network interface by name, or nil for default
+// GetInterface returns a network interface by name, index or address, or nil for default
 func GetInterface(interfaceName string) (*net.Interface, error) {
 	if interfaceName == "" {
 		return nil, nil
 	}
 
-	iface, err := net.InterfaceByName(interfaceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find interface %s: %w", interfaceName, err)
+	return ResolveInterface(interfaceName)
+}
+
+// ResolveInterface finds an interface given its name, its index, or one of its IP addresses
+func ResolveInterface(spec string) (*net.Interface, error) {
+	if index, err := strconv.Atoi(spec); err == nil {
+		iface, err := net.InterfaceByIndex(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find interface %s: %w", spec, err)
+		}
+		return iface, nil
 	}
 
-	return iface, nil
-}
+	if ip := net.ParseIP(spec); ip != nil {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list interfaces: %w", err)
+		}
+		for i := range ifaces {
+			if InterfaceHasIP(&ifaces[i], ip) {
+				return &ifaces[i], nil
+			}
+		}
+		return nil, fmt.Errorf("failed to find interface %s: no interface has this address", spec)
+	}
 
-// DialUDPOnInterface creates a UDP connection bound to a specific interface
-func DialUDPOnInterface(interfaceName string, remoteAddr *net.UDPAddr, sport int) (*net.UDPConn, error) {
-	iface, err := net.InterfaceByName(interfaceName)
+	iface, err := net.InterfaceByName(spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find interface %s: %w", interfaceName, err)
+		return nil, fmt.Errorf("failed to find interface %s: %w", spec, err)
 	}
 
+	return iface, nil
+}
+
+// InterfaceHasIP reports whether ip is configured on iface
+func InterfaceHasIP(iface *net.Interface, ip net.IP) bool {
 	addrs, err := iface.Addrs()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get interface addresses: %w", err)
+		return false
 	}
 
-	var localAddr *net.UDPAddr
 	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				localAddr = &net.UDPAddr{IP: ipnet.IP, Port: sport}
-				break
-			}
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return true
 		}
 	}
 
-	if localAddr == nil {
-		return nil, fmt.Errorf("no suitable IPv4 address found on interface %s", interfaceName)
+	return false
+}
+
+// DialOptions controls how a sending socket is bound and which interface it uses
+type DialOptions struct {
+	// Interface selects the outgoing interface via IP_MULTICAST_IF / IPV6_MULTICAST_IF
+	Interface *net.Interface
+	// SourceIP binds the socket to an explicit local address (nil = kernel choice)
+	SourceIP net.IP
+	// SourcePort binds the socket to a local port (0 = random)
+	SourcePort int
+	// BindToDevice additionally restricts the socket to Interface with SO_BINDTODEVICE
+	BindToDevice bool
+}
+
+// DialMulticastUDP creates a UDP connection to a multicast group, selecting the
+// outgoing interface by index rather than by binding to one of its addresses
+func DialMulticastUDP(remoteAddr *net.UDPAddr, opts DialOptions) (*net.UDPConn, error) {
+	if opts.BindToDevice && opts.Interface == nil {
+		return nil, fmt.Errorf("binding to a device requires an interface")
 	}
 
-	return net.DialUDP("udp", localAddr, remoteAddr)
+	if opts.SourceIP != nil && opts.Interface != nil && !InterfaceHasIP(opts.Interface, opts.SourceIP) {
+		return nil, fmt.Errorf("source IP %s is not configured on interface %s", opts.SourceIP, opts.Interface.Name)
+	}
+
+	ipv6 := remoteAddr.IP.To4() == nil
+	dialer := net.Dialer{
+		LocalAddr: &net.UDPAddr{IP: opts.SourceIP, Port: opts.SourcePort},
+		Control: func(network, address string, c syscall.RawConn) error {
+			if opts.Interface == nil {
+				return nil
+			}
+
+			var setErr error
+			err := c.Control(func(fd uintptr) {
+				if setErr = setMulticastInterface(int(fd), opts.Interface, ipv6); setErr != nil {
+					setErr = fmt.Errorf("failed to set multicast interface: %w", setErr)
+					return
+				}
+				if opts.BindToDevice {
+					if setErr = bindToDevice(int(fd), opts.Interface); setErr != nil {
+						setErr = fmt.Errorf("failed to bind to device: %w", setErr)
+					}
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("failed to control socket: %w", err)
+			}
+			return setErr
+		},
+	}
+
+	conn, err := dialer.Dial("udp", remoteAddr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
+
+// DialUDPOnInterface creates a UDP connection that sends via a specific interface
+func DialUDPOnInterface(interfaceName string, remoteAddr *net.UDPAddr, sport int) (*net.UDPConn, error) {
+	iface, err := ResolveInterface(interfaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DialMulticastUDP(remoteAddr, DialOptions{Interface: iface, SourcePort: sport})
 }
 
 // SetMulticastTTL sets the TTL for multicast packets on a UDP connection
@@ -74,6 +155,84 @@ func SetMulticastTTL(conn *net.UDPConn, ttl int) error {
 	return nil
 }
 
+// SetMulticastLoopback enables or disables local delivery of sent multicast packets
+func SetMulticastLoopback(conn *net.UDPConn, enabled bool) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("failed to get raw connection: %w", err)
+	}
+
+	value := 0
+	if enabled {
+		value = 1
+	}
+
+	// A connected socket sends to its remote address's family
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	if remote, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
+		addr = remote
+	}
+	ipv6 := addr.IP.To4() == nil
+
+	var setErr error
+	err = rawConn.Control(func(fd uintptr) {
+		// Set IP_MULTICAST_LOOP or IPV6_MULTICAST_LOOP socket option
+		if ipv6 {
+			setErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_LOOP, value)
+		} else {
+			setErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_LOOP, value)
+		}
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to control socket: %w", err)
+	}
+	if setErr != nil {
+		return fmt.Errorf("failed to set multicast loopback: %w", setErr)
+	}
+
+	return nil
+}
+
+// ResolveGroups resolves a comma-separated list of group addresses, applying the
+// destination port override to each of them
+func ResolveGroups(groupList string, dport int) ([]*net.UDPAddr, error) {
+	var groups []*net.UDPAddr
+
+	for _, groupAddr := range strings.Split(groupList, ",") {
+		groupAddr = strings.TrimSpace(groupAddr)
+		if groupAddr == "" {
+			continue
+		}
+
+		finalGroupAddr, err := OverrideGroupPort(groupAddr, dport)
+		if err != nil {
+			return nil, err
+		}
+
+		addr, err := net.ResolveUDPAddr("udp", finalGroupAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve multicast address: %w", err)
+		}
+		groups = append(groups, addr)
+	}
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no group address given")
+	}
+
+	return groups, nil
+}
+
+// GroupIPs returns the IP addresses of a list of group addresses
+func GroupIPs(groups []*net.UDPAddr) []net.IP {
+	ips := make([]net.IP, len(groups))
+	for i, group := range groups {
+		ips[i] = group.IP
+	}
+	return ips
+}
+
 // OverrideGroupPort overrides the port in a group address string if dport > 0
 func OverrideGroupPort(groupAddr string, dport int) (string, error) {
 	if dport <= 0 {
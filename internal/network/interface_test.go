@@ -2,6 +2,8 @@ package network
 
 import (
 	"net"
+	"strconv"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -107,6 +109,66 @@ func TestOverrideGroupPort(t *testing.T) {
 	}
 }
 
+func TestResolveGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		groupList string
+		dport     int
+		expected  []string
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "single group",
+			groupList: "239.23.23.23:2323",
+			expected:  []string{"239.23.23.23:2323"},
+		},
+		{
+			name:      "several groups",
+			groupList: "239.1.1.1:5000, 239.1.1.2:5000",
+			expected:  []string{"239.1.1.1:5000", "239.1.1.2:5000"},
+		},
+		{
+			name:      "port override applies to every group",
+			groupList: "239.1.1.1,239.1.1.2:5000",
+			dport:     6000,
+			expected:  []string{"239.1.1.1:6000", "239.1.1.2:6000"},
+		},
+		{
+			name:      "empty list",
+			groupList: " , ",
+			wantErr:   true,
+			errMsg:    "no group address given",
+		},
+		{
+			name:      "invalid entry",
+			groupList: "239.1.1.1:5000,invalid-address",
+			wantErr:   true,
+			errMsg:    "failed to resolve multicast address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := ResolveGroups(tt.groupList, tt.dport)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			var actual []string
+			for _, group := range groups {
+				actual = append(actual, group.String())
+			}
+			assert.Equal(t, tt.expected, actual)
+			assert.Len(t, GroupIPs(groups), len(tt.expected))
+		})
+	}
+}
+
 func TestGetInterface(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -203,7 +265,171 @@ func TestDialUDPOnInterface(t *testing.T) {
 	}
 }
 
+func TestResolveInterface(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "by name",
+			spec: "lo",
+		},
+		{
+			name: "by index",
+			spec: strconv.Itoa(lo.Index),
+		},
+		{
+			name: "by address",
+			spec: "127.0.0.1",
+		},
+		{
+			name:    "nonexistent name",
+			spec:    "nonexistent-interface-12345",
+			wantErr: true,
+			errMsg:  "failed to find interface",
+		},
+		{
+			name:    "nonexistent index",
+			spec:    "99999",
+			wantErr: true,
+			errMsg:  "failed to find interface",
+		},
+		{
+			name:    "address not on any interface",
+			spec:    "203.0.113.254",
+			wantErr: true,
+			errMsg:  "no interface has this address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iface, err := ResolveInterface(tt.spec)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.Nil(t, iface)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, iface)
+				assert.Equal(t, lo.Index, iface.Index)
+			}
+		})
+	}
+}
+
+func TestDialMulticastUDP(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	remoteAddr, err := net.ResolveUDPAddr("udp", "239.23.23.23:2323")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		opts    DialOptions
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "no interface",
+			opts: DialOptions{},
+		},
+		{
+			name: "interface by index",
+			opts: DialOptions{Interface: lo},
+		},
+		{
+			name: "interface with matching source IP",
+			opts: DialOptions{Interface: lo, SourceIP: net.ParseIP("127.0.0.1")},
+		},
+		{
+			name:    "source IP not on interface",
+			opts:    DialOptions{Interface: lo, SourceIP: net.ParseIP("203.0.113.254")},
+			wantErr: true,
+			errMsg:  "is not configured on interface",
+		},
+		{
+			name:    "bind to device without interface",
+			opts:    DialOptions{BindToDevice: true},
+			wantErr: true,
+			errMsg:  "requires an interface",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := DialMulticastUDP(remoteAddr, tt.opts)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.Nil(t, conn)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, conn)
+				if tt.opts.SourceIP != nil {
+					assert.True(t, tt.opts.SourceIP.Equal(conn.LocalAddr().(*net.UDPAddr).IP))
+				}
+				conn.Close()
+			}
+		})
+	}
+}
+
 // Integration test for network functionality
+func TestSetMulticastLoopback(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		addr    string
+		level   int
+		option  int
+	}{
+		{name: "IPv4", network: "udp4", addr: "127.0.0.1:0", level: syscall.IPPROTO_IP, option: syscall.IP_MULTICAST_LOOP},
+		{name: "IPv6", network: "udp6", addr: "[::1]:0", level: syscall.IPPROTO_IPV6, option: syscall.IPV6_MULTICAST_LOOP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			laddr, err := net.ResolveUDPAddr(tt.network, tt.addr)
+			require.NoError(t, err)
+			conn, err := net.ListenUDP(tt.network, laddr)
+			if err != nil {
+				t.Skipf("%s not available: %v", tt.name, err)
+			}
+			defer conn.Close()
+
+			// The option of the socket's own family must change
+			loop := func() int {
+				rawConn, err := conn.SyscallConn()
+				require.NoError(t, err)
+				var value int
+				var getErr error
+				require.NoError(t, rawConn.Control(func(fd uintptr) {
+					value, getErr = syscall.GetsockoptInt(int(fd), tt.level, tt.option)
+				}))
+				require.NoError(t, getErr)
+				return value
+			}
+			require.NoError(t, SetMulticastLoopback(conn, false))
+			assert.Equal(t, 0, loop())
+			require.NoError(t, SetMulticastLoopback(conn, true))
+			assert.Equal(t, 1, loop())
+		})
+	}
+}
+
 func TestNetworkIntegration(t *testing.T) {
 	t.Run("address override and resolution", func(t *testing.T) {
 		// Test the complete flow of overriding port and resolving address
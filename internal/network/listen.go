@@ -0,0 +1,204 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// Bind modes accepted by ParseBindAddr
+const (
+	BindGroup = "group"
+	BindAny   = "any"
+)
+
+// ListenOptions controls how a receiving socket is bound and shared with other listeners
+type ListenOptions struct {
+	// Interface selects the interface groups are joined on (nil = kernel choice)
+	Interface *net.Interface
+	// BindAddr is the local address to bind to (nil = wildcard)
+	BindAddr net.IP
+	// ReuseAddr sets SO_REUSEADDR so several listeners can bind the same port
+	ReuseAddr bool
+	// ReusePort sets SO_REUSEPORT to share the port with other SO_REUSEPORT listeners
+	ReusePort bool
+	// MulticastAll keeps the kernel default of delivering every group joined on the
+	// host to wildcard sockets (Linux IP_MULTICAST_ALL); false limits delivery to
+	// the groups joined on this socket
+	MulticastAll bool
+}
+
+// ParseBindAddr converts a bind mode (group, any or an IP address) into the address to bind to
+func ParseBindAddr(mode string, group net.IP) (net.IP, error) {
+	switch mode {
+	case "", BindGroup:
+		return group, nil
+	case BindAny:
+		return nil, nil
+	}
+
+	ip := net.ParseIP(mode)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid bind mode %q: must be group, any or an IP address", mode)
+	}
+	if (ip.To4() == nil) != (group.To4() == nil) {
+		return nil, fmt.Errorf("bind address %s does not match the address family of group %s", ip, group)
+	}
+
+	return ip, nil
+}
+
+// ListenMulticastUDP binds a UDP socket as described by opts and joins group on it.
+// The socket is created directly rather than through net.ListenUDP, which always
+// rewrites a multicast bind address to the wildcard address.
+func ListenMulticastUDP(group *net.UDPAddr, opts ListenOptions) (*net.UDPConn, error) {
+	ipv6 := group.IP.To4() == nil
+
+	var family int
+	var sa unix.Sockaddr
+	if ipv6 {
+		family = unix.AF_INET6
+		sa6 := &unix.SockaddrInet6{Port: group.Port}
+		// Link-local scoped addresses can only be bound with a zone, so without
+		// an interface fall back to the wildcard address
+		if opts.Interface == nil && opts.BindAddr != nil &&
+			(opts.BindAddr.IsLinkLocalMulticast() || opts.BindAddr.IsInterfaceLocalMulticast()) {
+			opts.BindAddr = nil
+		}
+		if opts.BindAddr != nil {
+			copy(sa6.Addr[:], opts.BindAddr.To16())
+		}
+		if opts.Interface != nil {
+			sa6.ZoneId = uint32(opts.Interface.Index)
+		}
+		sa = sa6
+	} else {
+		family = unix.AF_INET
+		sa4 := &unix.SockaddrInet4{Port: group.Port}
+		if opts.BindAddr != nil {
+			copy(sa4.Addr[:], opts.BindAddr.To4())
+		}
+		sa = sa4
+	}
+
+	fd, err := unix.Socket(family, unix.SOCK_DGRAM, unix.IPPROTO_UDP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket: %w", err)
+	}
+	unix.CloseOnExec(fd)
+
+	if err := setListenOptions(fd, opts, ipv6); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	if err := unix.Bind(fd, sa); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind to %s: %w", bindAddrString(opts.BindAddr, group), err)
+	}
+
+	// FilePacketConn duplicates the descriptor, so the file is closed either way
+	file := os.NewFile(uintptr(fd), "mcaster-udp")
+	pc, err := net.FilePacketConn(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create UDP connection: %w", err)
+	}
+	conn := pc.(*net.UDPConn)
+
+	// Unicast addresses are received without a membership
+	if group.IP.IsMulticast() {
+		if err := JoinGroup(conn, opts.Interface, group.IP); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func bindAddrString(bindAddr net.IP, group *net.UDPAddr) string {
+	host := ""
+	if bindAddr != nil {
+		host = bindAddr.String()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(group.Port))
+}
+
+// defaultMaxMemberships is the Linux default of igmp_max_memberships
+const defaultMaxMemberships = 20
+
+// JoinGroup joins a multicast group on conn via iface (nil = kernel choice)
+func JoinGroup(conn *net.UDPConn, iface *net.Interface, group net.IP) error {
+	if err := controlMembership(conn, iface, group, true); err != nil {
+		if errors.Is(err, unix.ENOBUFS) {
+			return fmt.Errorf("failed to join group %s: %w (too many groups on one socket, see net.ipv4.igmp_max_memberships)", group, err)
+		}
+		return fmt.Errorf("failed to join group %s: %w", group, err)
+	}
+	return nil
+}
+
+// LeaveGroup leaves a multicast group previously joined with JoinGroup
+func LeaveGroup(conn *net.UDPConn, iface *net.Interface, group net.IP) error {
+	if err := controlMembership(conn, iface, group, false); err != nil {
+		return fmt.Errorf("failed to leave group %s: %w", group, err)
+	}
+	return nil
+}
+
+func controlMembership(conn *net.UDPConn, iface *net.Interface, group net.IP, join bool) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("failed to get raw connection: %w", err)
+	}
+
+	var setErr error
+	err = rawConn.Control(func(fd uintptr) {
+		setErr = setGroupMembership(int(fd), iface, group, join)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to control socket: %w", err)
+	}
+
+	return setErr
+}
+
+func setListenOptions(fd int, opts ListenOptions, ipv6 bool) error {
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, boolToInt(opts.ReuseAddr)); err != nil {
+		return fmt.Errorf("failed to set SO_REUSEADDR: %w", err)
+	}
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT, boolToInt(opts.ReusePort)); err != nil {
+		return fmt.Errorf("failed to set SO_REUSEPORT: %w", err)
+	}
+	if err := setMulticastAll(fd, opts.MulticastAll, ipv6); err != nil {
+		return fmt.Errorf("failed to set multicast all: %w", err)
+	}
+	return nil
+}
+
+// setGroupMembership6 joins or leaves an IPv6 group on an interface selected by index
+func setGroupMembership6(fd int, iface *net.Interface, group net.IP, join bool) error {
+	mreq := &unix.IPv6Mreq{}
+	copy(mreq.Multiaddr[:], group.To16())
+	if iface != nil {
+		mreq.Interface = uint32(iface.Index)
+	}
+
+	opt := unix.IPV6_JOIN_GROUP
+	if !join {
+		opt = unix.IPV6_LEAVE_GROUP
+	}
+	return unix.SetsockoptIPv6Mreq(fd, unix.IPPROTO_IPV6, opt, mreq)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+package network
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBindAddr(t *testing.T) {
+	group := net.ParseIP("239.23.23.23")
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected net.IP
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "default binds to group",
+			mode:     "",
+			expected: group,
+		},
+		{
+			name:     "group",
+			mode:     BindGroup,
+			expected: group,
+		},
+		{
+			name:     "any",
+			mode:     BindAny,
+			expected: nil,
+		},
+		{
+			name:     "explicit address",
+			mode:     "127.0.0.1",
+			expected: net.ParseIP("127.0.0.1"),
+		},
+		{
+			name:    "invalid mode",
+			mode:    "everything",
+			wantErr: true,
+			errMsg:  "invalid bind mode",
+		},
+		{
+			name:    "address family mismatch",
+			mode:    "::1",
+			wantErr: true,
+			errMsg:  "does not match the address family",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := ParseBindAddr(tt.mode, group)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, tt.expected.Equal(ip), "expected %v, got %v", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestListenMulticastUDP(t *testing.T) {
+	group := &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 23231}
+
+	t.Run("bind to group", func(t *testing.T) {
+		conn, err := ListenMulticastUDP(group, ListenOptions{BindAddr: group.IP, ReuseAddr: true})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		local := conn.LocalAddr().(*net.UDPAddr)
+		assert.Equal(t, "239.23.23.23", local.IP.String())
+		assert.Equal(t, group.Port, local.Port)
+	})
+
+	t.Run("bind to wildcard", func(t *testing.T) {
+		conn, err := ListenMulticastUDP(group, ListenOptions{ReuseAddr: true})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		local := conn.LocalAddr().(*net.UDPAddr)
+		assert.True(t, local.IP.IsUnspecified())
+		assert.Equal(t, group.Port, local.Port)
+	})
+
+	t.Run("shared port with SO_REUSEPORT", func(t *testing.T) {
+		first, err := ListenMulticastUDP(group, ListenOptions{ReusePort: true})
+		require.NoError(t, err)
+		defer first.Close()
+
+		second, err := ListenMulticastUDP(group, ListenOptions{ReusePort: true})
+		require.NoError(t, err)
+		defer second.Close()
+	})
+
+	t.Run("exclusive port without reuse", func(t *testing.T) {
+		first, err := ListenMulticastUDP(group, ListenOptions{})
+		require.NoError(t, err)
+		defer first.Close()
+
+		second, err := ListenMulticastUDP(group, ListenOptions{})
+		assert.Error(t, err)
+		if second != nil {
+			second.Close()
+		}
+	})
+}
+
+func TestListenOnlyJoinedGroups(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("IP_MULTICAST_ALL is Linux specific")
+	}
+
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	joined := &net.UDPAddr{IP: net.ParseIP("239.23.23.24"), Port: 23232}
+	other := &net.UDPAddr{IP: net.ParseIP("239.23.23.25"), Port: 23232}
+
+	// A wildcard listener that joins only one group...
+	conn, err := ListenMulticastUDP(joined, ListenOptions{Interface: lo, ReuseAddr: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// ...next to another application's listener on a different group
+	neighbour, err := ListenMulticastUDP(other, ListenOptions{Interface: lo, ReuseAddr: true})
+	require.NoError(t, err)
+	defer neighbour.Close()
+
+	send := func(group *net.UDPAddr, payload string) {
+		out, err := DialMulticastUDP(group, DialOptions{Interface: lo})
+		require.NoError(t, err)
+		defer out.Close()
+		_, err = out.Write([]byte(payload))
+		require.NoError(t, err)
+	}
+
+	send(other, "other")
+	send(joined, "joined")
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+	assert.Equal(t, "joined", string(buf[:n]))
+}
+
+func TestJoinLeaveGroup(t *testing.T) {
+	group := &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 23233}
+
+	conn, err := ListenMulticastUDP(group, ListenOptions{ReuseAddr: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	extra := net.ParseIP("239.23.23.26")
+	assert.NoError(t, JoinGroup(conn, nil, extra))
+	assert.NoError(t, LeaveGroup(conn, nil, extra))
+
+	// Leaving a group that was never joined is reported
+	err = LeaveGroup(conn, nil, net.ParseIP("239.23.23.27"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to leave group")
+}
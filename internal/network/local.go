@@ -0,0 +1,59 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// LocalHost describes the names and addresses that identify this host
+type LocalHost struct {
+	hostname string
+	addrs    map[string]bool
+}
+
+// NewLocalHost collects the hostname and all interface addresses of this host
+func NewLocalHost() (*LocalHost, error) {
+	hostname, _ := os.Hostname()
+
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local addresses: %w", err)
+	}
+
+	addrs := make(map[string]bool, len(ifaceAddrs))
+	for _, addr := range ifaceAddrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			addrs[ipnet.IP.String()] = true
+		}
+	}
+
+	return &LocalHost{
+		hostname: hostname,
+		addrs:    addrs,
+	}, nil
+}
+
+// Hostname returns the local hostname, or an empty string if it is unknown
+func (h *LocalHost) Hostname() string {
+	return h.hostname
+}
+
+// IsLocalIP reports whether ip is configured on one of this host's interfaces
+func (h *LocalHost) IsLocalIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return h.addrs[ip.String()]
+}
+
+// IsLocalName reports whether name is this host's hostname or one of its addresses
+func (h *LocalHost) IsLocalName(name string) bool {
+	if name == "" {
+		return false
+	}
+	if h.hostname != "" && name == h.hostname {
+		return true
+	}
+	return h.IsLocalIP(net.ParseIP(name))
+}
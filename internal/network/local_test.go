@@ -0,0 +1,97 @@
+package network
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLocalHost(t *testing.T) {
+	host, err := NewLocalHost()
+	require.NoError(t, err)
+	require.NotNil(t, host)
+
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, host.Hostname())
+}
+
+func TestLocalHostIsLocalIP(t *testing.T) {
+	host, err := NewLocalHost()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		ip       net.IP
+		expected bool
+	}{
+		{
+			name:     "loopback address",
+			ip:       net.ParseIP("127.0.0.1"),
+			expected: true,
+		},
+		{
+			name:     "documentation address",
+			ip:       net.ParseIP("203.0.113.254"),
+			expected: false,
+		},
+		{
+			name:     "nil address",
+			ip:       nil,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, host.IsLocalIP(tt.ip))
+		})
+	}
+}
+
+func TestLocalHostIsLocalName(t *testing.T) {
+	host := &LocalHost{
+		hostname: "test-host",
+		addrs:    map[string]bool{"192.0.2.10": true},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected bool
+	}{
+		{
+			name:     "matching hostname",
+			source:   "test-host",
+			expected: true,
+		},
+		{
+			name:     "matching address",
+			source:   "192.0.2.10",
+			expected: true,
+		},
+		{
+			name:     "other hostname",
+			source:   "other-host",
+			expected: false,
+		},
+		{
+			name:     "other address",
+			source:   "192.0.2.11",
+			expected: false,
+		},
+		{
+			name:     "empty source",
+			source:   "",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, host.IsLocalName(tt.source))
+		})
+	}
+}
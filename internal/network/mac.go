@@ -0,0 +1,93 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// MulticastMAC returns the Ethernet destination address a multicast group maps to:
+// 01:00:5e plus the low 23 bits for IPv4 (RFC 1112), 33:33 plus the low 32 bits
+// for IPv6 (RFC 2464). It returns nil for non-multicast addresses.
+func MulticastMAC(ip net.IP) net.HardwareAddr {
+	if !ip.IsMulticast() {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.HardwareAddr{0x01, 0x00, 0x5e, ip4[1] & 0x7f, ip4[2], ip4[3]}
+	}
+
+	ip16 := ip.To16()
+	return net.HardwareAddr{0x33, 0x33, ip16[12], ip16[13], ip16[14], ip16[15]}
+}
+
+// MACAliases returns the other IPv4 groups that map to the same MAC address as
+// ip. Only 23 of the 28 group bits reach the MAC, so every IPv4 MAC is shared by
+// 32 groups. IPv6 groups share a MAC with 2^96 others and return nil.
+func MACAliases(ip net.IP) []net.IP {
+	ip4 := ip.To4()
+	if ip4 == nil || !ip4.IsMulticast() {
+		return nil
+	}
+
+	low23 := binary.BigEndian.Uint32(ip4) & 0x007fffff
+	aliases := make([]net.IP, 0, 31)
+	for high := uint32(0); high < 32; high++ {
+		// 1110 prefix, then the 5 bits that are lost in the mapping
+		value := 0xe0000000 | high<<23 | low23
+		alias := make(net.IP, 4)
+		binary.BigEndian.PutUint32(alias, value)
+		if !alias.Equal(ip4) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+// MACOverlap is a pair of distinct groups that share an Ethernet MAC address
+type MACOverlap struct {
+	A   net.IP
+	B   net.IP
+	MAC net.HardwareAddr
+}
+
+// String describes the overlap as a warning
+func (o MACOverlap) String() string {
+	return fmt.Sprintf("%s and %s share MAC address %s: NICs and switches without IGMP snooping cannot tell them apart",
+		o.A, o.B, o.MAC)
+}
+
+// FindMACOverlaps lists every pair of distinct groups that map to the same MAC address
+func FindMACOverlaps(groups []net.IP) []MACOverlap {
+	var overlaps []MACOverlap
+	seen := make(map[string][]net.IP)
+
+	for _, group := range groups {
+		mac := MulticastMAC(group)
+		if mac == nil {
+			continue
+		}
+
+		key := mac.String()
+		if containsIP(seen[key], group) {
+			continue
+		}
+		for _, other := range seen[key] {
+			overlaps = append(overlaps, MACOverlap{A: other, B: group, MAC: mac})
+		}
+		seen[key] = append(seen[key], group)
+	}
+
+	return overlaps
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, other := range ips {
+		if other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMulticastMAC(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{
+			name:     "IPv4 low bits",
+			ip:       "239.23.23.23",
+			expected: "01:00:5e:17:17:17",
+		},
+		{
+			name:     "IPv4 high bit of second byte dropped",
+			ip:       "224.129.1.1",
+			expected: "01:00:5e:01:01:01",
+		},
+		{
+			name:     "IPv4 all hosts",
+			ip:       "224.0.0.1",
+			expected: "01:00:5e:00:00:01",
+		},
+		{
+			name:     "IPv6 all nodes",
+			ip:       "ff02::1",
+			expected: "33:33:00:00:00:01",
+		},
+		{
+			name:     "IPv6 low 32 bits",
+			ip:       "ff05::1:3",
+			expected: "33:33:00:01:00:03",
+		},
+		{
+			name:     "unicast has no multicast MAC",
+			ip:       "192.0.2.1",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MulticastMAC(net.ParseIP(tt.ip)).String())
+		})
+	}
+}
+
+func TestFindMACOverlaps(t *testing.T) {
+	t.Run("no overlaps", func(t *testing.T) {
+		overlaps := FindMACOverlaps([]net.IP{
+			net.ParseIP("239.1.1.1"),
+			net.ParseIP("239.1.1.2"),
+		})
+		assert.Empty(t, overlaps)
+	})
+
+	t.Run("overlapping pair", func(t *testing.T) {
+		overlaps := FindMACOverlaps([]net.IP{
+			net.ParseIP("239.1.1.1"),
+			net.ParseIP("224.1.1.1"),
+			net.ParseIP("239.1.1.2"),
+		})
+		if assert.Len(t, overlaps, 1) {
+			assert.Equal(t, "239.1.1.1", overlaps[0].A.String())
+			assert.Equal(t, "224.1.1.1", overlaps[0].B.String())
+			assert.Equal(t, "01:00:5e:01:01:01", overlaps[0].MAC.String())
+			assert.Contains(t, overlaps[0].String(), "share MAC address")
+		}
+	})
+
+	t.Run("duplicates are not overlaps", func(t *testing.T) {
+		overlaps := FindMACOverlaps([]net.IP{
+			net.ParseIP("239.1.1.1"),
+			net.ParseIP("224.1.1.1"),
+			net.ParseIP("239.1.1.1"),
+		})
+		assert.Len(t, overlaps, 1)
+	})
+
+	t.Run("three-way overlap", func(t *testing.T) {
+		overlaps := FindMACOverlaps([]net.IP{
+			net.ParseIP("225.1.1.1"),
+			net.ParseIP("226.1.1.1"),
+			net.ParseIP("227.129.1.1"),
+		})
+		assert.Len(t, overlaps, 3)
+	})
+}
+
+func TestMACAliases(t *testing.T) {
+	t.Run("IPv4 group has 31 aliases", func(t *testing.T) {
+		group := net.ParseIP("239.23.23.23")
+		aliases := MACAliases(group)
+		require.Len(t, aliases, 31)
+
+		names := make([]string, len(aliases))
+		for i, alias := range aliases {
+			assert.Equal(t, MulticastMAC(group), MulticastMAC(alias))
+			names[i] = alias.String()
+		}
+		assert.Contains(t, names, "224.23.23.23")
+		assert.Contains(t, names, "224.151.23.23")
+		assert.Contains(t, names, "239.151.23.23")
+		assert.NotContains(t, names, "239.23.23.23")
+	})
+
+	t.Run("IPv6 group", func(t *testing.T) {
+		assert.Nil(t, MACAliases(net.ParseIP("ff15::1")))
+	})
+
+	t.Run("unicast", func(t *testing.T) {
+		assert.Nil(t, MACAliases(net.ParseIP("192.0.2.1")))
+	})
+}
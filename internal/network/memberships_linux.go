@@ -0,0 +1,21 @@
+package network
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MaxMemberships returns how many IPv4 groups one socket may join, from the
+// net.ipv4.igmp_max_memberships sysctl
+func MaxMemberships() int {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/igmp_max_memberships")
+	if err != nil {
+		return defaultMaxMemberships
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n < 1 {
+		return defaultMaxMemberships
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package network
+
+// MaxMemberships returns how many groups one socket may join; BSD systems
+// allow at least IP_MAX_MEMBERSHIPS, traditionally 20
+func MaxMemberships() int {
+	return defaultMaxMemberships
+}
@@ -0,0 +1,81 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// PacketInfo is the per-datagram metadata the kernel reports in control messages
+type PacketInfo struct {
+	// Destination is the address the datagram was sent to, e.g. the group
+	Destination net.IP
+	// Timestamp is the kernel receive time (zero if not reported)
+	Timestamp time.Time
+	// TTL is the IPv4 TTL or IPv6 hop limit (-1 if not reported)
+	TTL int
+}
+
+// EnablePacketInfo enables reporting of the destination address, the kernel
+// receive timestamp and the TTL of every datagram read with ReadMsgUDP
+func EnablePacketInfo(conn *net.UDPConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("failed to get raw connection: %w", err)
+	}
+
+	ipv6 := conn.LocalAddr().(*net.UDPAddr).IP.To4() == nil
+
+	var setErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if setErr = setRecvDestination(int(fd), ipv6); setErr != nil {
+			setErr = fmt.Errorf("failed to enable destination address reporting: %w", setErr)
+			return
+		}
+		if setErr = setRecvTimestamp(int(fd)); setErr != nil {
+			setErr = fmt.Errorf("failed to enable receive timestamps: %w", setErr)
+			return
+		}
+		if setErr = setRecvTTL(int(fd), ipv6); setErr != nil {
+			setErr = fmt.Errorf("failed to enable TTL reporting: %w", setErr)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to control socket: %w", err)
+	}
+
+	return setErr
+}
+
+// ParsePacketInfo extracts the metadata enabled by EnablePacketInfo from the
+// control messages of a datagram
+func ParsePacketInfo(oob []byte) PacketInfo {
+	info := PacketInfo{TTL: -1}
+
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return info
+	}
+
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_PKTINFO && len(msg.Data) >= 16:
+			info.Destination = net.IP(append([]byte(nil), msg.Data[:16]...))
+		case msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_HOPLIMIT && len(msg.Data) >= 4:
+			info.TTL = int(binary.NativeEndian.Uint32(msg.Data))
+		default:
+			if ip := parseDestination4(msg); ip != nil {
+				info.Destination = ip
+			} else if ts, ok := parseTimestamp(msg); ok {
+				info.Timestamp = ts
+			} else if ttl, ok := parseTTL4(msg); ok {
+				info.TTL = ttl
+			}
+		}
+	}
+
+	return info
+}
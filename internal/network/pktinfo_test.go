@@ -0,0 +1,90 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketInfo(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	group := &net.UDPAddr{IP: net.ParseIP("239.23.23.30"), Port: 23235}
+
+	conn, err := ListenMulticastUDP(group, ListenOptions{Interface: lo, ReuseAddr: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, EnablePacketInfo(conn))
+
+	out, err := DialMulticastUDP(group, DialOptions{Interface: lo})
+	require.NoError(t, err)
+	defer out.Close()
+	require.NoError(t, SetMulticastTTL(out, 7))
+
+	before := time.Now()
+	_, err = out.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	oob := make([]byte, 256)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, oobn, _, _, err := conn.ReadMsgUDP(buf, oob)
+	if err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+
+	info := ParsePacketInfo(oob[:oobn])
+	require.NotNil(t, info.Destination)
+	assert.Equal(t, group.IP.String(), info.Destination.String())
+	assert.Equal(t, 7, info.TTL)
+	assert.False(t, info.Timestamp.IsZero())
+	assert.WithinDuration(t, before, info.Timestamp, time.Second)
+}
+
+func TestPacketInfoJoinedGroup(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	first := &net.UDPAddr{IP: net.ParseIP("239.23.23.28"), Port: 23234}
+	second := &net.UDPAddr{IP: net.ParseIP("239.23.23.29"), Port: 23234}
+
+	conn, err := ListenMulticastUDP(first, ListenOptions{Interface: lo, ReuseAddr: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, JoinGroup(conn, lo, second.IP))
+	require.NoError(t, EnablePacketInfo(conn))
+
+	out, err := DialMulticastUDP(second, DialOptions{Interface: lo})
+	require.NoError(t, err)
+	defer out.Close()
+	_, err = out.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	oob := make([]byte, 256)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, oobn, _, _, err := conn.ReadMsgUDP(buf, oob)
+	if err != nil {
+		t.Skipf("multicast loopback not available: %v", err)
+	}
+
+	// The socket is bound to the first group, but the datagram was sent to the second
+	info := ParsePacketInfo(oob[:oobn])
+	require.NotNil(t, info.Destination)
+	assert.Equal(t, second.IP.String(), info.Destination.String())
+}
+
+func TestParsePacketInfoWithoutControlMessages(t *testing.T) {
+	info := ParsePacketInfo(nil)
+	assert.Nil(t, info.Destination)
+	assert.True(t, info.Timestamp.IsZero())
+	assert.Equal(t, -1, info.TTL)
+}
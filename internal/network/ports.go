@@ -0,0 +1,55 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePorts parses a comma-separated list of ports and first-last port ranges,
+// such as 5000-5010,5500
+func ParsePorts(spec string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		firstStr, lastStr, isRange := strings.Cut(part, "-")
+		first, err := parsePort(firstStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q: %w", part, err)
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(lastStr); err != nil {
+				return nil, fmt.Errorf("invalid port range %q: %w", part, err)
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range %q: last port is below the first", part)
+			}
+		}
+
+		for port := first; port <= last; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports in %q", spec)
+	}
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port must be between 1 and 65535, got %q", strings.TrimSpace(s))
+	}
+	return port, nil
+}
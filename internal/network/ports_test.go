@@ -0,0 +1,42 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expected    []int
+		expectError bool
+	}{
+		{name: "single port", spec: "5000", expected: []int{5000}},
+		{name: "range", spec: "5000-5003", expected: []int{5000, 5001, 5002, 5003}},
+		{name: "list and range", spec: "5004, 5000-5001,5000", expected: []int{5004, 5000, 5001}},
+		{name: "reversed range", spec: "5010-5000", expectError: true},
+		{name: "port 0", spec: "0", expectError: true},
+		{name: "too large", spec: "5000-65536", expectError: true},
+		{name: "not a number", spec: "rtp", expectError: true},
+		{name: "empty", spec: " , ", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, err := ParsePorts(tt.spec)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ports)
+		})
+	}
+}
+
+func TestMaxMemberships(t *testing.T) {
+	assert.Positive(t, MaxMemberships())
+}
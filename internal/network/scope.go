@@ -0,0 +1,160 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// Scope classifies a group address by the range it belongs to
+type Scope string
+
+// Scopes reported by ClassifyGroup
+const (
+	ScopeUnicast           Scope = "unicast"
+	ScopeLinkLocal         Scope = "link-local"
+	ScopeInternetwork      Scope = "internetwork control"
+	ScopeSSM               Scope = "source-specific"
+	ScopeGLOP              Scope = "GLOP"
+	ScopeAdHoc             Scope = "ad-hoc"
+	ScopeAdminScoped       Scope = "administratively scoped"
+	ScopeGlobal            Scope = "global"
+	ScopeInterfaceLocal    Scope = "interface-local"
+	ScopeRealmLocal        Scope = "realm-local"
+	ScopeAdminLocal        Scope = "admin-local"
+	ScopeSiteLocal         Scope = "site-local"
+	ScopeOrganizationLocal Scope = "organization-local"
+)
+
+// GroupClass describes what kind of group an address is and what to watch out for
+type GroupClass struct {
+	IP       net.IP
+	Scope    Scope
+	Detail   string
+	Warnings []string
+}
+
+// IsMulticast reports whether the classified address is a multicast group
+func (c GroupClass) IsMulticast() bool {
+	return c.Scope != ScopeUnicast
+}
+
+// String returns a short human readable description of the group
+func (c GroupClass) String() string {
+	if c.Detail != "" {
+		return fmt.Sprintf("%s (%s)", c.Scope, c.Detail)
+	}
+	return string(c.Scope)
+}
+
+var (
+	linkLocal4    = mustCIDR("224.0.0.0/24")
+	internetwork4 = mustCIDR("224.0.1.0/24")
+	ssm4          = mustCIDR("232.0.0.0/8")
+	adHoc4        = mustCIDR("233.252.0.0/14")
+	glop4         = mustCIDR("233.0.0.0/8")
+	adminScoped4  = mustCIDR("239.0.0.0/8")
+	ssm6          = mustCIDR("ff30::/12")
+)
+
+// ClassifyGroup determines the scope of a group address (RFC 5771 for IPv4,
+// RFC 4291/7346 for IPv6) along with any caveats for testing with it
+func ClassifyGroup(ip net.IP) GroupClass {
+	class := GroupClass{IP: ip}
+
+	if !ip.IsMulticast() {
+		class.Scope = ScopeUnicast
+		return class
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		switch {
+		case linkLocal4.Contains(ip4):
+			class.Scope = ScopeLinkLocal
+			class.Detail = "224.0.0.0/24"
+			class.Warnings = append(class.Warnings,
+				fmt.Sprintf("%s is link-local: it is never routed and the TTL is ignored", ip))
+		case internetwork4.Contains(ip4):
+			class.Scope = ScopeInternetwork
+			class.Detail = "224.0.1.0/24"
+		case ssm4.Contains(ip4):
+			class.Scope = ScopeSSM
+			class.Detail = "232.0.0.0/8"
+			class.Warnings = append(class.Warnings,
+				fmt.Sprintf("%s is an SSM group: routers only forward it to (S,G) joins, not to mcaster's (*,G) join", ip))
+		case adHoc4.Contains(ip4):
+			class.Scope = ScopeAdHoc
+			class.Detail = "233.252.0.0/14"
+		case glop4.Contains(ip4):
+			class.Scope = ScopeGLOP
+			class.Detail = fmt.Sprintf("233.0.0.0/8, AS %d", int(ip4[1])<<8|int(ip4[2]))
+		case adminScoped4.Contains(ip4):
+			class.Scope = ScopeAdminScoped
+			class.Detail = "239.0.0.0/8"
+		default:
+			class.Scope = ScopeGlobal
+		}
+		return class
+	}
+
+	if ssm6.Contains(ip) {
+		class.Warnings = append(class.Warnings,
+			fmt.Sprintf("%s is an SSM group: routers only forward it to (S,G) joins, not to mcaster's (*,G) join", ip))
+	}
+
+	// The low nibble of the second byte is the IPv6 multicast scope
+	switch ip[1] & 0x0f {
+	case 0x1:
+		class.Scope = ScopeInterfaceLocal
+		class.Warnings = append(class.Warnings,
+			fmt.Sprintf("%s is interface-local: it never leaves this host", ip))
+	case 0x2:
+		class.Scope = ScopeLinkLocal
+		class.Warnings = append(class.Warnings,
+			fmt.Sprintf("%s is link-local: it is never routed and the hop limit is ignored", ip))
+	case 0x3:
+		class.Scope = ScopeRealmLocal
+	case 0x4:
+		class.Scope = ScopeAdminLocal
+	case 0x5:
+		class.Scope = ScopeSiteLocal
+	case 0x8:
+		class.Scope = ScopeOrganizationLocal
+	default:
+		class.Scope = ScopeGlobal
+	}
+	class.Detail = fmt.Sprintf("ff%02x::/16", ip[1])
+
+	return class
+}
+
+// ValidateGroup rejects non-multicast group addresses unless allowUnicast is set
+func ValidateGroup(ip net.IP, allowUnicast bool) error {
+	if ip == nil {
+		return fmt.Errorf("group address is missing an IP address")
+	}
+	if !ip.IsMulticast() && !allowUnicast {
+		return fmt.Errorf("%s is not a multicast address (use --allow-unicast to test unicast)", ip)
+	}
+	return nil
+}
+
+// GroupWarnings returns the scope caveats for each group together with any
+// Ethernet MAC address overlaps between them
+func GroupWarnings(groups []net.IP) []string {
+	var warnings []string
+	for _, group := range groups {
+		warnings = append(warnings, ClassifyGroup(group).Warnings...)
+	}
+	for _, overlap := range FindMACOverlaps(groups) {
+		warnings = append(warnings, overlap.String())
+	}
+	return warnings
+}
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipnet
+}
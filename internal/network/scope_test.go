@@ -0,0 +1,182 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		ip           string
+		scope        Scope
+		detail       string
+		wantWarnings bool
+	}{
+		{
+			name:  "unicast",
+			ip:    "192.0.2.1",
+			scope: ScopeUnicast,
+		},
+		{
+			name:         "IPv4 link-local",
+			ip:           "224.0.0.251",
+			scope:        ScopeLinkLocal,
+			detail:       "224.0.0.0/24",
+			wantWarnings: true,
+		},
+		{
+			name:   "internetwork control",
+			ip:     "224.0.1.1",
+			scope:  ScopeInternetwork,
+			detail: "224.0.1.0/24",
+		},
+		{
+			name:         "source-specific",
+			ip:           "232.1.1.1",
+			scope:        ScopeSSM,
+			detail:       "232.0.0.0/8",
+			wantWarnings: true,
+		},
+		{
+			name:   "ad-hoc block III",
+			ip:     "233.253.232.1",
+			scope:  ScopeAdHoc,
+			detail: "233.252.0.0/14",
+		},
+		{
+			name:   "GLOP",
+			ip:     "233.1.2.3",
+			scope:  ScopeGLOP,
+			detail: "233.0.0.0/8, AS 258",
+		},
+		{
+			name:   "administratively scoped",
+			ip:     "239.23.23.23",
+			scope:  ScopeAdminScoped,
+			detail: "239.0.0.0/8",
+		},
+		{
+			name:  "global IPv4",
+			ip:    "225.1.1.1",
+			scope: ScopeGlobal,
+		},
+		{
+			name:         "IPv6 interface-local",
+			ip:           "ff01::1",
+			scope:        ScopeInterfaceLocal,
+			detail:       "ff01::/16",
+			wantWarnings: true,
+		},
+		{
+			name:         "IPv6 link-local",
+			ip:           "ff02::1",
+			scope:        ScopeLinkLocal,
+			detail:       "ff02::/16",
+			wantWarnings: true,
+		},
+		{
+			name:   "IPv6 site-local",
+			ip:     "ff05::1:3",
+			scope:  ScopeSiteLocal,
+			detail: "ff05::/16",
+		},
+		{
+			name:   "IPv6 organization-local",
+			ip:     "ff08::1234",
+			scope:  ScopeOrganizationLocal,
+			detail: "ff08::/16",
+		},
+		{
+			name:   "IPv6 global",
+			ip:     "ff0e::1234",
+			scope:  ScopeGlobal,
+			detail: "ff0e::/16",
+		},
+		{
+			name:         "IPv6 source-specific",
+			ip:           "ff3e::8000:1",
+			scope:        ScopeGlobal,
+			detail:       "ff3e::/16",
+			wantWarnings: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class := ClassifyGroup(net.ParseIP(tt.ip))
+
+			assert.Equal(t, tt.scope, class.Scope)
+			assert.Equal(t, tt.detail, class.Detail)
+			assert.Equal(t, tt.scope != ScopeUnicast, class.IsMulticast())
+			if tt.wantWarnings {
+				assert.NotEmpty(t, class.Warnings)
+			} else {
+				assert.Empty(t, class.Warnings)
+			}
+		})
+	}
+}
+
+func TestValidateGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		ip           net.IP
+		allowUnicast bool
+		wantErr      bool
+		errMsg       string
+	}{
+		{
+			name: "multicast group",
+			ip:   net.ParseIP("239.23.23.23"),
+		},
+		{
+			name: "IPv6 multicast group",
+			ip:   net.ParseIP("ff05::1"),
+		},
+		{
+			name:    "unicast rejected",
+			ip:      net.ParseIP("192.0.2.1"),
+			wantErr: true,
+			errMsg:  "is not a multicast address",
+		},
+		{
+			name:         "unicast allowed",
+			ip:           net.ParseIP("192.0.2.1"),
+			allowUnicast: true,
+		},
+		{
+			name:    "missing address",
+			ip:      nil,
+			wantErr: true,
+			errMsg:  "missing an IP address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGroup(tt.ip, tt.allowUnicast)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGroupWarnings(t *testing.T) {
+	warnings := GroupWarnings([]net.IP{
+		net.ParseIP("239.1.1.1"),
+		net.ParseIP("224.0.0.251"),
+		net.ParseIP("239.129.1.1"),
+	})
+
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "link-local")
+	assert.Contains(t, warnings[1], "share MAC address 01:00:5e:01:01:01")
+}
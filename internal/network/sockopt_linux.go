@@ -0,0 +1,106 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// setMulticastInterface selects the outgoing multicast interface by index
+func setMulticastInterface(fd int, iface *net.Interface, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, iface.Index)
+	}
+
+	// ip_mreqn lets the kernel pick the interface by index, so unnumbered
+	// interfaces and interfaces with secondary addresses work as expected
+	mreq := &unix.IPMreqn{Ifindex: int32(iface.Index)}
+	return unix.SetsockoptIPMreqn(fd, unix.IPPROTO_IP, unix.IP_MULTICAST_IF, mreq)
+}
+
+// bindToDevice restricts the socket to a single interface with SO_BINDTODEVICE
+func bindToDevice(fd int, iface *net.Interface) error {
+	return unix.BindToDevice(fd, iface.Name)
+}
+
+// setMulticastAll controls IP_MULTICAST_ALL; when disabled the socket only
+// receives groups that were joined on it, not every group joined on the host
+func setMulticastAll(fd int, enabled, ipv6 bool) error {
+	value := 0
+	if enabled {
+		value = 1
+	}
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_ALL, value)
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_MULTICAST_ALL, value)
+}
+
+// setGroupMembership joins or leaves an IPv4 group on an interface selected by index
+func setGroupMembership(fd int, iface *net.Interface, group net.IP, join bool) error {
+	if group.To4() == nil {
+		return setGroupMembership6(fd, iface, group, join)
+	}
+
+	mreq := &unix.IPMreqn{}
+	copy(mreq.Multiaddr[:], group.To4())
+	if iface != nil {
+		mreq.Ifindex = int32(iface.Index)
+	}
+
+	opt := unix.IP_ADD_MEMBERSHIP
+	if !join {
+		opt = unix.IP_DROP_MEMBERSHIP
+	}
+	return unix.SetsockoptIPMreqn(fd, unix.IPPROTO_IP, opt, mreq)
+}
+
+// setRecvDestination enables IP_PKTINFO so each datagram reports its destination address
+func setRecvDestination(fd int, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_RECVPKTINFO, 1)
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_PKTINFO, 1)
+}
+
+// parseDestination4 extracts ipi_addr from an IP_PKTINFO control message
+func parseDestination4(msg unix.SocketControlMessage) net.IP {
+	if msg.Header.Level != unix.IPPROTO_IP || msg.Header.Type != unix.IP_PKTINFO || len(msg.Data) < 12 {
+		return nil
+	}
+	return net.IPv4(msg.Data[8], msg.Data[9], msg.Data[10], msg.Data[11])
+}
+
+// setRecvTimestamp enables SO_TIMESTAMPNS so each datagram carries its kernel receive time
+func setRecvTimestamp(fd int) error {
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1)
+}
+
+// setRecvTTL enables IP_RECVTTL (IPV6_RECVHOPLIMIT) so each datagram reports its TTL
+func setRecvTTL(fd int, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_RECVHOPLIMIT, 1)
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_RECVTTL, 1)
+}
+
+// parseTimestamp extracts the time from an SCM_TIMESTAMPNS control message
+func parseTimestamp(msg unix.SocketControlMessage) (time.Time, bool) {
+	var ts unix.Timespec
+	if msg.Header.Level != unix.SOL_SOCKET || msg.Header.Type != unix.SCM_TIMESTAMPNS || len(msg.Data) < int(unsafe.Sizeof(ts)) {
+		return time.Time{}, false
+	}
+	ts = *(*unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+	return time.Unix(ts.Unix()), true
+}
+
+// parseTTL4 extracts the TTL from an IP_TTL control message
+func parseTTL4(msg unix.SocketControlMessage) (int, bool) {
+	if msg.Header.Level != unix.IPPROTO_IP || msg.Header.Type != unix.IP_TTL || len(msg.Data) < 4 {
+		return 0, false
+	}
+	return int(binary.NativeEndian.Uint32(msg.Data)), true
+}
@@ -0,0 +1,126 @@
+//go:build !linux
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// setMulticastInterface selects the outgoing multicast interface. IPv6 uses the
+// interface index; IPv4 falls back to the interface's first IPv4 address.
+func setMulticastInterface(fd int, iface *net.Interface, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, iface.Index)
+	}
+
+	ip, err := firstIPv4(iface)
+	if err != nil {
+		return err
+	}
+
+	var addr [4]byte
+	copy(addr[:], ip)
+	return unix.SetsockoptInet4Addr(fd, unix.IPPROTO_IP, unix.IP_MULTICAST_IF, addr)
+}
+
+// bindToDevice is only available on Linux
+func bindToDevice(fd int, iface *net.Interface) error {
+	return fmt.Errorf("SO_BINDTODEVICE is not supported on this platform")
+}
+
+// setMulticastAll is a no-op: BSD-derived stacks only deliver groups joined on the socket
+func setMulticastAll(fd int, enabled, ipv6 bool) error {
+	return nil
+}
+
+// setGroupMembership joins or leaves an IPv4 group on the interface's first IPv4 address
+func setGroupMembership(fd int, iface *net.Interface, group net.IP, join bool) error {
+	if group.To4() == nil {
+		return setGroupMembership6(fd, iface, group, join)
+	}
+
+	mreq := &unix.IPMreq{}
+	copy(mreq.Multiaddr[:], group.To4())
+	if iface != nil {
+		ip, err := firstIPv4(iface)
+		if err != nil {
+			return err
+		}
+		copy(mreq.Interface[:], ip)
+	}
+
+	opt := unix.IP_ADD_MEMBERSHIP
+	if !join {
+		opt = unix.IP_DROP_MEMBERSHIP
+	}
+	return unix.SetsockoptIPMreq(fd, unix.IPPROTO_IP, opt, mreq)
+}
+
+// setRecvDestination enables IP_RECVDSTADDR so each datagram reports its destination address
+func setRecvDestination(fd int, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_RECVPKTINFO, 1)
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_RECVDSTADDR, 1)
+}
+
+// parseDestination4 extracts the address from an IP_RECVDSTADDR control message
+func parseDestination4(msg unix.SocketControlMessage) net.IP {
+	if msg.Header.Level != unix.IPPROTO_IP || msg.Header.Type != unix.IP_RECVDSTADDR || len(msg.Data) < 4 {
+		return nil
+	}
+	return net.IPv4(msg.Data[0], msg.Data[1], msg.Data[2], msg.Data[3])
+}
+
+// setRecvTimestamp enables SO_TIMESTAMP so each datagram carries its kernel receive time
+func setRecvTimestamp(fd int) error {
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMP, 1)
+}
+
+// setRecvTTL enables IP_RECVTTL (IPV6_RECVHOPLIMIT) so each datagram reports its TTL
+func setRecvTTL(fd int, ipv6 bool) error {
+	if ipv6 {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_RECVHOPLIMIT, 1)
+	}
+	return unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_RECVTTL, 1)
+}
+
+// parseTimestamp extracts the time from an SCM_TIMESTAMP control message
+func parseTimestamp(msg unix.SocketControlMessage) (time.Time, bool) {
+	var tv unix.Timeval
+	if msg.Header.Level != unix.SOL_SOCKET || msg.Header.Type != unix.SCM_TIMESTAMP || len(msg.Data) < int(unsafe.Sizeof(tv)) {
+		return time.Time{}, false
+	}
+	tv = *(*unix.Timeval)(unsafe.Pointer(&msg.Data[0]))
+	return time.Unix(tv.Unix()), true
+}
+
+// parseTTL4 extracts the TTL from an IP_RECVTTL control message, which BSD
+// kernels report as a single byte
+func parseTTL4(msg unix.SocketControlMessage) (int, bool) {
+	if msg.Header.Level != unix.IPPROTO_IP || msg.Header.Type != unix.IP_RECVTTL || len(msg.Data) < 1 {
+		return 0, false
+	}
+	return int(msg.Data[0]), true
+}
+
+// firstIPv4 returns the first IPv4 address configured on iface
+func firstIPv4(iface *net.Interface) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface addresses: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no IPv4 address found on interface %s", iface.Name)
+}
@@ -0,0 +1,120 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+const (
+	ethernetHeaderLen = 14
+	ipv4HeaderLen     = 20
+	ipv6HeaderLen     = 40
+	udpHeaderLen      = 8
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	protocolUDP   = 17
+
+	// maxUDPPayload is the largest payload that fits in an IPv4 UDP datagram
+	maxUDPPayload = 65535 - ipv4HeaderLen - udpHeaderLen
+)
+
+// EncodeUDPFrame synthesizes the Ethernet, IP and UDP headers a datagram was
+// carried in. The destination MAC is derived from the group; the source MAC is
+// not known to a UDP socket and is left as zeros. A negative TTL is written as 0.
+func EncodeUDPFrame(src, dst *net.UDPAddr, ttl int, payload []byte) ([]byte, error) {
+	if len(payload) > maxUDPPayload {
+		return nil, fmt.Errorf("payload of %d bytes does not fit in a UDP datagram", len(payload))
+	}
+	if ttl < 0 || ttl > 255 {
+		ttl = 0
+	}
+
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	if (src4 == nil) != (dst4 == nil) {
+		return nil, fmt.Errorf("source %s and destination %s have different address families", src.IP, dst.IP)
+	}
+
+	udpLen := udpHeaderLen + len(payload)
+	ipHeaderLen := ipv4HeaderLen
+	if dst4 == nil {
+		ipHeaderLen = ipv6HeaderLen
+	}
+
+	frame := make([]byte, ethernetHeaderLen+ipHeaderLen+udpLen)
+
+	// Ethernet: the group MAC, or zeros for a unicast destination
+	copy(frame[0:6], network.MulticastMAC(dst.IP))
+	ip := frame[ethernetHeaderLen:]
+	udp := ip[ipHeaderLen:]
+
+	var pseudoHeader []byte
+	if dst4 != nil {
+		binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(ipv4HeaderLen+udpLen))
+		ip[8] = byte(ttl)
+		ip[9] = protocolUDP
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		binary.BigEndian.PutUint16(ip[10:12], checksum(ip[:ipv4HeaderLen], 0))
+
+		pseudoHeader = make([]byte, 12)
+		copy(pseudoHeader[0:8], ip[12:20])
+		pseudoHeader[9] = protocolUDP
+		binary.BigEndian.PutUint16(pseudoHeader[10:12], uint16(udpLen))
+	} else {
+		binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv6)
+
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(udpLen))
+		ip[6] = protocolUDP
+		ip[7] = byte(ttl)
+		copy(ip[8:24], src.IP.To16())
+		copy(ip[24:40], dst.IP.To16())
+
+		pseudoHeader = make([]byte, 40)
+		copy(pseudoHeader[0:32], ip[8:40])
+		binary.BigEndian.PutUint32(pseudoHeader[32:36], uint32(udpLen))
+		pseudoHeader[39] = protocolUDP
+	}
+
+	binary.BigEndian.PutUint16(udp[0:2], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpLen))
+	copy(udp[udpHeaderLen:], payload)
+
+	sum := checksum(udp, sum16(pseudoHeader))
+	if sum == 0 {
+		// An all-zero UDP checksum means "no checksum"
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+
+	return frame, nil
+}
+
+// sum16 adds up data as big-endian 16-bit words
+func sum16(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
+
+// checksum computes the Internet checksum (RFC 1071) of data plus an initial sum
+func checksum(data []byte, initial uint32) uint16 {
+	sum := initial + sum16(data)
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,95 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeUDPFrameIPv4(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 54321}
+	dst := &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 2323}
+	payload := []byte("hello")
+
+	frame, err := EncodeUDPFrame(src, dst, 16, payload)
+	require.NoError(t, err)
+	require.Len(t, frame, 14+20+8+len(payload))
+
+	// Ethernet
+	assert.Equal(t, "01:00:5e:17:17:17", net.HardwareAddr(frame[0:6]).String())
+	assert.Equal(t, "00:00:00:00:00:00", net.HardwareAddr(frame[6:12]).String())
+	assert.Equal(t, uint16(0x0800), binary.BigEndian.Uint16(frame[12:14]))
+
+	// IPv4
+	ip := frame[14:34]
+	assert.Equal(t, byte(0x45), ip[0])
+	assert.Equal(t, uint16(20+8+len(payload)), binary.BigEndian.Uint16(ip[2:4]))
+	assert.Equal(t, byte(16), ip[8])
+	assert.Equal(t, byte(17), ip[9])
+	assert.Equal(t, "192.0.2.10", net.IP(ip[12:16]).String())
+	assert.Equal(t, "239.23.23.23", net.IP(ip[16:20]).String())
+	assert.Equal(t, uint16(0), checksum(ip, 0), "IPv4 header checksum must verify")
+
+	// UDP
+	udp := frame[34:]
+	assert.Equal(t, uint16(54321), binary.BigEndian.Uint16(udp[0:2]))
+	assert.Equal(t, uint16(2323), binary.BigEndian.Uint16(udp[2:4]))
+	assert.Equal(t, uint16(8+len(payload)), binary.BigEndian.Uint16(udp[4:6]))
+	assert.Equal(t, payload, udp[8:])
+
+	pseudo := append(append([]byte{}, ip[12:20]...), 0, 17, 0, byte(len(udp)))
+	assert.Equal(t, uint16(0), checksum(udp, sum16(pseudo)), "UDP checksum must verify")
+}
+
+func TestEncodeUDPFrameIPv6(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1000}
+	dst := &net.UDPAddr{IP: net.ParseIP("ff15::1:3"), Port: 2000}
+	payload := []byte("odd")
+
+	frame, err := EncodeUDPFrame(src, dst, 5, payload)
+	require.NoError(t, err)
+	require.Len(t, frame, 14+40+8+len(payload))
+
+	assert.Equal(t, "33:33:00:01:00:03", net.HardwareAddr(frame[0:6]).String())
+	assert.Equal(t, uint16(0x86dd), binary.BigEndian.Uint16(frame[12:14]))
+
+	ip := frame[14:54]
+	assert.Equal(t, byte(0x60), ip[0])
+	assert.Equal(t, uint16(8+len(payload)), binary.BigEndian.Uint16(ip[4:6]))
+	assert.Equal(t, byte(17), ip[6])
+	assert.Equal(t, byte(5), ip[7])
+	assert.Equal(t, "2001:db8::1", net.IP(ip[8:24]).String())
+	assert.Equal(t, "ff15::1:3", net.IP(ip[24:40]).String())
+
+	udp := frame[54:]
+	pseudo := make([]byte, 40)
+	copy(pseudo, ip[8:40])
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(len(udp)))
+	pseudo[39] = 17
+	assert.Equal(t, uint16(0), checksum(udp, sum16(pseudo)), "UDP checksum must verify")
+}
+
+func TestEncodeUDPFrameErrors(t *testing.T) {
+	v4 := &net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 1}
+	v6 := &net.UDPAddr{IP: net.ParseIP("ff15::1"), Port: 1}
+
+	_, err := EncodeUDPFrame(v6, v4, 1, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "different address families")
+
+	_, err = EncodeUDPFrame(v4, v4, 1, make([]byte, 70000))
+	assert.Error(t, err)
+}
+
+func TestEncodeUDPFrameUnknownTTL(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 1}
+	dst := &net.UDPAddr{IP: net.ParseIP("192.0.2.20"), Port: 2}
+
+	frame, err := EncodeUDPFrame(src, dst, -1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), frame[14+8])
+	assert.Equal(t, "00:00:00:00:00:00", net.HardwareAddr(frame[0:6]).String())
+}
@@ -0,0 +1,243 @@
+// Package pcap reads and writes UDP datagrams in pcap and pcapng captures
+// without libpcap, synthesizing the link, network and transport headers.
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a capture file format
+type Format int
+
+// Supported capture formats
+const (
+	FormatPcap Format = iota
+	FormatPcapNG
+)
+
+const (
+	// linkTypeEthernet is LINKTYPE_ETHERNET
+	linkTypeEthernet = 1
+	snapLen          = 262144
+
+	pcapMagic = 0xa1b2c3d4
+
+	blockTypeSHB        = 0x0a0d0d0a
+	blockTypeIDB        = 0x00000001
+	blockTypeEPB        = 0x00000006
+	byteOrderMagic      = 0x1a2b3c4d
+	optEndOfOpt         = 0
+	optComment          = 1
+	optSHBUserAppl      = 4
+	optIFName           = 2
+	optIFTimeResolution = 9
+)
+
+// Packet is a UDP datagram to be written to a capture
+type Packet struct {
+	// Timestamp is when the datagram was received
+	Timestamp time.Time
+	// Source and Destination are the addresses of the datagram
+	Source      *net.UDPAddr
+	Destination *net.UDPAddr
+	// TTL is the IPv4 TTL or IPv6 hop limit (-1 if unknown)
+	TTL int
+	// Payload is the UDP payload
+	Payload []byte
+	// Comment is stored as a packet comment (pcapng only)
+	Comment string
+}
+
+// Writer writes packets to a capture file
+type Writer struct {
+	w      io.Writer
+	format Format
+}
+
+// FormatForPath picks the capture format from a file extension
+func FormatForPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pcap":
+		return FormatPcap, nil
+	case ".pcapng":
+		return FormatPcapNG, nil
+	}
+	return 0, fmt.Errorf("unsupported capture file %q: use a .pcap or .pcapng extension", path)
+}
+
+// Create creates a capture file, choosing the format from its extension
+func Create(path string) (*Writer, error) {
+	format, err := FormatForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	writer, err := NewWriter(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// NewWriter writes the file header for format to w and returns a Writer for its packets
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	writer := &Writer{w: w, format: format}
+
+	var header []byte
+	switch format {
+	case FormatPcap:
+		header = pcapHeader()
+	case FormatPcapNG:
+		header = append(sectionHeaderBlock(), interfaceDescriptionBlock()...)
+	default:
+		return nil, fmt.Errorf("unknown capture format %d", format)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write capture header: %w", err)
+	}
+
+	return writer, nil
+}
+
+// WritePacket synthesizes the frame for a packet and appends it to the capture.
+// Every packet is written with a single call so the file stays readable while
+// the capture is still running.
+func (w *Writer) WritePacket(pkt Packet) error {
+	frame, err := EncodeUDPFrame(pkt.Source, pkt.Destination, pkt.TTL, pkt.Payload)
+	if err != nil {
+		return err
+	}
+
+	var record []byte
+	if w.format == FormatPcap {
+		record = pcapRecord(pkt.Timestamp, frame)
+	} else {
+		record = enhancedPacketBlock(pkt.Timestamp, frame, pkt.Comment)
+	}
+
+	if _, err := w.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write packet: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying file, if the Writer owns one
+func (w *Writer) Close() error {
+	if closer, ok := w.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func pcapHeader() []byte {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:4], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:6], 2)
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], snapLen)
+	binary.LittleEndian.PutUint32(header[20:24], linkTypeEthernet)
+	return header
+}
+
+func pcapRecord(ts time.Time, frame []byte) []byte {
+	record := make([]byte, 16+len(frame))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(record[4:8], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:12], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(record[12:16], uint32(len(frame)))
+	copy(record[16:], frame)
+	return record
+}
+
+func sectionHeaderBlock() []byte {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body[0:4], byteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:6], 1)
+	binary.LittleEndian.PutUint16(body[6:8], 0)
+	// Section length is unknown while writing
+	binary.LittleEndian.PutUint64(body[8:16], 0xffffffffffffffff)
+
+	body = appendOption(body, optSHBUserAppl, []byte("mcaster"))
+	body = appendOption(body, optEndOfOpt, nil)
+	return block(blockTypeSHB, body)
+}
+
+func interfaceDescriptionBlock() []byte {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:2], linkTypeEthernet)
+	binary.LittleEndian.PutUint32(body[4:8], snapLen)
+
+	body = appendOption(body, optIFName, []byte("mcaster"))
+	// Timestamps are in nanoseconds (10^-9)
+	body = appendOption(body, optIFTimeResolution, []byte{9})
+	body = appendOption(body, optEndOfOpt, nil)
+	return block(blockTypeIDB, body)
+}
+
+func enhancedPacketBlock(ts time.Time, frame []byte, comment string) []byte {
+	var body bytes.Buffer
+
+	nanos := uint64(ts.UnixNano())
+	fields := make([]byte, 20)
+	binary.LittleEndian.PutUint32(fields[0:4], 0)
+	binary.LittleEndian.PutUint32(fields[4:8], uint32(nanos>>32))
+	binary.LittleEndian.PutUint32(fields[8:12], uint32(nanos))
+	binary.LittleEndian.PutUint32(fields[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(fields[16:20], uint32(len(frame)))
+	body.Write(fields)
+	body.Write(pad(frame))
+
+	if comment != "" {
+		options := appendOption(nil, optComment, []byte(comment))
+		body.Write(appendOption(options, optEndOfOpt, nil))
+	}
+
+	return block(blockTypeEPB, body.Bytes())
+}
+
+// appendOption appends a pcapng option padded to 32 bits
+func appendOption(buf []byte, code uint16, value []byte) []byte {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint16(header[0:2], code)
+	binary.LittleEndian.PutUint16(header[2:4], uint16(len(value)))
+	buf = append(buf, header...)
+	return append(buf, pad(value)...)
+}
+
+// block wraps a pcapng block body with its type and both length fields
+func block(blockType uint32, body []byte) []byte {
+	total := 12 + len(body)
+	buf := make([]byte, total)
+	binary.LittleEndian.PutUint32(buf[0:4], blockType)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(total))
+	copy(buf[8:], body)
+	binary.LittleEndian.PutUint32(buf[total-4:], uint32(total))
+	return buf
+}
+
+// pad returns data padded with zeros to a multiple of 4 bytes
+func pad(data []byte) []byte {
+	if len(data)%4 == 0 {
+		return data
+	}
+	padded := make([]byte, len(data)+4-len(data)%4)
+	copy(padded, data)
+	return padded
+}
@@ -0,0 +1,107 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPacket(comment string) Packet {
+	return Packet{
+		Timestamp:   time.Unix(1700000000, 123456789),
+		Source:      &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 54321},
+		Destination: &net.UDPAddr{IP: net.ParseIP("239.23.23.23"), Port: 2323},
+		TTL:         1,
+		Payload:     []byte(`{"id":1}`),
+		Comment:     comment,
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		expected    Format
+		expectError bool
+	}{
+		{path: "out.pcap", expected: FormatPcap},
+		{path: "out.pcapng", expected: FormatPcapNG},
+		{path: "/tmp/OUT.PCAPNG", expected: FormatPcapNG},
+		{path: "out.txt", expectError: true},
+		{path: "out", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			format, err := FormatForPath(tt.path)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
+
+func TestWriterPcap(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, FormatPcap)
+	require.NoError(t, err)
+	require.NoError(t, writer.WritePacket(testPacket("ignored in pcap")))
+
+	data := buf.Bytes()
+	require.GreaterOrEqual(t, len(data), 24+16)
+	assert.Equal(t, uint32(0xa1b2c3d4), binary.LittleEndian.Uint32(data[0:4]))
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(data[20:24]))
+
+	record := data[24:]
+	assert.Equal(t, uint32(1700000000), binary.LittleEndian.Uint32(record[0:4]))
+	assert.Equal(t, uint32(123456), binary.LittleEndian.Uint32(record[4:8]))
+	frameLen := binary.LittleEndian.Uint32(record[8:12])
+	assert.Equal(t, uint32(14+20+8+8), frameLen)
+	assert.Equal(t, frameLen, binary.LittleEndian.Uint32(record[12:16]))
+	assert.Len(t, record, 16+int(frameLen))
+	assert.NotContains(t, string(data), "ignored in pcap")
+}
+
+func TestWriterPcapNG(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, FormatPcapNG)
+	require.NoError(t, err)
+	require.NoError(t, writer.WritePacket(testPacket("mcaster packet #1")))
+	require.NoError(t, writer.WritePacket(testPacket("")))
+
+	// Walk the blocks: every length must be a multiple of 4 and repeated at the end
+	var types []uint32
+	data := buf.Bytes()
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 12)
+		blockType := binary.LittleEndian.Uint32(data[0:4])
+		length := binary.LittleEndian.Uint32(data[4:8])
+		require.Zero(t, length%4)
+		require.LessOrEqual(t, int(length), len(data))
+		assert.Equal(t, length, binary.LittleEndian.Uint32(data[length-4:length]))
+
+		if blockType == blockTypeEPB {
+			ts := uint64(binary.LittleEndian.Uint32(data[12:16]))<<32 | uint64(binary.LittleEndian.Uint32(data[16:20]))
+			assert.Equal(t, uint64(time.Unix(1700000000, 123456789).UnixNano()), ts)
+			assert.Equal(t, uint32(50), binary.LittleEndian.Uint32(data[20:24]))
+		}
+
+		types = append(types, blockType)
+		data = data[length:]
+	}
+
+	assert.Equal(t, []uint32{blockTypeSHB, blockTypeIDB, blockTypeEPB, blockTypeEPB}, types)
+	assert.Contains(t, buf.String(), "mcaster packet #1")
+}
+
+func TestWriterRejectsUnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, Format(42))
+	assert.Error(t, err)
+}
@@ -0,0 +1,499 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"os"
+	"time"
+)
+
+// Link types understood by DecodeFrame
+const (
+	linkTypeNull     = 0
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+
+	pcapMagicNanos = 0xa1b23c4d
+
+	blockTypeOPB = 0x00000002
+	blockTypeSPB = 0x00000003
+)
+
+// ErrNotUDP is returned by DecodeFrame for frames that are not complete UDP datagrams
+var ErrNotUDP = errors.New("not a UDP datagram")
+
+// pcapngInterface holds the parts of an interface description block needed to
+// decode its packets
+type pcapngInterface struct {
+	linkType int
+	// unitsPerSecond is the timestamp resolution (if_tsresol)
+	unitsPerSecond uint64
+}
+
+// Reader reads the UDP datagrams of a pcap or pcapng capture
+type Reader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	ng     bool
+	order  binary.ByteOrder
+
+	// Classic pcap
+	linkType int
+	nanos    bool
+
+	// pcapng
+	interfaces []pcapngInterface
+
+	skipped int
+}
+
+// Open opens a capture file for reading
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	reader.closer = file
+
+	return reader, nil
+}
+
+// IsCapture reports whether header starts like a pcap or pcapng file
+func IsCapture(header []byte) bool {
+	if len(header) < 4 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(header) {
+	case pcapMagic, pcapMagicNanos, blockTypeSHB:
+		return true
+	}
+	switch binary.BigEndian.Uint32(header) {
+	case pcapMagic, pcapMagicNanos:
+		return true
+	}
+	return false
+}
+
+// NewReader reads the file header from r, detecting the format and byte order
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture header: %w", err)
+	}
+
+	if binary.LittleEndian.Uint32(magic) == blockTypeSHB {
+		reader.ng = true
+		if err := reader.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return nil, fmt.Errorf("failed to read capture header: %w", err)
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:4]) {
+		case pcapMagic:
+			reader.order = order
+		case pcapMagicNanos:
+			reader.order = order
+			reader.nanos = true
+		}
+	}
+	if reader.order == nil {
+		return nil, fmt.Errorf("not a pcap or pcapng file")
+	}
+	reader.linkType = int(reader.order.Uint32(header[20:24]) & 0xffff)
+
+	return reader, nil
+}
+
+// ReadPacket returns the next UDP datagram in the capture. Frames that are not
+// UDP, are IP fragments or were truncated by the snap length are skipped and
+// counted by Skipped. It returns io.EOF at the end of the capture.
+func (r *Reader) ReadPacket() (Packet, error) {
+	for {
+		var (
+			pkt Packet
+			err error
+		)
+		if r.ng {
+			pkt, err = r.readBlock()
+		} else {
+			pkt, err = r.readRecord()
+		}
+		if errors.Is(err, ErrNotUDP) {
+			r.skipped++
+			continue
+		}
+		return pkt, err
+	}
+}
+
+// Skipped returns how many frames were not UDP datagrams so far
+func (r *Reader) Skipped() int {
+	return r.skipped
+}
+
+// Close closes the underlying file, if the Reader opened one
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+func (r *Reader) readRecord() (Packet, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Packet{}, fmt.Errorf("truncated capture record: %w", err)
+		}
+		return Packet{}, err
+	}
+
+	sec := int64(r.order.Uint32(header[0:4]))
+	frac := int64(r.order.Uint32(header[4:8]))
+	capLen := r.order.Uint32(header[8:12])
+	origLen := r.order.Uint32(header[12:16])
+	if capLen > snapLen*4 {
+		return Packet{}, fmt.Errorf("invalid capture record length %d", capLen)
+	}
+
+	frame := make([]byte, capLen)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		return Packet{}, fmt.Errorf("truncated capture record: %w", err)
+	}
+	if capLen < origLen {
+		return Packet{}, ErrNotUDP
+	}
+
+	if !r.nanos {
+		frac *= 1000
+	}
+	pkt, err := DecodeFrame(r.linkType, frame)
+	pkt.Timestamp = time.Unix(sec, frac)
+	return pkt, err
+}
+
+// readSectionHeader reads a pcapng section header block, which sets the byte
+// order of the blocks that follow
+func (r *Reader) readSectionHeader() error {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return fmt.Errorf("failed to read section header: %w", err)
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(header[8:12]) == byteOrderMagic:
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[8:12]) == byteOrderMagic:
+		r.order = binary.BigEndian
+	default:
+		return fmt.Errorf("invalid pcapng byte-order magic")
+	}
+
+	length := r.order.Uint32(header[4:8])
+	if length < 28 || length%4 != 0 {
+		return fmt.Errorf("invalid pcapng section header length %d", length)
+	}
+	if _, err := io.CopyN(io.Discard, r.r, int64(length)-12); err != nil {
+		return fmt.Errorf("failed to read section header: %w", err)
+	}
+
+	r.interfaces = nil
+	return nil
+}
+
+func (r *Reader) readBlock() (Packet, error) {
+	for {
+		header, err := r.r.Peek(8)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(header) == 0 {
+				return Packet{}, io.EOF
+			}
+			return Packet{}, fmt.Errorf("truncated pcapng block: %w", err)
+		}
+
+		if binary.LittleEndian.Uint32(header) == blockTypeSHB {
+			if err := r.readSectionHeader(); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+
+		blockType := r.order.Uint32(header[0:4])
+		length := r.order.Uint32(header[4:8])
+		if length < 12 || length%4 != 0 || length > snapLen*4 {
+			return Packet{}, fmt.Errorf("invalid pcapng block length %d", length)
+		}
+
+		block := make([]byte, length)
+		if _, err := io.ReadFull(r.r, block); err != nil {
+			return Packet{}, fmt.Errorf("truncated pcapng block: %w", err)
+		}
+		body := block[8 : length-4]
+
+		switch blockType {
+		case blockTypeIDB:
+			if err := r.readInterface(body); err != nil {
+				return Packet{}, err
+			}
+		case blockTypeEPB:
+			return r.readEnhancedPacket(body)
+		case blockTypeSPB, blockTypeOPB:
+			// Packets without usable timestamps cannot be replayed in time
+			r.skipped++
+		}
+	}
+}
+
+func (r *Reader) readInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("invalid pcapng interface description block")
+	}
+
+	iface := pcapngInterface{
+		linkType:       int(r.order.Uint16(body[0:2])),
+		unitsPerSecond: 1000000,
+	}
+
+	for _, opt := range r.options(body[8:]) {
+		if opt.code == optIFTimeResolution && len(opt.value) >= 1 {
+			units, err := timeResolution(opt.value[0])
+			if err != nil {
+				return err
+			}
+			iface.unitsPerSecond = units
+		}
+	}
+
+	r.interfaces = append(r.interfaces, iface)
+	return nil
+}
+
+// timeResolution returns the timestamp units per second of an if_tsresol
+// option: a negative power of 2 if the top bit is set, of 10 otherwise.
+// Resolutions whose units per second do not fit in 64 bits are rejected.
+func timeResolution(resolution byte) (uint64, error) {
+	exponent := int(resolution & 0x7f)
+	if resolution&0x80 != 0 {
+		if exponent > 63 {
+			return 0, fmt.Errorf("unsupported pcapng timestamp resolution 2^-%d", exponent)
+		}
+		return uint64(1) << exponent, nil
+	}
+	if exponent > 19 {
+		return 0, fmt.Errorf("unsupported pcapng timestamp resolution 10^-%d", exponent)
+	}
+	units := uint64(1)
+	for i := 0; i < exponent; i++ {
+		units *= 10
+	}
+	return units, nil
+}
+
+func (r *Reader) readEnhancedPacket(body []byte) (Packet, error) {
+	if len(body) < 20 {
+		return Packet{}, fmt.Errorf("invalid pcapng enhanced packet block")
+	}
+
+	ifaceID := int(r.order.Uint32(body[0:4]))
+	if ifaceID >= len(r.interfaces) {
+		return Packet{}, fmt.Errorf("pcapng packet refers to unknown interface %d", ifaceID)
+	}
+	iface := r.interfaces[ifaceID]
+
+	ts := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+	capLen := int(r.order.Uint32(body[12:16]))
+	origLen := int(r.order.Uint32(body[16:20]))
+	if 20+capLen > len(body) {
+		return Packet{}, fmt.Errorf("invalid pcapng packet length %d", capLen)
+	}
+	frame := body[20 : 20+capLen]
+
+	if capLen < origLen {
+		return Packet{}, ErrNotUDP
+	}
+
+	pkt, err := DecodeFrame(iface.linkType, frame)
+	sec := ts / iface.unitsPerSecond
+	// The remainder times 10^9 overflows 64 bits for resolutions finer than
+	// about 10^-10, so it is computed on 128 bits
+	hi, lo := bits.Mul64(ts%iface.unitsPerSecond, uint64(time.Second))
+	nanos, _ := bits.Div64(hi, lo, iface.unitsPerSecond)
+	pkt.Timestamp = time.Unix(int64(sec), int64(nanos))
+
+	optStart := 20 + len(pad(frame))
+	if optStart < len(body) {
+		for _, opt := range r.options(body[optStart:]) {
+			if opt.code == optComment {
+				pkt.Comment = string(opt.value)
+			}
+		}
+	}
+
+	return pkt, err
+}
+
+type option struct {
+	code  uint16
+	value []byte
+}
+
+// options parses a pcapng option list
+func (r *Reader) options(data []byte) []option {
+	var opts []option
+	for len(data) >= 4 {
+		code := r.order.Uint16(data[0:2])
+		length := int(r.order.Uint16(data[2:4]))
+		if code == optEndOfOpt || 4+length > len(data) {
+			break
+		}
+		opts = append(opts, option{code: code, value: data[4 : 4+length]})
+		data = data[4+len(pad(data[4:4+length])):]
+	}
+	return opts
+}
+
+// DecodeFrame extracts the UDP datagram from a captured frame of the given
+// link type. It returns ErrNotUDP for anything else.
+func DecodeFrame(linkType int, frame []byte) (Packet, error) {
+	var ip []byte
+
+	switch linkType {
+	case linkTypeEthernet:
+		if len(frame) < ethernetHeaderLen {
+			return Packet{}, ErrNotUDP
+		}
+		etherType := binary.BigEndian.Uint16(frame[12:14])
+		offset := ethernetHeaderLen
+		// Skip 802.1Q and 802.1ad VLAN tags
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(frame) >= offset+4 {
+			etherType = binary.BigEndian.Uint16(frame[offset+2 : offset+4])
+			offset += 4
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return Packet{}, ErrNotUDP
+		}
+		ip = frame[offset:]
+	case linkTypeNull:
+		if len(frame) < 4 {
+			return Packet{}, ErrNotUDP
+		}
+		ip = frame[4:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		ip = frame
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return Packet{}, ErrNotUDP
+		}
+		ip = frame[16:]
+	case linkTypeSLL2:
+		if len(frame) < 20 {
+			return Packet{}, ErrNotUDP
+		}
+		ip = frame[20:]
+	default:
+		return Packet{}, fmt.Errorf("unsupported link type %d", linkType)
+	}
+
+	return decodeIP(ip)
+}
+
+func decodeIP(ip []byte) (Packet, error) {
+	if len(ip) < 1 {
+		return Packet{}, ErrNotUDP
+	}
+
+	var (
+		pkt Packet
+		udp []byte
+	)
+
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < ipv4HeaderLen {
+			return Packet{}, ErrNotUDP
+		}
+		headerLen := int(ip[0]&0x0f) * 4
+		totalLen := int(binary.BigEndian.Uint16(ip[2:4]))
+		fragment := binary.BigEndian.Uint16(ip[6:8])
+		if headerLen < ipv4HeaderLen || totalLen < headerLen || totalLen > len(ip) || ip[9] != protocolUDP {
+			return Packet{}, ErrNotUDP
+		}
+		// More fragments, or a fragment offset: only whole datagrams can be replayed
+		if fragment&0x3fff != 0 {
+			return Packet{}, ErrNotUDP
+		}
+		pkt.Source = &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[12:16]...))}
+		pkt.Destination = &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[16:20]...))}
+		pkt.TTL = int(ip[8])
+		udp = ip[headerLen:totalLen]
+	case 6:
+		if len(ip) < ipv6HeaderLen {
+			return Packet{}, ErrNotUDP
+		}
+		payloadLen := int(binary.BigEndian.Uint16(ip[4:6]))
+		if ipv6HeaderLen+payloadLen > len(ip) {
+			return Packet{}, ErrNotUDP
+		}
+		pkt.Source = &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[8:24]...))}
+		pkt.Destination = &net.UDPAddr{IP: net.IP(append([]byte(nil), ip[24:40]...))}
+		pkt.TTL = int(ip[7])
+
+		next := ip[6]
+		rest := ip[ipv6HeaderLen : ipv6HeaderLen+payloadLen]
+		// Walk hop-by-hop, routing and destination options headers
+		for next == 0 || next == 43 || next == 60 {
+			if len(rest) < 8 {
+				return Packet{}, ErrNotUDP
+			}
+			extLen := (int(rest[1]) + 1) * 8
+			if extLen > len(rest) {
+				return Packet{}, ErrNotUDP
+			}
+			next = rest[0]
+			rest = rest[extLen:]
+		}
+		if next != protocolUDP {
+			return Packet{}, ErrNotUDP
+		}
+		udp = rest
+	default:
+		return Packet{}, ErrNotUDP
+	}
+
+	if len(udp) < udpHeaderLen {
+		return Packet{}, ErrNotUDP
+	}
+	udpLen := int(binary.BigEndian.Uint16(udp[4:6]))
+	if udpLen < udpHeaderLen || udpLen > len(udp) {
+		return Packet{}, ErrNotUDP
+	}
+
+	pkt.Source.Port = int(binary.BigEndian.Uint16(udp[0:2]))
+	pkt.Destination.Port = int(binary.BigEndian.Uint16(udp[2:4]))
+	pkt.Payload = append([]byte(nil), udp[udpHeaderLen:udpLen]...)
+
+	return pkt, nil
+}
@@ -0,0 +1,250 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderRoundTrip(t *testing.T) {
+	packets := []Packet{
+		testPacket("first"),
+		{
+			Timestamp:   time.Unix(1700000001, 500000000),
+			Source:      &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1000},
+			Destination: &net.UDPAddr{IP: net.ParseIP("ff15::1"), Port: 2000},
+			TTL:         9,
+			Payload:     []byte{0x47, 0x00, 0x11},
+		},
+	}
+
+	for _, format := range []Format{FormatPcap, FormatPcapNG} {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, format)
+		require.NoError(t, err)
+		for _, pkt := range packets {
+			require.NoError(t, writer.WritePacket(pkt))
+		}
+
+		assert.True(t, IsCapture(buf.Bytes()))
+
+		reader, err := NewReader(&buf)
+		require.NoError(t, err)
+
+		for i, expected := range packets {
+			pkt, err := reader.ReadPacket()
+			require.NoError(t, err)
+
+			assert.Equal(t, expected.Source.String(), pkt.Source.String())
+			assert.Equal(t, expected.Destination.String(), pkt.Destination.String())
+			assert.Equal(t, expected.TTL, pkt.TTL)
+			assert.Equal(t, expected.Payload, pkt.Payload)
+
+			if format == FormatPcapNG {
+				assert.True(t, expected.Timestamp.Equal(pkt.Timestamp), "packet %d timestamp", i)
+				assert.Equal(t, expected.Comment, pkt.Comment)
+			} else {
+				// Classic pcap stores microseconds
+				assert.True(t, expected.Timestamp.Truncate(time.Microsecond).Equal(pkt.Timestamp), "packet %d timestamp", i)
+			}
+		}
+
+		_, err = reader.ReadPacket()
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Zero(t, reader.Skipped())
+	}
+}
+
+func TestReaderBigEndianNanosecondPcap(t *testing.T) {
+	frame, err := EncodeUDPFrame(
+		&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1},
+		&net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 2}, 4, []byte("x"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.BigEndian.PutUint32(header[0:4], pcapMagicNanos)
+	binary.BigEndian.PutUint16(header[4:6], 2)
+	binary.BigEndian.PutUint16(header[6:8], 4)
+	binary.BigEndian.PutUint32(header[16:20], snapLen)
+	binary.BigEndian.PutUint32(header[20:24], linkTypeEthernet)
+	buf.Write(header)
+
+	record := make([]byte, 16)
+	binary.BigEndian.PutUint32(record[0:4], 100)
+	binary.BigEndian.PutUint32(record[4:8], 42)
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(frame)))
+	binary.BigEndian.PutUint32(record[12:16], uint32(len(frame)))
+	buf.Write(record)
+	buf.Write(frame)
+
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	pkt, err := reader.ReadPacket()
+	require.NoError(t, err)
+	assert.True(t, time.Unix(100, 42).Equal(pkt.Timestamp))
+	assert.Equal(t, "239.1.1.1:2", pkt.Destination.String())
+	assert.Equal(t, []byte("x"), pkt.Payload)
+}
+
+// pcapngWithResolution returns a pcapng capture of one frame whose interface
+// has the given if_tsresol and whose timestamp is ts in its units
+func pcapngWithResolution(t *testing.T, resolution byte, ts uint64) []byte {
+	frame, err := EncodeUDPFrame(
+		&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1},
+		&net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 2}, 4, []byte("x"))
+	require.NoError(t, err)
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:2], linkTypeEthernet)
+	binary.LittleEndian.PutUint32(idb[4:8], snapLen)
+	idb = appendOption(idb, optIFTimeResolution, []byte{resolution})
+	idb = appendOption(idb, optEndOfOpt, nil)
+
+	epb := make([]byte, 20)
+	binary.LittleEndian.PutUint32(epb[4:8], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:12], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(epb[16:20], uint32(len(frame)))
+	epb = append(epb, pad(frame)...)
+
+	var buf bytes.Buffer
+	buf.Write(sectionHeaderBlock())
+	buf.Write(block(blockTypeIDB, idb))
+	buf.Write(block(blockTypeEPB, epb))
+	return buf.Bytes()
+}
+
+func TestReaderTimeResolution(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolution byte
+		ts         uint64
+		expected   time.Time
+	}{
+		{name: "microseconds", resolution: 6, ts: 1700000000_123456, expected: time.Unix(1700000000, 123456000)},
+		{name: "2^-30", resolution: 0x80 | 30, ts: 5<<30 | 1<<29, expected: time.Unix(5, 500000000)},
+		// The remainder times 10^9 does not fit in 64 bits at the finest resolutions
+		{name: "10^-19", resolution: 19, ts: 15_000000000_000000000, expected: time.Unix(1, 500000000)},
+		{name: "2^-63", resolution: 0x80 | 63, ts: 1<<63 | 1<<62, expected: time.Unix(1, 500000000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(bytes.NewReader(pcapngWithResolution(t, tt.resolution, tt.ts)))
+			require.NoError(t, err)
+			pkt, err := reader.ReadPacket()
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(pkt.Timestamp), "got %v", pkt.Timestamp)
+		})
+	}
+}
+
+func TestReaderRejectsTimeResolution(t *testing.T) {
+	// Units per second that do not fit in 64 bits used to divide by zero or overflow
+	for _, resolution := range []byte{0x80 | 64, 0xff, 20, 127} {
+		reader, err := NewReader(bytes.NewReader(pcapngWithResolution(t, resolution, 1)))
+		if err == nil {
+			_, err = reader.ReadPacket()
+		}
+		assert.ErrorContains(t, err, "unsupported pcapng timestamp resolution", "resolution 0x%02x", resolution)
+	}
+}
+
+func TestReaderRejectsGarbage(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("this is not a capture file")))
+	assert.Error(t, err)
+	assert.False(t, IsCapture([]byte(`{"source":"x"}`)))
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.pcap"))
+	assert.Error(t, err)
+}
+
+func TestDecodeFrame(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1111}
+	dst := &net.UDPAddr{IP: net.ParseIP("239.1.1.1"), Port: 2222}
+	frame, err := EncodeUDPFrame(src, dst, 8, []byte("payload"))
+	require.NoError(t, err)
+	ip := frame[ethernetHeaderLen:]
+
+	vlan := append(append(append([]byte{}, frame[:12]...), 0x81, 0x00, 0x00, 0x0a), frame[12:]...)
+
+	sll := make([]byte, 16)
+	binary.BigEndian.PutUint16(sll[14:16], etherTypeIPv4)
+	sll = append(sll, ip...)
+
+	null := append([]byte{2, 0, 0, 0}, ip...)
+
+	fragment := append([]byte{}, frame...)
+	fragment[ethernetHeaderLen+6] = 0x20 // more fragments
+
+	arp := append([]byte{}, frame...)
+	binary.BigEndian.PutUint16(arp[12:14], 0x0806)
+
+	tests := []struct {
+		name      string
+		linkType  int
+		frame     []byte
+		expectUDP bool
+	}{
+		{name: "ethernet", linkType: linkTypeEthernet, frame: frame, expectUDP: true},
+		{name: "ethernet with VLAN tag", linkType: linkTypeEthernet, frame: vlan, expectUDP: true},
+		{name: "raw IP", linkType: linkTypeRaw, frame: ip, expectUDP: true},
+		{name: "Linux cooked", linkType: linkTypeLinuxSLL, frame: sll, expectUDP: true},
+		{name: "BSD loopback", linkType: linkTypeNull, frame: null, expectUDP: true},
+		{name: "IP fragment", linkType: linkTypeEthernet, frame: fragment},
+		{name: "ARP", linkType: linkTypeEthernet, frame: arp},
+		{name: "short frame", linkType: linkTypeEthernet, frame: frame[:20]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkt, err := DecodeFrame(tt.linkType, tt.frame)
+			if !tt.expectUDP {
+				assert.ErrorIs(t, err, ErrNotUDP)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, src.String(), pkt.Source.String())
+			assert.Equal(t, dst.String(), pkt.Destination.String())
+			assert.Equal(t, 8, pkt.TTL)
+			assert.Equal(t, []byte("payload"), pkt.Payload)
+		})
+	}
+
+	_, err = DecodeFrame(9999, frame)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotUDP)
+}
+
+func TestReaderSkipsNonUDP(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, FormatPcap)
+	require.NoError(t, err)
+	require.NoError(t, writer.WritePacket(testPacket("")))
+
+	// Append an ARP frame by hand
+	frame := make([]byte, 42)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0806)
+	buf.Write(pcapRecord(time.Unix(1, 0), frame))
+	require.NoError(t, writer.WritePacket(testPacket("")))
+
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := reader.ReadPacket()
+		require.NoError(t, err)
+	}
+	_, err = reader.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 1, reader.Skipped())
+}
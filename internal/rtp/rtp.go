@@ -0,0 +1,158 @@
+// Package rtp parses and builds RTP (RFC 3550) packet headers.
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Version is the only RTP version in use
+const Version = 2
+
+// HeaderSize is the size of the fixed RTP header, without CSRCs or extensions
+const HeaderSize = 12
+
+// ErrNotRTP is returned for data that cannot be an RTP packet
+var ErrNotRTP = errors.New("not an RTP packet")
+
+// Header is a parsed RTP header
+type Header struct {
+	Padding     bool
+	Extension   bool
+	Marker      bool
+	PayloadType uint8
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	CSRC        []uint32
+	// PayloadOffset is where the payload starts in the packet
+	PayloadOffset int
+	// PayloadSize excludes any padding
+	PayloadSize int
+}
+
+// Parse parses the RTP header at the start of data
+func Parse(data []byte) (*Header, error) {
+	if len(data) < HeaderSize || data[0]>>6 != Version {
+		return nil, ErrNotRTP
+	}
+
+	h := &Header{
+		Padding:     data[0]&0x20 != 0,
+		Extension:   data[0]&0x10 != 0,
+		Marker:      data[1]&0x80 != 0,
+		PayloadType: data[1] & 0x7f,
+		Sequence:    binary.BigEndian.Uint16(data[2:]),
+		Timestamp:   binary.BigEndian.Uint32(data[4:]),
+		SSRC:        binary.BigEndian.Uint32(data[8:]),
+	}
+
+	offset := HeaderSize
+	csrcCount := int(data[0] & 0x0f)
+	if len(data) < offset+4*csrcCount {
+		return nil, fmt.Errorf("%w: %d CSRCs do not fit in %d bytes", ErrNotRTP, csrcCount, len(data))
+	}
+	for i := 0; i < csrcCount; i++ {
+		h.CSRC = append(h.CSRC, binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+	}
+
+	if h.Extension {
+		if len(data) < offset+4 {
+			return nil, fmt.Errorf("%w: truncated header extension", ErrNotRTP)
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(data[offset+2:]))
+		if len(data) < offset {
+			return nil, fmt.Errorf("%w: truncated header extension", ErrNotRTP)
+		}
+	}
+
+	end := len(data)
+	if h.Padding {
+		padding := int(data[len(data)-1])
+		if padding == 0 || end-padding < offset {
+			return nil, fmt.Errorf("%w: invalid padding", ErrNotRTP)
+		}
+		end -= padding
+	}
+
+	h.PayloadOffset = offset
+	h.PayloadSize = end - offset
+	return h, nil
+}
+
+// Append appends the header, without extension or padding, and the payload to b
+func (h *Header) Append(b, payload []byte) []byte {
+	first := byte(Version<<6) | byte(len(h.CSRC)&0x0f)
+	second := h.PayloadType & 0x7f
+	if h.Marker {
+		second |= 0x80
+	}
+	b = append(b, first, second)
+	b = binary.BigEndian.AppendUint16(b, h.Sequence)
+	b = binary.BigEndian.AppendUint32(b, h.Timestamp)
+	b = binary.BigEndian.AppendUint32(b, h.SSRC)
+	for _, csrc := range h.CSRC {
+		b = binary.BigEndian.AppendUint32(b, csrc)
+	}
+	return append(b, payload...)
+}
+
+// IsRTCP reports whether a packet with this payload type byte would be RTCP
+// (types 200-204 read as marker bit plus payload type 72-76)
+func IsRTCP(data []byte) bool {
+	return len(data) >= 2 && data[0]>>6 == Version && data[1] >= 200 && data[1] <= 204
+}
+
+// PayloadTypeName returns the name of a statically assigned payload type
+// (RFC 3551), or "dynamic"/"unassigned"
+func PayloadTypeName(pt uint8) string {
+	if name, ok := staticPayloadTypes[pt]; ok {
+		return name
+	}
+	if pt >= 96 && pt <= 127 {
+		return "dynamic"
+	}
+	return "unassigned"
+}
+
+// ClockRate returns the clock rate of a statically assigned payload type, or 0
+// if it is not known
+func ClockRate(pt uint8) int {
+	return staticClockRates[pt]
+}
+
+var staticPayloadTypes = map[uint8]string{
+	0:  "PCMU",
+	3:  "GSM",
+	4:  "G723",
+	5:  "DVI4",
+	6:  "DVI4",
+	7:  "LPC",
+	8:  "PCMA",
+	9:  "G722",
+	10: "L16",
+	11: "L16",
+	12: "QCELP",
+	13: "CN",
+	14: "MPA",
+	15: "G728",
+	16: "DVI4",
+	17: "DVI4",
+	18: "G729",
+	25: "CelB",
+	26: "JPEG",
+	28: "nv",
+	31: "H261",
+	32: "MPV",
+	33: "MP2T",
+	34: "H263",
+}
+
+var staticClockRates = map[uint8]int{
+	0: 8000, 3: 8000, 4: 8000, 5: 8000, 6: 16000, 7: 8000, 8: 8000, 9: 8000,
+	10: 44100, 11: 44100, 12: 8000, 13: 8000, 14: 90000, 15: 8000, 16: 11025,
+	17: 22050, 18: 8000, 25: 90000, 26: 90000, 28: 90000, 31: 90000, 32: 90000,
+	33: 90000, 34: 90000,
+}
@@ -0,0 +1,86 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *Header
+	}{
+		{
+			name: "basic",
+			data: []byte{0x80, 0xa1, 0x12, 0x34, 0, 0, 0x10, 0, 0xde, 0xad, 0xbe, 0xef, 'x', 'y'},
+			expected: &Header{Marker: true, PayloadType: 33, Sequence: 0x1234, Timestamp: 0x1000,
+				SSRC: 0xdeadbeef, PayloadOffset: 12, PayloadSize: 2},
+		},
+		{
+			name: "CSRC, extension and padding",
+			data: []byte{
+				0xb1, 96, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3,
+				0, 0, 0, 4, // CSRC
+				0xbe, 0xde, 0, 1, 1, 2, 3, 4, // one-word extension
+				'p', 'a', 'y', 0, 0, 2, // payload and two bytes of padding
+			},
+			expected: &Header{Padding: true, Extension: true, PayloadType: 96, Sequence: 1, Timestamp: 2,
+				SSRC: 3, CSRC: []uint32{4}, PayloadOffset: 24, PayloadSize: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Parse(tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, h)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: []byte{0x80, 0, 0}},
+		{name: "version 1", data: make([]byte, 12)},
+		{name: "missing CSRC", data: []byte{0x82, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}},
+		{name: "missing extension", data: []byte{0x90, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xbe, 0xde}},
+		{name: "padding beyond payload", data: []byte{0xa0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			assert.ErrorIs(t, err, ErrNotRTP)
+		})
+	}
+}
+
+func TestPayloadTypes(t *testing.T) {
+	assert.Equal(t, "MP2T", PayloadTypeName(33))
+	assert.Equal(t, "dynamic", PayloadTypeName(96))
+	assert.Equal(t, "unassigned", PayloadTypeName(50))
+	assert.Equal(t, 90000, ClockRate(33))
+	assert.Equal(t, 8000, ClockRate(0))
+	assert.Equal(t, 0, ClockRate(96))
+
+	assert.True(t, IsRTCP([]byte{0x80, 200}))
+	assert.False(t, IsRTCP([]byte{0x80, 96}))
+}
+
+func TestAppend(t *testing.T) {
+	h := &Header{Marker: true, PayloadType: 96, Sequence: 65535, Timestamp: 90000, SSRC: 0xdeadbeef, CSRC: []uint32{7}}
+	data := h.Append(nil, []byte("payload"))
+	require.Len(t, data, HeaderSize+4+7)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, &Header{Marker: true, PayloadType: 96, Sequence: 65535, Timestamp: 90000,
+		SSRC: 0xdeadbeef, CSRC: []uint32{7}, PayloadOffset: 16, PayloadSize: 7}, parsed)
+	assert.Equal(t, "payload", string(data[parsed.PayloadOffset:]))
+}
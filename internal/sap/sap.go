@@ -0,0 +1,109 @@
+// Package sap parses and builds Session Announcement Protocol (RFC 2974)
+// packets and the SDP (RFC 4566) session descriptions they carry.
+package sap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Port is the well-known SAP port
+const Port = 9875
+
+// Address is the group for announcements of globally scoped IPv4 sessions
+const Address = "224.2.127.254"
+
+// sdpType is the default payload type of SAP announcements
+const sdpType = "application/sdp"
+
+// ErrNotSAP is returned for data that is not a SAP packet
+var ErrNotSAP = errors.New("not a SAP packet")
+
+// Packet is a parsed SAP packet
+type Packet struct {
+	// Deletion is set for session deletion messages, clear for announcements
+	Deletion    bool
+	Encrypted   bool
+	Compressed  bool
+	MessageHash uint16
+	Origin      net.IP
+	PayloadType string
+	Payload     []byte
+}
+
+// Parse parses a SAP packet
+func Parse(data []byte) (*Packet, error) {
+	if len(data) < 4 || data[0]>>5 != 1 {
+		return nil, ErrNotSAP
+	}
+
+	p := &Packet{
+		Deletion:    data[0]&0x04 != 0,
+		Encrypted:   data[0]&0x02 != 0,
+		Compressed:  data[0]&0x01 != 0,
+		MessageHash: uint16(data[2])<<8 | uint16(data[3]),
+	}
+
+	originLen := net.IPv4len
+	if data[0]&0x10 != 0 {
+		originLen = net.IPv6len
+	}
+	offset := 4 + originLen + 4*int(data[1])
+	if len(data) < offset {
+		return nil, fmt.Errorf("%w: truncated header", ErrNotSAP)
+	}
+	p.Origin = net.IP(append([]byte(nil), data[4:4+originLen]...))
+
+	payload := data[offset:]
+	p.PayloadType = sdpType
+	// The payload type is optional; SDP always starts with "v="
+	if !bytes.HasPrefix(payload, []byte("v=")) && !p.Encrypted && !p.Compressed {
+		end := bytes.IndexByte(payload, 0)
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated payload type", ErrNotSAP)
+		}
+		p.PayloadType = string(payload[:end])
+		payload = payload[end+1:]
+	}
+	p.Payload = payload
+
+	return p, nil
+}
+
+// Marshal encodes the packet without authentication data. Encrypted and
+// compressed payloads are not supported.
+func (p *Packet) Marshal() ([]byte, error) {
+	if p.Encrypted || p.Compressed {
+		return nil, fmt.Errorf("encrypted and compressed SAP packets are not supported")
+	}
+
+	flags := byte(0x20)
+	origin := p.Origin.To4()
+	if origin == nil {
+		origin = p.Origin.To16()
+		if origin == nil {
+			return nil, fmt.Errorf("invalid SAP origin %v", p.Origin)
+		}
+		flags |= 0x10
+	}
+	if p.Deletion {
+		flags |= 0x04
+	}
+
+	data := []byte{flags, 0, byte(p.MessageHash >> 8), byte(p.MessageHash)}
+	data = append(data, origin...)
+	payloadType := p.PayloadType
+	if payloadType == "" {
+		payloadType = sdpType
+	}
+	data = append(data, payloadType...)
+	data = append(data, 0)
+	return append(data, p.Payload...), nil
+}
+
+// IsSDP reports whether the packet carries a readable session description
+func (p *Packet) IsSDP() bool {
+	return p.PayloadType == sdpType && !p.Encrypted && !p.Compressed
+}
@@ -0,0 +1,88 @@
+package sap
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSDP = "v=0\r\no=- 1 1 IN IP4 192.0.2.10\r\ns=Channel 1\r\nc=IN IP4 239.1.1.1/32\r\nt=0 0\r\nm=video 5000 RTP/AVP 33\r\n"
+
+func TestParse(t *testing.T) {
+	header := []byte{0x20, 0, 0x12, 0x34, 192, 0, 2, 10}
+
+	t.Run("with payload type", func(t *testing.T) {
+		data := append(append(append([]byte(nil), header...), "application/sdp\x00"...), testSDP...)
+		p, err := Parse(data)
+		require.NoError(t, err)
+		assert.False(t, p.Deletion)
+		assert.Equal(t, uint16(0x1234), p.MessageHash)
+		assert.True(t, p.Origin.Equal(net.ParseIP("192.0.2.10")))
+		assert.True(t, p.IsSDP())
+		assert.Equal(t, testSDP, string(p.Payload))
+	})
+
+	t.Run("without payload type", func(t *testing.T) {
+		data := append(append([]byte(nil), header...), testSDP...)
+		data[0] |= 0x04
+		p, err := Parse(data)
+		require.NoError(t, err)
+		assert.True(t, p.Deletion)
+		assert.True(t, p.IsSDP())
+	})
+
+	t.Run("IPv6 origin with authentication data", func(t *testing.T) {
+		data := []byte{0x30, 1, 0, 1}
+		data = append(data, net.ParseIP("2001:db8::1")...)
+		data = append(data, 0, 0, 0, 0)
+		data = append(data, testSDP...)
+		p, err := Parse(data)
+		require.NoError(t, err)
+		assert.Equal(t, "2001:db8::1", p.Origin.String())
+		assert.Equal(t, testSDP, string(p.Payload))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, data := range [][]byte{
+			{0x80, 0, 0, 0, 1, 2, 3, 4},
+			{0x20, 0, 0},
+			{0x20, 4, 0, 0, 1, 2, 3, 4},
+			append(append([]byte(nil), header...), "application/sdp"...),
+		} {
+			_, err := Parse(data)
+			assert.ErrorIs(t, err, ErrNotSAP)
+		}
+	})
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet Packet
+	}{
+		{name: "IPv4 announcement", packet: Packet{MessageHash: 0x1234, Origin: net.ParseIP("192.0.2.10"), Payload: []byte(testSDP)}},
+		{name: "IPv6 deletion", packet: Packet{Deletion: true, MessageHash: 1, Origin: net.ParseIP("2001:db8::1"), Payload: []byte(testSDP)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.packet.Marshal()
+			require.NoError(t, err)
+
+			p, err := Parse(data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.packet.Deletion, p.Deletion)
+			assert.Equal(t, tt.packet.MessageHash, p.MessageHash)
+			assert.True(t, p.Origin.Equal(tt.packet.Origin))
+			assert.True(t, p.IsSDP())
+			assert.Equal(t, testSDP, string(p.Payload))
+		})
+	}
+
+	_, err := (&Packet{Origin: net.ParseIP("192.0.2.10"), Compressed: true}).Marshal()
+	assert.Error(t, err)
+	_, err = (&Packet{}).Marshal()
+	assert.Error(t, err)
+}
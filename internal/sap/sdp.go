@@ -0,0 +1,169 @@
+package sap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Session is the subset of an SDP session description mcaster uses
+type Session struct {
+	Origin     string
+	Name       string
+	Info       string
+	Connection string
+	Attributes []string
+	Media      []Media
+}
+
+// Media is one m= line of a session description
+type Media struct {
+	Type       string
+	Port       int
+	Protocol   string
+	Formats    []string
+	Connection string
+	Attributes []string
+}
+
+// ParseSDP parses a session description. Unknown lines are ignored.
+func ParseSDP(text string) (*Session, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "v=") {
+		return nil, fmt.Errorf("invalid SDP: must start with v=")
+	}
+
+	s := &Session{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || len(key) != 1 {
+			continue
+		}
+
+		switch key {
+		case "o":
+			s.Origin = value
+		case "s":
+			s.Name = value
+		case "i":
+			if len(s.Media) == 0 {
+				s.Info = value
+			}
+		case "c":
+			if len(s.Media) > 0 {
+				s.Media[len(s.Media)-1].Connection = value
+			} else {
+				s.Connection = value
+			}
+		case "a":
+			if len(s.Media) > 0 {
+				m := &s.Media[len(s.Media)-1]
+				m.Attributes = append(m.Attributes, value)
+			} else {
+				s.Attributes = append(s.Attributes, value)
+			}
+		case "m":
+			media, err := parseMedia(value)
+			if err != nil {
+				return nil, err
+			}
+			s.Media = append(s.Media, media)
+		}
+	}
+	return s, nil
+}
+
+func parseMedia(value string) (Media, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return Media{}, fmt.Errorf("invalid SDP media line %q", value)
+	}
+	port, _, _ := strings.Cut(fields[1], "/")
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return Media{}, fmt.Errorf("invalid SDP media port %q", fields[1])
+	}
+	return Media{Type: fields[0], Port: portNum, Protocol: fields[2], Formats: fields[3:]}, nil
+}
+
+// String formats the session description as SDP, for a session that is
+// permanently active
+func (s *Session) String() string {
+	var b strings.Builder
+	line := func(key, value string) {
+		if value != "" {
+			b.WriteString(key + "=" + value + "\r\n")
+		}
+	}
+
+	line("v", "0")
+	line("o", s.Origin)
+	name := s.Name
+	if name == "" {
+		name = "-"
+	}
+	line("s", name)
+	line("i", s.Info)
+	line("c", s.Connection)
+	line("t", "0 0")
+	for _, a := range s.Attributes {
+		line("a", a)
+	}
+	for _, m := range s.Media {
+		line("m", strings.Join(append([]string{m.Type, strconv.Itoa(m.Port), m.Protocol}, m.Formats...), " "))
+		line("c", m.Connection)
+		for _, a := range m.Attributes {
+			line("a", a)
+		}
+	}
+	return b.String()
+}
+
+// Attribute returns the value of the first session attribute with the given
+// name, as in a=name:value
+func (s *Session) Attribute(name string) (string, bool) {
+	for _, a := range s.Attributes {
+		key, value, _ := strings.Cut(a, ":")
+		if key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Address returns the connection address of a media stream without TTL or
+// count suffixes, falling back to the session connection
+func (s *Session) Address(m Media) string {
+	conn := m.Connection
+	if conn == "" {
+		conn = s.Connection
+	}
+	// "IN IP4 239.1.1.1/32"
+	fields := strings.Fields(conn)
+	if len(fields) < 3 {
+		return ""
+	}
+	addr, _, _ := strings.Cut(fields[2], "/")
+	return addr
+}
+
+// Summary describes the session in one line
+func (s *Session) Summary() string {
+	var streams []string
+	for _, m := range s.Media {
+		stream := fmt.Sprintf("%s %s:%d %s", m.Type, s.Address(m), m.Port, m.Protocol)
+		if len(m.Formats) > 0 {
+			stream += " " + strings.Join(m.Formats, " ")
+		}
+		streams = append(streams, stream)
+	}
+	name := s.Name
+	if name == "" || name == "-" {
+		name = "(unnamed)"
+	}
+	if len(streams) == 0 {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("%q: %s", name, strings.Join(streams, ", "))
+}
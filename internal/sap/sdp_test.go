@@ -0,0 +1,57 @@
+package sap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSDP(t *testing.T) {
+	s, err := ParseSDP(testSDP + "m=audio 5002 RTP/AVP 0\r\nc=IN IP4 239.1.1.2/16\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Channel 1", s.Name)
+	assert.Equal(t, "- 1 1 IN IP4 192.0.2.10", s.Origin)
+	require.Len(t, s.Media, 2)
+	assert.Equal(t, Media{Type: "video", Port: 5000, Protocol: "RTP/AVP", Formats: []string{"33"}}, s.Media[0])
+	assert.Equal(t, "239.1.1.1", s.Address(s.Media[0]))
+	assert.Equal(t, "239.1.1.2", s.Address(s.Media[1]))
+	assert.Equal(t, `"Channel 1": video 239.1.1.1:5000 RTP/AVP 33, audio 239.1.1.2:5002 RTP/AVP 0`, s.Summary())
+}
+
+func TestParseSDPErrors(t *testing.T) {
+	_, err := ParseSDP("s=no version\r\n")
+	assert.Error(t, err)
+	_, err = ParseSDP("v=0\r\nm=video\r\n")
+	assert.Error(t, err)
+	_, err = ParseSDP("v=0\r\nm=video x RTP/AVP 33\r\n")
+	assert.Error(t, err)
+
+	s, err := ParseSDP("v=0\r\ns=-\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, `"(unnamed)"`, s.Summary())
+}
+
+func TestSessionString(t *testing.T) {
+	s := &Session{
+		Origin:     "- 42 1 IN IP4 192.0.2.10",
+		Name:       "mcaster 239.1.1.1:5004",
+		Connection: "IN IP4 239.1.1.1/8",
+		Attributes: []string{"tool:mcaster", "type:broadcast"},
+		Media: []Media{{Type: "video", Port: 5004, Protocol: "RTP/AVP", Formats: []string{"96"},
+			Attributes: []string{"rtpmap:96 mcaster/90000"}}},
+	}
+	text := s.String()
+	assert.Equal(t, "v=0\r\no=- 42 1 IN IP4 192.0.2.10\r\ns=mcaster 239.1.1.1:5004\r\nc=IN IP4 239.1.1.1/8\r\n"+
+		"t=0 0\r\na=tool:mcaster\r\na=type:broadcast\r\nm=video 5004 RTP/AVP 96\r\na=rtpmap:96 mcaster/90000\r\n", text)
+
+	parsed, err := ParseSDP(text)
+	require.NoError(t, err)
+	assert.Equal(t, s, parsed)
+
+	tool, ok := parsed.Attribute("tool")
+	assert.True(t, ok)
+	assert.Equal(t, "mcaster", tool)
+	_, ok = parsed.Attribute("missing")
+	assert.False(t, ok)
+}
@@ -0,0 +1,66 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// Expectation is what receivers should get of streams during a phase. It
+// applies to every receiver and stream unless it names one.
+type Expectation struct {
+	Receiver string `yaml:"receiver,omitempty"`
+	Stream   string `yaml:"stream,omitempty"`
+	// MaxLoss is the highest share of messages that may be lost, in percent
+	MaxLoss *float64 `yaml:"max_loss,omitempty"`
+	// MaxDelay is the highest 99th percentile delay
+	MaxDelay *server.Duration `yaml:"max_delay,omitempty"`
+	// Received requires some messages (true) or none at all (false)
+	Received *bool `yaml:"received,omitempty"`
+}
+
+// String describes the expectation's requirements
+func (e Expectation) String() string {
+	var parts []string
+	if e.Received != nil {
+		if *e.Received {
+			parts = append(parts, "received")
+		} else {
+			parts = append(parts, "nothing received")
+		}
+	}
+	if e.MaxLoss != nil {
+		parts = append(parts, fmt.Sprintf("loss ≤ %g%%", *e.MaxLoss))
+	}
+	if e.MaxDelay != nil {
+		parts = append(parts, fmt.Sprintf("p99 delay ≤ %v", time.Duration(*e.MaxDelay)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// check returns why a path's result fails the expectation, or "" if it passes
+func (e Expectation) check(r PathResult) string {
+	var failures []string
+	if e.Received != nil {
+		switch {
+		case *e.Received && r.Received == 0:
+			failures = append(failures, "nothing received")
+		case !*e.Received && r.Received > 0:
+			failures = append(failures, fmt.Sprintf("%d received", r.Received))
+		}
+	}
+	if e.MaxLoss != nil && r.LossPercent > *e.MaxLoss {
+		failures = append(failures, fmt.Sprintf("loss %.1f%%", r.LossPercent))
+	}
+	if e.MaxDelay != nil {
+		switch {
+		case r.DelayP99 == nil:
+			failures = append(failures, "no delay measured")
+		case *r.DelayP99 > *e.MaxDelay:
+			failures = append(failures, fmt.Sprintf("p99 delay %v", time.Duration(*r.DelayP99)))
+		}
+	}
+	return strings.Join(failures, ", ")
+}
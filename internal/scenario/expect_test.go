@@ -0,0 +1,70 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+func TestExpectation(t *testing.T) {
+	yes, no := true, false
+	loss := 1.0
+	delay := server.Duration(10 * time.Millisecond)
+	fast, slow := server.Duration(time.Millisecond), server.Duration(20*time.Millisecond)
+
+	tests := []struct {
+		name    string
+		expect  Expectation
+		result  PathResult
+		want    string
+		failure string
+	}{
+		{
+			name:   "received",
+			expect: Expectation{Received: &yes},
+			result: PathResult{Received: 10},
+			want:   "received",
+		},
+		{
+			name:    "nothing received",
+			expect:  Expectation{Received: &yes},
+			want:    "received",
+			failure: "nothing received",
+		},
+		{
+			name:    "received after leaving",
+			expect:  Expectation{Received: &no},
+			result:  PathResult{Received: 3},
+			want:    "nothing received",
+			failure: "3 received",
+		},
+		{
+			name:   "loss within bounds",
+			expect: Expectation{MaxLoss: &loss, MaxDelay: &delay},
+			result: PathResult{Received: 100, Lost: 1, LossPercent: 0.99, DelayP50: &fast, DelayP99: &fast},
+			want:   "loss ≤ 1%, p99 delay ≤ 10ms",
+		},
+		{
+			name:    "loss and delay too high",
+			expect:  Expectation{MaxLoss: &loss, MaxDelay: &delay},
+			result:  PathResult{Received: 90, Lost: 10, LossPercent: 10, DelayP50: &fast, DelayP99: &slow},
+			want:    "loss ≤ 1%, p99 delay ≤ 10ms",
+			failure: "loss 10.0%, p99 delay 20ms",
+		},
+		{
+			name:    "no delay",
+			expect:  Expectation{MaxDelay: &delay},
+			want:    "p99 delay ≤ 10ms",
+			failure: "no delay measured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.expect.String())
+			assert.Equal(t, tt.failure, tt.expect.check(tt.result))
+		})
+	}
+}
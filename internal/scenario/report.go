@@ -0,0 +1,160 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+	"github.com/hyposcaler-bot/mcaster/internal/table"
+)
+
+// Report is the outcome of a scenario run, phase by phase
+type Report struct {
+	Scenario string        `json:"scenario" yaml:"scenario"`
+	Started  time.Time     `json:"started" yaml:"started"`
+	Finished time.Time     `json:"finished" yaml:"finished"`
+	Passed   bool          `json:"passed" yaml:"passed"`
+	Phases   []PhaseReport `json:"phases" yaml:"phases"`
+}
+
+// PhaseReport is what happened during one phase
+type PhaseReport struct {
+	Name    string          `json:"name" yaml:"name"`
+	Started time.Time       `json:"started" yaml:"started"`
+	Elapsed server.Duration `json:"elapsed" yaml:"elapsed"`
+	Actions []ActionReport  `json:"actions,omitempty" yaml:"actions,omitempty"`
+	// Sent counts the messages sent per stream
+	Sent   map[string]int `json:"sent" yaml:"sent"`
+	Paths  []PathResult   `json:"paths" yaml:"paths"`
+	Checks []CheckResult  `json:"checks,omitempty" yaml:"checks,omitempty"`
+	Passed bool           `json:"passed" yaml:"passed"`
+}
+
+// ActionReport records when an action ran
+type ActionReport struct {
+	Action string    `json:"action" yaml:"action"`
+	Time   time.Time `json:"time" yaml:"time"`
+}
+
+// PathResult is what a receiver got of a stream during a phase
+type PathResult struct {
+	Receiver string `json:"receiver" yaml:"receiver"`
+	Stream   string `json:"stream" yaml:"stream"`
+	// Sent counts the messages of the stream sent during the phase
+	Sent     int `json:"sent" yaml:"sent"`
+	Received int `json:"received" yaml:"received"`
+	// Lost counts the gaps in message IDs noticed during the phase; a
+	// receiver that left notices the messages it missed when it joins again
+	Lost        int     `json:"lost" yaml:"lost"`
+	LossPercent float64 `json:"loss_percent" yaml:"loss_percent"`
+	// DelayP50 and DelayP99 are the delay percentiles of the latest messages
+	// at the end of the phase, unset if the receiver had left
+	DelayP50 *server.Duration `json:"delay_p50,omitempty" yaml:"delay_p50,omitempty"`
+	DelayP99 *server.Duration `json:"delay_p99,omitempty" yaml:"delay_p99,omitempty"`
+}
+
+// CheckResult is the outcome of an expectation for one path
+type CheckResult struct {
+	Receiver    string `json:"receiver" yaml:"receiver"`
+	Stream      string `json:"stream" yaml:"stream"`
+	Expectation string `json:"expectation" yaml:"expectation"`
+	Passed      bool   `json:"passed" yaml:"passed"`
+	// Failure says why the check failed
+	Failure string `json:"failure,omitempty" yaml:"failure,omitempty"`
+}
+
+// Failed counts the checks that failed in every phase
+func (r *Report) Failed() int {
+	failed := 0
+	for _, phase := range r.Phases {
+		for _, check := range phase.Checks {
+			if !check.Passed {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// Checks counts the checks of every phase
+func (r *Report) Checks() int {
+	checks := 0
+	for _, phase := range r.Phases {
+		checks += len(phase.Checks)
+	}
+	return checks
+}
+
+// Write saves the report to a file, as YAML if its name ends in .yaml or
+// .yml and as JSON otherwise
+func (r *Report) Write(path string) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(r)
+	default:
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// Print writes a table per phase of what every receiver got of every stream,
+// followed by the checks of the phase
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n📋 Scenario %s\n", r.Scenario)
+	for _, phase := range r.Phases {
+		fmt.Fprintf(w, "\n▶️  %s (%v)\n", phase.Name, time.Duration(phase.Elapsed).Round(time.Millisecond))
+		for _, action := range phase.Actions {
+			fmt.Fprintf(w, "   🔧 +%v %s\n", action.Time.Sub(phase.Started).Round(time.Millisecond), action.Action)
+		}
+		printPaths(w, phase.Paths)
+		for _, check := range phase.Checks {
+			if check.Passed {
+				fmt.Fprintf(w, "   ✅ %s ← %s: %s\n", check.Receiver, check.Stream, check.Expectation)
+			} else {
+				fmt.Fprintf(w, "   ❌ %s ← %s: %s (%s)\n", check.Receiver, check.Stream, check.Expectation, check.Failure)
+			}
+		}
+	}
+
+	fmt.Fprintln(w)
+	switch failed := r.Failed(); {
+	case failed > 0:
+		fmt.Fprintf(w, "❌ %d of %d check(s) failed\n", failed, r.Checks())
+	case !r.Passed:
+		fmt.Fprintf(w, "❌ Scenario did not complete\n")
+	default:
+		fmt.Fprintf(w, "✅ All %d check(s) passed\n", r.Checks())
+	}
+}
+
+// printPaths writes the paths of a phase as a table
+func printPaths(w io.Writer, paths []PathResult) {
+	rows := [][]string{{"RECEIVER", "STREAM", "SENT", "RECEIVED", "LOSS", "P50", "P99"}}
+	for _, p := range paths {
+		p50, p99 := "-", "-"
+		if p.DelayP50 != nil {
+			p50 = table.FormatDelay(time.Duration(*p.DelayP50))
+			p99 = table.FormatDelay(time.Duration(*p.DelayP99))
+		}
+		rows = append(rows, []string{p.Receiver, p.Stream, fmt.Sprint(p.Sent), fmt.Sprint(p.Received),
+			fmt.Sprintf("%.1f%%", p.LossPercent), p50, p99})
+	}
+
+	table.Write(w, "   ", rows)
+}
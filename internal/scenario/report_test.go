@@ -0,0 +1,108 @@
+package scenario
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+func testReport() *Report {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p50, p99 := server.Duration(1100*time.Microsecond), server.Duration(2*time.Millisecond)
+	return &Report{
+		Scenario: "failover",
+		Started:  start,
+		Finished: start.Add(3 * time.Second),
+		Phases: []PhaseReport{
+			{
+				Name:    "steady",
+				Started: start,
+				Elapsed: server.Duration(time.Second),
+				Sent:    map[string]int{"video": 10},
+				Paths:   []PathResult{{Receiver: "leaf", Stream: "video", Sent: 10, Received: 10, DelayP50: &p50, DelayP99: &p99}},
+				Checks:  []CheckResult{{Receiver: "leaf", Stream: "video", Expectation: "loss ≤ 1%", Passed: true}},
+				Passed:  true,
+			},
+			{
+				Name:    "rejoin",
+				Started: start.Add(time.Second),
+				Elapsed: server.Duration(2 * time.Second),
+				Actions: []ActionReport{{Action: "leave leaf", Time: start.Add(time.Second)}, {Action: "join leaf", Time: start.Add(2 * time.Second)}},
+				Sent:    map[string]int{"video": 20},
+				Paths:   []PathResult{{Receiver: "leaf", Stream: "video", Sent: 20, Received: 10, Lost: 10, LossPercent: 50}},
+				Checks:  []CheckResult{{Receiver: "leaf", Stream: "video", Expectation: "loss ≤ 1%", Failure: "loss 50.0%"}},
+			},
+		},
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	var out strings.Builder
+	report := testReport()
+	report.Print(&out)
+	assert.Equal(t, 2, report.Checks())
+	assert.Equal(t, 1, report.Failed())
+
+	assert.Equal(t, `
+📋 Scenario failover
+
+▶️  steady (1s)
+   RECEIVER  STREAM  SENT  RECEIVED  LOSS  P50    P99
+   leaf      video   10    10        0.0%  1.1ms  2ms
+   ✅ leaf ← video: loss ≤ 1%
+
+▶️  rejoin (2s)
+   🔧 +0s leave leaf
+   🔧 +1s join leaf
+   RECEIVER  STREAM  SENT  RECEIVED  LOSS   P50  P99
+   leaf      video   20    10        50.0%  -    -
+   ❌ leaf ← video: loss ≤ 1% (loss 50.0%)
+
+❌ 1 of 2 check(s) failed
+`, out.String())
+
+	out.Reset()
+	report.Phases = report.Phases[:1]
+	report.Print(&out)
+	assert.Contains(t, out.String(), "❌ Scenario did not complete")
+
+	out.Reset()
+	report.Passed = true
+	report.Print(&out)
+	assert.Contains(t, out.String(), "✅ All 1 check(s) passed")
+}
+
+func TestReportWrite(t *testing.T) {
+	dir := t.TempDir()
+	report := testReport()
+
+	path := filepath.Join(dir, "report.json")
+	require.NoError(t, report.Write(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"loss_percent": 50`)
+	assert.Contains(t, string(data), `"delay_p50": "1.1ms"`)
+	var decoded Report
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report.Phases[1].Paths, decoded.Phases[1].Paths)
+
+	path = filepath.Join(dir, "report.yaml")
+	require.NoError(t, report.Write(path))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "failure: loss 50.0%")
+	decoded = Report{}
+	require.NoError(t, yaml.Unmarshal(data, &decoded))
+	assert.Equal(t, report.Phases[0].Paths, decoded.Phases[0].Paths)
+
+	assert.ErrorContains(t, report.Write(filepath.Join(dir, "missing", "report.json")), "failed to write report")
+}
@@ -0,0 +1,392 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/clock"
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// stream is a stream of a running scenario. It is sent by a new sender each
+// time it starts.
+type stream struct {
+	Stream
+	interval server.Duration
+	sender   *multicast.Sender
+	done     chan struct{}
+	// sent counts the messages of the senders closed
+	sent int
+}
+
+// receiver is a receiver of a running scenario. It keeps its socket for the
+// whole run, leaving and joining its groups on it.
+type receiver struct {
+	Receiver
+	receiver *multicast.Receiver
+	done     chan struct{}
+	// groups are the groups the receiver joined when it opened
+	groups []*net.UDPAddr
+	joined bool
+}
+
+// tally counts the messages of a stream received and lost
+type tally struct {
+	received int
+	lost     int
+}
+
+// runner holds the state of a scenario while it runs
+type runner struct {
+	sc        *Scenario
+	out       io.Writer
+	streams   map[string]*stream
+	receivers map[string]*receiver
+	// sessions maps the session of every sender started to its stream
+	sessions map[multicast.SessionID]string
+}
+
+// Run executes a scenario: it joins the receivers, starts the streams and
+// goes through the phases, checking the expectations of each. Cancelling ctx
+// ends the run early; the report then covers the phases so far and does not
+// pass.
+func Run(ctx context.Context, sc *Scenario, out io.Writer) (*Report, error) {
+	r := &runner{
+		sc:        sc,
+		out:       out,
+		streams:   make(map[string]*stream),
+		receivers: make(map[string]*receiver),
+		sessions:  make(map[multicast.SessionID]string),
+	}
+	defer r.close()
+
+	fmt.Fprintf(out, "🎬 Scenario %s: %s\n", sc.Name, sc.describe())
+	for _, spec := range sc.Receivers {
+		rx := &receiver{Receiver: spec}
+		r.receivers[spec.Name] = rx
+		if err := r.open(rx); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range sc.Streams {
+		s := &stream{Stream: spec, interval: spec.Interval}
+		r.streams[spec.Name] = s
+		if err := r.start(s); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{Scenario: sc.Name, Started: time.Now()}
+	completed := true
+	for i, phase := range sc.Phases {
+		result, err := r.runPhase(ctx, phaseName(i, phase), phase, i == len(sc.Phases)-1)
+		if err != nil {
+			return nil, err
+		}
+		report.Phases = append(report.Phases, *result)
+		if ctx.Err() != nil {
+			completed = false
+			break
+		}
+	}
+	report.Finished = time.Now()
+	report.Passed = completed && report.Failed() == 0
+	return report, nil
+}
+
+// runPhase runs the actions of a phase, waits for its duration and measures
+// what every receiver got of every stream meanwhile. After the last phase the
+// streams stop and the receivers drain first.
+func (r *runner) runPhase(ctx context.Context, name string, phase Phase, last bool) (*PhaseReport, error) {
+	sent, received := r.sent(), r.received()
+	result := &PhaseReport{Name: name, Started: time.Now(), Sent: make(map[string]int)}
+	fmt.Fprintf(r.out, "▶️  [%s] Phase %s\n", result.Started.Format("15:04:05.000"), name)
+
+	live := true
+	for _, s := range r.sc.Streams {
+		if err := r.setInterval(r.streams[s.Name], phase.Intervals); err != nil {
+			return nil, err
+		}
+	}
+	for _, action := range phase.Actions {
+		if !live {
+			break
+		}
+		now := time.Now()
+		fmt.Fprintf(r.out, "🔧 [%s] %s\n", now.Format("15:04:05.000"), action)
+		result.Actions = append(result.Actions, ActionReport{Action: action.String(), Time: now})
+		var err error
+		switch {
+		case action.Leave != "":
+			err = r.leave(r.receivers[action.Leave])
+		case action.Join != "":
+			err = r.join(r.receivers[action.Join])
+		case action.Stop != "":
+			r.stop(r.streams[action.Stop])
+		case action.Start != "":
+			err = r.start(r.streams[action.Start])
+		default:
+			live = clock.Sleep(ctx, time.Duration(action.Wait))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if live {
+		live = clock.Sleep(ctx, time.Duration(phase.Duration))
+	}
+	if last || !live {
+		for _, s := range r.streams {
+			r.stop(s)
+		}
+		// The drain is not cut short, so an interrupted phase still counts
+		// the packets in flight
+		clock.Sleep(context.Background(), time.Duration(r.sc.Drain))
+	}
+
+	sentNow, receivedNow := r.sent(), r.received()
+	delays := r.delays()
+	result.Elapsed = server.Duration(time.Since(result.Started))
+	for _, s := range r.sc.Streams {
+		result.Sent[s.Name] = sentNow[s.Name] - sent[s.Name]
+	}
+	for _, path := range r.sc.allPaths() {
+		p := PathResult{
+			Receiver: path.Receiver,
+			Stream:   path.Stream,
+			Sent:     result.Sent[path.Stream],
+			Received: receivedNow[path.Receiver][path.Stream].received - received[path.Receiver][path.Stream].received,
+			Lost:     receivedNow[path.Receiver][path.Stream].lost - received[path.Receiver][path.Stream].lost,
+		}
+		if p.Received+p.Lost > 0 {
+			p.LossPercent = float64(p.Lost) * 100 / float64(p.Received+p.Lost)
+		}
+		if d, ok := delays[path]; ok {
+			p.DelayP50, p.DelayP99 = &d[0], &d[1]
+		}
+		result.Paths = append(result.Paths, p)
+	}
+	if !live {
+		fmt.Fprintf(r.out, "⏹️  [%s] Interrupted during phase %s\n", time.Now().Format("15:04:05.000"), name)
+		return result, nil
+	}
+
+	result.Passed = true
+	for _, expect := range phase.Expect {
+		for _, path := range r.sc.paths(expect) {
+			check := CheckResult{Receiver: path.Receiver, Stream: path.Stream, Expectation: expect.String()}
+			check.Failure = expect.check(result.path(path))
+			check.Passed = check.Failure == ""
+			result.Passed = result.Passed && check.Passed
+			result.Checks = append(result.Checks, check)
+		}
+	}
+	if failed := len(result.Checks) - result.passedChecks(); failed > 0 {
+		fmt.Fprintf(r.out, "❌ [%s] Phase %s: %d of %d check(s) failed\n", time.Now().Format("15:04:05.000"), name, failed, len(result.Checks))
+	} else if len(result.Checks) > 0 {
+		fmt.Fprintf(r.out, "✅ [%s] Phase %s: %d check(s) passed\n", time.Now().Format("15:04:05.000"), name, len(result.Checks))
+	}
+	return result, nil
+}
+
+// path returns the result of a path in the phase
+func (p *PhaseReport) path(path Path) PathResult {
+	for _, result := range p.Paths {
+		if result.Receiver == path.Receiver && result.Stream == path.Stream {
+			return result
+		}
+	}
+	return PathResult{Receiver: path.Receiver, Stream: path.Stream}
+}
+
+// passedChecks counts the checks of the phase that passed
+func (p *PhaseReport) passedChecks() int {
+	passed := 0
+	for _, check := range p.Checks {
+		if check.Passed {
+			passed++
+		}
+	}
+	return passed
+}
+
+// setInterval changes the pace of a stream if the phase sends it at another
+// interval; the sender keeps its session, so receivers see no new stream
+func (r *runner) setInterval(s *stream, intervals map[string]server.Duration) error {
+	interval, ok := intervals[s.Name]
+	if !ok {
+		interval = s.Interval
+	}
+	if interval == s.interval {
+		return nil
+	}
+	s.interval = interval
+	if s.sender == nil {
+		return nil
+	}
+	fmt.Fprintf(r.out, "⏩ [%s] %s every %v\n", time.Now().Format("15:04:05.000"), s.Name, time.Duration(interval))
+	if err := s.sender.SetInterval(time.Duration(interval)); err != nil {
+		return fmt.Errorf("stream %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// start starts sending a stream at its current interval
+func (r *runner) start(s *stream) error {
+	spec := s.JobSpec
+	spec.Interval = s.interval
+	sender, err := server.NewSender(spec, io.Discard)
+	if err != nil {
+		return fmt.Errorf("stream %s: %w", s.Name, err)
+	}
+	s.sender, s.done = sender, make(chan struct{})
+	r.sessions[sender.Session()] = s.Name
+	go func(done chan struct{}) {
+		defer close(done)
+		sender.Start()
+	}(s.done)
+	return nil
+}
+
+// stop stops sending a stream, keeping count of what its sender sent
+func (r *runner) stop(s *stream) {
+	if s.sender == nil {
+		return
+	}
+	s.sender.Close()
+	<-s.done
+	s.sent += s.sender.Stats().Messages
+	s.sender = nil
+}
+
+// open starts a receiver, joining its groups
+func (r *runner) open(rx *receiver) error {
+	receiver, err := server.NewReceiver(rx.JobSpec, io.Discard)
+	if err != nil {
+		return fmt.Errorf("receiver %s: %w", rx.Name, err)
+	}
+	rx.receiver, rx.done = receiver, make(chan struct{})
+	rx.groups, rx.joined = receiver.Joined(), true
+	go func() {
+		defer close(rx.done)
+		receiver.Start()
+	}()
+	return nil
+}
+
+// join joins the groups of a receiver that left again
+func (r *runner) join(rx *receiver) error {
+	for _, group := range rx.groups {
+		if err := rx.receiver.Join(group.IP); err != nil {
+			return fmt.Errorf("receiver %s: %w", rx.Name, err)
+		}
+	}
+	rx.joined = true
+	return nil
+}
+
+// leave leaves the groups of a receiver. Its socket stays open, so the
+// messages it misses until it joins again count as lost.
+func (r *runner) leave(rx *receiver) error {
+	for _, group := range rx.groups {
+		if err := rx.receiver.Leave(group.IP); err != nil {
+			return fmt.Errorf("receiver %s: %w", rx.Name, err)
+		}
+	}
+	rx.joined = false
+	settle(rx.receiver)
+	return nil
+}
+
+// settle waits for a receiver to read the messages queued on its socket, so
+// they count towards the phase they arrived in
+func settle(rx *multicast.Receiver) {
+	received := -1
+	for i := 0; i < 20; i++ {
+		total := 0
+		for _, stats := range rx.Streams() {
+			total += stats.Received
+		}
+		if total == received {
+			return
+		}
+		received = total
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// close stops every stream and receiver still running
+func (r *runner) close() {
+	for _, s := range r.streams {
+		r.stop(s)
+	}
+	for _, rx := range r.receivers {
+		if rx.receiver != nil {
+			rx.receiver.Close()
+			<-rx.done
+		}
+	}
+}
+
+// sent returns the messages sent so far per stream
+func (r *runner) sent() map[string]int {
+	sent := make(map[string]int, len(r.streams))
+	for name, s := range r.streams {
+		sent[name] = s.sent
+		if s.sender != nil {
+			sent[name] += s.sender.Stats().Messages
+		}
+	}
+	return sent
+}
+
+// received returns what every receiver got of each stream so far. The
+// messages of a session before the first one a receiver got count as lost.
+func (r *runner) received() map[string]map[string]tally {
+	received := make(map[string]map[string]tally, len(r.receivers))
+	for name, rx := range r.receivers {
+		counts := make(map[string]tally)
+		for key, stats := range rx.receiver.Streams() {
+			stream, ok := r.sessions[key.Session]
+			if !ok {
+				continue
+			}
+			t := counts[stream]
+			t.received += stats.Received
+			t.lost += stats.Lost + max(0, stats.FirstID-1)
+			counts[stream] = t
+		}
+		received[name] = counts
+	}
+	return received
+}
+
+// delays returns the delay percentiles of the latest session of each stream
+// at every receiver still joined
+func (r *runner) delays() map[Path][2]server.Duration {
+	delays := make(map[Path][2]server.Duration)
+	for name, rx := range r.receivers {
+		if !rx.joined {
+			continue
+		}
+		latest := make(map[string]time.Time)
+		for key, stats := range rx.receiver.Streams() {
+			stream, ok := r.sessions[key.Session]
+			if !ok || stats.LastSeen.Before(latest[stream]) {
+				continue
+			}
+			p50, ok := stats.DelayPercentile(50)
+			if !ok {
+				continue
+			}
+			p99, _ := stats.DelayPercentile(99)
+			latest[stream] = stats.LastSeen
+			delays[Path{Receiver: name, Stream: stream}] = [2]server.Duration{server.Duration(p50), server.Duration(p99)}
+		}
+	}
+	return delays
+}
@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	sc, err := Parse([]byte(`
+name: rejoin
+interface: lo
+drain: 50ms
+streams:
+  - {name: video, group: 239.23.23.59:23260, interval: 10ms}
+  - {name: audio, group: 239.23.23.60:23260, interval: 10ms}
+receivers:
+  - {name: leaf, group: "239.23.23.59:23260,239.23.23.60:23260"}
+  - {name: other, group: 239.23.23.60:23260}
+phases:
+  - name: steady
+    duration: 300ms
+    expect:
+      - received: true
+  - name: leave
+    actions:
+      - leave: leaf
+      - stop: audio
+  - name: away
+    intervals: {video: 5ms}
+    duration: 300ms
+    expect:
+      - receiver: leaf
+        received: false
+      - receiver: other
+        received: false
+  - name: back
+    actions:
+      - join: leaf
+      - start: audio
+    duration: 300ms
+    expect:
+      - receiver: leaf
+        stream: video
+        received: true
+        max_loss: 1
+`))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	report, err := Run(context.Background(), sc, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Scenario rejoin: 2 stream(s), 2 receiver(s), 4 phase(s)")
+	assert.Contains(t, out.String(), "video every 5ms")
+	assert.Contains(t, out.String(), "leave leaf")
+
+	require.Len(t, report.Phases, 4)
+	steady := report.Phases[0]
+	if steady.path(Path{Receiver: "other", Stream: "audio"}).Received == 0 {
+		t.Skip("multicast loopback not available")
+	}
+	assert.True(t, steady.Passed, "%+v", steady.Checks)
+	assert.Len(t, steady.Checks, 3)
+	assert.Positive(t, steady.Sent["video"])
+
+	leave := report.Phases[1]
+	assert.Equal(t, []string{"leave leaf", "stop audio"}, []string{leave.Actions[0].Action, leave.Actions[1].Action})
+
+	away := report.Phases[2]
+	assert.True(t, away.Passed, "%+v", away.Checks)
+	assert.Greater(t, away.Sent["video"], steady.Sent["video"], "video is sent faster")
+	assert.Zero(t, away.Sent["audio"])
+
+	back := report.Phases[3]
+	video := back.path(Path{Receiver: "leaf", Stream: "video"})
+	assert.Positive(t, video.Received)
+	// The messages missed while away count as lost once the receiver is back
+	assert.GreaterOrEqual(t, video.Lost, away.Sent["video"]-1)
+	assert.False(t, back.Passed)
+	assert.False(t, report.Passed)
+	assert.Equal(t, 1, report.Failed())
+
+	// Streams restarted with a new session are received in full
+	audio := back.path(Path{Receiver: "other", Stream: "audio"})
+	assert.Positive(t, audio.Received)
+	assert.Zero(t, audio.Lost)
+}
+
+func TestRunInterrupted(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	sc, err := Parse([]byte(`
+interface: lo
+drain: 10ms
+streams: [{name: s, group: 239.23.23.59:23260, interval: 10ms}]
+receivers: [{name: r, group: 239.23.23.59:23260}]
+phases:
+  - {name: first, duration: 1m, expect: [{received: true}]}
+  - {name: second, duration: 1m}
+`))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	report, err := Run(ctx, sc, &out)
+	require.NoError(t, err)
+	require.Len(t, report.Phases, 1)
+	assert.Empty(t, report.Phases[0].Checks)
+	assert.False(t, report.Passed)
+	assert.Contains(t, out.String(), "Interrupted during phase first")
+}
@@ -0,0 +1,391 @@
+// Package scenario runs scripted tests on one host: streams sent and
+// receivers joined through a series of timed phases, with actions between
+// them and expectations checked for every phase.
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// DefaultDrain is how long receivers keep listening after the last phase
+const DefaultDrain = time.Second
+
+// Scenario describes a test: the streams to send, the receivers to run and
+// the phases to go through
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Interface is used by streams and receivers that name none
+	Interface string `yaml:"interface"`
+	// Drain is the time between stopping the streams and the receivers after
+	// the last phase, so packets in flight arrive
+	Drain     server.Duration `yaml:"drain"`
+	Streams   []Stream        `yaml:"streams"`
+	Receivers []Receiver      `yaml:"receivers"`
+	Phases    []Phase         `yaml:"phases"`
+}
+
+// Stream is a sender of the scenario
+type Stream struct {
+	Name           string `yaml:"name"`
+	server.JobSpec `yaml:",inline"`
+}
+
+// Receiver is a receiver of the scenario
+type Receiver struct {
+	Name           string `yaml:"name"`
+	server.JobSpec `yaml:",inline"`
+}
+
+// Phase is a step of the scenario: its actions run first, then it lasts its
+// duration. What is received during the actions counts towards the phase.
+type Phase struct {
+	Name     string          `yaml:"name"`
+	Duration server.Duration `yaml:"duration"`
+	Actions  []Action        `yaml:"actions"`
+	// Intervals overrides the send interval of streams during the phase
+	Intervals map[string]server.Duration `yaml:"intervals"`
+	Expect    []Expectation              `yaml:"expect"`
+}
+
+// Action changes the scenario between phases; exactly one field is set
+type Action struct {
+	// Leave leaves the groups of a receiver, which keeps its socket
+	Leave string `yaml:"leave,omitempty"`
+	// Join joins the groups of a receiver that left again
+	Join string `yaml:"join,omitempty"`
+	// Stop stops sending a stream
+	Stop string `yaml:"stop,omitempty"`
+	// Start resumes sending a stream that was stopped
+	Start string          `yaml:"start,omitempty"`
+	Wait  server.Duration `yaml:"wait,omitempty"`
+}
+
+// String describes the action for display
+func (a Action) String() string {
+	switch {
+	case a.Leave != "":
+		return "leave " + a.Leave
+	case a.Join != "":
+		return "join " + a.Join
+	case a.Stop != "":
+		return "stop " + a.Stop
+	case a.Start != "":
+		return "start " + a.Start
+	}
+	return "wait " + time.Duration(a.Wait).String()
+}
+
+// Load reads a scenario from a YAML file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	sc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// Parse reads a scenario from YAML, fills in the defaults and validates it
+func Parse(data []byte) (*Scenario, error) {
+	var sc Scenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&sc); err != nil {
+		return nil, err
+	}
+
+	if sc.Drain == 0 {
+		sc.Drain = server.Duration(DefaultDrain)
+	}
+	for i := range sc.Streams {
+		s := &sc.Streams[i]
+		if s.Interface == "" {
+			s.Interface = sc.Interface
+		}
+		if s.StreamID == "" {
+			s.StreamID = s.Name
+		}
+		s.JobSpec = s.JobSpec.WithDefaults()
+	}
+	for i := range sc.Receivers {
+		r := &sc.Receivers[i]
+		if r.Interface == "" {
+			r.Interface = sc.Interface
+		}
+		r.JobSpec = r.JobSpec.WithDefaults()
+	}
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// DefaultInterface uses name for the streams and receivers that name no
+// interface, e.g. the interface given on the command line
+func (sc *Scenario) DefaultInterface(name string) {
+	for i := range sc.Streams {
+		if sc.Streams[i].Interface == "" {
+			sc.Streams[i].Interface = name
+		}
+	}
+	for i := range sc.Receivers {
+		if sc.Receivers[i].Interface == "" {
+			sc.Receivers[i].Interface = name
+		}
+	}
+}
+
+// validate checks a scenario, following the actions so that e.g. a receiver
+// is not joined twice
+func (sc *Scenario) validate() error {
+	if sc.Drain < 0 {
+		return fmt.Errorf("drain must not be negative")
+	}
+	if len(sc.Streams) == 0 {
+		return fmt.Errorf("no streams")
+	}
+	if len(sc.Receivers) == 0 {
+		return fmt.Errorf("no receivers")
+	}
+	if len(sc.Phases) == 0 {
+		return fmt.Errorf("no phases")
+	}
+
+	check := func(where, kind, name string, spec *server.JobSpec, names map[string]bool) error {
+		if name == "" {
+			return fmt.Errorf("%s: no name", where)
+		}
+		if names[name] {
+			return fmt.Errorf("%s: duplicate name %q", where, name)
+		}
+		names[name] = true
+		if spec.Type != "" && spec.Type != kind {
+			return fmt.Errorf("%s: type %q does not match the section", where, spec.Type)
+		}
+		spec.Type = kind
+		// The phases decide when streams and receivers stop
+		if spec.Duration != 0 {
+			return fmt.Errorf("%s: set the duration of the phases instead", where)
+		}
+		if kind == server.TypeSender && spec.Interval < 0 {
+			return fmt.Errorf("%s: interval must be positive", where)
+		}
+		if _, err := network.ResolveGroups(spec.Group, spec.DPort); err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+		return nil
+	}
+	sending := make(map[string]bool)
+	for i := range sc.Streams {
+		s := &sc.Streams[i]
+		if err := check(fmt.Sprintf("stream %d", i+1), server.TypeSender, s.Name, &s.JobSpec, sending); err != nil {
+			return err
+		}
+	}
+	joined := make(map[string]bool)
+	for i := range sc.Receivers {
+		r := &sc.Receivers[i]
+		if err := check(fmt.Sprintf("receiver %d", i+1), server.TypeReceiver, r.Name, &r.JobSpec, joined); err != nil {
+			return err
+		}
+	}
+
+	for i, phase := range sc.Phases {
+		where := fmt.Sprintf("phase %d", i+1)
+		if phase.Name != "" {
+			where = fmt.Sprintf("phase %q", phase.Name)
+		}
+		if phase.Duration < 0 {
+			return fmt.Errorf("%s: duration must not be negative", where)
+		}
+		if phase.Duration == 0 && len(phase.Actions) == 0 {
+			return fmt.Errorf("%s: no duration", where)
+		}
+		for name, interval := range phase.Intervals {
+			if _, ok := sending[name]; !ok {
+				return fmt.Errorf("%s: unknown stream %q", where, name)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("%s: interval of %s must be positive", where, name)
+			}
+		}
+		for k, action := range phase.Actions {
+			if err := validateAction(action, sending, joined); err != nil {
+				return fmt.Errorf("%s: action %d: %w", where, k+1, err)
+			}
+		}
+		for k, expect := range phase.Expect {
+			if err := sc.validateExpectation(expect); err != nil {
+				return fmt.Errorf("%s: expectation %d: %w", where, k+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAction checks an action against the streams sending and the
+// receivers joined at that point, and updates them
+func validateAction(a Action, sending, joined map[string]bool) error {
+	set := 0
+	for _, field := range []string{a.Leave, a.Join, a.Stop, a.Start} {
+		if field != "" {
+			set++
+		}
+	}
+	if a.Wait != 0 {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("set exactly one of leave, join, stop, start and wait")
+	}
+
+	toggle := func(states map[string]bool, name, kind string, want bool, already string) error {
+		state, ok := states[name]
+		if !ok {
+			return fmt.Errorf("unknown %s %q", kind, name)
+		}
+		if state == want {
+			return fmt.Errorf("%s %s %s", kind, name, already)
+		}
+		states[name] = want
+		return nil
+	}
+	switch {
+	case a.Leave != "":
+		return toggle(joined, a.Leave, "receiver", false, "has already left")
+	case a.Join != "":
+		return toggle(joined, a.Join, "receiver", true, "is already joined")
+	case a.Stop != "":
+		return toggle(sending, a.Stop, "stream", false, "is already stopped")
+	case a.Start != "":
+		return toggle(sending, a.Start, "stream", true, "is already sending")
+	}
+	if a.Wait < 0 {
+		return fmt.Errorf("wait must not be negative")
+	}
+	return nil
+}
+
+// validateExpectation checks that an expectation names known streams and
+// receivers, and that a receiver it names joins a stream it names
+func (sc *Scenario) validateExpectation(e Expectation) error {
+	if e.MaxLoss == nil && e.MaxDelay == nil && e.Received == nil {
+		return fmt.Errorf("set at least one of max_loss, max_delay and received")
+	}
+	if e.MaxLoss != nil && (*e.MaxLoss < 0 || *e.MaxLoss > 100) {
+		return fmt.Errorf("max_loss must be a percentage between 0 and 100")
+	}
+	if e.Stream != "" && sc.stream(e.Stream) == nil {
+		return fmt.Errorf("unknown stream %q", e.Stream)
+	}
+	if e.Receiver != "" && sc.receiver(e.Receiver) == nil {
+		return fmt.Errorf("unknown receiver %q", e.Receiver)
+	}
+	if len(sc.paths(e)) == 0 {
+		return fmt.Errorf("no receiver joins the group of a stream it applies to")
+	}
+	return nil
+}
+
+// stream returns the stream named name, or nil
+func (sc *Scenario) stream(name string) *Stream {
+	for i := range sc.Streams {
+		if sc.Streams[i].Name == name {
+			return &sc.Streams[i]
+		}
+	}
+	return nil
+}
+
+// receiver returns the receiver named name, or nil
+func (sc *Scenario) receiver(name string) *Receiver {
+	for i := range sc.Receivers {
+		if sc.Receivers[i].Name == name {
+			return &sc.Receivers[i]
+		}
+	}
+	return nil
+}
+
+// Path is a stream as one receiver gets it
+type Path struct {
+	Receiver string
+	Stream   string
+}
+
+// paths returns the paths an expectation applies to: every receiver it names
+// with every stream it names whose group the receiver joins
+func (sc *Scenario) paths(e Expectation) []Path {
+	var paths []Path
+	for _, r := range sc.Receivers {
+		if e.Receiver != "" && e.Receiver != r.Name {
+			continue
+		}
+		groups := resolveGroups(r.JobSpec)
+		for _, s := range sc.Streams {
+			if e.Stream != "" && e.Stream != s.Name {
+				continue
+			}
+			for group := range resolveGroups(s.JobSpec) {
+				if groups[group] {
+					paths = append(paths, Path{Receiver: r.Name, Stream: s.Name})
+					break
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// allPaths returns every stream as every receiver joining its group gets it
+func (sc *Scenario) allPaths() []Path {
+	return sc.paths(Expectation{})
+}
+
+// resolveGroups returns the addresses of a job spec's groups; specs are
+// validated before, so errors are not expected
+func resolveGroups(spec server.JobSpec) map[string]bool {
+	addrs, _ := network.ResolveGroups(spec.Group, spec.DPort)
+	groups := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		groups[addr.String()] = true
+	}
+	return groups
+}
+
+// phaseName names a phase for display, numbering unnamed phases
+func phaseName(i int, phase Phase) string {
+	if phase.Name != "" {
+		return phase.Name
+	}
+	return fmt.Sprintf("phase %d", i+1)
+}
+
+// describe summarises the scenario in one line
+func (sc *Scenario) describe() string {
+	var total time.Duration
+	for _, phase := range sc.Phases {
+		total += time.Duration(phase.Duration)
+		for _, action := range phase.Actions {
+			total += time.Duration(action.Wait)
+		}
+	}
+	names := make([]string, len(sc.Phases))
+	for i, phase := range sc.Phases {
+		names[i] = phaseName(i, phase)
+	}
+	return fmt.Sprintf("%d stream(s), %d receiver(s), %d phase(s) (%s) over %v",
+		len(sc.Streams), len(sc.Receivers), len(sc.Phases), strings.Join(names, ", "), total)
+}
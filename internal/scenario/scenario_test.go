@@ -0,0 +1,155 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+const testScenario = `
+name: failover
+interface: eth0
+streams:
+  - name: video
+    group: 239.1.1.1:5000
+    interval: 100ms
+  - name: audio
+    group: 239.1.1.2:5000
+    interface: eth1
+receivers:
+  - name: leaf
+    group: 239.1.1.1,239.1.1.2
+    dport: 5000
+  - name: spine
+    group: 239.1.1.2:5000
+phases:
+  - name: warmup
+    duration: 2s
+  - name: burst
+    duration: 5s
+    intervals: {video: 10ms}
+    expect:
+      - max_loss: 1
+  - actions:
+      - leave: leaf
+      - wait: 5s
+      - join: leaf
+    duration: 5s
+    expect:
+      - receiver: leaf
+        stream: video
+        received: true
+`
+
+func TestParse(t *testing.T) {
+	sc, err := Parse([]byte(testScenario))
+	require.NoError(t, err)
+
+	assert.Equal(t, "failover", sc.Name)
+	assert.Equal(t, server.Duration(DefaultDrain), sc.Drain)
+
+	require.Len(t, sc.Streams, 2)
+	video := sc.Streams[0]
+	assert.Equal(t, server.TypeSender, video.Type)
+	assert.Equal(t, "eth0", video.Interface)
+	assert.Equal(t, "video", video.StreamID)
+	assert.Equal(t, server.Duration(100*time.Millisecond), video.Interval)
+	assert.Equal(t, 1, video.TTL)
+	assert.Equal(t, "eth1", sc.Streams[1].Interface)
+	// The default interval of the send command
+	assert.Equal(t, server.Duration(time.Second), sc.Streams[1].Interval)
+
+	require.Len(t, sc.Receivers, 2)
+	assert.Equal(t, server.TypeReceiver, sc.Receivers[0].Type)
+	assert.Equal(t, "eth0", sc.Receivers[0].Interface)
+
+	require.Len(t, sc.Phases, 3)
+	assert.Equal(t, server.Duration(10*time.Millisecond), sc.Phases[1].Intervals["video"])
+	assert.Equal(t, []string{"leave leaf", "wait 5s", "join leaf"}, []string{
+		sc.Phases[2].Actions[0].String(), sc.Phases[2].Actions[1].String(), sc.Phases[2].Actions[2].String(),
+	})
+	assert.Equal(t, "phase 3", phaseName(2, sc.Phases[2]))
+	assert.Equal(t, "2 stream(s), 2 receiver(s), 3 phase(s) (warmup, burst, phase 3) over 17s", sc.describe())
+
+	assert.Equal(t, []Path{
+		{Receiver: "leaf", Stream: "video"},
+		{Receiver: "leaf", Stream: "audio"},
+		{Receiver: "spine", Stream: "audio"},
+	}, sc.allPaths())
+	assert.Equal(t, []Path{{Receiver: "spine", Stream: "audio"}}, sc.paths(Expectation{Receiver: "spine"}))
+}
+
+func TestDefaultInterface(t *testing.T) {
+	sc, err := Parse([]byte(`
+streams: [{name: a, interface: eth1}, {name: b}]
+receivers: [{name: r}]
+phases: [{duration: 1s}]
+`))
+	require.NoError(t, err)
+	sc.DefaultInterface("lo")
+	assert.Equal(t, "eth1", sc.Streams[0].Interface)
+	assert.Equal(t, "lo", sc.Streams[1].Interface)
+	assert.Equal(t, "lo", sc.Receivers[0].Interface)
+}
+
+func TestParseErrors(t *testing.T) {
+	const base = "streams: [{name: s, group: 239.1.1.1:5000}]\nreceivers: [{name: r, group: 239.1.1.1:5000}]\n"
+	tests := []struct {
+		name     string
+		scenario string
+		err      string
+	}{
+		{name: "unknown field", scenario: base + "phases: [{duration: 1s, length: 2s}]", err: "field length not found"},
+		{name: "no streams", scenario: "receivers: [{name: r}]\nphases: [{duration: 1s}]", err: "no streams"},
+		{name: "no receivers", scenario: "streams: [{name: s}]\nphases: [{duration: 1s}]", err: "no receivers"},
+		{name: "no phases", scenario: base, err: "no phases"},
+		{name: "unnamed stream", scenario: "streams: [{group: 239.1.1.1:5000}]\nreceivers: [{name: r}]\nphases: [{duration: 1s}]", err: "stream 1: no name"},
+		{name: "duplicate receiver", scenario: "streams: [{name: s}]\nreceivers: [{name: r}, {name: r}]\nphases: [{duration: 1s}]", err: `receiver 2: duplicate name "r"`},
+		{name: "wrong type", scenario: "streams: [{name: s, type: receiver}]\nreceivers: [{name: r}]\nphases: [{duration: 1s}]", err: `stream 1: type "receiver" does not match the section`},
+		{name: "stream duration", scenario: "streams: [{name: s, duration: 5s}]\nreceivers: [{name: r}]\nphases: [{duration: 1s}]", err: "set the duration of the phases instead"},
+		{name: "negative interval", scenario: "streams: [{name: s, interval: -1s}]\nreceivers: [{name: r}]\nphases: [{duration: 1s}]", err: "stream 1: interval must be positive"},
+		{name: "invalid group", scenario: "streams: [{name: s, group: \"239.1.1.1:x\"}]\nreceivers: [{name: r}]\nphases: [{duration: 1s}]", err: "stream 1:"},
+		{name: "no duration", scenario: base + "phases: [{name: steady}]", err: `phase "steady": no duration`},
+		{name: "unknown interval stream", scenario: base + "phases: [{duration: 1s, intervals: {x: 1s}}]", err: `phase 1: unknown stream "x"`},
+		{name: "two actions in one", scenario: base + "phases: [{actions: [{leave: r, wait: 1s}]}]", err: "action 1: set exactly one"},
+		{name: "unknown receiver", scenario: base + "phases: [{actions: [{leave: x}]}]", err: `unknown receiver "x"`},
+		{name: "join twice", scenario: base + "phases: [{actions: [{join: r}]}]", err: "receiver r is already joined"},
+		{name: "leave twice", scenario: base + "phases: [{actions: [{leave: r}]}, {actions: [{leave: r}]}]", err: `phase 2: action 1: receiver r has already left`},
+		{name: "start twice", scenario: base + "phases: [{actions: [{start: s}]}]", err: "stream s is already sending"},
+		{name: "empty expectation", scenario: base + "phases: [{duration: 1s, expect: [{stream: s}]}]", err: "expectation 1: set at least one"},
+		{name: "loss out of range", scenario: base + "phases: [{duration: 1s, expect: [{max_loss: 101}]}]", err: "max_loss must be a percentage"},
+		{name: "unknown expected stream", scenario: base + "phases: [{duration: 1s, expect: [{stream: x, received: true}]}]", err: `unknown stream "x"`},
+		{
+			name:     "receiver not joining the stream",
+			scenario: "streams: [{name: s, group: 239.1.1.1:5000}]\nreceivers: [{name: r, group: 239.1.1.2:5000}]\nphases: [{duration: 1s, expect: [{received: true}]}]",
+			err:      "no receiver joins the group",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.scenario))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testScenario), 0o644))
+	sc, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "failover", sc.Name)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read scenario")
+
+	require.NoError(t, os.WriteFile(path, []byte("streams: []"), 0o644))
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "invalid scenario "+path+": no streams")
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	// EventOutput carries a line a job printed
+	EventOutput = "output"
+	// EventState reports that a job started or stopped
+	EventState = "state"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 256
+
+// Event is a line of output or a state change of a job
+type Event struct {
+	Job   string    `json:"job"`
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	Line  string    `json:"line,omitempty"`
+	State string    `json:"state,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Broker fans events out to subscribers
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]string
+}
+
+// NewBroker creates a broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]string)}
+}
+
+// Subscribe returns a channel receiving the events of one job, or of every
+// job if job is empty, and a function that ends the subscription
+func (b *Broker) Subscribe(job string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = job
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends an event to its subscribers without waiting for them
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, job := range b.subscribers {
+		if job != "" && job != e.Job {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// lineWriter publishes what a job writes as one output event per line
+type lineWriter struct {
+	mu      sync.Mutex
+	broker  *Broker
+	job     string
+	pending []byte
+}
+
+func newLineWriter(broker *Broker, job string) *lineWriter {
+	return &lineWriter{broker: broker, job: job}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.publish(string(w.pending[:i]))
+		w.pending = w.pending[i+1:]
+	}
+}
+
+// Flush publishes a last line that was not terminated
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.publish(string(w.pending))
+		w.pending = nil
+	}
+}
+
+func (w *lineWriter) publish(line string) {
+	w.broker.Publish(Event{Job: w.job, Time: time.Now(), Type: EventOutput, Line: line})
+}
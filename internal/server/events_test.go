@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+	all, cancelAll := broker.Subscribe("")
+	one, cancelOne := broker.Subscribe("job-1")
+
+	broker.Publish(Event{Job: "job-1", Type: EventState, State: StateRunning})
+	broker.Publish(Event{Job: "job-2", Type: EventState, State: StateRunning})
+
+	assert.Equal(t, "job-1", (<-all).Job)
+	assert.Equal(t, "job-2", (<-all).Job)
+	assert.Equal(t, "job-1", (<-one).Job)
+	assert.Empty(t, one)
+
+	// Ending a subscription closes its channel, and twice is harmless
+	cancelOne()
+	cancelOne()
+	_, open := <-one
+	assert.False(t, open)
+
+	// A slow subscriber misses events rather than blocking the publisher
+	for i := 0; i < subscriberBuffer+10; i++ {
+		broker.Publish(Event{Job: "job-1", Type: EventOutput, Line: fmt.Sprint(i)})
+	}
+	assert.Len(t, all, subscriberBuffer)
+	cancelAll()
+}
+
+func TestLineWriter(t *testing.T) {
+	broker := NewBroker()
+	events, cancel := broker.Subscribe("job-1")
+	defer cancel()
+
+	w := newLineWriter(broker, "job-1")
+	n, err := w.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	assert.Equal(t, 18, n)
+	w.Write([]byte("line\n"))
+	w.Write([]byte("unterminated"))
+	w.Flush()
+	w.Flush()
+
+	var lines []string
+	for len(events) > 0 {
+		e := <-events
+		assert.Equal(t, EventOutput, e.Type)
+		assert.Equal(t, "job-1", e.Job)
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []string{"first line", "second line", "unterminated"}, lines)
+}
@@ -0,0 +1,530 @@
+// Package server runs senders and receivers as jobs controlled over a REST
+// API, with their output streamed to clients as server-sent events.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+// Job types
+const (
+	TypeSender   = "sender"
+	TypeReceiver = "receiver"
+)
+
+// Job states
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+	StateFailed  = "failed"
+)
+
+// Duration is a time.Duration written in JSON and YAML as a string such as "1.5s"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"1s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// JobSpec describes a job: the parameters of NewSender or NewReceiver and
+// the options that make sense without a terminal
+type JobSpec struct {
+	Type      string   `json:"type" yaml:"type,omitempty"`
+	Group     string   `json:"group" yaml:"group,omitempty"`
+	Interface string   `json:"interface,omitempty" yaml:"interface,omitempty"`
+	Interval  Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	TTL       int      `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	SPort     int      `json:"sport,omitempty" yaml:"sport,omitempty"`
+	DPort     int      `json:"dport,omitempty" yaml:"dport,omitempty"`
+	// Duration stops the job after this long; zero runs it until it is stopped
+	Duration     Duration `json:"duration,omitempty" yaml:"duration,omitempty"`
+	AllowUnicast bool     `json:"allow_unicast,omitempty" yaml:"allow_unicast,omitempty"`
+
+	// Sender options
+	Loopback     *bool             `json:"loopback,omitempty" yaml:"loopback,omitempty"`
+	SourceIP     string            `json:"source_ip,omitempty" yaml:"source_ip,omitempty"`
+	BindToDevice bool              `json:"bind_device,omitempty" yaml:"bind_device,omitempty"`
+	SenderID     string            `json:"sender_id,omitempty" yaml:"sender_id,omitempty"`
+	StreamID     string            `json:"stream_id,omitempty" yaml:"stream_id,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Receiver options
+	IgnoreLocal bool   `json:"ignore_local,omitempty" yaml:"ignore_local,omitempty"`
+	Bind        string `json:"bind,omitempty" yaml:"bind,omitempty"`
+	ReuseAddr   *bool  `json:"reuseaddr,omitempty" yaml:"reuseaddr,omitempty"`
+	ReusePort   bool   `json:"reuseport,omitempty" yaml:"reuseport,omitempty"`
+}
+
+// WithDefaults fills in the defaults of the send and receive commands
+func (s JobSpec) WithDefaults() JobSpec {
+	if s.Group == "" {
+		s.Group = "239.23.23.23:2323"
+	}
+	if s.Interval == 0 {
+		s.Interval = Duration(time.Second)
+	}
+	if s.TTL == 0 {
+		s.TTL = 1
+	}
+	return s
+}
+
+// JobInfo describes a job and its state
+type JobInfo struct {
+	ID      string     `json:"id"`
+	Spec    JobSpec    `json:"spec"`
+	State   string     `json:"state"`
+	Error   string     `json:"error,omitempty"`
+	Started time.Time  `json:"started"`
+	Stopped *time.Time `json:"stopped,omitempty"`
+}
+
+// job is a running or finished sender or receiver
+type job struct {
+	info     JobInfo
+	sender   *multicast.Sender
+	receiver *multicast.Receiver
+	output   *lineWriter
+	timer    *time.Timer
+	done     chan struct{}
+}
+
+func (j *job) close() {
+	if j.sender != nil {
+		j.sender.Close()
+	} else {
+		j.receiver.Close()
+	}
+}
+
+// Manager creates jobs, runs them concurrently and stops them
+type Manager struct {
+	mu     sync.Mutex
+	jobs   map[string]*job
+	nextID int
+	events *Broker
+	out    io.Writer
+}
+
+// NewManager creates a manager with no jobs that logs their lifecycle to out
+func NewManager(out io.Writer) *Manager {
+	return &Manager{
+		jobs:   make(map[string]*job),
+		events: NewBroker(),
+		out:    out,
+	}
+}
+
+// Events returns the broker publishing the output and state changes of every job
+func (m *Manager) Events() *Broker {
+	return m.events
+}
+
+// Create starts a job; invalid parameters are reported before it starts
+func (m *Manager) Create(spec JobSpec) (JobInfo, error) {
+	spec = spec.WithDefaults()
+	if spec.Duration < 0 {
+		return JobInfo{}, fmt.Errorf("duration must not be negative, got %v", time.Duration(spec.Duration))
+	}
+
+	m.mu.Lock()
+	m.nextID++
+	id := "job-" + strconv.Itoa(m.nextID)
+	m.mu.Unlock()
+
+	j := &job{
+		info:   JobInfo{ID: id, Spec: spec, State: StateRunning},
+		output: newLineWriter(m.events, id),
+		done:   make(chan struct{}),
+	}
+
+	var start func() error
+	switch spec.Type {
+	case TypeSender:
+		sender, err := NewSender(spec, j.output)
+		if err != nil {
+			return JobInfo{}, err
+		}
+		j.sender, start = sender, sender.Start
+	case TypeReceiver:
+		receiver, err := NewReceiver(spec, j.output)
+		if err != nil {
+			return JobInfo{}, err
+		}
+		j.receiver, start = receiver, receiver.Start
+	default:
+		return JobInfo{}, fmt.Errorf("job type must be %s or %s, got %q", TypeSender, TypeReceiver, spec.Type)
+	}
+
+	m.mu.Lock()
+	j.info.Started = time.Now()
+	m.jobs[id] = j
+	info := j.info
+	m.mu.Unlock()
+
+	m.events.Publish(Event{Job: id, Time: info.Started, Type: EventState, State: StateRunning})
+	fmt.Fprintf(m.out, "▶️  [%s] Started %s %s on %s\n", info.Started.Format("15:04:05.000"), spec.Type, id, spec.Group)
+
+	go func() {
+		err := start()
+		j.output.Flush()
+
+		m.mu.Lock()
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		now := time.Now()
+		j.info.Stopped = &now
+		j.info.State = StateStopped
+		if err != nil {
+			j.info.State = StateFailed
+			j.info.Error = err.Error()
+		}
+		info := j.info
+		m.mu.Unlock()
+
+		m.events.Publish(Event{Job: id, Time: now, Type: EventState, State: info.State, Error: info.Error})
+		fmt.Fprintf(m.out, "⏹️  [%s] %s %s %s\n", now.Format("15:04:05.000"), spec.Type, id, info.State)
+		close(j.done)
+	}()
+
+	if spec.Duration > 0 {
+		m.mu.Lock()
+		j.timer = time.AfterFunc(time.Duration(spec.Duration), j.close)
+		m.mu.Unlock()
+	}
+	return info, nil
+}
+
+// NewSender creates the sender of a job spec
+func NewSender(spec JobSpec, out io.Writer) (*multicast.Sender, error) {
+	opts := []multicast.SenderOption{
+		multicast.WithSenderOutput(out),
+		multicast.WithBindToDevice(spec.BindToDevice),
+		multicast.WithUnicastDestination(spec.AllowUnicast),
+		multicast.WithSenderID(spec.SenderID),
+		multicast.WithStreamID(spec.StreamID),
+		multicast.WithLabels(spec.Labels),
+	}
+	if spec.Loopback != nil {
+		opts = append(opts, multicast.WithLoopback(*spec.Loopback))
+	}
+	if spec.SourceIP != "" {
+		ip := net.ParseIP(spec.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", spec.SourceIP)
+		}
+		opts = append(opts, multicast.WithSourceIP(ip))
+	}
+	return multicast.NewSender(spec.Group, spec.Interface, time.Duration(spec.Interval), spec.TTL, spec.SPort, spec.DPort, opts...)
+}
+
+// NewReceiver creates the receiver of a job spec
+func NewReceiver(spec JobSpec, out io.Writer) (*multicast.Receiver, error) {
+	opts := []multicast.ReceiverOption{
+		multicast.WithOutput(out),
+		multicast.WithIgnoreLocal(spec.IgnoreLocal),
+		multicast.WithBind(spec.Bind),
+		multicast.WithReusePort(spec.ReusePort),
+		multicast.WithUnicastGroups(spec.AllowUnicast),
+	}
+	if spec.ReuseAddr != nil {
+		opts = append(opts, multicast.WithReuseAddr(*spec.ReuseAddr))
+	}
+	return multicast.NewReceiver(spec.Group, spec.Interface, spec.DPort, opts...)
+}
+
+// Info describes the host a manager runs on and its jobs
+type Info struct {
+	Hostname string `json:"hostname"`
+	Jobs     int    `json:"jobs"`
+	Running  int    `json:"running"`
+}
+
+// Info describes the manager's host and counts its jobs
+func (m *Manager) Info() Info {
+	hostname, _ := os.Hostname()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info := Info{Hostname: hostname, Jobs: len(m.jobs)}
+	for _, j := range m.jobs {
+		if j.info.State == StateRunning {
+			info.Running++
+		}
+	}
+	return info
+}
+
+// List returns every job in the order they were created
+func (m *Manager) List() []JobInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]JobInfo, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.info)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobNumber(jobs[i].ID) < jobNumber(jobs[k].ID)
+	})
+	return jobs
+}
+
+func jobNumber(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "job-"))
+	return n
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (JobInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobInfo{}, false
+	}
+	return j.info, true
+}
+
+// ErrNotFound is returned for an unknown job ID
+var ErrNotFound = errors.New("job not found")
+
+// Stop stops a job and waits until it has finished; its results are kept
+func (m *Manager) Stop(id string) (JobInfo, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return JobInfo{}, ErrNotFound
+	}
+	j.close()
+	<-j.done
+
+	info, _ := m.Get(id)
+	return info, nil
+}
+
+// Remove stops a job if it runs and forgets it
+func (m *Manager) Remove(id string) error {
+	if _, err := m.Stop(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.jobs, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// Shutdown stops every job and waits until they have finished
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	jobs := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	m.mu.Unlock()
+
+	for _, j := range jobs {
+		j.close()
+	}
+	for _, j := range jobs {
+		<-j.done
+	}
+}
+
+// StreamReport is the live state of one stream a receiver hears
+type StreamReport struct {
+	Stream      string    `json:"stream"`
+	Group       string    `json:"group"`
+	Address     string    `json:"address"`
+	Received    int       `json:"received"`
+	Lost        int       `json:"lost"`
+	Reordered   int       `json:"reordered"`
+	Duplicates  int       `json:"duplicates"`
+	Bytes       int       `json:"bytes"`
+	LossPercent float64   `json:"loss_percent"`
+	Jitter      Duration  `json:"jitter"`
+	DelayP50    *Duration `json:"delay_p50,omitempty"`
+	DelayP99    *Duration `json:"delay_p99,omitempty"`
+	LastSeen    time.Time `json:"last_seen"`
+	TTL         int       `json:"ttl"`
+}
+
+// Stats is the live state of a job: what a sender has sent or the streams a
+// receiver hears
+type Stats struct {
+	Job     string                 `json:"job"`
+	State   string                 `json:"state"`
+	Time    time.Time              `json:"time"`
+	Sent    *multicast.SenderStats `json:"sent,omitempty"`
+	Streams []StreamReport         `json:"streams,omitempty"`
+}
+
+// Stats returns the live state of a job; it stays available once the job stopped
+func (m *Manager) Stats(id string) (Stats, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	var state string
+	if ok {
+		state = j.info.State
+	}
+	m.mu.Unlock()
+	if !ok {
+		return Stats{}, ErrNotFound
+	}
+
+	stats := Stats{Job: id, State: state, Time: time.Now()}
+	if j.sender != nil {
+		sent := j.sender.Stats()
+		stats.Sent = &sent
+		return stats, nil
+	}
+	stats.Streams = streamReports(j.receiver.Streams())
+	return stats, nil
+}
+
+// streamReports orders the streams of a receiver by group and stream
+func streamReports(streams map[multicast.StreamKey]multicast.StreamStats) []StreamReport {
+	reports := make([]StreamReport, 0, len(streams))
+	for key, s := range streams {
+		report := StreamReport{
+			Stream:      key.String(),
+			Group:       s.Group,
+			Address:     s.Address,
+			Received:    s.Received,
+			Lost:        s.Lost,
+			Reordered:   s.Reordered,
+			Duplicates:  s.Duplicates,
+			Bytes:       s.Bytes,
+			LossPercent: s.LossPercent(),
+			Jitter:      Duration(s.Jitter),
+			LastSeen:    s.LastSeen,
+			TTL:         s.TTL,
+		}
+		if p50, ok := s.DelayPercentile(50); ok {
+			p99, _ := s.DelayPercentile(99)
+			report.DelayP50, report.DelayP99 = durationPtr(p50), durationPtr(p99)
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, k int) bool {
+		if reports[i].Group != reports[k].Group {
+			return reports[i].Group < reports[k].Group
+		}
+		return reports[i].Stream < reports[k].Stream
+	})
+	return reports
+}
+
+func durationPtr(d time.Duration) *Duration {
+	v := Duration(d)
+	return &v
+}
+
+// Summary totals the results of a job, as the CLI prints them when it stops
+type Summary struct {
+	JobInfo
+	Elapsed Duration `json:"elapsed"`
+	// Rate is messages sent or received per second
+	Rate     float64                `json:"rate"`
+	Sent     *multicast.SenderStats `json:"sent,omitempty"`
+	Received *ReceivedTotals        `json:"received,omitempty"`
+}
+
+// ReceivedTotals adds up the streams a receiver heard
+type ReceivedTotals struct {
+	Streams     int            `json:"streams"`
+	Messages    int            `json:"messages"`
+	Lost        int            `json:"lost"`
+	Reordered   int            `json:"reordered"`
+	Duplicates  int            `json:"duplicates"`
+	Bytes       int            `json:"bytes"`
+	LossPercent float64        `json:"loss_percent"`
+	PerStream   []StreamReport `json:"per_stream"`
+}
+
+// Summary returns the totals of a job so far, or final once it stopped
+func (m *Manager) Summary(id string) (Summary, error) {
+	info, ok := m.Get(id)
+	if !ok {
+		return Summary{}, ErrNotFound
+	}
+	stats, err := m.Stats(id)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	end := stats.Time
+	if info.Stopped != nil {
+		end = *info.Stopped
+	}
+	elapsed := end.Sub(info.Started)
+	summary := Summary{JobInfo: info, Elapsed: Duration(elapsed)}
+
+	if stats.Sent != nil {
+		summary.Sent = stats.Sent
+		summary.Rate = rate(stats.Sent.Messages, elapsed)
+		return summary, nil
+	}
+
+	totals := &ReceivedTotals{Streams: len(stats.Streams), PerStream: stats.Streams}
+	for _, s := range stats.Streams {
+		totals.Messages += s.Received
+		totals.Lost += s.Lost
+		totals.Reordered += s.Reordered
+		totals.Duplicates += s.Duplicates
+		totals.Bytes += s.Bytes
+	}
+	if totals.Messages+totals.Lost > 0 {
+		totals.LossPercent = float64(totals.Lost) * 100 / float64(totals.Messages+totals.Lost)
+	}
+	summary.Received = totals
+	summary.Rate = rate(totals.Messages, elapsed)
+	return summary, nil
+}
+
+func rate(n int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
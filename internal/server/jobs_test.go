@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDurationJSON(t *testing.T) {
+	data, err := json.Marshal(Duration(1500 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, `"1.5s"`, string(data))
+
+	var d Duration
+	require.NoError(t, json.Unmarshal([]byte(`"250ms"`), &d))
+	assert.Equal(t, Duration(250*time.Millisecond), d)
+	assert.Error(t, json.Unmarshal([]byte(`250`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`"soon"`), &d))
+}
+
+func TestDurationYAML(t *testing.T) {
+	var spec JobSpec
+	require.NoError(t, yaml.Unmarshal([]byte("interval: 250ms\nttl: 4"), &spec))
+	assert.Equal(t, Duration(250*time.Millisecond), spec.Interval)
+	assert.Equal(t, 4, spec.TTL)
+	assert.ErrorContains(t, yaml.Unmarshal([]byte("interval: soon"), &spec), "line 1")
+
+	data, err := yaml.Marshal(JobSpec{Interval: Duration(time.Second)})
+	require.NoError(t, err)
+	assert.Equal(t, "interval: 1s\n", string(data))
+}
+
+func TestManagerCreateValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		spec JobSpec
+		err  string
+	}{
+		{name: "no type", spec: JobSpec{}, err: "job type must be sender or receiver"},
+		{name: "unknown type", spec: JobSpec{Type: "relay"}, err: "job type must be sender or receiver"},
+		{name: "negative duration", spec: JobSpec{Type: TypeSender, Duration: -1}, err: "duration must not be negative"},
+		{name: "invalid TTL", spec: JobSpec{Type: TypeSender, TTL: 300}, err: "TTL must be between 1 and 255"},
+		{name: "negative interval", spec: JobSpec{Type: TypeSender, Interval: Duration(-time.Second)}, err: "interval must not be negative"},
+		{name: "invalid source IP", spec: JobSpec{Type: TypeSender, SourceIP: "nowhere"}, err: "invalid source IP"},
+		{name: "unicast group", spec: JobSpec{Type: TypeReceiver, Group: "192.0.2.1:5000"}, err: "not a multicast address"},
+	}
+
+	m := NewManager(io.Discard)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Create(tt.spec)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+	assert.Empty(t, m.List())
+}
+
+func TestManagerJobs(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	m := NewManager(io.Discard)
+	events, cancel := m.Events().Subscribe("")
+	defer cancel()
+
+	receiver, err := m.Create(JobSpec{Type: TypeReceiver, Group: "239.23.23.54:23257", Interface: "lo"})
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", receiver.ID)
+	assert.Equal(t, StateRunning, receiver.State)
+	assert.Equal(t, Duration(time.Second), receiver.Spec.Interval)
+
+	sender, err := m.Create(JobSpec{Type: TypeSender, Group: "239.23.23.54:23257", Interface: "lo",
+		Interval: Duration(10 * time.Millisecond), Duration: Duration(200 * time.Millisecond), SenderID: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "job-2", sender.ID)
+
+	jobs := m.List()
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "job-1", jobs[0].ID)
+	assert.Equal(t, "job-2", jobs[1].ID)
+
+	// The sender stops by itself after its duration
+	require.Eventually(t, func() bool {
+		info, _ := m.Get(sender.ID)
+		return info.State == StateStopped
+	}, 2*time.Second, 10*time.Millisecond)
+
+	summary, err := m.Summary(sender.ID)
+	require.NoError(t, err)
+	require.NotNil(t, summary.Sent)
+	assert.Nil(t, summary.Received)
+	assert.Positive(t, summary.Sent.Messages)
+	assert.Positive(t, summary.Rate)
+
+	stopped, err := m.Stop(receiver.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, stopped.State)
+	require.NotNil(t, stopped.Stopped)
+
+	// Results stay available once the receiver stopped
+	summary, err = m.Summary(receiver.ID)
+	require.NoError(t, err)
+	require.NotNil(t, summary.Received)
+	if summary.Received.Messages == 0 {
+		t.Skip("multicast loopback not available")
+	}
+	assert.Equal(t, 1, summary.Received.Streams)
+	assert.Equal(t, 0, summary.Received.Lost)
+	stream := summary.Received.PerStream[0]
+	assert.Equal(t, "239.23.23.54:23257", stream.Group)
+	assert.Contains(t, stream.Stream, "a@")
+	assert.NotNil(t, stream.DelayP50)
+
+	states := map[string][]string{}
+	lines := 0
+	for len(events) > 0 {
+		e := <-events
+		switch e.Type {
+		case EventState:
+			states[e.Job] = append(states[e.Job], e.State)
+		case EventOutput:
+			lines++
+		}
+	}
+	assert.Equal(t, []string{StateRunning, StateStopped}, states["job-1"])
+	assert.Equal(t, []string{StateRunning, StateStopped}, states["job-2"])
+	assert.Positive(t, lines)
+
+	require.NoError(t, m.Remove(sender.ID))
+	_, ok := m.Get(sender.ID)
+	assert.False(t, ok)
+	assert.ErrorIs(t, m.Remove(sender.ID), ErrNotFound)
+	_, err = m.Stats("job-9")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManagerStopWaitingSender(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("loopback interface 'lo' not available")
+	}
+
+	m := NewManager(io.Discard)
+	sender, err := m.Create(JobSpec{Type: TypeSender, Group: "239.23.23.69:23266", Interface: "lo",
+		Interval: Duration(time.Hour)})
+	require.NoError(t, err)
+
+	// Stopping does not wait for the next send
+	start := time.Now()
+	stopped, err := m.Stop(sender.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, stopped.State)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestManagerShutdown(t *testing.T) {
+	m := NewManager(io.Discard)
+	for i := 0; i < 3; i++ {
+		_, err := m.Create(JobSpec{Type: TypeReceiver, Group: "239.23.23.54:23257"})
+		require.NoError(t, err)
+	}
+
+	m.Shutdown()
+	for _, job := range m.List() {
+		assert.Equal(t, StateStopped, job.State)
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// NewHandler serves the REST API of a manager:
+//
+//	GET    /api/info               the host name and number of jobs
+//	POST   /api/jobs               create a job from a JobSpec
+//	GET    /api/jobs               list the jobs
+//	GET    /api/jobs/{id}          describe a job
+//	DELETE /api/jobs/{id}          stop a job and forget it
+//	POST   /api/jobs/{id}/stop     stop a job, keeping its results
+//	GET    /api/jobs/{id}/stats    live stats of a job
+//	GET    /api/jobs/{id}/summary  totals of a job
+//	GET    /api/events[?job={id}]  output and state changes as server-sent events
+func NewHandler(m *Manager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		writeJSON(w, http.StatusOK, m.Info())
+	})
+	mux.HandleFunc("/api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, m.List())
+		case http.MethodPost:
+			var spec JobSpec
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&spec); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job: %w", err))
+				return
+			}
+			info, err := m.Create(spec)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			w.Header().Set("Location", "/api/jobs/"+info.ID)
+			writeJSON(w, http.StatusCreated, info)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	})
+	mux.HandleFunc("/api/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/")
+		handleJob(m, w, r, id, action)
+	})
+	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		serveEvents(m, w, r)
+	})
+	return mux
+}
+
+// handleJob serves the requests about one job
+func handleJob(m *Manager, w http.ResponseWriter, r *http.Request, id, action string) {
+	var (
+		result any
+		err    error
+	)
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		info, ok := m.Get(id)
+		if !ok {
+			err = ErrNotFound
+		}
+		result = info
+	case action == "" && r.Method == http.MethodDelete:
+		if err = m.Remove(id); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	case action == "":
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		return
+	case action == "stop" && r.Method == http.MethodPost:
+		result, err = m.Stop(id)
+	case action == "stats" && r.Method == http.MethodGet:
+		result, err = m.Stats(id)
+	case action == "summary" && r.Method == http.MethodGet:
+		result, err = m.Summary(id)
+	case action == "stop":
+		methodNotAllowed(w, http.MethodPost)
+		return
+	case action == "stats" || action == "summary":
+		methodNotAllowed(w, http.MethodGet)
+		return
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+		return
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", err, id))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveEvents streams events as server-sent events until the client goes away
+func serveEvents(m *Manager, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	job := r.URL.Query().Get("job")
+	if job != "" {
+		if _, ok := m.Get(job); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrNotFound, job))
+			return
+		}
+	}
+
+	events, cancel := m.Events().Subscribe(job)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// RequireToken lets only the requests carrying "Authorization: Bearer
+// <token>" through to next; an empty token lets every request through
+func RequireToken(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+}
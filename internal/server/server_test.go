@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	m := NewManager(io.Discard)
+	defer m.Shutdown()
+	srv := httptest.NewServer(NewHandler(m))
+	defer srv.Close()
+
+	request := func(method, path, body string) (int, map[string]any) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		error  string
+	}{
+		{name: "invalid JSON", method: http.MethodPost, path: "/api/jobs", body: "{", status: http.StatusBadRequest, error: "invalid job"},
+		{name: "unknown field", method: http.MethodPost, path: "/api/jobs", body: `{"type":"sender","rate":5}`, status: http.StatusBadRequest, error: "unknown field"},
+		{name: "invalid job", method: http.MethodPost, path: "/api/jobs", body: `{"type":"sender","ttl":0,"group":"nowhere"}`, status: http.StatusBadRequest},
+		{name: "negative interval", method: http.MethodPost, path: "/api/jobs", body: `{"type":"sender","interval":"-1s"}`, status: http.StatusBadRequest, error: "interval must not be negative"},
+		{name: "unknown job", method: http.MethodGet, path: "/api/jobs/job-9", status: http.StatusNotFound, error: "job not found: job-9"},
+		{name: "unknown stats", method: http.MethodGet, path: "/api/jobs/job-9/stats", status: http.StatusNotFound},
+		{name: "unknown action", method: http.MethodGet, path: "/api/jobs/job-9/logs", status: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPut, path: "/api/jobs", status: http.StatusMethodNotAllowed},
+		{name: "wrong stop method", method: http.MethodGet, path: "/api/jobs/job-9/stop", status: http.StatusMethodNotAllowed},
+		{name: "events of unknown job", method: http.MethodGet, path: "/api/events?job=job-9", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := request(tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.Contains(t, result["error"], tt.error)
+		})
+	}
+
+	t.Run("info", func(t *testing.T) {
+		status, info := request(http.MethodGet, "/api/info", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, info["hostname"])
+		assert.Contains(t, info, "running")
+	})
+
+	t.Run("lifecycle", func(t *testing.T) {
+		status, job := request(http.MethodPost, "/api/jobs", `{"type":"receiver","group":"239.23.23.54:23257"}`)
+		require.Equal(t, http.StatusCreated, status)
+		id := job["id"].(string)
+		assert.Equal(t, "running", job["state"])
+
+		status, job = request(http.MethodGet, "/api/jobs/"+id, "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "239.23.23.54:23257", job["spec"].(map[string]any)["group"])
+
+		status, stats := request(http.MethodGet, "/api/jobs/"+id+"/stats", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, id, stats["job"])
+
+		status, job = request(http.MethodPost, "/api/jobs/"+id+"/stop", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "stopped", job["state"])
+
+		status, summary := request(http.MethodGet, "/api/jobs/"+id+"/summary", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, summary, "received")
+
+		status, _ = request(http.MethodDelete, "/api/jobs/"+id, "")
+		assert.Equal(t, http.StatusNoContent, status)
+		status, _ = request(http.MethodGet, "/api/jobs/"+id, "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestHandlerEvents(t *testing.T) {
+	m := NewManager(io.Discard)
+	defer m.Shutdown()
+	srv := httptest.NewServer(NewHandler(m))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	m.Events().Publish(Event{Job: "job-1", Type: EventOutput, Line: "hello"})
+
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "event: output", scanner.Text())
+	require.True(t, scanner.Scan())
+	data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+	require.True(t, ok)
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(data), &e))
+	assert.Equal(t, "hello", e.Line)
+	assert.Equal(t, "job-1", e.Job)
+}
+
+func TestRequireToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{name: "no token required", token: "", header: "", status: http.StatusNoContent},
+		{name: "valid token", token: "s3cret", header: "Bearer s3cret", status: http.StatusNoContent},
+		{name: "missing token", token: "s3cret", header: "", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "s3cret", header: "Bearer guess", status: http.StatusUnauthorized},
+		{name: "token without scheme", token: "s3cret", header: "s3cret", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			RequireToken(next, tt.token).ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusUnauthorized {
+				assert.Contains(t, rec.Body.String(), "missing or invalid token")
+			}
+		})
+	}
+}
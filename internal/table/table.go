@@ -0,0 +1,58 @@
+// Package table lays out the plain-text tables of reports and the values in
+// their cells
+package table
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Write writes rows with every column as wide as its widest cell, each line
+// starting with indent
+func Write(w io.Writer, indent string, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		WriteRow(w, indent, row, widths)
+	}
+}
+
+// WriteRow writes one line of cells, each padded to its width and followed by
+// two spaces, without trailing spaces
+func WriteRow(w io.Writer, indent string, cells []string, widths []int) {
+	var b strings.Builder
+	b.WriteString(indent)
+	for i, cell := range cells {
+		b.WriteString(Pad(cell, widths[i]+2))
+	}
+	fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+}
+
+// Pad pads s with spaces to width characters, or adds one space if it is
+// already as wide
+func Pad(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s + " "
+}
+
+// FormatDelay rounds a delay to the precision worth showing
+func FormatDelay(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,40 @@
+package table
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	var out strings.Builder
+	Write(&out, "   ", [][]string{
+		{"NAME", "LOSS"},
+		{"✓ video", "0.0%"},
+		{"audio-backup", "12.5%"},
+	})
+	assert.Equal(t, ""+
+		"   NAME          LOSS\n"+
+		"   ✓ video       0.0%\n"+
+		"   audio-backup  12.5%\n", out.String())
+}
+
+func TestWriteRow(t *testing.T) {
+	var out strings.Builder
+	WriteRow(&out, "", []string{"a", "b", ""}, []int{3, 3, 3})
+	assert.Equal(t, "a    b\n", out.String())
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "ab  ", Pad("ab", 4))
+	assert.Equal(t, "✓   ", Pad("✓", 4))
+	assert.Equal(t, "abcd ", Pad("abcd", 4))
+}
+
+func TestFormatDelay(t *testing.T) {
+	assert.Equal(t, "412µs", FormatDelay(412345*time.Nanosecond))
+	assert.Equal(t, "1.23ms", FormatDelay(1234567*time.Nanosecond))
+	assert.Equal(t, "1.23s", FormatDelay(1234567890*time.Nanosecond))
+}
@@ -0,0 +1,20 @@
+package top
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as bars scaled from zero to the largest value
+func Sparkline(values []float64) string {
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	bars := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if peak > 0 && v > 0 {
+			level = min(int(v/peak*float64(len(sparkBars)-1)+0.5), len(sparkBars)-1)
+		}
+		bars[i] = sparkBars[level]
+	}
+	return string(bars)
+}
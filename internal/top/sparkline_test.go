@@ -0,0 +1,26 @@
+package top
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		expected string
+	}{
+		{name: "empty", expected: ""},
+		{name: "all zero", values: []float64{0, 0, 0}, expected: "▁▁▁"},
+		{name: "ramp", values: []float64{0, 1, 2, 3, 4, 5, 6, 7}, expected: "▁▂▃▄▅▆▇█"},
+		{name: "scaled to the peak", values: []float64{50, 100, 50}, expected: "▅█▅"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Sparkline(tt.values))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package top
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Terminal is a full-screen session on a terminal: the alternate screen with
+// the cursor hidden and keys delivered as they are typed
+type Terminal struct {
+	in       *os.File
+	out      io.Writer
+	original *unix.Termios
+}
+
+// OpenTerminal switches the terminal on in to unbuffered input without echo
+// and out to the alternate screen. Ctrl+C still raises SIGINT.
+func OpenTerminal(in *os.File, out io.Writer) (*Terminal, error) {
+	fd := int(in.Fd())
+	original, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings (is stdin a terminal?): %w", err)
+	}
+
+	raw := *original
+	raw.Lflag &^= unix.ECHO | unix.ICANON
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("failed to set terminal settings: %w", err)
+	}
+
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	return &Terminal{in: in, out: out, original: original}, nil
+}
+
+// Size returns the width and height of the terminal, or 80x24 if unknown
+func (t *Terminal) Size() (width, height int) {
+	ws, err := unix.IoctlGetWinsize(int(t.in.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// Draw replaces the screen with the given lines
+func (t *Terminal) Draw(screen string) {
+	fmt.Fprint(t.out, "\x1b[H\x1b[2J"+screen)
+}
+
+// Keys delivers the keys typed until the terminal is closed
+func (t *Terminal) Keys() <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := t.in.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, b := range buf[:n] {
+				keys <- b
+			}
+		}
+	}()
+	return keys
+}
+
+// Close restores the screen and the terminal settings
+func (t *Terminal) Close() error {
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	if err := unix.IoctlSetTermios(int(t.in.Fd()), ioctlSetTermios, t.original); err != nil {
+		return fmt.Errorf("failed to restore terminal settings: %w", err)
+	}
+	return nil
+}
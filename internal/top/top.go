@@ -0,0 +1,338 @@
+// Package top keeps the rates and history of received streams and renders
+// them as a full-screen table that can be sorted and filtered from the keyboard.
+package top
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+// historySize is how many rate samples the sparklines show
+const historySize = 20
+
+// Column is a column of the table the rows can be sorted by
+type Column int
+
+// Columns, in display order; keys 1-9 and 0 sort by them
+const (
+	ColumnGroup Column = iota
+	ColumnSource
+	ColumnPPS
+	ColumnBPS
+	ColumnLoss
+	ColumnJitter
+	ColumnDelayP50
+	ColumnDelayP99
+	ColumnLastSeen
+	ColumnTTL
+)
+
+var columnNames = []string{"GROUP", "SOURCE", "PPS", "BPS", "LOSS%", "JITTER", "P50", "P99", "LAST", "TTL"}
+
+func (c Column) String() string {
+	return columnNames[c]
+}
+
+// Row is one stream as shown in the table
+type Row struct {
+	Key      multicast.StreamKey
+	Group    string
+	Source   string
+	PPS      float64
+	BPS      float64
+	Loss     float64
+	Jitter   time.Duration
+	DelayP50 time.Duration
+	DelayP99 time.Duration
+	// HasDelay is false for streams without send times, such as RTP
+	HasDelay bool
+	LastSeen time.Time
+	TTL      int
+	// History holds the packet rates of the latest updates, oldest first
+	History []float64
+}
+
+// Model is the state of the table: the streams with their rates, and the
+// sort order and filter chosen with the keyboard
+type Model struct {
+	rows       map[multicast.StreamKey]*Row
+	previous   map[multicast.StreamKey]multicast.StreamStats
+	lastUpdate time.Time
+	sortBy     Column
+	descending bool
+	filter     string
+	editing    bool
+}
+
+// NewModel creates an empty model sorted by packet rate, highest first
+func NewModel() *Model {
+	return &Model{
+		rows:       make(map[multicast.StreamKey]*Row),
+		previous:   make(map[multicast.StreamKey]multicast.StreamStats),
+		sortBy:     ColumnPPS,
+		descending: true,
+	}
+}
+
+// Update takes a snapshot of the receiver's streams; rates are computed from
+// the difference to the previous snapshot
+func (m *Model) Update(streams map[multicast.StreamKey]multicast.StreamStats, at time.Time) {
+	elapsed := at.Sub(m.lastUpdate).Seconds()
+	first := m.lastUpdate.IsZero()
+	m.lastUpdate = at
+
+	for key, stats := range streams {
+		row, ok := m.rows[key]
+		if !ok {
+			row = &Row{Key: key, Source: source(key)}
+			m.rows[key] = row
+		}
+		prev, seen := m.previous[key]
+		m.previous[key] = stats
+
+		row.PPS, row.BPS = 0, 0
+		if !first && elapsed > 0 {
+			if !seen {
+				prev = multicast.StreamStats{}
+			}
+			row.PPS = float64(stats.Received-prev.Received) / elapsed
+			row.BPS = float64(stats.Bytes-prev.Bytes) * 8 / elapsed
+		}
+		row.Group = stats.Group
+		row.Loss = stats.LossPercent()
+		row.Jitter = stats.Jitter
+		row.DelayP50, row.HasDelay = stats.DelayPercentile(50)
+		row.DelayP99, _ = stats.DelayPercentile(99)
+		row.LastSeen = stats.LastSeen
+		row.TTL = stats.TTL
+		row.History = append(row.History, row.PPS)
+		if len(row.History) > historySize {
+			row.History = row.History[len(row.History)-historySize:]
+		}
+	}
+}
+
+// source names the sender of a stream
+func source(key multicast.StreamKey) string {
+	if key.Protocol != "" {
+		return fmt.Sprintf("%s %s SSRC 0x%08x", key.Source, key.Protocol, uint64(key.Session))
+	}
+	if key.SenderID != "" {
+		return key.SenderID + "@" + key.Source
+	}
+	return key.Source
+}
+
+// Rows returns the rows matching the filter in the chosen order
+func (m *Model) Rows() []Row {
+	var rows []Row
+	filter := strings.ToLower(m.filter)
+	for _, row := range m.rows {
+		if filter == "" || strings.Contains(strings.ToLower(row.Group+" "+row.Source), filter) {
+			rows = append(rows, *row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if m.descending {
+			a, b = b, a
+		}
+		if c := compare(m.sortBy, a, b); c != 0 {
+			return c < 0
+		}
+		// Keep a stable order between refreshes
+		return rows[i].Group+rows[i].Key.String() < rows[j].Group+rows[j].Key.String()
+	})
+	return rows
+}
+
+// compare orders two rows by a column
+func compare(column Column, a, b Row) int {
+	switch column {
+	case ColumnGroup:
+		return strings.Compare(a.Group, b.Group)
+	case ColumnSource:
+		return strings.Compare(a.Source, b.Source)
+	case ColumnPPS:
+		return compareValues(a.PPS, b.PPS)
+	case ColumnBPS:
+		return compareValues(a.BPS, b.BPS)
+	case ColumnLoss:
+		return compareValues(a.Loss, b.Loss)
+	case ColumnJitter:
+		return compareValues(a.Jitter, b.Jitter)
+	case ColumnDelayP50:
+		return compareValues(a.DelayP50, b.DelayP50)
+	case ColumnDelayP99:
+		return compareValues(a.DelayP99, b.DelayP99)
+	case ColumnLastSeen:
+		return compareValues(a.LastSeen.UnixNano(), b.LastSeen.UnixNano())
+	case ColumnTTL:
+		return compareValues(a.TTL, b.TTL)
+	}
+	return 0
+}
+
+func compareValues[T int | int64 | float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// HandleKey acts on a key and reports whether it asks to quit. Keys 1-9 and 0
+// sort by a column, again to reverse the order; / edits the filter, which
+// Enter keeps and Escape clears.
+func (m *Model) HandleKey(key byte) (quit bool) {
+	if m.editing {
+		switch key {
+		case '\r', '\n':
+			m.editing = false
+		case 0x1b:
+			m.editing = false
+			m.filter = ""
+		case 0x7f, '\b':
+			if len(m.filter) > 0 {
+				m.filter = m.filter[:len(m.filter)-1]
+			}
+		default:
+			if key >= 0x20 && key < 0x7f {
+				m.filter += string(key)
+			}
+		}
+		return false
+	}
+
+	switch {
+	case key == 'q' || key == 'Q':
+		return true
+	case key == '/':
+		m.editing = true
+	case key == 0x1b:
+		m.filter = ""
+	case key >= '0' && key <= '9':
+		column := Column(key - '1')
+		if key == '0' {
+			column = ColumnTTL
+		}
+		if column == m.sortBy {
+			m.descending = !m.descending
+		} else {
+			m.sortBy = column
+			// Text sorts A-Z, numbers highest first
+			m.descending = column != ColumnGroup && column != ColumnSource
+		}
+	}
+	return false
+}
+
+// Render draws the table to fit width by height characters
+func (m *Model) Render(width, height int, at time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		if len([]rune(s)) > width {
+			s = string([]rune(s)[:width])
+		}
+		b.WriteString(s + "\n")
+	}
+
+	rows := m.Rows()
+	order := "↑"
+	if m.descending {
+		order = "↓"
+	}
+	filter := m.filter
+	if m.editing {
+		filter += "▏"
+	}
+	line(fmt.Sprintf("mcaster top - %s - %d stream(s), sorted by %s %s, filter: %s",
+		at.Format("15:04:05"), len(rows), m.sortBy, order, filter))
+	line("keys: 1-9,0 sort by column (again to reverse)  / filter  Esc clear filter  q quit")
+	line("")
+
+	header := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		header[i] = fmt.Sprintf("%d:%s", (i+1)%10, name)
+	}
+	line(formatRow(header, "TREND"))
+
+	for i, row := range rows {
+		if i >= height-5 {
+			line(fmt.Sprintf("... %d more", len(rows)-i))
+			break
+		}
+		p50, p99 := "-", "-"
+		if row.HasDelay {
+			p50, p99 = formatDuration(row.DelayP50), formatDuration(row.DelayP99)
+		}
+		ttl := "-"
+		if row.TTL >= 0 {
+			ttl = fmt.Sprint(row.TTL)
+		}
+		line(formatRow([]string{
+			row.Group,
+			row.Source,
+			fmt.Sprintf("%.1f", row.PPS),
+			formatBitRate(row.BPS),
+			fmt.Sprintf("%.2f", row.Loss),
+			formatDuration(row.Jitter),
+			p50,
+			p99,
+			formatDuration(at.Sub(row.LastSeen)),
+			ttl,
+		}, Sparkline(row.History)))
+	}
+	return b.String()
+}
+
+var columnWidths = []int{21, 17, 8, 9, 8, 9, 9, 9, 7, 6}
+
+func formatRow(cells []string, trend string) string {
+	var b strings.Builder
+	for i, cell := range cells {
+		w := columnWidths[i] - 1
+		runes := []rune(cell)
+		if len(runes) > w {
+			runes = runes[:w]
+		}
+		padding := strings.Repeat(" ", w-len(runes))
+		// Text is left aligned, numbers right aligned
+		if i < 2 {
+			b.WriteString(string(runes) + padding + " ")
+		} else {
+			b.WriteString(padding + string(runes) + " ")
+		}
+	}
+	return b.String() + trend
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 10*time.Second:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+func formatBitRate(bps float64) string {
+	switch {
+	case bps >= 1e9:
+		return fmt.Sprintf("%.2fG", bps/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2fM", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.1fk", bps/1e3)
+	}
+	return fmt.Sprintf("%.0f", bps)
+}
@@ -0,0 +1,116 @@
+package top
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	keyA = multicast.StreamKey{Source: "host-a", SenderID: "probe", Session: 1, Stream: "239.1.1.1:5000"}
+	keyB = multicast.StreamKey{Source: "host-b", Session: 2, Stream: "239.1.1.2:5000"}
+)
+
+func snapshot(receivedA, receivedB int, at time.Time) map[multicast.StreamKey]multicast.StreamStats {
+	return map[multicast.StreamKey]multicast.StreamStats{
+		keyA: {Received: receivedA, Lost: 1, Bytes: receivedA * 100, Group: "239.1.1.1:5000", LastSeen: at, TTL: 64},
+		keyB: {Received: receivedB, Bytes: receivedB * 1000, Group: "239.1.1.2:5000", LastSeen: at.Add(-3 * time.Second), TTL: -1},
+	}
+}
+
+func TestModelUpdate(t *testing.T) {
+	m := NewModel()
+	start := time.Now()
+	m.Update(snapshot(10, 10, start), start)
+	m.Update(snapshot(110, 20, start.Add(time.Second)), start.Add(time.Second))
+	m.Update(snapshot(310, 30, start.Add(2*time.Second)), start.Add(2*time.Second))
+
+	rows := m.Rows()
+	require.Len(t, rows, 2)
+
+	// Sorted by packet rate, highest first
+	a, b := rows[0], rows[1]
+	assert.Equal(t, keyA, a.Key)
+	assert.Equal(t, "probe@host-a", a.Source)
+	assert.Equal(t, 200.0, a.PPS)
+	assert.Equal(t, 200.0*100*8, a.BPS)
+	assert.InDelta(t, 100.0/311, a.Loss, 0.0001)
+	assert.Equal(t, []float64{0, 100, 200}, a.History)
+	assert.False(t, a.HasDelay)
+	assert.Equal(t, 64, a.TTL)
+	assert.Equal(t, 10.0, b.PPS)
+	assert.Equal(t, "host-b", b.Source)
+}
+
+func TestModelKeys(t *testing.T) {
+	m := NewModel()
+	start := time.Now()
+	m.Update(snapshot(10, 10, start), start)
+	m.Update(snapshot(20, 100, start.Add(time.Second)), start.Add(time.Second))
+
+	order := func() []string {
+		var groups []string
+		for _, row := range m.Rows() {
+			groups = append(groups, row.Group)
+		}
+		return groups
+	}
+
+	assert.Equal(t, []string{"239.1.1.2:5000", "239.1.1.1:5000"}, order())
+
+	// 1 sorts by group A-Z, again reverses
+	assert.False(t, m.HandleKey('1'))
+	assert.Equal(t, []string{"239.1.1.1:5000", "239.1.1.2:5000"}, order())
+	m.HandleKey('1')
+	assert.Equal(t, []string{"239.1.1.2:5000", "239.1.1.1:5000"}, order())
+
+	// 5 sorts by loss, highest first; 0 by TTL
+	m.HandleKey('5')
+	assert.Equal(t, ColumnLoss, m.sortBy)
+	assert.Equal(t, []string{"239.1.1.1:5000", "239.1.1.2:5000"}, order())
+	m.HandleKey('0')
+	assert.Equal(t, ColumnTTL, m.sortBy)
+
+	// / edits the filter, Enter keeps it and Escape clears it
+	for _, key := range []byte("/PROBEx\x7f\r") {
+		assert.False(t, m.HandleKey(key))
+	}
+	assert.Equal(t, "PROBE", m.filter)
+	assert.Equal(t, []string{"239.1.1.1:5000"}, order())
+	m.HandleKey(0x1b)
+	assert.Len(t, order(), 2)
+
+	// q in the filter is text, outside it quits
+	m.HandleKey('/')
+	assert.False(t, m.HandleKey('q'))
+	m.HandleKey('\r')
+	assert.True(t, m.HandleKey('q'))
+}
+
+func TestModelRender(t *testing.T) {
+	m := NewModel()
+	start := time.Now()
+	m.Update(snapshot(10, 10, start), start)
+	m.Update(snapshot(20, 100, start.Add(time.Second)), start.Add(time.Second))
+
+	screen := m.Render(200, 24, start.Add(time.Second))
+	lines := strings.Split(strings.TrimSuffix(screen, "\n"), "\n")
+	require.Len(t, lines, 6)
+	assert.Contains(t, lines[0], "2 stream(s), sorted by PPS ↓")
+	assert.Contains(t, lines[3], "3:PPS")
+	assert.Contains(t, lines[4], "239.1.1.2:5000")
+	assert.Contains(t, lines[4], "720.0k")
+	assert.Contains(t, lines[4], "3s")
+	assert.True(t, strings.HasSuffix(lines[4], "▁█"))
+	assert.Contains(t, lines[5], "probe@host-a")
+
+	// Lines are cut to the width and rows to the height
+	for _, line := range strings.Split(m.Render(40, 5, start), "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 40)
+	}
+	assert.Contains(t, m.Render(200, 5, start), "... 2 more")
+}
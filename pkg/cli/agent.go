@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newAgentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run senders and receivers for a controller running \"mcaster run\"",
+		Long: `Run on each host of a test so "mcaster run" can start receivers and senders
+on it over HTTP and collect their results. The agent serves the same API as
+"mcaster serve"; several agents may run on one host on different ports.
+
+Anyone who can reach an agent can start senders with any TTL, rate and
+destination, so it listens on 127.0.0.1 by default. Agents serving a
+controller on another host should require a token with --token or
+MULTICAST_API_TOKEN, which "mcaster run" then sends.`,
+		Example: `  # On each host of the test, requiring a token
+  MULTICAST_API_TOKEN=s3cret mcaster agent --listen :7400
+
+  # Three agents on one host, to try a plan locally
+  mcaster agent --listen 127.0.0.1:7401 &
+  mcaster agent --listen 127.0.0.1:7402 &
+  mcaster agent --listen 127.0.0.1:7403 &`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			return serveAPI(listen, apiToken(cmd), "agent API")
+		},
+	}
+
+	cmd.Flags().String("listen", "127.0.0.1:7400", "address and port to serve the agent API on")
+	cmd.Flags().String("token", "", "require this bearer token on every request (default $MULTICAST_API_TOKEN)")
+
+	return cmd
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/auth"
+)
+
+// loadKeyRing loads the configured authentication keys, or returns nil if no
+// key file is configured
+func loadKeyRing() (*auth.KeyRing, error) {
+	return loadKeyFile("auth-key-file")
+}
+
+// loadCipherKeyRing loads the configured encryption keys, or returns nil if no
+// key file is configured
+func loadCipherKeyRing() (*auth.KeyRing, error) {
+	return loadKeyFile("encrypt-key-file")
+}
+
+func loadKeyFile(setting string) (*auth.KeyRing, error) {
+	path := viper.GetString(setting)
+	if path == "" {
+		return nil, nil
+	}
+	return auth.LoadKeyFile(path)
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+// expandGroupList replaces the addresses in groupList with the groups of
+// groupRange, keeping the port of the first configured group
+func expandGroupList(groupList, groupRange string, dport int) (string, error) {
+	if groupRange == "" {
+		return groupList, nil
+	}
+
+	configured, err := network.ResolveGroups(groupList, dport)
+	if err != nil {
+		return "", err
+	}
+
+	ips, err := expandGroupRanges(groupRange)
+	if err != nil {
+		return "", err
+	}
+
+	port := strconv.Itoa(configured[0].Port)
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.JoinHostPort(ip.String(), port)
+	}
+
+	return strings.Join(addrs, ","), nil
+}
+
+// expandGroupRanges expands a comma-separated list of group ranges
+func expandGroupRanges(groupRanges string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, spec := range strings.Split(groupRanges, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		groups, err := network.ExpandGroupRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, groups...)
+	}
+	return ips, nil
+}
+
+// parseGroupIPs accepts groups given as bare IP addresses or as address:port
+func parseGroupIPs(args []string) ([]net.IP, error) {
+	ips := make([]net.IP, 0, len(args))
+	for _, arg := range args {
+		for _, entry := range strings.Split(arg, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if ip := net.ParseIP(strings.Trim(entry, "[]")); ip != nil {
+				ips = append(ips, ip)
+				continue
+			}
+			addr, err := net.ResolveUDPAddr("udp", entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid group %q: %w", entry, err)
+			}
+			ips = append(ips, addr.IP)
+		}
+	}
+	return ips, nil
+}
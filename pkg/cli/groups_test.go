@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandGroupList(t *testing.T) {
+	tests := []struct {
+		name        string
+		groupList   string
+		groupRange  string
+		dport       int
+		expected    string
+		expectError bool
+	}{
+		{
+			name:      "no range keeps the group list",
+			groupList: "239.1.1.1:5000,239.1.1.2:5000",
+			expected:  "239.1.1.1:5000,239.1.1.2:5000",
+		},
+		{
+			name:       "range uses the group port",
+			groupList:  "239.23.23.23:2323",
+			groupRange: "239.1.1.1-3",
+			expected:   "239.1.1.1:2323,239.1.1.2:2323,239.1.1.3:2323",
+		},
+		{
+			name:       "range uses the dport override",
+			groupList:  "239.23.23.23:2323",
+			groupRange: "239.1.1.0/31",
+			dport:      5000,
+			expected:   "239.1.1.0:5000,239.1.1.1:5000",
+		},
+		{
+			name:       "several ranges",
+			groupList:  "239.23.23.23:2323",
+			groupRange: "239.1.1.1-239.1.1.1,224.1.1.1/32",
+			expected:   "239.1.1.1:2323,224.1.1.1:2323",
+		},
+		{
+			name:       "IPv6 range",
+			groupList:  "[ff15::1]:2323",
+			groupRange: "ff15::1-ff15::2",
+			expected:   "[ff15::1]:2323,[ff15::2]:2323",
+		},
+		{
+			name:        "invalid range",
+			groupList:   "239.23.23.23:2323",
+			groupRange:  "239.1.1.1",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := expandGroupList(tt.groupList, tt.groupRange, tt.dport)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseGroupIPs(t *testing.T) {
+	ips, err := parseGroupIPs([]string{"239.1.1.1", "224.1.1.1:5000,ff02::1", "[ff15::1]:2323"})
+	require.NoError(t, err)
+	require.Len(t, ips, 4)
+	assert.Equal(t, "239.1.1.1", ips[0].String())
+	assert.Equal(t, "224.1.1.1", ips[1].String())
+	assert.Equal(t, "ff02::1", ips[2].String())
+	assert.Equal(t, "ff15::1", ips[3].String())
+
+	_, err = parseGroupIPs([]string{"not-a-group"})
+	assert.Error(t, err)
+}
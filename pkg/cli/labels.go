@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseLabels parses key=value label arguments
+func parseLabels(args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", arg)
+		}
+		if _, exists := labels[key]; exists {
+			return nil, fmt.Errorf("duplicate label %q", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// readPayload reads the payload file, or returns nil if none is configured
+func readPayload(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload file: %w", err)
+	}
+	return payload, nil
+}
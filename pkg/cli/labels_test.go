@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expected    map[string]string
+		expectError bool
+	}{
+		{name: "none", args: nil, expected: nil},
+		{name: "single", args: []string{"run=chg1234"}, expected: map[string]string{"run": "chg1234"}},
+		{
+			name:     "several, values may contain = and commas",
+			args:     []string{"run=chg1234", "note=a=b,c", "empty="},
+			expected: map[string]string{"run": "chg1234", "note": "a=b,c", "empty": ""},
+		},
+		{name: "missing value separator", args: []string{"run"}, expectError: true},
+		{name: "missing key", args: []string{"=x"}, expectError: true},
+		{name: "duplicate key", args: []string{"run=1", "run=2"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := parseLabels(tt.args)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, labels)
+		})
+	}
+}
+
+func TestReadPayload(t *testing.T) {
+	payload, err := readPayload("")
+	require.NoError(t, err)
+	assert.Nil(t, payload)
+
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	require.NoError(t, os.WriteFile(path, []byte{0, 1, 2}, 0o600))
+	payload, err = readPayload(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, payload)
+
+	_, err = readPayload(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+func newMacCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mac [group...]",
+		Short: "Show the Ethernet MAC addresses groups map to",
+		Long: `Compute the Ethernet destination MAC address (01:00:5e for IPv4, 33:33 for
+IPv6) of each group, list the other IPv4 groups that share it, and warn about
+configured groups that alias each other. Without arguments the configured
+--group list or --group-range is checked.`,
+		Example: `  # Show the MAC address of a group and the 31 groups aliasing it
+  mcaster mac 239.1.1.1
+
+  # Check a set of groups for aliasing
+  mcaster mac 239.1.1.1 224.1.1.1 239.129.1.1
+
+  # Check a whole range before assigning it
+  mcaster mac --group-range 239.0.0.0/24`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groups, err := parseGroupIPs(args)
+			if err != nil {
+				return err
+			}
+
+			if groupRange, _ := cmd.Flags().GetString("group-range"); groupRange != "" {
+				rangeGroups, err := expandGroupRanges(groupRange)
+				if err != nil {
+					return err
+				}
+				groups = append(groups, rangeGroups...)
+			}
+
+			if len(groups) == 0 {
+				groups, err = parseGroupIPs([]string{viper.GetString("group")})
+				if err != nil {
+					return err
+				}
+			}
+
+			printGroupMACs(cmd, groups)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("group-range", "", "check ranges of groups (CIDR or first-last, comma-separated)")
+
+	return cmd
+}
+
+func printGroupMACs(cmd *cobra.Command, groups []net.IP) {
+	out := cmd.OutOrStdout()
+
+	// Listing 31 aliases per group is only useful for a handful of groups
+	showAliases := len(groups) <= 16
+
+	for _, group := range groups {
+		mac := network.MulticastMAC(group)
+		if mac == nil {
+			fmt.Fprintf(out, "%-18s %-19s %s\n", group, "-", "not a multicast address")
+			continue
+		}
+
+		fmt.Fprintf(out, "%-18s %-19s %s\n", group, mac, network.ClassifyGroup(group))
+		if !showAliases {
+			continue
+		}
+
+		if aliases := network.MACAliases(group); aliases != nil {
+			names := make([]string, len(aliases))
+			for i, alias := range aliases {
+				names[i] = alias.String()
+			}
+			fmt.Fprintf(out, "    also used by: %s\n", strings.Join(names, ", "))
+		} else {
+			fmt.Fprintf(out, "    also used by: every IPv6 group ending in the same 32 bits\n")
+		}
+	}
+
+	overlaps := network.FindMACOverlaps(groups)
+	if len(overlaps) == 0 {
+		if len(groups) > 1 {
+			fmt.Fprintf(out, "\n✅ No MAC address aliasing between the %d groups\n", len(groups))
+		}
+		return
+	}
+
+	fmt.Fprintln(out)
+	for _, overlap := range overlaps {
+		fmt.Fprintf(out, "⚠️  %s\n", overlap)
+	}
+}
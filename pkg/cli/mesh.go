@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/mesh"
+)
+
+func newMeshCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mesh",
+		Short: "Send and receive among many nodes and show an N×N matrix",
+		Long: `Run one node of a full mesh, as on fabrics where every site is both a source
+and a receiver. Every node sends its own stream to the group, identified by
+--sender-id, and receives the streams of all others. Once a second each node
+multicasts what it receives, so every node can print the whole matrix on exit:
+a row per sending node and a column per receiving node with loss and median
+delay. Paths that fail in one direction only are marked as asymmetric.
+
+Run the same command on every site with a different --sender-id; several nodes
+may run on one host. The exit status is non-zero if any path received nothing.`,
+		Example: `  # On each site, for a minute
+  mcaster mesh -g 239.1.1.1:5000 -i eth0 --sender-id site-a --duration 1m
+
+  # List the sites expected, so sites never heard of show up in the matrix
+  mcaster mesh --sender-id site-a --peers site-a,site-b,site-c
+
+  # Three nodes on one host
+  for n in a b c; do mcaster mesh -i lo --sender-id $n --duration 10s & done; wait`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bound here rather than at construction so send's flags keep their keys
+			for _, name := range []string{"interval", "ttl", "sender-id"} {
+				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			peerList, _ := cmd.Flags().GetString("peers")
+			duration, _ := cmd.Flags().GetDuration("duration")
+			reportInterval, _ := cmd.Flags().GetDuration("report-interval")
+
+			name := viper.GetString("sender-id")
+			if name == "" {
+				name, _ = os.Hostname()
+			}
+			var peers []string
+			for _, peer := range strings.Split(peerList, ",") {
+				if peer = strings.TrimSpace(peer); peer != "" {
+					peers = append(peers, peer)
+				}
+			}
+
+			node, err := mesh.NewNode(name, viper.GetString("group"), viper.GetString("interface"),
+				viper.GetDuration("interval"), viper.GetInt("ttl"),
+				mesh.WithPeers(peers), mesh.WithReportInterval(reportInterval), mesh.WithOutput(os.Stdout))
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+			fmt.Printf("🕸️  Node %s joined the mesh on %s, reporting every %v\n", name, viper.GetString("group"), reportInterval)
+			if len(peers) > 0 {
+				fmt.Printf("👥 Expecting %s\n", strings.Join(peers, ", "))
+			}
+			fmt.Printf("⏹️  Press Ctrl+C to stop and show the matrix\n\n")
+
+			matrix, err := node.Run(ctx)
+			if err != nil {
+				return err
+			}
+			matrix.Print(os.Stdout)
+
+			if failed := matrix.Failed(); len(failed) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d mesh path(s) received nothing", len(failed))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("sender-id", "", "name of this node, carried in its messages (default: the hostname)")
+	cmd.Flags().String("peers", "", "comma-separated names of the nodes expected in the mesh")
+	cmd.Flags().DurationP("interval", "t", time.Second, "send interval")
+	cmd.Flags().Int("ttl", 1, "TTL (Time To Live) for multicast packets (1-255)")
+	cmd.Flags().Duration("duration", 0, "stop and show the matrix after this long (0 = until Ctrl+C)")
+	cmd.Flags().Duration("report-interval", mesh.DefaultReportInterval, "time between the reports nodes exchange")
+
+	return cmd
+}
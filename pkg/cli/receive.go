@@ -1,18 +1,33 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/hyposcaler-bot/mcaster/internal/decode"
 	"github.com/hyposcaler-bot/mcaster/internal/multicast"
 )
 
 func newReceiveCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "receive",
 		Short: "Receive multicast packets",
 		Long: `Listen for multicast packets and display their contents including
-timing information and network delay calculations.`,
+timing information and network delay calculations.
+
+With --rejoin the receiver leaves its groups and joins them again, over and
+over, and shows how long the first packet took to arrive after each join and
+the last after each leave, with their distribution when it stops. A leave
+takes effect on the socket at once, so the time to the last packet only
+covers datagrams already on their way up the host's stack; how long the
+network keeps forwarding a group after a leave is not visible to a receiver.`,
 		Example: `  # Receive from default group
   mcaster receive
 
@@ -20,18 +35,166 @@ timing information and network delay calculations.`,
   mcaster receive -g 224.0.1.1:8080 -i eth0
 
   # Receive on specific destination port
-  mcaster receive --dport 8080`,
+  mcaster receive --dport 8080
+
+  # Receive several groups on the same port, which needs the wildcard address
+  mcaster receive -g 239.1.1.1:5000,239.1.1.2:5000 --bind any
+
+  # Receive every group of a range on the port of --group
+  mcaster receive --group-range 239.1.1.0/28 -g 239.1.1.0:5000 --bind any
+
+  # Bind to the wildcard address and share the port with other SO_REUSEPORT listeners
+  mcaster receive --bind any --reuseport on
+
+  # Record received datagrams for Wireshark
+  mcaster receive --write capture.pcapng
+
+  # Reject messages not signed with one of the keys in a key file
+  mcaster receive --auth-key-file keys.txt
+
+  # Decrypt messages encrypted with one of the keys in a key file
+  mcaster receive --encrypt-key-file keys.txt
+
+  # Show other traffic on a group as a hex dump instead of identifying it
+  mcaster receive -g 239.255.255.250:1900 --decode hex
+
+  # Monitor an RTP video feed: loss, reordering and jitter per SSRC
+  mcaster receive -g 239.1.1.1:5004 --rtp
+
+  # Check an IPTV transport stream: continuity, PAT/PMT and PCR (TR 101 290)
+  mcaster receive -g 239.1.1.1:5000 --mpegts
+
+  # Drop packets looped back from a sender on this host
+  mcaster receive --ignore-local
+
+  # Time channel changes: leave every 5s, rejoin after 1s, 20 times
+  mcaster receive -g 239.1.1.1:5000 --rejoin 5s --rejoin-left 1s --rejoin-count 20
+
+  # Leave and rejoin at random times, between half and 1.5 times the periods
+  mcaster receive -g 239.1.1.1:5000 --rejoin 2s --rejoin-random`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			group := viper.GetString("group")
 			iface := viper.GetString("interface")
 			dport := viper.GetInt("dport")
 
-			receiver, err := multicast.NewReceiver(group, iface, dport)
+			group, err := expandGroupList(viper.GetString("group"), viper.GetString("group-range"), dport)
 			if err != nil {
 				return err
 			}
+			ignoreLocal := viper.GetBool("ignore-local")
+
+			reuseAddr, err := parseOnOff("reuseaddr", viper.GetString("reuseaddr"))
+			if err != nil {
+				return err
+			}
+			reusePort, err := parseOnOff("reuseport", viper.GetString("reuseport"))
+			if err != nil {
+				return err
+			}
+
+			decodeMode, err := decode.ParseMode(viper.GetString("decode"))
+			if err != nil {
+				return err
+			}
+
+			opts := []multicast.ReceiverOption{
+				multicast.WithDecodeMode(decodeMode),
+				multicast.WithIgnoreLocal(ignoreLocal),
+				multicast.WithBind(viper.GetString("bind")),
+				multicast.WithReuseAddr(reuseAddr),
+				multicast.WithReusePort(reusePort),
+				multicast.WithUnicastGroups(viper.GetBool("allow-unicast")),
+			}
+
+			ring, err := loadKeyRing()
+			if err != nil {
+				return err
+			}
+			if ring != nil {
+				opts = append(opts, multicast.WithVerifyKeys(ring))
+			}
+
+			cipherRing, err := loadCipherKeyRing()
+			if err != nil {
+				return err
+			}
+			if cipherRing != nil {
+				opts = append(opts, multicast.WithDecryptKeys(cipherRing))
+			}
+
+			if viper.GetBool("rtp") {
+				clockRate := viper.GetInt("rtp-clock-rate")
+				if clockRate < 0 {
+					return fmt.Errorf("RTP clock rate must not be negative, got %d", clockRate)
+				}
+				opts = append(opts, multicast.WithRTPAnalysis(clockRate))
+			}
+			if viper.GetBool("mpegts") {
+				opts = append(opts, multicast.WithTSAnalysis())
+			}
+
+			if joined := viper.GetDuration("rejoin"); joined > 0 {
+				opts = append(opts, multicast.WithRejoin(multicast.RejoinConfig{
+					Joined: joined,
+					Left:   viper.GetDuration("rejoin-left"),
+					Random: viper.GetBool("rejoin-random"),
+					Count:  viper.GetInt("rejoin-count"),
+				}))
+			}
+
+			if path := viper.GetString("write"); path != "" {
+				recorder, err := multicast.OpenRecorder(path)
+				if err != nil {
+					return err
+				}
+				defer recorder.Close()
+				opts = append(opts, multicast.WithRecorder(recorder))
+			}
+
+			receiver, err := multicast.NewReceiver(group, iface, dport, opts...)
+			if err != nil {
+				return err
+			}
+
+			// Stop cleanly on Ctrl+C so recordings are complete
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				receiver.Close()
+			}()
 
 			return receiver.Start()
 		},
 	}
+
+	cmd.Flags().Bool("ignore-local", false, "ignore packets sent from this host instead of labelling them as local echo")
+	cmd.Flags().String("group-range", "", "receive ranges of groups (CIDR or first-last, comma-separated) on the port of --group")
+	cmd.Flags().String("bind", "group", "address to bind to: group, any, or a local IP address")
+	cmd.Flags().String("reuseaddr", "on", "set SO_REUSEADDR on the receiving socket (on|off)")
+	cmd.Flags().String("reuseport", "off", "set SO_REUSEPORT on the receiving socket (on|off)")
+	cmd.Flags().String("decode", "auto", "show datagrams that are not mcaster messages: auto (identify protocols), hex, raw or none")
+	cmd.Flags().Bool("rtp", false, "analyse RTP streams: loss, reordering, jitter, payload type and markers per SSRC")
+	cmd.Flags().Int("rtp-clock-rate", 0, "RTP clock rate in Hz for jitter (0 = from the payload type, 90000 for dynamic types)")
+	cmd.Flags().Bool("mpegts", false, "run TR 101 290 checks on MPEG transport streams: sync, continuity, PAT/PMT and PCR")
+	cmd.Flags().String("write", "", "record received datagrams to a capture (.pcap, .pcapng) or replayable recording (.jsonl)")
+	cmd.Flags().Duration("rejoin", 0, "leave the groups after they were joined this long and join them again, timing the first packet after each join and the last after each leave (0 = stay joined)")
+	cmd.Flags().Duration("rejoin-left", time.Second, "how long the groups stay left before each rejoin")
+	cmd.Flags().Bool("rejoin-random", false, "draw every rejoin period at random between half and 1.5 times its setting")
+	cmd.Flags().Int("rejoin-count", 0, "stop after this many rejoins (0 = until interrupted)")
+	viper.BindPFlag("ignore-local", cmd.Flags().Lookup("ignore-local"))
+	viper.BindPFlag("group-range", cmd.Flags().Lookup("group-range"))
+	viper.BindPFlag("bind", cmd.Flags().Lookup("bind"))
+	viper.BindPFlag("reuseaddr", cmd.Flags().Lookup("reuseaddr"))
+	viper.BindPFlag("reuseport", cmd.Flags().Lookup("reuseport"))
+	viper.BindPFlag("decode", cmd.Flags().Lookup("decode"))
+	viper.BindPFlag("rtp", cmd.Flags().Lookup("rtp"))
+	viper.BindPFlag("rtp-clock-rate", cmd.Flags().Lookup("rtp-clock-rate"))
+	viper.BindPFlag("mpegts", cmd.Flags().Lookup("mpegts"))
+	viper.BindPFlag("write", cmd.Flags().Lookup("write"))
+	viper.BindPFlag("rejoin", cmd.Flags().Lookup("rejoin"))
+	viper.BindPFlag("rejoin-left", cmd.Flags().Lookup("rejoin-left"))
+	viper.BindPFlag("rejoin-random", cmd.Flags().Lookup("rejoin-random"))
+	viper.BindPFlag("rejoin-count", cmd.Flags().Lookup("rejoin-count"))
+
+	return cmd
 }
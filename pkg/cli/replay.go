@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+func newReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay FILE",
+		Short: "Resend the traffic of a capture or recording",
+		Long: `Replay the UDP multicast datagrams of a pcap or pcapng capture, or of a
+jsonl recording written by 'mcaster receive --write', to their original groups
+or to remapped groups and ports. Payloads are sent unchanged.`,
+		Example: `  # Replay a customer capture with its original timing
+  mcaster replay customer.pcap
+
+  # Replay twice as fast, or as fast as possible
+  mcaster replay customer.pcap --speed 2x
+  mcaster replay customer.pcap --speed max
+
+  # Replay only two of the recorded groups
+  mcaster replay customer.pcapng --groups 239.1.1.1,239.1.1.2:5000
+
+  # Move a group and port into the lab range
+  mcaster replay customer.pcapng --map 239.1.1.1:5000=239.255.1.1:6000 --ttl 8 -i eth1
+
+  # Replay an mcaster recording
+  mcaster replay session.jsonl`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bound here rather than at construction so send's flags keep their keys
+			for _, name := range []string{"ttl", "loopback", "source-ip", "bind-device"} {
+				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			speedFlag, _ := cmd.Flags().GetString("speed")
+			speed, err := parseSpeed(speedFlag)
+			if err != nil {
+				return err
+			}
+
+			groupsFlag, _ := cmd.Flags().GetString("groups")
+			groups, err := parseEndpoints(groupsFlag)
+			if err != nil {
+				return err
+			}
+
+			mapFlags, _ := cmd.Flags().GetStringArray("map")
+			var mappings []multicast.GroupMapping
+			for _, spec := range mapFlags {
+				mapping, err := multicast.ParseGroupMapping(spec)
+				if err != nil {
+					return err
+				}
+				mappings = append(mappings, mapping)
+			}
+
+			senderOpts, err := senderOptions()
+			if err != nil {
+				return err
+			}
+
+			replayer, err := multicast.NewReplayer(args[0], viper.GetString("interface"),
+				viper.GetInt("ttl"), viper.GetInt("dport"),
+				multicast.WithSpeed(speed),
+				multicast.WithGroupFilter(groups),
+				multicast.WithGroupMappings(mappings),
+				multicast.WithReplaySenderOptions(senderOpts...))
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				replayer.Close()
+			}()
+
+			return replayer.Start()
+		},
+	}
+
+	cmd.Flags().String("speed", "1x", "replay speed: 1x for the original timing, 2x, 0.5x, or max for as fast as possible")
+	cmd.Flags().String("groups", "", "only replay these groups (comma-separated address, address:port or :port)")
+	cmd.Flags().StringArray("map", nil, "rewrite a destination, FROM=TO (address, address:port or :port); repeatable")
+	cmd.Flags().Int("ttl", 1, "TTL (Time To Live) for replayed packets (1-255)")
+	cmd.Flags().String("loopback", "on", "deliver replayed packets to receivers on this host (on|off)")
+	cmd.Flags().String("source-ip", "", "local source address to send from (must be configured on the interface)")
+	cmd.Flags().Bool("bind-device", false, "restrict the socket to the interface with SO_BINDTODEVICE (Linux only)")
+
+	return cmd
+}
+
+// parseSpeed converts a replay speed such as 2x, 0.5 or max to a factor; 0
+// means as fast as possible
+func parseSpeed(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "max" {
+		return 0, nil
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed <= 0 || math.IsNaN(speed) || math.IsInf(speed, 0) {
+		return 0, fmt.Errorf("--speed must be a positive factor such as 2x, or max, got %q", value)
+	}
+	return speed, nil
+}
+
+// parseEndpoints parses a comma-separated list of address, address:port or :port
+func parseEndpoints(list string) ([]*net.UDPAddr, error) {
+	var endpoints []*net.UDPAddr
+	for _, spec := range strings.Split(list, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		endpoint, err := network.ParseEndpoint(spec)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
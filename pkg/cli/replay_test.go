@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    float64
+		expectError bool
+	}{
+		{value: "1x", expected: 1},
+		{value: "2x", expected: 2},
+		{value: "0.5x", expected: 0.5},
+		{value: "3", expected: 3},
+		{value: "MAX", expected: 0},
+		{value: "0x", expectError: true},
+		{value: "-2x", expectError: true},
+		{value: "fast", expectError: true},
+		{value: "nan", expectError: true},
+		{value: "inf", expectError: true},
+		{value: "+Infx", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			speed, err := parseSpeed(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, speed)
+		})
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := parseEndpoints("239.1.1.1, 239.1.1.2:5000,:6000")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 3)
+	assert.Equal(t, "239.1.1.1", endpoints[0].IP.String())
+	assert.Equal(t, 5000, endpoints[1].Port)
+	assert.Nil(t, endpoints[2].IP)
+
+	endpoints, err = parseEndpoints("")
+	require.NoError(t, err)
+	assert.Empty(t, endpoints)
+
+	_, err = parseEndpoints("239.1.1.1,bogus")
+	assert.Error(t, err)
+}
@@ -21,6 +21,8 @@ Examples:
   mcaster receive                        # Receive from default group
   mcaster send -g 224.0.1.1:8080        # Send to specific group
   mcaster receive -i eth0                # Receive via specific interface
+  mcaster mac 239.1.1.1                  # Show the MAC address a group maps to
+  mcaster replay capture.pcap            # Resend the traffic of a capture
   MULTICAST_GROUP=239.23.23.23:2323 mcaster send  # Use environment variable`,
 	}
 )
@@ -35,14 +37,20 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mcaster.yaml)")
-	rootCmd.PersistentFlags().StringP("group", "g", "239.23.23.23:2323", "multicast group address:port")
-	rootCmd.PersistentFlags().StringP("interface", "i", "", "network interface name")
+	rootCmd.PersistentFlags().StringP("group", "g", "239.23.23.23:2323", "multicast group address:port (receive accepts a comma-separated list)")
+	rootCmd.PersistentFlags().StringP("interface", "i", "", "network interface name, index or IP address")
 	rootCmd.PersistentFlags().IntP("dport", "d", 0, "destination port (overrides port in group address)")
+	rootCmd.PersistentFlags().Bool("allow-unicast", false, "accept a unicast address in place of a multicast group")
+	rootCmd.PersistentFlags().String("auth-key-file", "", "sign (send) or verify (receive) messages with the HMAC keys in this file")
+	rootCmd.PersistentFlags().String("encrypt-key-file", "", "encrypt (send) or decrypt (receive) messages with the AES-GCM keys in this file")
 
 	// Bind flags to viper
 	viper.BindPFlag("group", rootCmd.PersistentFlags().Lookup("group"))
 	viper.BindPFlag("interface", rootCmd.PersistentFlags().Lookup("interface"))
 	viper.BindPFlag("dport", rootCmd.PersistentFlags().Lookup("dport"))
+	viper.BindPFlag("allow-unicast", rootCmd.PersistentFlags().Lookup("allow-unicast"))
+	viper.BindPFlag("auth-key-file", rootCmd.PersistentFlags().Lookup("auth-key-file"))
+	viper.BindPFlag("encrypt-key-file", rootCmd.PersistentFlags().Lookup("encrypt-key-file"))
 
 	// Environment variable bindings
 	viper.SetEnvPrefix("MULTICAST")
@@ -52,10 +60,55 @@ func init() {
 	viper.BindEnv("ttl", "MULTICAST_TTL")
 	viper.BindEnv("sport", "MULTICAST_SPORT")
 	viper.BindEnv("dport", "MULTICAST_DPORT")
+	viper.BindEnv("allow-unicast", "MULTICAST_ALLOW_UNICAST")
+	viper.BindEnv("loopback", "MULTICAST_LOOPBACK")
+	viper.BindEnv("ignore-local", "MULTICAST_IGNORE_LOCAL")
+	viper.BindEnv("source-ip", "MULTICAST_SOURCE_IP")
+	viper.BindEnv("bind-device", "MULTICAST_BIND_DEVICE")
+	viper.BindEnv("bind", "MULTICAST_BIND")
+	viper.BindEnv("reuseaddr", "MULTICAST_REUSEADDR")
+	viper.BindEnv("reuseport", "MULTICAST_REUSEPORT")
+	viper.BindEnv("decode", "MULTICAST_DECODE")
+	viper.BindEnv("rtp", "MULTICAST_RTP")
+	viper.BindEnv("rtp-clock-rate", "MULTICAST_RTP_CLOCK_RATE")
+	viper.BindEnv("rtp-payload-type", "MULTICAST_RTP_PAYLOAD_TYPE")
+	viper.BindEnv("rtp-ssrc", "MULTICAST_RTP_SSRC")
+	viper.BindEnv("rtp-packets-per-frame", "MULTICAST_RTP_PACKETS_PER_FRAME")
+	viper.BindEnv("rtp-frame-rate", "MULTICAST_RTP_FRAME_RATE")
+	viper.BindEnv("mpegts", "MULTICAST_MPEGTS")
+	viper.BindEnv("mpegts-bitrate", "MULTICAST_MPEGTS_BITRATE")
+	viper.BindEnv("mpegts-program", "MULTICAST_MPEGTS_PROGRAM")
+	viper.BindEnv("mpegts-pmt-pid", "MULTICAST_MPEGTS_PMT_PID")
+	viper.BindEnv("mpegts-pid", "MULTICAST_MPEGTS_PID")
+	viper.BindEnv("announce", "MULTICAST_ANNOUNCE")
+	viper.BindEnv("announce-interval", "MULTICAST_ANNOUNCE_INTERVAL")
+	viper.BindEnv("sap-group", "MULTICAST_SAP_GROUP")
+	viper.BindEnv("group-range", "MULTICAST_GROUP_RANGE")
+	viper.BindEnv("sender-id", "MULTICAST_SENDER_ID")
+	viper.BindEnv("stream-id", "MULTICAST_STREAM_ID")
+	viper.BindEnv("payload-file", "MULTICAST_PAYLOAD_FILE")
+	viper.BindEnv("auth-key-file", "MULTICAST_AUTH_KEY_FILE")
+	viper.BindEnv("auth-key-id", "MULTICAST_AUTH_KEY_ID")
+	viper.BindEnv("encrypt-key-file", "MULTICAST_ENCRYPT_KEY_FILE")
+	viper.BindEnv("encrypt-key-id", "MULTICAST_ENCRYPT_KEY_ID")
+	viper.BindEnv("rejoin", "MULTICAST_REJOIN")
+	viper.BindEnv("rejoin-left", "MULTICAST_REJOIN_LEFT")
+	viper.BindEnv("rejoin-random", "MULTICAST_REJOIN_RANDOM")
+	viper.BindEnv("rejoin-count", "MULTICAST_REJOIN_COUNT")
 
 	// Add subcommands
 	rootCmd.AddCommand(newSendCmd())
 	rootCmd.AddCommand(newReceiveCmd())
+	rootCmd.AddCommand(newMacCmd())
+	rootCmd.AddCommand(newReplayCmd())
+	rootCmd.AddCommand(newSAPCmd())
+	rootCmd.AddCommand(newScanCmd())
+	rootCmd.AddCommand(newTopCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newAgentCmd())
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(newMeshCmd())
+	rootCmd.AddCommand(newScenarioCmd())
 }
 
 func initConfig() {
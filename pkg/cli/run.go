@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hyposcaler-bot/mcaster/internal/controller"
+)
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run plan.yaml",
+		Short: "Run a test plan across hosts through their agents",
+		Long: `Run the senders and receivers of a plan on the hosts running "mcaster agent":
+start every receiver first, wait for the joins to settle, send for the plan's
+duration, then print a matrix of which receivers got which groups with loss and
+delay. The exit status is non-zero if a receiver got nothing of a group it joined.
+
+A plan names the agents and lists the senders and receivers, which take the
+parameters of the job API (see "mcaster serve --help"):
+
+  duration: 10s          # how long the senders send
+  settle: 2s             # between starting the receivers and the senders
+  drain: 1s              # between stopping the senders and the receivers
+  agents:
+    src: 192.0.2.10:7400
+    leaf1: 192.0.2.21:7400
+    leaf2: 192.0.2.22:7400
+  senders:
+    - agent: src
+      group: 239.1.1.1:5000
+      interface: eth0
+      interval: 100ms
+      ttl: 16
+  receivers:
+    - agent: leaf1
+      group: 239.1.1.1:5000
+    - agent: leaf2
+      group: 239.1.1.1:5000`,
+		Example: `  # Run a plan
+  mcaster run tree.yaml
+
+  # Run a plan on agents requiring a token
+  MULTICAST_API_TOKEN=s3cret mcaster run tree.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := controller.Load(args[0])
+			if err != nil {
+				return err
+			}
+			plan.Token = apiToken(cmd)
+
+			// Ctrl+C stops the senders early; the results are still collected
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			results, err := controller.Run(ctx, plan, os.Stdout)
+			if err != nil {
+				return err
+			}
+			results.Print(os.Stdout)
+
+			if missing := results.Missing(); missing > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d joined group(s) not received", missing)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("token", "", "bearer token the agents require (default $MULTICAST_API_TOKEN)")
+
+	return cmd
+}
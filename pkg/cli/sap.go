@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+)
+
+func newSAPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sap",
+		Short: "Work with SAP (RFC 2974) session announcements",
+	}
+	cmd.AddCommand(newSAPListenCmd())
+	return cmd
+}
+
+func newSAPListenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "listen",
+		Short: "List the sessions announced with SAP",
+		Long: `Join the SAP group and list the sessions announced on it, such as those of
+"mcaster send --announce" or IPTV headends, with the receive command for each
+of their streams. Deletions and updated descriptions are shown as they arrive,
+and the sessions still announced are listed on exit.`,
+		Example: `  # List sessions on the global IPv4 SAP group until Ctrl+C
+  mcaster sap listen -i eth0
+
+  # Collect announcements for a minute, then list them
+  mcaster sap listen --duration 1m
+
+  # Listen on the administratively scoped SAP group of 239.255.0.0/16
+  mcaster sap listen --sap-group 239.255.255.255:9875`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bound here rather than at construction so send's flag keeps its key
+			return viper.BindPFlag("sap-group", cmd.Flags().Lookup("sap-group"))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			duration, _ := cmd.Flags().GetDuration("duration")
+
+			receiver, err := multicast.NewReceiver(viper.GetString("sap-group"), viper.GetString("interface"), 0,
+				multicast.WithSAPListing())
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+			go func() {
+				<-ctx.Done()
+				receiver.Close()
+			}()
+
+			return receiver.Start()
+		},
+	}
+
+	cmd.Flags().String("sap-group", multicast.DefaultSAPGroup, "SAP group and port to listen on")
+	cmd.Flags().Duration("duration", 0, "stop and list the sessions after this long (0 = until Ctrl+C)")
+
+	return cmd
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/network"
+)
+
+func newScanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Find the groups with traffic in a range",
+		Long: `Join the groups of a range in batches, as many at a time as one socket may
+join (net.ipv4.igmp_max_memberships on Linux), listen to each batch for the
+dwell time and report the groups with traffic: packet and bit rates, sources
+and the detected payload type.`,
+		Example: `  # Find streams in 239.0.0.0/16 on ports 5000-5010
+  mcaster scan --range 239.0.0.0/16 --ports 5000-5010 --dwell 2s -i eth0
+
+  # Quickly check a few groups on the port of --group
+  mcaster scan --range 239.1.1.1-32 --dwell 500ms`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groupRange, _ := cmd.Flags().GetString("range")
+			portSpec, _ := cmd.Flags().GetString("ports")
+			dwell, _ := cmd.Flags().GetDuration("dwell")
+			batchSize, _ := cmd.Flags().GetInt("batch")
+
+			if groupRange == "" {
+				return fmt.Errorf("--range is required")
+			}
+			groups, err := expandGroupRanges(groupRange)
+			if err != nil {
+				return err
+			}
+
+			if portSpec == "" {
+				configured, err := network.ResolveGroups(viper.GetString("group"), viper.GetInt("dport"))
+				if err != nil {
+					return err
+				}
+				portSpec = strconv.Itoa(configured[0].Port)
+			}
+			ports, err := network.ParsePorts(portSpec)
+			if err != nil {
+				return err
+			}
+
+			scanner, err := multicast.NewScanner(groups, ports, viper.GetString("interface"), dwell,
+				multicast.WithBatchSize(batchSize))
+			if err != nil {
+				return err
+			}
+
+			// Stop on Ctrl+C and still list what was found
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				scanner.Close()
+			}()
+
+			return scanner.Start()
+		},
+	}
+
+	cmd.Flags().String("range", "", "groups to scan (CIDR or first-last, comma-separated)")
+	cmd.Flags().String("ports", "", "ports to scan, e.g. 5000-5010,5500 (default: the port of --group)")
+	cmd.Flags().Duration("dwell", 2*time.Second, "time to listen to each batch of groups")
+	cmd.Flags().Int("batch", 0, "groups to join at a time (0 = the kernel's membership limit)")
+
+	return cmd
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/scenario"
+)
+
+func newScenarioCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Run scripted tests with phases, actions and expectations",
+	}
+	cmd.AddCommand(newScenarioRunCmd())
+	return cmd
+}
+
+func newScenarioRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run file.yaml",
+		Short: "Run a scenario and report every phase",
+		Long: `Run a scenario on this host: join its receivers, send its streams and go
+through its phases in order. Each phase first runs its actions, such as leaving
+and rejoining a group, then lasts its duration; its expectations are checked
+against what every receiver got of every stream during the phase. The report is
+printed at the end and can be saved as JSON or YAML with --report. The exit
+status is non-zero if an expectation failed.
+
+Streams and receivers take the parameters of the job API (see
+"mcaster serve --help"):
+
+  name: leaf failover
+  interface: eth0
+  streams:
+    - name: video
+      group: 239.1.1.1:5000
+      interval: 100ms
+  receivers:
+    - name: leaf
+      group: 239.1.1.1:5000
+  phases:
+    - name: warmup
+      duration: 2s
+    - name: steady
+      duration: 10s
+      expect:
+        - max_loss: 0
+          max_delay: 20ms
+    - name: burst
+      duration: 5s
+      intervals: {video: 10ms}
+      expect:
+        - max_loss: 1
+    - name: rejoin
+      actions:
+        - leave: leaf
+        - wait: 5s
+        - join: leaf
+      duration: 5s
+      expect:
+        - receiver: leaf
+          stream: video
+          received: true
+    - name: cooldown
+      duration: 2s
+
+Actions are leave and join for receivers, stop and start for streams, and
+wait. What arrives while a phase runs its actions counts towards the phase; a
+phase with actions and no duration keeps them apart from the phase after it.
+Messages missed while a receiver was away count as lost once it joins again.
+Expectations apply to every receiver and stream unless they name one, and take
+max_loss (percent), max_delay (99th percentile) and received (true or false).`,
+		Example: `  # Run a scenario
+  mcaster scenario run failover.yaml
+
+  # Keep the report next to the scenario
+  mcaster scenario run failover.yaml --report failover-report.json
+
+  # Run a scenario without interfaces on the loopback interface
+  mcaster scenario run failover.yaml -i lo`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reportPath, _ := cmd.Flags().GetString("report")
+
+			sc, err := scenario.Load(args[0])
+			if err != nil {
+				return err
+			}
+			if iface := viper.GetString("interface"); iface != "" {
+				sc.DefaultInterface(iface)
+			}
+
+			// Ctrl+C ends the run early; the phases so far are still reported
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			report, err := scenario.Run(ctx, sc, os.Stdout)
+			if err != nil {
+				return err
+			}
+			report.Print(os.Stdout)
+			if reportPath != "" {
+				if err := report.Write(reportPath); err != nil {
+					return err
+				}
+				fmt.Printf("💾 Report saved to %s\n", reportPath)
+			}
+
+			if !report.Passed {
+				cmd.SilenceUsage = true
+				if failed := report.Failed(); failed > 0 {
+					return fmt.Errorf("%d of %d check(s) failed", failed, report.Checks())
+				}
+				return fmt.Errorf("scenario did not complete")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("report", "", "Save the report to this file, as YAML if it ends in .yaml or .yml and as JSON otherwise")
+
+	return cmd
+}
@@ -1,11 +1,18 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/hyposcaler-bot/mcaster/internal/mpegts"
 	"github.com/hyposcaler-bot/mcaster/internal/multicast"
 )
 
@@ -31,7 +38,49 @@ Each packet contains an incrementing ID, timestamp, and source hostname.`,
   mcaster send --sport 12345
 
   # Send to specific destination port
-  mcaster send --dport 8080`,
+  mcaster send --dport 8080
+
+  # Send via interface index 3 using one of its secondary addresses
+  mcaster send -i 3 --source-ip 192.0.2.20
+
+  # Restrict the socket to an interface with SO_BINDTODEVICE (Linux)
+  mcaster send -i eth0 --bind-device
+
+  # Keep packets from being delivered to receivers on this host
+  mcaster send --loopback off
+
+  # Sign messages with the key k2 from a key file
+  mcaster send --auth-key-file keys.txt --auth-key-id k2
+
+  # Label the messages of this sender, e.g. one of several on the same host
+  mcaster send --sender-id probe-a --stream-id video-1
+
+  # Tag messages with a change ticket and carry a file in each of them
+  mcaster send --label run=chg1234 --label site=lab2 --payload-file probe.bin
+
+  # Encrypt messages with the first key from a key file
+  mcaster send --encrypt-key-file keys.txt
+
+  # Send an RTP stream like 25 fps video, 4 packets per frame
+  mcaster send -g 239.1.1.1:5004 --rtp --rtp-packets-per-frame 4
+
+  # Send RTP like 20ms G.711 audio frames
+  mcaster send -g 239.1.1.1:5004 --rtp --rtp-payload-type 0 --rtp-frame-rate 50
+
+  # Send an MPEG-TS channel at 4 Mbit/s that set-top boxes can tune to
+  mcaster send -g 239.1.1.1:5000 --mpegts --mpegts-bitrate 4000000
+
+  # Announce the stream with SAP so "mcaster sap listen" and IPTV players find it
+  mcaster send -g 239.1.1.1:5000 --mpegts --announce`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bound here rather than at construction so receive's flags keep their keys
+			for _, name := range []string{"rtp", "rtp-clock-rate", "mpegts", "sap-group"} {
+				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			group := viper.GetString("group")
 			iface := viper.GetString("interface")
@@ -40,21 +89,193 @@ Each packet contains an incrementing ID, timestamp, and source hostname.`,
 			sport := viper.GetInt("sport")
 			dport := viper.GetInt("dport")
 
-			sender, err := multicast.NewSender(group, iface, interval, ttl, sport, dport)
+			opts, err := senderOptions()
+			if err != nil {
+				return err
+			}
+			labels, err := parseLabels(viper.GetStringSlice("label"))
+			if err != nil {
+				return err
+			}
+			payload, err := readPayload(viper.GetString("payload-file"))
+			if err != nil {
+				return err
+			}
+			opts = append(opts,
+				multicast.WithSenderID(viper.GetString("sender-id")),
+				multicast.WithStreamID(viper.GetString("stream-id")),
+				multicast.WithLabels(labels),
+				multicast.WithPayload(payload))
+
+			if viper.GetBool("rtp") {
+				pt := viper.GetInt("rtp-payload-type")
+				if pt < 0 || pt > 127 {
+					return fmt.Errorf("RTP payload type must be between 0 and 127, got %d", pt)
+				}
+				opts = append(opts, multicast.WithRTP(multicast.RTPConfig{
+					PayloadType:     uint8(pt),
+					ClockRate:       viper.GetInt("rtp-clock-rate"),
+					SSRC:            viper.GetUint32("rtp-ssrc"),
+					PacketsPerFrame: viper.GetInt("rtp-packets-per-frame"),
+					FrameRate:       viper.GetFloat64("rtp-frame-rate"),
+				}))
+			}
+
+			if viper.GetBool("mpegts") {
+				opts = append(opts, multicast.WithMPEGTS(mpegts.MuxConfig{
+					Program: viper.GetUint16("mpegts-program"),
+					PMTPID:  viper.GetUint16("mpegts-pmt-pid"),
+					PID:     viper.GetUint16("mpegts-pid"),
+					Bitrate: viper.GetInt("mpegts-bitrate"),
+				}))
+			}
+
+			ring, err := loadKeyRing()
+			if err != nil {
+				return err
+			}
+			if ring != nil {
+				key, err := ring.Key(viper.GetString("auth-key-id"))
+				if err != nil {
+					return err
+				}
+				opts = append(opts, multicast.WithSigningKey(key))
+			}
+
+			cipherRing, err := loadCipherKeyRing()
+			if err != nil {
+				return err
+			}
+			if cipherRing != nil {
+				key, err := cipherRing.Key(viper.GetString("encrypt-key-id"))
+				if err != nil {
+					return err
+				}
+				opts = append(opts, multicast.WithEncryptionKey(key))
+			}
+
+			sender, err := multicast.NewSender(group, iface, interval, ttl, sport, dport, opts...)
+			if err != nil {
+				return err
+			}
+
+			if !viper.GetBool("announce") {
+				return sender.Start()
+			}
+
+			announcer, err := multicast.NewAnnouncer(sender, viper.GetString("sap-group"), viper.GetDuration("announce-interval"))
 			if err != nil {
+				sender.Close()
 				return err
 			}
 
-			return sender.Start()
+			// Stop on Ctrl+C after telling listeners the session is gone
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			announced := make(chan struct{})
+			go func() {
+				announcer.Run(ctx)
+				close(announced)
+			}()
+			sent := make(chan error, 1)
+			go func() {
+				sent <- sender.Start()
+			}()
+
+			select {
+			case err = <-sent:
+				stop()
+			case <-ctx.Done():
+			}
+			<-announced
+			return err
 		},
 	}
 
 	cmd.Flags().DurationP("interval", "t", time.Second, "send interval")
 	cmd.Flags().IntP("ttl", "", 1, "TTL (Time To Live) for multicast packets (1-255)")
 	cmd.Flags().IntP("sport", "s", 0, "source port for sending packets (0 = random)")
+	cmd.Flags().String("loopback", "on", "deliver sent packets to receivers on this host (on|off)")
+	cmd.Flags().String("source-ip", "", "local source address to send from (must be configured on the interface)")
+	cmd.Flags().Bool("bind-device", false, "restrict the socket to the interface with SO_BINDTODEVICE (Linux only)")
+	cmd.Flags().String("sender-id", "", "label for this sender, carried in every message")
+	cmd.Flags().String("stream-id", "", "stream name carried in every message (default: the group address)")
+	cmd.Flags().StringArray("label", nil, "key=value label carried in every message (repeatable)")
+	cmd.Flags().String("payload-file", "", "carry the contents of this file in every message")
+	cmd.Flags().String("auth-key-id", "", "key from --auth-key-file to sign with (default: the first key)")
+	cmd.Flags().String("encrypt-key-id", "", "key from --encrypt-key-file to encrypt with (default: the first key)")
+	cmd.Flags().Bool("rtp", false, "send each message as the payload of an RTP packet, in frames at --rtp-frame-rate instead of every --interval")
+	cmd.Flags().Int("rtp-payload-type", 96, "RTP payload type (0-127)")
+	cmd.Flags().Int("rtp-clock-rate", 0, "RTP timestamp clock rate in Hz (0 = from the payload type, 90000 for dynamic types)")
+	cmd.Flags().Uint32("rtp-ssrc", 0, "RTP SSRC, decimal or 0x hex (0 = random)")
+	cmd.Flags().Int("rtp-packets-per-frame", 1, "RTP packets per frame; the last one has the marker bit set")
+	cmd.Flags().Float64("rtp-frame-rate", 25, "RTP frames per second")
+	cmd.Flags().Bool("mpegts", false, "send each message in a constant bitrate MPEG transport stream with PAT, PMT and PCR")
+	cmd.Flags().Int("mpegts-bitrate", 2000000, "MPEG-TS bitrate in bit/s")
+	cmd.Flags().Uint16("mpegts-program", 1, "MPEG-TS program number")
+	cmd.Flags().Uint16("mpegts-pmt-pid", 0x1000, "MPEG-TS PID of the PMT, decimal or 0x hex")
+	cmd.Flags().Uint16("mpegts-pid", 0x100, "MPEG-TS PID carrying the messages and the PCR, decimal or 0x hex")
+	cmd.Flags().Bool("announce", false, "announce the stream with SAP (RFC 2974) for \"mcaster sap listen\" and players")
+	cmd.Flags().Duration("announce-interval", 10*time.Second, "time between SAP announcements, randomised by ±1/3")
+	cmd.Flags().String("sap-group", multicast.DefaultSAPGroup, "SAP group and port to announce to")
 	viper.BindPFlag("interval", cmd.Flags().Lookup("interval"))
 	viper.BindPFlag("ttl", cmd.Flags().Lookup("ttl"))
 	viper.BindPFlag("sport", cmd.Flags().Lookup("sport"))
+	viper.BindPFlag("loopback", cmd.Flags().Lookup("loopback"))
+	viper.BindPFlag("source-ip", cmd.Flags().Lookup("source-ip"))
+	viper.BindPFlag("bind-device", cmd.Flags().Lookup("bind-device"))
+	viper.BindPFlag("sender-id", cmd.Flags().Lookup("sender-id"))
+	viper.BindPFlag("stream-id", cmd.Flags().Lookup("stream-id"))
+	viper.BindPFlag("label", cmd.Flags().Lookup("label"))
+	viper.BindPFlag("payload-file", cmd.Flags().Lookup("payload-file"))
+	viper.BindPFlag("auth-key-id", cmd.Flags().Lookup("auth-key-id"))
+	viper.BindPFlag("encrypt-key-id", cmd.Flags().Lookup("encrypt-key-id"))
+	viper.BindPFlag("rtp-payload-type", cmd.Flags().Lookup("rtp-payload-type"))
+	viper.BindPFlag("rtp-ssrc", cmd.Flags().Lookup("rtp-ssrc"))
+	viper.BindPFlag("rtp-packets-per-frame", cmd.Flags().Lookup("rtp-packets-per-frame"))
+	viper.BindPFlag("rtp-frame-rate", cmd.Flags().Lookup("rtp-frame-rate"))
+	viper.BindPFlag("mpegts-bitrate", cmd.Flags().Lookup("mpegts-bitrate"))
+	viper.BindPFlag("mpegts-program", cmd.Flags().Lookup("mpegts-program"))
+	viper.BindPFlag("mpegts-pmt-pid", cmd.Flags().Lookup("mpegts-pmt-pid"))
+	viper.BindPFlag("mpegts-pid", cmd.Flags().Lookup("mpegts-pid"))
+	viper.BindPFlag("announce", cmd.Flags().Lookup("announce"))
+	viper.BindPFlag("announce-interval", cmd.Flags().Lookup("announce-interval"))
 
 	return cmd
 }
+
+// senderOptions builds the sender options shared by every sending command
+func senderOptions() ([]multicast.SenderOption, error) {
+	loopback, err := parseOnOff("loopback", viper.GetString("loopback"))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []multicast.SenderOption{
+		multicast.WithLoopback(loopback),
+		multicast.WithBindToDevice(viper.GetBool("bind-device")),
+		multicast.WithUnicastDestination(viper.GetBool("allow-unicast")),
+	}
+
+	if sourceIP := viper.GetString("source-ip"); sourceIP != "" {
+		ip := net.ParseIP(sourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", sourceIP)
+		}
+		opts = append(opts, multicast.WithSourceIP(ip))
+	}
+
+	return opts, nil
+}
+
+// parseOnOff converts an on|off flag value to a bool
+func parseOnOff(name, value string) (bool, error) {
+	switch value {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("--%s must be on or off, got %q", name, value)
+	}
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hyposcaler-bot/mcaster/internal/server"
+)
+
+// shutdownTimeout bounds how long open requests may take once the server stops
+const shutdownTimeout = 5 * time.Second
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run senders and receivers as jobs controlled over a REST API",
+		Long: `Run as a daemon that creates, lists and stops sender and receiver jobs on
+request. Jobs take the parameters of "mcaster send" and "mcaster receive",
+run concurrently and keep their stats once stopped. Their output and state
+changes are streamed as server-sent events.
+
+  POST   /api/jobs               create a job, e.g. {"type":"receiver","group":"239.1.1.1:5000"}
+  GET    /api/jobs               list the jobs
+  GET    /api/jobs/{id}          describe a job
+  POST   /api/jobs/{id}/stop     stop a job, keeping its results
+  DELETE /api/jobs/{id}          stop a job and forget it
+  GET    /api/jobs/{id}/stats    live stats: sent counts or per-stream loss, jitter and delay
+  GET    /api/jobs/{id}/summary  totals of a job
+  GET    /api/events[?job={id}]  output and state changes (text/event-stream)
+
+Anyone who can reach the API can start senders with any TTL, rate and
+destination, so it listens on 127.0.0.1 by default. To serve other hosts,
+listen on their network and require a token with --token or
+MULTICAST_API_TOKEN; requests must then carry "Authorization: Bearer <token>".`,
+		Example: `  # Serve the API on localhost port 8080
+  mcaster serve
+
+  # Serve the API to other hosts, requiring a token
+  MULTICAST_API_TOKEN=s3cret mcaster serve --listen :8080
+
+  # Start a sender for 30 seconds and follow its output
+  curl -d '{"type":"sender","group":"239.1.1.1:5000","interface":"eth0","duration":"30s"}' localhost:8080/api/jobs
+  curl -N localhost:8080/api/events?job=job-1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+
+			return serveAPI(listen, apiToken(cmd), "job API")
+		},
+	}
+
+	cmd.Flags().String("listen", "127.0.0.1:8080", "address and port to serve the API on")
+	cmd.Flags().String("token", "", "require this bearer token on every request (default $MULTICAST_API_TOKEN)")
+
+	return cmd
+}
+
+// apiToken returns the token of --token, or of MULTICAST_API_TOKEN, which
+// keeps it out of the process list
+func apiToken(cmd *cobra.Command) string {
+	if token, _ := cmd.Flags().GetString("token"); token != "" {
+		return token
+	}
+	return os.Getenv("MULTICAST_API_TOKEN")
+}
+
+// serveAPI serves the job API on listen until Ctrl+C, then stops every job.
+// A token, if set, is required on every request.
+func serveAPI(listen, token, what string) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+
+	manager := server.NewManager(os.Stdout)
+	// Requests are cancelled on shutdown, which ends the event streams
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Handler:     server.RequireToken(server.NewHandler(manager), token),
+		BaseContext: func(net.Listener) context.Context { return requests },
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+	fmt.Printf("🛰️  Serving the %s on http://%s/api/jobs\n", what, listener.Addr())
+	if token != "" {
+		fmt.Printf("🔐 Requiring a bearer token\n")
+	} else if !listener.Addr().(*net.TCPAddr).IP.IsLoopback() {
+		fmt.Printf("⚠️  Other hosts can reach the %s without a token; set --token to require one\n", what)
+	}
+	fmt.Printf("⏹️  Press Ctrl+C to stop\n\n")
+
+	select {
+	case err := <-served:
+		manager.Shutdown()
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Printf("\n🛑 Stopping all jobs\n")
+	manager.Shutdown()
+	cancelRequests()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop the server: %w", err)
+	}
+	return nil
+}
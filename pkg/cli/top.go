@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/top"
+)
+
+func newTopCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show received streams in a full-screen table",
+		Long: `Receive like "mcaster receive", but instead of a line per packet show a table
+of the streams, one row per group and sender, refreshed every second: packet
+and bit rates, loss, jitter, delay percentiles, time since the last packet,
+TTL and a sparkline of the packet rate. Keys 1-9 and 0 sort by a column, /
+filters by group or sender and q quits; the stream summary follows on exit.`,
+		Example: `  # Watch the streams of a range of groups
+  mcaster top --group-range 239.1.1.0/28 -g 239.1.1.0:5000 -i eth0
+
+  # Refresh twice a second
+  mcaster top -g 239.1.1.1:5000 --refresh 500ms`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groupRange, _ := cmd.Flags().GetString("group-range")
+			refresh, _ := cmd.Flags().GetDuration("refresh")
+			if refresh <= 0 {
+				return fmt.Errorf("refresh interval must be positive, got %v", refresh)
+			}
+
+			group, err := expandGroupList(viper.GetString("group"), groupRange, viper.GetInt("dport"))
+			if err != nil {
+				return err
+			}
+
+			opts := []multicast.ReceiverOption{
+				multicast.WithQuiet(),
+				multicast.WithUnicastGroups(viper.GetBool("allow-unicast")),
+			}
+			ring, err := loadKeyRing()
+			if err != nil {
+				return err
+			}
+			if ring != nil {
+				opts = append(opts, multicast.WithVerifyKeys(ring))
+			}
+			cipherRing, err := loadCipherKeyRing()
+			if err != nil {
+				return err
+			}
+			if cipherRing != nil {
+				opts = append(opts, multicast.WithDecryptKeys(cipherRing))
+			}
+
+			receiver, err := multicast.NewReceiver(group, viper.GetString("interface"), viper.GetInt("dport"), opts...)
+			if err != nil {
+				return err
+			}
+
+			term, err := top.OpenTerminal(os.Stdin, os.Stdout)
+			if err != nil {
+				receiver.Close()
+				return err
+			}
+
+			received := make(chan error, 1)
+			go func() {
+				received <- receiver.Start()
+			}()
+
+			err = runTop(term, receiver, refresh)
+
+			// The summary is printed once the normal screen is back
+			term.Close()
+			receiver.Close()
+			if startErr := <-received; err == nil {
+				err = startErr
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().String("group-range", "", "receive ranges of groups (CIDR or first-last, comma-separated) on the port of --group")
+	cmd.Flags().Duration("refresh", time.Second, "time between screen updates")
+
+	return cmd
+}
+
+// runTop redraws the table until q, Ctrl+C or SIGTERM
+func runTop(term *top.Terminal, receiver *multicast.Receiver, refresh time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	model := top.NewModel()
+	draw := func() {
+		width, height := term.Size()
+		term.Draw(model.Render(width, height, time.Now()))
+	}
+	model.Update(receiver.Streams(), time.Now())
+	draw()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	keys := term.Keys()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			model.Update(receiver.Streams(), now)
+			draw()
+		case key, ok := <-keys:
+			if !ok || model.HandleKey(key) {
+				return nil
+			}
+			draw()
+		}
+	}
+}
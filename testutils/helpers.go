@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/hyposcaler-bot/mcaster/internal/multicast"
+	"github.com/hyposcaler-bot/mcaster/internal/network"
 )
 
 // CreateTestMessage creates a test message with the given ID
@@ -31,7 +32,7 @@ func CreateTestMessageWithTime(id int, timestamp time.Time) *multicast.Message {
 func AssertValidMulticastAddr(t *testing.T, addr string) {
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	assert.NoError(t, err, "Address should be resolvable")
-	assert.True(t, udpAddr.IP.IsMulticast(), "Address should be multicast")
+	assert.NoError(t, network.ValidateGroup(udpAddr.IP, false), "Address should be multicast")
 }
 
 // AssertValidPort validates that a port is in the valid range